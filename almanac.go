@@ -1,9 +1,15 @@
 package rulesengine
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/tidwall/gjson"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type EventOutcome string
@@ -17,18 +23,62 @@ const (
 // It allows storing raw facts, caching results of rules, and logging events (success/failure).
 // The Almanac plays a key role in the rules engine by allowing rules to evaluate facts efficiently.
 type Almanac struct {
-	factMap             FactMap                  // A map storing facts for quick lookup
-	allowUndefinedFacts bool                     // Flag to allow or disallow undefined facts
-	events              map[EventOutcome][]Event // Maps success or failure outcomes to their events
-	ruleResults         []RuleResult             // A slice to store rule evaluation results
-	rawFacts            gjson.Result             // The raw input facts in JSON format
-	ruleResultsCapacity int                      // Initial capacity for rule results to optimize memory
+	factMap             FactMap                                       // A map storing facts for quick lookup
+	allowUndefinedFacts bool                                          // Flag to allow or disallow undefined facts
+	events              map[EventOutcome][]Event                      // Maps success or failure outcomes to their events
+	ruleResults         []*RuleResult                                 // A slice to store rule evaluation results
+	rawFacts            gjson.Result                                  // The raw input facts in JSON format
+	ruleResultsCapacity int                                           // Initial capacity for rule results to optimize memory
+	budget              *evaluationBudget                             // Per-run evaluation budget, nil when unlimited
+	maxCachedFactBytes  int64                                         // Estimated size after which raw facts are no longer cached, 0 for unlimited
+	lazyArrayThreshold  int                                           // Arrays with more elements are never cached, 0 to disable
+	cachedFactBytes     atomic.Int64                                  // Estimated size of the raw facts cached so far
+	factCacheLimitHit   atomic.Bool                                   // Set once the cached facts cap has been reached
+	values              *Values                                       // Run-scoped side-channel values, not readable from conditions
+	noPriorityBarriers  bool                                          // Set when rules of all priorities are evaluated concurrently
+	barrierViolation    atomic.Bool                                   // Set when a runtime fact was added while priority barriers were disabled
+	eventConflicts      []EventConflict                               // Resolved violations of exclusive event groups
+	droppedEvents       []Event                                       // Success events removed while resolving event conflicts
+	memoMu              sync.Mutex                                    // Guards memo
+	memo                map[string]*memoEntry                         // Per-run scratch cache used by Memo
+	mutations           atomic.Uint64                                 // Incremented whenever a fact is added, invalidating the condition memo
+	conditionMemo       *conditionMemo                                // Results of leaf conditions, nil when memoization is disabled
+	priorityGroup       int                                           // Index of the priority group being evaluated
+	quiet               bool                                          // Set when events are collected but not published to handlers
+	trace               bool                                          // Set when leaf condition durations are recorded, see RunOptions.Trace
+	ruleTimings         *ruleTimings                                  // Evaluation durations per rule, nil unless requested
+	groupDurations      []time.Duration                               // Time spent on each evaluated priority group
+	accessed            *factAccessLog                                // Fact values resolved during the run, nil unless tracked
+	replay              *replayFacts                                  // Recorded facts served in place of the fact document, see Engine.Replay
+	execCtx             *ExecutionContext                             // The execution context of the run
+	ruleTimeout         time.Duration                                 // Rules evaluating longer are diagnosed, see RunOptions.RuleTimeout
+	diagnosticsMu       sync.Mutex                                    // Guards diagnostics
+	diagnostics         []Diagnostic                                  // Diagnostics emitted by the run
+	checkDeterminism    bool                                          // Set when every rule is evaluated twice, see RunOptions.CheckDeterminism
+	shadow              bool                                          // Set on the copies evaluating rules a second time
+	session             *Session                                      // Shares session-cached facts between runs, nil outside sessions
+	undefinedMu         sync.Mutex                                    // Guards undefinedConditions
+	undefinedConditions map[undefinedConditionKey]*UndefinedCondition // Tolerated references to missing conditions
+	caseInsensitive     bool                                          // Set when fact paths missing from the document are matched ignoring case
+	foldMu              sync.Mutex                                    // Guards foldIndex
+	foldIndex           map[string]map[string]foldedKey               // Lowercase keys of the document objects by object path, built on demand
+}
+
+// memoEntry holds a memoized computation; done is closed once value and err are set
+type memoEntry struct {
+	done  chan struct{}
+	value *ValueNode
+	err   error
 }
 
 // Options defines the optional settings for the Almanac.
 // It includes a flag to allow or disallow the use of undefined facts during rule evaluation.
 type Options struct {
 	AllowUndefinedFacts *bool // Optional flag to allow undefined facts
+	MaxCachedFactBytes  int64 // Optional cap on the estimated size of cached raw facts
+	LazyArrayThreshold  int   // Optional element count above which arrays are resolved from the raw facts on every access
+	// CaseInsensitiveFactPaths matches fact paths missing from the document against its keys ignoring case
+	CaseInsensitiveFactPaths bool
 }
 
 // NewAlmanac creates and returns a new Almanac instance.
@@ -47,8 +97,12 @@ func NewAlmanac(rf gjson.Result, options Options, initialCapacity int) *Almanac
 		rawFacts:            rf,
 		allowUndefinedFacts: allowUndefinedFacts,
 		events:              map[EventOutcome][]Event{"success": {}, "failure": {}},
-		ruleResults:         make([]RuleResult, 0, initialCapacity),
+		ruleResults:         make([]*RuleResult, 0, initialCapacity),
 		ruleResultsCapacity: initialCapacity,
+		maxCachedFactBytes:  options.MaxCachedFactBytes,
+		lazyArrayThreshold:  options.LazyArrayThreshold,
+		caseInsensitive:     options.CaseInsensitiveFactPaths,
+		values:              NewValues(nil),
 	}
 }
 
@@ -97,60 +151,459 @@ func (a *Almanac) AddResult(ruleResult *RuleResult) {
 		if newCapacity == 0 {
 			newCapacity = 4 // Start with a small capacity if it was initially 0
 		}
-		newSlice := make([]RuleResult, len(a.ruleResults), newCapacity)
+		newSlice := make([]*RuleResult, len(a.ruleResults), newCapacity)
 		copy(newSlice, a.ruleResults)
 		a.ruleResults = newSlice
 		a.ruleResultsCapacity = newCapacity
 	}
-	a.ruleResults = append(a.ruleResults, *ruleResult)
+	a.ruleResults = append(a.ruleResults, ruleResult)
 }
 
 // GetResults retrieves all rule results
-func (a *Almanac) GetResults() []RuleResult {
+func (a *Almanac) GetResults() []*RuleResult {
 	return a.ruleResults
 }
 
+// orderResults sorts the rule results by the position of their rule and rebuilds the events in the same order
+func (a *Almanac) orderResults(position map[*Rule]int) {
+	sort.SliceStable(a.ruleResults, func(i, j int) bool {
+		return position[a.ruleResults[i].rule] < position[a.ruleResults[j].rule]
+	})
+	a.events = map[EventOutcome][]Event{Success: {}, Failure: {}}
+	for _, ruleResult := range a.ruleResults {
+		if ruleResult.Result != nil && *ruleResult.Result {
+			a.events[Success] = append(a.events[Success], ruleResult.Event)
+		} else {
+			a.events[Failure] = append(a.events[Failure], ruleResult.Event)
+		}
+	}
+}
+
 func (a *Almanac) AddFact(key string, value *Fact) {
+	a.mutations.Add(1)
 	a.factMap.Set(key, value)
 }
 
 // AddRuntimeFact adds a constant fact during runtime
 func (a *Almanac) AddRuntimeFact(path string, value ValueNode) error {
 	Debug(fmt.Sprintf("almanac::addRuntimeFact id:%s", path))
+	if a.noPriorityBarriers {
+		a.barrierViolation.Store(true)
+		return fmt.Errorf("%w: runtime fact %s", ErrPriorityBarriersRequired, path)
+	}
 	f, err := NewFact(path, value, nil)
 	if err != nil {
 		return err
 	}
 	a.AddFact(f.Path, f)
+	if a.accessed != nil {
+		a.accessed.recordRuntime(path, value)
+	}
 	return nil
 }
 
+// FactValue resolves the fact at the given path.
+// Facts are resolved with the following precedence:
+//  1. runtime facts added with AddRuntimeFact
+//  2. facts registered on the engine (AddFact / AddCalculatedFact)
+//  3. the raw fact document
+//
+// A static engine fact registered with FactOptions{Cache: false} requests raw-first resolution:
+// the raw document is re-read on every reference and the registered value is only used when the path is missing from it.
+// Runtime facts still shadow such a fact.
+//
+// A path below a runtime or engine fact, e.g. "customer.address.city" for a calculated fact "customer", resolves into
+// the value of that fact. Conditions and event params both resolve facts this way.
 func (a *Almanac) FactValue(path string) (*Fact, error) {
+	return a.FactValueWithParams(path, nil)
+}
+
+// factLookup resolves a fact with the params and options of the referencing condition
+type factLookup func(path string, options FactLookupOptions) (*Fact, error)
+
+// FactLookupOptions are the options of a single fact lookup, see Almanac.FactValueWithOptions.
+type FactLookupOptions struct {
+	// Params are passed to calculated facts, see FactValueWithParams
+	Params map[string]interface{}
+	// Fresh resolves the fact again instead of reading the fact caches of the run and session: calculated facts are
+	// calculated again and the fact document is read again. The fresh value is not cached for other lookups.
+	Fresh bool
+}
+
+// FactValueWithParams resolves the fact at the given path like FactValue, passing params to calculated facts.
+// A calculated fact receives the params as its first callback argument; cached calculated facts are computed once
+// per run for each distinct set of params. Other facts ignore the params.
+// Params:
+// - path: The path of the fact.
+// - params: The params of the referencing condition, may be nil.
+func (a *Almanac) FactValueWithParams(path string, params map[string]interface{}) (*Fact, error) {
+	return a.FactValueWithOptions(path, FactLookupOptions{Params: params})
+}
+
+// FactValueWithOptions resolves the fact at the given path like FactValueWithParams, with the options of the lookup.
+// Params:
+// - path: The path of the fact.
+// - options: The params of the referencing condition and whether to bypass the fact caches, see FactLookupOptions.
+func (a *Almanac) FactValueWithOptions(path string, options FactLookupOptions) (*Fact, error) {
+	return a.ruleFactValue("", path, options)
+}
+
+// ruleFactValue resolves a fact like FactValueWithOptions, charging the resolution to the rule whose condition looked
+// it up, empty for lookups outside of conditions, e.g. by calculated facts
+func (a *Almanac) ruleFactValue(rule, path string, options FactLookupOptions) (*Fact, error) {
+	if err := a.budget.useFactResolution(rule, path); err != nil {
+		return nil, err
+	}
+	if a.replay != nil {
+		return a.replay.fact(path, options.Params, a.allowUndefinedFacts)
+	}
+	f, err := a.factValue(path, options)
+	if err == nil && a.accessed != nil {
+		a.accessed.record(path, options.Params, f)
+	}
+	return f, err
+}
+
+// factValue resolves a fact from the runtime and engine facts or the raw fact document
+func (a *Almanac) factValue(path string, options FactLookupOptions) (*Fact, error) {
 	// Check if the fact is in the cache
 	f, ok := a.factMap.Load(path)
 	if ok {
+		if f.Dynamic {
+			return a.calculateFact(f, options.Params, options.Fresh)
+		}
+		if f.Cached {
+			return f, nil
+		}
+		// Uncached static fact, prefer the raw document and fall back to the registered value
+		if result := a.rawFacts.Get(path); result.Exists() {
+			return NewFact(path, *NewValueFromGjson(result), &FactOptions{Cache: false, Priority: f.Priority})
+		}
 		return f, nil
 	}
 
+	if f, found, err := a.drillFact(path, options); found {
+		return f, err
+	}
+
 	// If the fact is not in try to read it from the raw facts
 	result := a.rawFacts.Get(path)
+	resolvedPath := path
+	if !result.Exists() && a.caseInsensitive {
+		if folded, ok := a.foldPath(path); ok {
+			resolvedPath = folded
+			result = a.rawFacts.Get(folded)
+		}
+	}
 
 	if !result.Exists() {
+		mismatch := pathShapeMismatch(a.rawFacts, path)
 		if a.allowUndefinedFacts {
+			if mismatch != nil {
+				Debug(fmt.Sprintf("almanac::factValue %v", mismatch))
+			}
 			return nil, nil
 		}
+		if mismatch != nil {
+			return nil, mismatch
+		}
 		return nil, fmt.Errorf("undefined fact: %s", path)
 	}
 	vn := NewValueFromGjson(result)
-	// Create a new fact and add it to the cache
-	nf, err := NewFact(path, *vn, nil)
+	// Create a new fact and add it to the cache; a case-insensitive match keeps the path it matched in the document
+	nf, err := NewFact(resolvedPath, *vn, nil)
 	if err != nil {
 		return nil, err
 	}
-	a.AddFact(path, nf)
+	if !options.Fresh && a.shouldCache(result, vn) {
+		// Caching a raw fact does not change its value, so it is not counted as a mutation
+		a.factMap.Set(path, nf)
+	}
 	return nf, nil
 }
 
+// drillFact resolves a path into the value of a runtime or engine fact registered under one of its prefixes, e.g.
+// "customer.address.city" into the object of a calculated fact "customer", so a registered fact shadows the raw
+// document below its path as well. The longest registered prefix wins; the rest of the path may use gjson syntax like
+// the raw document, e.g. "customer.orders.#". Uncached static facts keep preferring the raw document.
+// found reports whether a registered prefix decided the lookup.
+func (a *Almanac) drillFact(path string, options FactLookupOptions) (f *Fact, found bool, err error) {
+	for i := strings.LastIndexByte(path, '.'); i > 0; i = strings.LastIndexByte(path[:i], '.') {
+		if strings.ContainsAny(path[:i], gjsonPathSyntax) {
+			continue
+		}
+		prefix, ok := a.factMap.Load(path[:i])
+		if !ok {
+			continue
+		}
+		if !prefix.Dynamic && !prefix.Cached && a.rawFacts.Get(path).Exists() {
+			return nil, false, nil
+		}
+		if prefix.Dynamic {
+			if prefix, err = a.calculateFact(prefix, options.Params, options.Fresh); err != nil {
+				return nil, true, err
+			}
+		}
+		var value *ValueNode
+		if prefix != nil && prefix.Value != nil {
+			value, err = drillValue(prefix.Value, path[i+1:])
+			if err != nil {
+				return nil, true, fmt.Errorf("fact %s: %w", path, err)
+			}
+		}
+		if value == nil {
+			if a.allowUndefinedFacts {
+				return nil, true, nil
+			}
+			return nil, true, fmt.Errorf("undefined fact: %s", path)
+		}
+		f, err = NewFact(path, *value, &FactOptions{Cache: prefix.Cached, Priority: prefix.Priority})
+		return f, true, err
+	}
+	return nil, false, nil
+}
+
+// gjsonPathSyntax are the characters of gjson paths beyond plain dotted keys and indexes
+const gjsonPathSyntax = `\#*?@|!`
+
+// drillValue resolves a path within a value; plain dotted paths are walked directly, other gjson paths are evaluated
+// against the JSON encoding of the value. nil when the path does not exist.
+func drillValue(v *ValueNode, path string) (*ValueNode, error) {
+	if !strings.ContainsAny(path, gjsonPathSyntax) {
+		value, _ := v.Get(path)
+		return value, nil
+	}
+	encoded, err := json.Marshal(v.Raw())
+	if err != nil {
+		return nil, err
+	}
+	result := gjson.GetBytes(encoded, path)
+	if !result.Exists() {
+		return nil, nil
+	}
+	return NewValueFromGjson(result), nil
+}
+
+// foldedKey is the key of a document object matching a lowercase key; ambiguous when several keys match it
+type foldedKey struct {
+	key       string
+	ambiguous bool
+}
+
+// foldPath resolves a plain dotted path against the fact document ignoring the case of object keys, e.g.
+// "user.userid" to "user.userID". A segment matching several keys of an object ignoring case is ambiguous and leaves
+// the path unresolved. The lowercase keys of an object are indexed once per run, when a segment first misses in it.
+func (a *Almanac) foldPath(path string) (string, bool) {
+	if strings.ContainsAny(path, gjsonPathSyntax) {
+		return "", false
+	}
+	current := a.rawFacts
+	resolved := make([]string, 0, strings.Count(path, ".")+1)
+	for _, segment := range strings.Split(path, ".") {
+		key := escapePathKey(segment)
+		child := current.Get(key)
+		if !child.Exists() && current.IsObject() {
+			folded, ok := a.foldKey(strings.Join(resolved, "."), current, segment)
+			if !ok {
+				return "", false
+			}
+			key = escapePathKey(folded)
+			child = current.Get(key)
+		}
+		if !child.Exists() {
+			return "", false
+		}
+		resolved = append(resolved, key)
+		current = child
+	}
+	return strings.Join(resolved, "."), true
+}
+
+// foldKey returns the key of the object at objectPath matching key ignoring case
+func (a *Almanac) foldKey(objectPath string, object gjson.Result, key string) (string, bool) {
+	a.foldMu.Lock()
+	defer a.foldMu.Unlock()
+	index, ok := a.foldIndex[objectPath]
+	if !ok {
+		index = map[string]foldedKey{}
+		object.ForEach(func(k, _ gjson.Result) bool {
+			lower := strings.ToLower(k.Str)
+			if existing, seen := index[lower]; seen && existing.key != k.Str {
+				index[lower] = foldedKey{ambiguous: true}
+			} else if !seen {
+				index[lower] = foldedKey{key: k.Str}
+			}
+			return true
+		})
+		if a.foldIndex == nil {
+			a.foldIndex = map[string]map[string]foldedKey{}
+		}
+		a.foldIndex[objectPath] = index
+	}
+	folded, ok := index[strings.ToLower(key)]
+	if !ok || folded.ambiguous {
+		return "", false
+	}
+	return folded.key, true
+}
+
+// calculateFact computes the value of a calculated fact when it is first referenced.
+// Cached facts are computed at most once per run and set of params; uncached facts on every reference.
+// Facts with FactOptions.SessionCache are read from the session of the run, when there is one.
+// Fresh calculations bypass both caches and are not stored in them.
+func (a *Almanac) calculateFact(f *Fact, params map[string]interface{}, fresh bool) (*Fact, error) {
+	compute := func() (*ValueNode, error) {
+		Debug(fmt.Sprintf("almanac::calculateFact id:%s", f.Path))
+		return f.calculate(a, params)
+	}
+	if f.SessionCache > 0 && a.session != nil && !fresh {
+		calculate := compute
+		compute = func() (*ValueNode, error) {
+			return a.session.fact(f, params, calculate)
+		}
+	}
+	var value *ValueNode
+	var err error
+	if f.Cached && !fresh {
+		key := "\x00fact:" + f.Path
+		if params != nil {
+			// encoding/json sorts map keys, so equal params share a key
+			encoded, err := json.Marshal(params)
+			if err != nil {
+				return nil, fmt.Errorf("almanac::calculateFact %s params: %w", f.Path, err)
+			}
+			key += "\x00" + string(encoded)
+		}
+		value, err = a.Memo(key, compute)
+	} else {
+		value, err = compute()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Fact{
+		Value:              value,
+		Path:               f.Path,
+		CalculationMethod:  f.CalculationMethod,
+		Cached:             f.Cached,
+		CalculationMethodE: f.CalculationMethodE,
+		OnError:            f.OnError,
+		Priority:           f.Priority,
+		Cost:               f.Cost,
+		Dynamic:            true,
+	}, nil
+}
+
+// shouldCache reports whether a value resolved from the raw facts may be kept in the fact cache.
+// Values are not cached once the cached facts cap has been reached, or when they are arrays above the lazy array threshold.
+func (a *Almanac) shouldCache(result gjson.Result, value *ValueNode) bool {
+	if a.lazyArrayThreshold > 0 && result.IsArray() && len(value.Array) > a.lazyArrayThreshold {
+		return false
+	}
+	if a.maxCachedFactBytes <= 0 {
+		return true
+	}
+	if a.factCacheLimitHit.Load() {
+		return false
+	}
+	if a.cachedFactBytes.Add(value.EstimatedSize()) > a.maxCachedFactBytes {
+		Debug(fmt.Sprintf("almanac::factValue cached facts limit of %d bytes reached", a.maxCachedFactBytes))
+		a.factCacheLimitHit.Store(true)
+		return false
+	}
+	return true
+}
+
+// FactElement resolves a single element of an array fact directly from the raw facts,
+// without materializing the whole array into ValueNodes.
+// Params:
+// - path: The path of the array fact.
+// - index: The index of the element.
+// Returns the element value, or nil if the path is not an array or the index is out of range.
+func (a *Almanac) FactElement(path string, index int) (*ValueNode, error) {
+	if err := a.budget.useFactResolution("", path); err != nil {
+		return nil, err
+	}
+	result := a.rawFacts.Get(path)
+	if !result.IsArray() {
+		return nil, nil
+	}
+	element := result.Get(fmt.Sprintf("%d", index))
+	if !element.Exists() {
+		return nil, nil
+	}
+	return NewValueFromGjson(element), nil
+}
+
+// FactLength returns the number of elements of an array fact directly from the raw facts.
+// Returns -1 if the path is not an array.
+func (a *Almanac) FactLength(path string) int {
+	result := a.rawFacts.Get(path)
+	if !result.IsArray() {
+		return -1
+	}
+	return int(result.Get("#").Int())
+}
+
+// ExecutionContext returns the execution context of the run, e.g. for event handlers to stop the run with
+// StopProcessing. Returns nil for an almanac not created by a run.
+func (a *Almanac) ExecutionContext() *ExecutionContext {
+	return a.execCtx
+}
+
+// Values returns the run-scoped key/value store, for use by fact resolvers and event handlers
+func (a *Almanac) Values() *Values {
+	return a.values
+}
+
+// Memo returns the value computed for key during this run, calling compute only the first time the key is requested.
+// Concurrent callers for the same key wait for the single computation in flight. Both the value and the error are
+// remembered for the rest of the run. Calculated facts can use it to share sub-computations between invocations.
+// Params:
+// - key: Identifies the computation; keys are shared by all facts of the run.
+// - compute: Computes the value.
+// Returns the computed value and error.
+func (a *Almanac) Memo(key string, compute func() (*ValueNode, error)) (*ValueNode, error) {
+	a.memoMu.Lock()
+	if a.memo == nil {
+		a.memo = make(map[string]*memoEntry)
+	}
+	if entry, ok := a.memo[key]; ok {
+		a.memoMu.Unlock()
+		<-entry.done
+		return entry.value, entry.err
+	}
+	entry := &memoEntry{done: make(chan struct{})}
+	a.memo[key] = entry
+	a.memoMu.Unlock()
+
+	defer close(entry.done)
+	defer func() {
+		// A panicking computation is remembered as an error so waiting callers are released
+		if r := recover(); r != nil {
+			entry.err = fmt.Errorf("almanac::memo %s panicked: %v", key, r)
+			panic(r)
+		}
+	}()
+	entry.value, entry.err = compute()
+	return entry.value, entry.err
+}
+
+// Stats returns the evaluation counters collected by the almanac during the run
+func (a *Almanac) Stats() RunStats {
+	stats := a.budget.stats()
+	stats.CachedFactBytes = a.cachedFactBytes.Load()
+	stats.FactCacheLimitReached = a.factCacheLimitHit.Load()
+	stats.EventConflicts = a.eventConflicts
+	if a.conditionMemo != nil {
+		stats.ConditionMemoHits = a.conditionMemo.hits.Load()
+		stats.CrossGroupMemoHits = a.conditionMemo.crossGroupHits.Load()
+	}
+	stats.PriorityGroupDurations = a.groupDurations
+	stats.UndefinedConditions = a.UndefinedConditions()
+	return stats
+}
+
 func (a *Almanac) GetValue(path string) (interface{}, error) {
 	f, err := a.FactValue(path)
 	if err != nil || f == nil || f.Value == nil {
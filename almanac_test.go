@@ -0,0 +1,271 @@
+package rulesengine
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAlmanacFactCacheLimits(t *testing.T) {
+	facts := gjson.Parse(`{"name": "a fairly long string value", "items": [1, 2, 3, 4, 5], "count": 1}`)
+
+	t.Run("Caching stops once the cap is reached", func(t *testing.T) {
+		almanac := NewAlmanac(facts, Options{MaxCachedFactBytes: 1}, 0)
+		if _, err := almanac.FactValue("name"); err != nil {
+			t.Fatalf("Expected fact to resolve, got error: %v", err)
+		}
+		if _, ok := almanac.factMap.Load("name"); ok {
+			t.Errorf("Expected fact not to be cached once the cap is reached")
+		}
+		f, err := almanac.FactValue("name")
+		if err != nil || f.Value.String != "a fairly long string value" {
+			t.Errorf("Expected fact to resolve from raw facts, got %v, %v", f, err)
+		}
+		if !almanac.Stats().FactCacheLimitReached {
+			t.Errorf("Expected stats to report the cap was reached")
+		}
+	})
+
+	t.Run("Large arrays are not cached", func(t *testing.T) {
+		almanac := NewAlmanac(facts, Options{LazyArrayThreshold: 3}, 0)
+		if _, err := almanac.FactValue("items"); err != nil {
+			t.Fatalf("Expected fact to resolve, got error: %v", err)
+		}
+		if _, ok := almanac.factMap.Load("items"); ok {
+			t.Errorf("Expected large array not to be cached")
+		}
+		if _, err := almanac.FactValue("count"); err != nil {
+			t.Fatalf("Expected fact to resolve, got error: %v", err)
+		}
+		if _, ok := almanac.factMap.Load("count"); !ok {
+			t.Errorf("Expected scalar fact to be cached")
+		}
+	})
+
+	t.Run("Array elements are accessed lazily", func(t *testing.T) {
+		almanac := NewAlmanac(facts, Options{}, 0)
+		if n := almanac.FactLength("items"); n != 5 {
+			t.Errorf("Expected 5 elements, got %d", n)
+		}
+		element, err := almanac.FactElement("items", 3)
+		if err != nil || element == nil || element.Number != 4 {
+			t.Errorf("Expected element 4, got %v, %v", element, err)
+		}
+		if element, _ := almanac.FactElement("items", 10); element != nil {
+			t.Errorf("Expected nil for out of range index, got %v", element)
+		}
+	})
+}
+
+func TestAlmanacFactPrecedence(t *testing.T) {
+	raw := gjson.Parse(`{"tier": "raw"}`)
+	engineFact := func(t *testing.T, cache bool) *Fact {
+		f, err := NewFact("tier", ValueNode{Type: String, String: "engine"}, &FactOptions{Cache: cache, Priority: 1})
+		if err != nil {
+			t.Fatalf("Failed to create fact: %v", err)
+		}
+		return f
+	}
+
+	testCases := []struct {
+		name     string
+		raw      gjson.Result
+		cache    *bool
+		runtime  bool
+		expected string
+	}{
+		{"raw document only", raw, nil, false, "raw"},
+		{"engine fact over raw document", raw, boolPtr(true), false, "engine"},
+		{"runtime fact over engine fact", raw, boolPtr(true), true, "runtime"},
+		{"runtime fact over raw document", raw, nil, true, "runtime"},
+		{"uncached engine fact prefers raw document", raw, boolPtr(false), false, "raw"},
+		{"uncached engine fact falls back when raw is missing", gjson.Parse(`{}`), boolPtr(false), false, "engine"},
+		{"runtime fact over uncached engine fact", raw, boolPtr(false), true, "runtime"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			almanac := NewAlmanac(tc.raw, Options{}, 0)
+			if tc.cache != nil {
+				almanac.AddFact("tier", engineFact(t, *tc.cache))
+			}
+			if tc.runtime {
+				if err := almanac.AddRuntimeFact("tier", ValueNode{Type: String, String: "runtime"}); err != nil {
+					t.Fatalf("Failed to add runtime fact: %v", err)
+				}
+			}
+			f, err := almanac.FactValue("tier")
+			if err != nil {
+				t.Fatalf("Expected fact to resolve, got error: %v", err)
+			}
+			if f.Value.String != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, f.Value.String)
+			}
+		})
+	}
+}
+
+func TestAlmanacDrillFact(t *testing.T) {
+	raw := gjson.Parse(`{"limits": {"eur": 1, "usd": 2}}`)
+	limits := ValueNode{Type: Object, Object: map[string]ValueNode{
+		"eur": {Type: Number, Number: 10},
+		"gbp": {Type: Number, Number: 5},
+	}}
+	testCases := []struct {
+		name     string
+		cache    bool
+		path     string
+		expected *float64
+	}{
+		{"engine fact shadows raw document", true, "limits.eur", floatPtr(10)},
+		{"missing below engine fact", true, "limits.usd", nil},
+		{"uncached engine fact prefers raw document", false, "limits.eur", floatPtr(1)},
+		{"uncached engine fact when raw is missing", false, "limits.gbp", floatPtr(5)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			almanac := NewAlmanac(raw, Options{AllowUndefinedFacts: boolPtr(true)}, 0)
+			f, _ := NewFact("limits", limits, &FactOptions{Cache: tc.cache, Priority: 1})
+			almanac.AddFact("limits", f)
+			f, err := almanac.FactValue(tc.path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expected == nil {
+				if f != nil {
+					t.Errorf("Expected %s to be undefined, got %v", tc.path, f.Value)
+				}
+				return
+			}
+			if f == nil || f.Value.Number != *tc.expected {
+				t.Errorf("Expected %v, got %v", *tc.expected, f)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestAlmanacMemo(t *testing.T) {
+	t.Run("Concurrent callers share one computation", func(t *testing.T) {
+		almanac := NewAlmanac(gjson.Parse(`{}`), Options{}, 0)
+		var calls atomic.Int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := almanac.Memo("ua", func() (*ValueNode, error) {
+					calls.Add(1)
+					<-release
+					return &ValueNode{Type: String, String: "firefox"}, nil
+				})
+				if err != nil || v.String != "firefox" {
+					t.Errorf("Expected memoized value, got %v, %v", v, err)
+				}
+			}()
+		}
+		close(release)
+		wg.Wait()
+		if calls.Load() != 1 {
+			t.Errorf("Expected 1 computation, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Errors are remembered and keys are independent", func(t *testing.T) {
+		almanac := NewAlmanac(gjson.Parse(`{}`), Options{}, 0)
+		failure := errors.New("boom")
+		for i := 0; i < 2; i++ {
+			if _, err := almanac.Memo("a", func() (*ValueNode, error) { return nil, failure }); !errors.Is(err, failure) {
+				t.Errorf("Expected the computation error, got %v", err)
+			}
+		}
+		v, err := almanac.Memo("b", func() (*ValueNode, error) { return &ValueNode{Type: Number, Number: 1}, nil })
+		if err != nil || v.Number != 1 {
+			t.Errorf("Expected an independent computation for another key, got %v, %v", v, err)
+		}
+	})
+}
+
+func TestAlmanacPathShapeMismatch(t *testing.T) {
+	facts := gjson.Parse(`{"orders": "pending", "customer": {"tier": "gold", "tags": ["a", "b"]}}`)
+	almanac := NewAlmanac(facts, Options{}, 0)
+
+	testCases := []struct {
+		name, path, validPath string
+	}{
+		{"scalar in the middle", "orders.#.items.#.sku", "orders"},
+		{"nested scalar", "customer.tier.level", "customer.tier"},
+		{"array where object expected", "customer.tags.first", "customer.tags"},
+		{"index out of range", "customer.tags.2", "customer.tags"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := almanac.FactValue(tc.path)
+			var mismatch *PathShapeMismatchError
+			if !errors.Is(err, ErrPathShapeMismatch) || !errors.As(err, &mismatch) {
+				t.Fatalf("Expected a path shape mismatch, got %v", err)
+			}
+			if mismatch.ValidPath != tc.validPath {
+				t.Errorf("Expected the deepest valid path %q, got %q", tc.validPath, mismatch.ValidPath)
+			}
+		})
+	}
+
+	if _, err := almanac.FactValue("customer.region"); err == nil || errors.Is(err, ErrPathShapeMismatch) {
+		t.Errorf("Expected a missing key to be a plain undefined fact, got %v", err)
+	}
+	allowUndefined := true
+	lenient := NewAlmanac(facts, Options{AllowUndefinedFacts: &allowUndefined}, 0)
+	if f, err := lenient.FactValue("orders.#.items.#.sku"); f != nil || err != nil {
+		t.Errorf("Expected mismatches to be undefined when undefined facts are allowed, got %v, %v", f, err)
+	}
+}
+
+func TestAlmanacCaseInsensitiveFactPaths(t *testing.T) {
+	raw := gjson.Parse(`{"user": {"userID": 7, "Tags": ["a"], "mail": "x", "MAIL": "y"}, "Total.Amount": 3}`)
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"user.userID", "user.userID"},
+		{"user.userId", "user.userID"},
+		{"USER.USERID", "user.userID"},
+		{"user.tags.0", "user.Tags.0"},
+		{"user.Mail", ""},
+		{"user.missing", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			almanac := NewAlmanac(raw, Options{AllowUndefinedFacts: boolPtr(true), CaseInsensitiveFactPaths: true}, 0)
+			f, err := almanac.FactValue(tc.path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expected == "" {
+				if f != nil {
+					t.Errorf("Expected %s not to match, got %s", tc.path, f.Path)
+				}
+				return
+			}
+			if f == nil || f.Path != tc.expected {
+				t.Errorf("Expected %s to match %s, got %v", tc.path, tc.expected, f)
+			}
+		})
+	}
+
+	almanac := NewAlmanac(raw, Options{AllowUndefinedFacts: boolPtr(true)}, 0)
+	if f, _ := almanac.FactValue("user.userId"); f != nil {
+		t.Errorf("Expected paths to be case sensitive by default, got %s", f.Path)
+	}
+}
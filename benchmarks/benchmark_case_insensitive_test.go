@@ -0,0 +1,66 @@
+package benchmarks_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	rulesEngine "github.com/nimbit-software/gojson-rules-engine"
+)
+
+// BenchmarkRuleEngineCaseInsensitiveFactPaths runs 20 rules on a document of 200 keys, with and without
+// RuleEngineOptions.CaseInsensitiveFactPaths, referencing the keys in their document casing and in lower case.
+// Exact paths cost the same either way; folded paths index the document keys once per run.
+func BenchmarkRuleEngineCaseInsensitiveFactPaths(b *testing.B) {
+	document := map[string]interface{}{}
+	for i := 0; i < 200; i++ {
+		document[fmt.Sprintf("attributeID%d", i)] = i
+	}
+	facts, err := json.Marshal(document)
+	if err != nil {
+		b.Fatalf("Failed to encode facts: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name            string
+		caseInsensitive bool
+		key             string
+	}{
+		{"off/exact", false, "attributeID%d"},
+		{"on/exact", true, "attributeID%d"},
+		{"on/folded", true, "attributeid%d"},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			engine := rulesEngine.NewEngine(nil, &rulesEngine.RuleEngineOptions{CaseInsensitiveFactPaths: tc.caseInsensitive})
+			for i := 0; i < 20; i++ {
+				rule, err := rulesEngine.NewRule(&rulesEngine.RuleConfig{
+					Name: fmt.Sprintf("attribute%d", i),
+					Conditions: rulesEngine.Condition{All: []*rulesEngine.Condition{
+						{Fact: fmt.Sprintf(tc.key, i*10), Operator: "greaterThanInclusive", Value: rulesEngine.ValueNode{Type: rulesEngine.Number, Number: 0}},
+					}},
+					Event: rulesEngine.EventConfig{Type: "attribute"},
+				})
+				if err != nil {
+					b.Fatalf("Failed to create rule: %v", err)
+				}
+				if err := engine.AddRule(rule); err != nil {
+					b.Fatalf("Failed to add rule: %v", err)
+				}
+			}
+
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				res, err := engine.Run(ctx, facts)
+				if err != nil {
+					b.Fatalf("Engine run failed: %v", err)
+				}
+				if len(res.Results) != 20 {
+					b.Fatalf("Expected every rule to pass, got %d", len(res.Results))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package benchmarks_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	rulesEngine "github.com/nimbit-software/gojson-rules-engine"
+)
+
+// BenchmarkRuleEngineManyConditions runs a rule with a large 'any' group of same-priority conditions,
+// exercising the prioritized condition grouping on every evaluation.
+func BenchmarkRuleEngineManyConditions(b *testing.B) {
+	const conditionCount = 40
+	anyConditions := make([]*rulesEngine.Condition, conditionCount)
+	facts := map[string]interface{}{}
+	for i := 0; i < conditionCount; i++ {
+		fact := fmt.Sprintf("fact%d", i)
+		anyConditions[i] = &rulesEngine.Condition{
+			Fact:     fact,
+			Operator: "equal",
+			Value:    rulesEngine.ValueNode{Type: rulesEngine.Number, Number: -1},
+		}
+		facts[fact] = i
+	}
+
+	rule, err := rulesEngine.NewRule(&rulesEngine.RuleConfig{
+		Name:       "many-conditions",
+		Conditions: rulesEngine.Condition{Any: anyConditions},
+		Event:      rulesEngine.EventConfig{Type: "many-conditions"},
+	})
+	if err != nil {
+		b.Fatalf("Failed to create rule: %v", err)
+	}
+	engine := rulesEngine.NewEngine(nil, nil)
+	if err := engine.AddRule(rule); err != nil {
+		b.Fatalf("Failed to add rule: %v", err)
+	}
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.RunWithMap(ctx, facts); err != nil {
+			b.Fatalf("Engine run failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package benchmarks_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rulesEngine "github.com/nimbit-software/gojson-rules-engine"
+	"github.com/nimbit-software/gojson-rules-engine/rulesenginetest"
+)
+
+// BenchmarkRuleEngineCostAwareOrdering runs an 'any' rule that lists a condition on an expensive calculated fact
+// before a cheap condition that always passes. Conditions are evaluated serially so the declared order is honoured;
+// with cost-aware ordering the expensive fact is never calculated.
+func BenchmarkRuleEngineCostAwareOrdering(b *testing.B) {
+	for _, costAware := range []bool{false, true} {
+		name := "declared-order"
+		if costAware {
+			name = "cost-aware"
+		}
+		b.Run(name, func(b *testing.B) {
+			rule, err := rulesEngine.NewRule(&rulesEngine.RuleConfig{
+				Name: "cost",
+				Conditions: rulesEngine.Condition{Any: []*rulesEngine.Condition{
+					{Fact: "expensive", Operator: "equal", Value: rulesEngine.ValueNode{Type: rulesEngine.Number, Number: 1}},
+					{Fact: "cheap", Operator: "equal", Value: rulesEngine.ValueNode{Type: rulesEngine.Number, Number: 1}},
+				}},
+				Event: rulesEngine.EventConfig{Type: "cost"},
+			})
+			if err != nil {
+				b.Fatalf("Failed to create rule: %v", err)
+			}
+			options := rulesEngine.DefaultRuleEngineOptions()
+			options.CostAwareOrdering = costAware
+			engine := rulesenginetest.NewEngine(nil, options)
+			if err := engine.AddRule(rule); err != nil {
+				b.Fatalf("Failed to add rule: %v", err)
+			}
+			var calls atomic.Int64
+			err = engine.AddCalculatedFact("expensive", func(a *rulesEngine.Almanac, params ...interface{}) *rulesEngine.ValueNode {
+				calls.Add(1)
+				time.Sleep(100 * time.Microsecond)
+				return &rulesEngine.ValueNode{Type: rulesEngine.Number, Number: 1}
+			}, &rulesEngine.FactOptions{Cache: true, Cost: 100})
+			if err != nil {
+				b.Fatalf("Failed to add fact: %v", err)
+			}
+
+			ctx := context.Background()
+			facts := []byte(`{"cheap": 1}`)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := engine.Run(ctx, facts); err != nil {
+					b.Fatalf("Engine run failed: %v", err)
+				}
+			}
+			b.ReportMetric(float64(calls.Load())/float64(b.N), "expensive-calls/op")
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package benchmarks_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	rulesEngine "github.com/nimbit-software/gojson-rules-engine"
+)
+
+// BenchmarkRuleEngineEventParams runs a passing rule whose event has many params referencing facts,
+// measuring the resolution of event params on success.
+func BenchmarkRuleEngineEventParams(b *testing.B) {
+	const paramCount = 200
+	params := make(map[string]interface{}, paramCount)
+	facts := map[string]interface{}{"score": 50}
+	for i := 0; i < paramCount; i++ {
+		fact := fmt.Sprintf("fact%d", i)
+		params[fmt.Sprintf("param%d", i)] = map[string]interface{}{"fact": fact}
+		facts[fact] = i
+	}
+
+	rule, err := rulesEngine.NewRule(&rulesEngine.RuleConfig{
+		Name: "many-params",
+		Conditions: rulesEngine.Condition{All: []*rulesEngine.Condition{
+			{Fact: "score", Operator: "greaterThan", Value: rulesEngine.ValueNode{Type: rulesEngine.Number, Number: 10}},
+		}},
+		Event: rulesEngine.EventConfig{Type: "many-params", Params: &params},
+	})
+	if err != nil {
+		b.Fatalf("Failed to create rule: %v", err)
+	}
+	engine := rulesEngine.NewEngine(nil, &rulesEngine.RuleEngineOptions{ReplaceFactsInEventParams: true})
+	if err := engine.AddRule(rule); err != nil {
+		b.Fatalf("Failed to add rule: %v", err)
+	}
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.RunWithMap(ctx, facts); err != nil {
+			b.Fatalf("Engine run failed: %v", err)
+		}
+	}
+}
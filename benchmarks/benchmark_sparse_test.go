@@ -0,0 +1,51 @@
+package benchmarks_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	rulesEngine "github.com/nimbit-software/gojson-rules-engine"
+)
+
+// BenchmarkRuleEngineSparseFacts runs 100 rules on the device section of a document without one, with and without
+// RunOptions.SkipRulesWithoutFacts. Skipped rules are reported under skippedResults instead of being evaluated.
+func BenchmarkRuleEngineSparseFacts(b *testing.B) {
+	for _, skip := range []bool{false, true} {
+		name := "evaluate-all"
+		if skip {
+			name = "skip-without-facts"
+		}
+		b.Run(name, func(b *testing.B) {
+			engine := rulesEngine.NewEngine(nil, &rulesEngine.RuleEngineOptions{AllowUndefinedFacts: true})
+			for i := 0; i < 100; i++ {
+				rule, err := rulesEngine.NewRule(&rulesEngine.RuleConfig{
+					Name: fmt.Sprintf("device%d", i),
+					Conditions: rulesEngine.Condition{All: []*rulesEngine.Condition{
+						{Fact: "device.os", Operator: "equal", Value: rulesEngine.ValueNode{Type: rulesEngine.String, String: "ios"}},
+						{Fact: "device.version", Operator: "greaterThan", Value: rulesEngine.ValueNode{Type: rulesEngine.Number, Number: float64(i)}},
+					}},
+					Event: rulesEngine.EventConfig{Type: "device"},
+				})
+				if err != nil {
+					b.Fatalf("Failed to create rule: %v", err)
+				}
+				if err := engine.AddRule(rule); err != nil {
+					b.Fatalf("Failed to add rule: %v", err)
+				}
+			}
+			options := rulesEngine.DefaultRunOptions()
+			options.SkipRulesWithoutFacts = skip
+
+			ctx := context.Background()
+			facts := []byte(`{"user": {"age": 30, "country": "DE"}}`)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := engine.RunWithOptions(ctx, facts, options); err != nil {
+					b.Fatalf("Engine run failed: %v", err)
+				}
+			}
+		})
+	}
+}
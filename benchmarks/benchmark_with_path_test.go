@@ -91,7 +91,8 @@ func BenchmarkRuleEngineWithPath(b *testing.B) {
 			for i := startIndex; i < endIndex; i++ {
 				_, err := engine.Run(ctx, testDataByte[i])
 				if err != nil {
-					b.Fatalf("Engine run failed: %v", err)
+					b.Errorf("Engine run failed: %v", err)
+					return
 				}
 			}
 		}(g)
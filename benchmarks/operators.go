@@ -0,0 +1,365 @@
+// Package benchmarks measures the performance of the rules engine. Besides the benchmarks of its test files it holds
+// the operator harness: rules generated for every registered operator and representative fact shapes, measured in
+// ns/op and allocs/op and compared against a baseline file to catch performance regressions.
+package benchmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	rulesEngine "github.com/nimbit-software/gojson-rules-engine"
+	"github.com/tidwall/gjson"
+)
+
+// Shape is a representative fact value operators are measured with
+type Shape struct {
+	Name string
+	// Type is the DataType name of the fact, matched against OperatorMetadata.FactType
+	Type string
+	Fact interface{}
+}
+
+// Shapes returns the fact shapes operators are measured with: short and long strings, small and large arrays,
+// small, wide and deep objects, and a number.
+func Shapes() []Shape {
+	smallArray := make([]interface{}, 8)
+	for i := range smallArray {
+		smallArray[i] = i
+	}
+	largeArray := make([]interface{}, 1000)
+	for i := range largeArray {
+		largeArray[i] = i
+	}
+	wideObject := make(map[string]interface{}, 1000)
+	for i := 0; i < 1000; i++ {
+		wideObject[fmt.Sprintf("k%d", i)] = i
+	}
+	deepObject := map[string]interface{}{"k0": 0}
+	for i := 0; i < 32; i++ {
+		deepObject = map[string]interface{}{"k0": i, "child": deepObject}
+	}
+	return []Shape{
+		{Name: "number", Type: "number", Fact: 42.5},
+		{Name: "short-string", Type: "string", Fact: "alice@example.com"},
+		{Name: "long-string", Type: "string", Fact: strings.Repeat("lorem ipsum ", 400) + "needle"},
+		{Name: "small-array", Type: "array", Fact: smallArray},
+		{Name: "large-array", Type: "array", Fact: largeArray},
+		{Name: "small-object", Type: "object", Fact: map[string]interface{}{"k0": 0, "k1": 1, "k2": 2, "k3": 3}},
+		{Name: "wide-object", Type: "object", Fact: wideObject},
+		{Name: "deep-object", Type: "object", Fact: deepObject},
+	}
+}
+
+// OperatorCase is a rule exercising an operator with a fact shape
+type OperatorCase struct {
+	Operator string
+	Shape    string
+	// Fact is the fact value; multi-fact operators compare it with itself
+	Fact      interface{}
+	Value     interface{}
+	multiFact bool
+}
+
+// Name identifies the case in benchmarks and baselines, e.g. "equal/short-string"
+func (c OperatorCase) Name() string {
+	return c.Operator + "/" + c.Shape
+}
+
+// Rule returns the rule of the case, a single condition on the "subject" fact
+func (c OperatorCase) Rule() (*rulesEngine.RuleConfig, error) {
+	condition := rulesEngine.Condition{Fact: "subject", Operator: c.Operator}
+	if c.multiFact {
+		condition = rulesEngine.Condition{Facts: []string{"subject", "other"}, Operator: c.Operator}
+	}
+	if c.Value != nil {
+		value, err := rulesEngine.NewValue(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name(), err)
+		}
+		condition.Value = *value
+	}
+	return &rulesEngine.RuleConfig{
+		Name:       c.Name(),
+		Conditions: rulesEngine.Condition{All: []*rulesEngine.Condition{&condition}},
+		Event:      rulesEngine.EventConfig{Type: c.Operator},
+	}, nil
+}
+
+// Facts returns the fact document of the case; "candidates" holds the set referenced by operators taking a fact value
+func (c OperatorCase) Facts() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"subject": c.Fact, "other": c.Fact, "candidates": candidates})
+}
+
+// candidates is a list of values missing from the facts, used for lists of candidates
+var candidates = []interface{}{10, 100000}
+
+// GenerateOperatorCases returns a case for every operator and each fact shape matching the fact type of its metadata,
+// sorted by operator name. Operators without metadata are measured with every shape.
+// Params:
+// - operators: The operators to measure, e.g. Engine.Operators.
+// Returns the cases and an error if a generated value is rejected by its operator.
+func GenerateOperatorCases(operators map[string]rulesEngine.Operator) ([]OperatorCase, error) {
+	names := make([]string, 0, len(operators))
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	shapes := Shapes()
+	var cases []OperatorCase
+	for _, name := range names {
+		op := operators[name]
+		factType, valueType := "any", "any"
+		if op.Metadata != nil {
+			factType, valueType = op.Metadata.FactType, op.Metadata.ValueType
+		}
+		for _, shape := range shapes {
+			if factType != "any" && factType != shape.Type {
+				continue
+			}
+			c := OperatorCase{Operator: name, Shape: shape.Name, Fact: shape.Fact, multiFact: op.IsMultiFact()}
+			if !c.multiFact {
+				c.Value = operatorValue(factType, valueType, shape)
+			}
+			if err := checkValue(op, c); err != nil {
+				return nil, err
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases, nil
+}
+
+// operatorValue returns a condition value of the given type: a value missing from the fact, so that searching
+// operators scan it all, a reference to the candidates for operators taking a fact value, or the fact itself for
+// operators comparing with any value
+func operatorValue(factType, valueType string, shape Shape) interface{} {
+	switch valueType {
+	case "number":
+		return 50
+	case "string":
+		// A literal pattern for the regular expression operators
+		return "needle"
+	case "array":
+		// A range for between, a list of candidates for in
+		return candidates
+	case "fact":
+		return map[string]interface{}{"fact": "candidates"}
+	}
+	if factType == "array" {
+		return -1
+	}
+	return shape.Fact
+}
+
+// checkValue reports generated values the operator rejects
+func checkValue(op rulesEngine.Operator, c OperatorCase) error {
+	if c.Value == nil {
+		return nil
+	}
+	value, err := rulesEngine.NewValue(c.Value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.Name(), err)
+	}
+	if !op.ValidateValue(value) {
+		return fmt.Errorf("%s: value rejected by the operator", c.Name())
+	}
+	if op.ValueCheck != nil {
+		if err := op.ValueCheck(value); err != nil {
+			return fmt.Errorf("%s: %w", c.Name(), err)
+		}
+	}
+	return nil
+}
+
+// evaluator returns a function evaluating the condition of the case against its facts. The facts are parsed once,
+// so that the operator dominates the measurement rather than the parsing of the document.
+func (c OperatorCase) evaluator(operators map[string]rulesEngine.Operator) (func() error, error) {
+	config, err := c.Rule()
+	if err != nil {
+		return nil, err
+	}
+	rule, err := rulesEngine.NewRule(config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name(), err)
+	}
+	facts, err := c.Facts()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name(), err)
+	}
+	almanac := rulesEngine.NewAlmanac(gjson.ParseBytes(facts), rulesEngine.Options{}, 1)
+	condition := rule.Conditions.All[0]
+	evaluate := func() error {
+		_, err := condition.Evaluate(almanac, operators)
+		return err
+	}
+	if err := evaluate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name(), err)
+	}
+	return evaluate, nil
+}
+
+// Benchmark measures the case with the testing package, e.g. from a Benchmark function of a test file
+func (c OperatorCase) Benchmark(b *testing.B, operators map[string]rulesEngine.Operator) {
+	evaluate, err := c.evaluator(operators)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := evaluate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Measurement is the cost of evaluating an operator case once
+type Measurement struct {
+	Name        string  `json:"name"`
+	NsPerOp     float64 `json:"nsPerOp"`
+	AllocsPerOp float64 `json:"allocsPerOp"`
+}
+
+// measureSamples is the number of timed samples of a case; the fastest is kept, as slower samples are slowed down by
+// garbage collection and the scheduler rather than by the operator
+const measureSamples = 5
+
+// Measure evaluates every case repeatedly and returns their cost, in the order of the cases.
+// Params:
+// - operators: The operators the cases were generated for.
+// - cases: The cases, see GenerateOperatorCases.
+// - duration: The minimum time spent timing each case; longer runs give steadier numbers.
+// Returns the measurements and an error if a case fails to evaluate.
+func Measure(operators map[string]rulesEngine.Operator, cases []OperatorCase, duration time.Duration) ([]Measurement, error) {
+	measurements := make([]Measurement, 0, len(cases))
+	for _, c := range cases {
+		evaluate, err := c.evaluator(operators)
+		if err != nil {
+			return nil, err
+		}
+		run := func(n int) time.Duration {
+			started := time.Now()
+			for i := 0; i < n; i++ {
+				// Errors were ruled out by the evaluator
+				_ = evaluate()
+			}
+			return time.Since(started)
+		}
+		allocs := testing.AllocsPerRun(10, func() { run(1) })
+
+		// Grow the sample size until a sample takes its share of the duration
+		n := 1
+		for n < 1<<30 && run(n) < duration/measureSamples {
+			n *= 2
+		}
+		fastest := time.Duration(math.MaxInt64)
+		for i := 0; i < measureSamples; i++ {
+			runtime.GC()
+			fastest = min(fastest, run(n))
+		}
+		measurements = append(measurements, Measurement{
+			Name:        c.Name(),
+			NsPerOp:     float64(fastest.Nanoseconds()) / float64(n),
+			AllocsPerOp: allocs,
+		})
+	}
+	return measurements, nil
+}
+
+// Report writes the measurements as a table of ns/op and allocs/op per case
+func Report(w io.Writer, measurements []Measurement) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "case\tns/op\tallocs/op\t")
+	for _, m := range measurements {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.0f\t\n", m.Name, m.NsPerOp, m.AllocsPerOp)
+	}
+	return tw.Flush()
+}
+
+// Baseline is a machine-readable record of measurements to compare later runs against. Timings only compare
+// between runs on the same machine; allocations also across machines of the same Go version.
+type Baseline struct {
+	GoVersion    string        `json:"goVersion"`
+	GOOS         string        `json:"goos"`
+	GOARCH       string        `json:"goarch"`
+	Measurements []Measurement `json:"measurements"`
+}
+
+// NewBaseline records the measurements with the Go version and platform they were taken on
+func NewBaseline(measurements []Measurement) *Baseline {
+	return &Baseline{GoVersion: runtime.Version(), GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, Measurements: measurements}
+}
+
+// WriteBaseline writes the baseline as indented JSON to the file at path
+func WriteBaseline(path string, baseline *Baseline) error {
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadBaseline reads a baseline written by WriteBaseline
+func ReadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", path, err)
+	}
+	return &baseline, nil
+}
+
+// Tolerance is the relative increase over the baseline accepted before a measurement is a regression,
+// e.g. 0.25 for 25%. A negative tolerance disables the comparison of its metric.
+type Tolerance struct {
+	Time   float64
+	Allocs float64
+}
+
+// Regression is a measurement exceeding its baseline by more than the tolerance
+type Regression struct {
+	Name     string
+	Metric   string // "ns/op" or "allocs/op"
+	Baseline float64
+	Current  float64
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %s %.1f, baseline %.1f", r.Name, r.Metric, r.Current, r.Baseline)
+}
+
+// Compare returns the measurements exceeding the baseline by more than the tolerance. Cases missing from the
+// baseline, e.g. of operators added since, are not compared.
+func Compare(baseline *Baseline, measurements []Measurement, tolerance Tolerance) []Regression {
+	base := make(map[string]Measurement, len(baseline.Measurements))
+	for _, m := range baseline.Measurements {
+		base[m.Name] = m
+	}
+	var regressions []Regression
+	for _, m := range measurements {
+		b, ok := base[m.Name]
+		if !ok {
+			continue
+		}
+		if tolerance.Time >= 0 && m.NsPerOp > b.NsPerOp*(1+tolerance.Time) {
+			regressions = append(regressions, Regression{Name: m.Name, Metric: "ns/op", Baseline: b.NsPerOp, Current: m.NsPerOp})
+		}
+		if tolerance.Allocs >= 0 && m.AllocsPerOp > b.AllocsPerOp*(1+tolerance.Allocs) {
+			regressions = append(regressions, Regression{Name: m.Name, Metric: "allocs/op", Baseline: b.AllocsPerOp, Current: m.AllocsPerOp})
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,123 @@
+package benchmarks_test
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	rulesEngine "github.com/nimbit-software/gojson-rules-engine"
+	"github.com/nimbit-software/gojson-rules-engine/benchmarks"
+)
+
+var (
+	operatorBaseline       = flag.String("operator-baseline", "testdata/operator_baseline.json", "baseline file of TestOperatorPerfRegression")
+	updateOperatorBaseline = flag.Bool("update-operator-baseline", false, "write the operator measurements to the baseline file")
+	operatorTimeTolerance  = flag.Float64("operator-tolerance", 0.25, "accepted relative ns/op increase over the baseline, negative to disable")
+	operatorAllocTolerance = flag.Float64("operator-alloc-tolerance", 0, "accepted relative allocs/op increase over the baseline, negative to disable")
+	operatorDuration       = flag.Duration("operator-duration", 50*time.Millisecond, "minimum time spent timing each operator case")
+)
+
+func operatorCases(tb testing.TB) (map[string]rulesEngine.Operator, []benchmarks.OperatorCase) {
+	tb.Helper()
+	operators := rulesEngine.NewEngine(nil, nil).Operators()
+	cases, err := benchmarks.GenerateOperatorCases(operators)
+	if err != nil {
+		tb.Fatalf("Failed to generate operator cases: %v", err)
+	}
+	return operators, cases
+}
+
+// BenchmarkOperators evaluates a condition of every default operator for each matching fact shape
+func BenchmarkOperators(b *testing.B) {
+	operators, cases := operatorCases(b)
+	for _, c := range cases {
+		b.Run(c.Name(), func(b *testing.B) {
+			c.Benchmark(b, operators)
+		})
+	}
+}
+
+func TestGenerateOperatorCases(t *testing.T) {
+	operators, cases := operatorCases(t)
+	covered := map[string]bool{}
+	for _, c := range cases {
+		covered[c.Operator] = true
+	}
+	for name := range operators {
+		if !covered[name] {
+			t.Errorf("Expected a case for operator %q", name)
+		}
+	}
+	measurements, err := benchmarks.Measure(operators, cases, 0)
+	if err != nil {
+		t.Fatalf("Failed to measure: %v", err)
+	}
+	if len(measurements) != len(cases) {
+		t.Errorf("Expected %d measurements, got %d", len(cases), len(measurements))
+	}
+}
+
+func TestCompareOperatorBaseline(t *testing.T) {
+	baseline := &benchmarks.Baseline{Measurements: []benchmarks.Measurement{
+		{Name: "equal/number", NsPerOp: 100, AllocsPerOp: 0},
+		{Name: "in/number", NsPerOp: 100, AllocsPerOp: 2},
+	}}
+	current := []benchmarks.Measurement{
+		{Name: "equal/number", NsPerOp: 120, AllocsPerOp: 1},
+		{Name: "in/number", NsPerOp: 130, AllocsPerOp: 2},
+		{Name: "new/number", NsPerOp: 1000, AllocsPerOp: 10},
+	}
+	regressions := benchmarks.Compare(baseline, current, benchmarks.Tolerance{Time: 0.25, Allocs: 0})
+	if len(regressions) != 2 || regressions[0].Metric != "allocs/op" || regressions[1].Name != "in/number" {
+		t.Errorf("Expected the equal allocs and in timing regressions, got %v", regressions)
+	}
+	if regressions := benchmarks.Compare(baseline, current, benchmarks.Tolerance{Time: -1, Allocs: -1}); len(regressions) != 0 {
+		t.Errorf("Expected disabled comparisons, got %v", regressions)
+	}
+}
+
+// TestOperatorPerfRegression compares the operators against a baseline recorded on the same machine, e.g.
+//
+//	go test ./benchmarks -run TestOperatorPerfRegression -update-operator-baseline
+//	go test ./benchmarks -run TestOperatorPerfRegression -operator-tolerance 0.1
+func TestOperatorPerfRegression(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measuring operators is skipped in short mode")
+	}
+	var baseline *benchmarks.Baseline
+	if !*updateOperatorBaseline {
+		var err error
+		baseline, err = benchmarks.ReadBaseline(*operatorBaseline)
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Skipf("no baseline at %s, record one with -update-operator-baseline", *operatorBaseline)
+		}
+		if err != nil {
+			t.Fatalf("Failed to read baseline: %v", err)
+		}
+	}
+
+	operators, cases := operatorCases(t)
+	measurements, err := benchmarks.Measure(operators, cases, *operatorDuration)
+	if err != nil {
+		t.Fatalf("Failed to measure: %v", err)
+	}
+	var report strings.Builder
+	if err := benchmarks.Report(&report, measurements); err != nil {
+		t.Fatalf("Failed to report: %v", err)
+	}
+	t.Log("\n" + report.String())
+
+	if *updateOperatorBaseline {
+		if err := benchmarks.WriteBaseline(*operatorBaseline, benchmarks.NewBaseline(measurements)); err != nil {
+			t.Fatalf("Failed to write baseline: %v", err)
+		}
+		return
+	}
+	tolerance := benchmarks.Tolerance{Time: *operatorTimeTolerance, Allocs: *operatorAllocTolerance}
+	for _, regression := range benchmarks.Compare(baseline, measurements, tolerance) {
+		t.Errorf("Regression %v", regression)
+	}
+}
@@ -0,0 +1,131 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Bundle is a set of static facts, named conditions, constants and rules loaded together by Engine.LoadBundle.
+// Constants are registered as static facts; they are kept in a separate section so bundles can
+// distinguish tunable values from fixed ones, and a key may not appear in both.
+type Bundle struct {
+	Facts      map[string]json.RawMessage `json:"facts"`
+	Conditions map[string]json.RawMessage `json:"conditions"`
+	Constants  map[string]json.RawMessage `json:"constants"`
+	Rules      []json.RawMessage          `json:"rules"`
+}
+
+// LoadBundle registers the facts, conditions, constants and rules of a JSON bundle in one call.
+// Everything is parsed and validated before the engine is modified, so either the whole bundle loads or nothing does.
+// Params:
+// - data: The JSON bundle, e.g. {"facts": {...}, "conditions": {...}, "constants": {...}, "rules": [...]}.
+// Returns a BundleError naming the section and key that failed.
+func (e *Engine) LoadBundle(data []byte) error {
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("bundle: %w", err)
+	}
+
+	facts := make(map[string]*Fact, len(bundle.Facts)+len(bundle.Constants))
+	for _, section := range []string{"facts", "constants"} {
+		entries := bundle.Facts
+		if section == "constants" {
+			entries = bundle.Constants
+		}
+		for _, key := range sortedKeys(entries) {
+			if section == "constants" {
+				if _, ok := bundle.Facts[key]; ok {
+					return &BundleError{Section: section, Key: key, Err: errors.New("already defined in facts")}
+				}
+			}
+			var value ValueNode
+			if err := json.Unmarshal(entries[key], &value); err != nil {
+				return &BundleError{Section: section, Key: key, Err: err}
+			}
+			fact, err := NewFact(key, value, nil)
+			if err != nil {
+				return &BundleError{Section: section, Key: key, Err: err}
+			}
+			facts[fact.Path] = fact
+		}
+	}
+
+	conditionNames := sortedKeys(bundle.Conditions)
+	conditions := make([]Condition, len(conditionNames))
+	for i, name := range conditionNames {
+		if err := json.Unmarshal(bundle.Conditions[name], &conditions[i]); err != nil {
+			return &BundleError{Section: "conditions", Key: name, Err: err}
+		}
+	}
+
+	rules := make([]*Rule, len(bundle.Rules))
+	for i, raw := range bundle.Rules {
+		key := fmt.Sprintf("%d", i)
+		var config RuleConfig
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return &BundleError{Section: "rules", Key: key, Err: err}
+		}
+		if config.Name != "" {
+			key = config.Name
+		}
+		r, err := NewRule(&config)
+		if err != nil {
+			return &BundleError{Section: "rules", Key: key, Err: err}
+		}
+		if err := e.validateRule(r); err != nil {
+			return &BundleError{Section: "rules", Key: key, Err: err}
+		}
+		// validateRule only knows the registered facts, the bundle's facts replace them once it loads
+		if e.StrictFactTypes {
+			if mismatches := e.factTypeMismatches(r, nil, facts); len(mismatches) > 0 {
+				return &BundleError{Section: "rules", Key: key, Err: newFactTypeError(r.Name, mismatches)}
+			}
+		}
+		rules[i] = r
+	}
+
+	// Everything is valid, register it
+	for _, fact := range facts {
+		e.Facts.Set(fact.Path, fact)
+	}
+	if len(bundle.Constants) > 0 {
+		e.mu.Lock()
+		if e.constants == nil {
+			e.constants = make(map[string]struct{}, len(bundle.Constants))
+		}
+		for key := range bundle.Constants {
+			e.constants[key] = struct{}{}
+		}
+		e.mu.Unlock()
+	}
+	if len(facts) > 0 {
+		e.factsVersion.Add(1)
+	}
+	for i, name := range conditionNames {
+		e.Conditions.Store(name, conditions[i])
+	}
+	if len(conditionNames) > 0 {
+		e.configVersion.Add(1)
+	}
+	if len(rules) > 0 {
+		for _, r := range rules {
+			r.SetEngine(e)
+		}
+		e.mu.Lock()
+		e.setRules(append(e.Rules, rules...))
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map, e.g. a bundle section, in sorted order, so errors are reported deterministically
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
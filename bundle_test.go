@@ -0,0 +1,165 @@
+package rulesengine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEngineLoadBundle(t *testing.T) {
+	bundle := `{
+		"facts": {"maxFouls": 6},
+		"constants": {"minAge": 18},
+		"conditions": {
+			"isAdult": {"all": [{"fact": "age", "operator": "greaterThanInclusive", "value": 18}]}
+		},
+		"rules": [
+			{"name": "adult", "conditions": {"all": [{"condition": "isAdult"}]}, "event": {"type": "adult"}}
+		]
+	}`
+	engine := NewEngine(nil, nil)
+	if err := engine.LoadBundle([]byte(bundle)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if f := engine.GetFact("maxFouls"); f == nil || f.Value.Number != 6 {
+		t.Errorf("Expected fact maxFouls to be registered")
+	}
+	if f := engine.GetFact("minAge"); f == nil || f.Value.Number != 18 {
+		t.Errorf("Expected constant minAge to be registered")
+	}
+	if _, ok := engine.Conditions.Load("isAdult"); !ok {
+		t.Errorf("Expected condition isAdult to be registered")
+	}
+
+	res, err := engine.Run(context.Background(), []byte(`{"age": 30}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if results := res.Results; len(results) != 1 || results[0].Name != "adult" {
+		t.Errorf("Expected rule adult to pass, got %v", results)
+	}
+}
+
+func TestEngineLoadBundleIsAtomic(t *testing.T) {
+	// Every bundle also carries a fact, a constant, a condition and a valid rule that must not be registered
+	const valid = `"facts": {"b": "text"}, "constants": {"max": 10}, "conditions": {"d": {"fact": "a", "operator": "equal", "value": 1}}`
+	const validRule = `{"name": "added", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "x"}}`
+	tests := []struct {
+		name    string
+		bundle  string
+		section string
+		key     string
+	}{
+		{
+			name:   "invalid JSON",
+			bundle: `{` + valid + `, "rules": [` + validRule + `]`,
+		},
+		{
+			name:    "constant shadowing a fact",
+			bundle:  `{` + valid + `, "constants": {"b": 2}, "rules": [` + validRule + `]}`,
+			section: "constants",
+			key:     "b",
+		},
+		{
+			name:    "invalid condition",
+			bundle:  `{` + valid + `, "conditions": {"bad": {"fact": "a", "operator": "equal"}}, "rules": [` + validRule + `]}`,
+			section: "conditions",
+			key:     "bad",
+		},
+		{
+			name:    "malformed rule",
+			bundle:  `{` + valid + `, "rules": [` + validRule + `, 1]}`,
+			section: "rules",
+			key:     "1",
+		},
+		{
+			name:    "invalid rule",
+			bundle:  `{` + valid + `, "rules": [` + validRule + `, {"name": "broken", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {}}]}`,
+			section: "rules",
+			key:     "broken",
+		},
+		{
+			name:    "invalid operator value",
+			bundle:  `{` + valid + `, "rules": [` + validRule + `, {"name": "broken", "conditions": {"all": [{"fact": "a", "operator": "in", "value": 1}]}, "event": {"type": "x"}}]}`,
+			section: "rules",
+			key:     "broken",
+		},
+		{
+			name:    "unknown operator",
+			bundle:  `{` + valid + `, "rules": [` + validRule + `, {"name": "broken", "conditions": {"all": [{"fact": "a", "operator": "nope", "value": 1}]}, "event": {"type": "x"}}]}`,
+			section: "rules",
+			key:     "broken",
+		},
+		{
+			name:    "unregistered event type",
+			bundle:  `{` + valid + `, "rules": [` + validRule + `, {"name": "broken", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "y"}}]}`,
+			section: "rules",
+			key:     "broken",
+		},
+		{
+			name:    "empty group",
+			bundle:  `{` + valid + `, "rules": [` + validRule + `, {"name": "broken", "conditions": {"all": []}, "event": {"type": "x"}}]}`,
+			section: "rules",
+			key:     "broken",
+		},
+		{
+			name:    "fact type of a bundle fact",
+			bundle:  `{` + valid + `, "rules": [` + validRule + `, {"name": "broken", "conditions": {"all": [{"fact": "b", "operator": "greaterThan", "value": 1}]}, "event": {"type": "x"}}]}`,
+			section: "rules",
+			key:     "broken",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(nil, &RuleEngineOptions{RejectEmptyGroups: true, StrictEventTypes: true, StrictFactTypes: true})
+			engine.RegisterEventTypes("x")
+			err := engine.LoadBundle([]byte(`{"facts": {"a": 1}, "constants": {"limit": 5}, "conditions": {"c": {"fact": "a", "operator": "equal", "value": 1}}, "rules": [{"name": "kept", "conditions": {"all": [{"condition": "c"}]}, "event": {"type": "x"}}]}`))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			factsVersion, configVersion := engine.factsVersion.Load(), engine.configVersion.Load()
+
+			err = engine.LoadBundle([]byte(tt.bundle))
+			if err == nil {
+				t.Fatalf("Expected the bundle to be rejected")
+			}
+			var bundleErr *BundleError
+			if tt.section == "" {
+				if errors.As(err, &bundleErr) {
+					t.Errorf("Expected a plain error for invalid JSON, got %v", err)
+				}
+			} else if !errors.As(err, &bundleErr) {
+				t.Fatalf("Expected a BundleError, got %v", err)
+			} else {
+				if bundleErr.Section != tt.section || bundleErr.Key != tt.key {
+					t.Errorf("Expected error in %s %q, got %s %q", tt.section, tt.key, bundleErr.Section, bundleErr.Key)
+				}
+				if !strings.Contains(err.Error(), tt.section) {
+					t.Errorf("Expected error message to name the section, got %q", err.Error())
+				}
+			}
+
+			for _, path := range []string{"b", "max"} {
+				if _, ok := engine.Facts.Load(path); ok {
+					t.Errorf("Expected fact %q not to be registered after a failed load", path)
+				}
+			}
+			if fact, ok := engine.Facts.Load("a"); !ok || fact.Value.Number != 1 {
+				t.Errorf("Expected fact a to be kept")
+			}
+			if _, ok := engine.Conditions.Load("d"); ok {
+				t.Errorf("Expected no conditions to be registered after a failed load")
+			}
+			if len(engine.constants) != 1 {
+				t.Errorf("Expected no constants to be registered after a failed load, got %v", engine.constants)
+			}
+			if rules := engine.GetRules(); len(rules) != 1 || rules[0].Name != "kept" {
+				t.Errorf("Expected no rules to be registered after a failed load, got %d", len(rules))
+			}
+			if engine.factsVersion.Load() != factsVersion || engine.configVersion.Load() != configVersion {
+				t.Errorf("Expected the engine's versions to be unchanged after a failed load")
+			}
+		})
+	}
+}
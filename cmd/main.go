@@ -90,5 +90,9 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	fmt.Println(res)
+	out, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
 }
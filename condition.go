@@ -1,60 +1,273 @@
 package rulesengine
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Condition represents an individual condition within a rule in the rules engine.
 // Conditions can compare facts to values using operators, and they can also nest other conditions.
 // Fields:
 // - Priority: Optional priority of the condition, must be greater than zero if set.
+// - Cost: Optional estimated cost of the condition, overriding the cost of its fact when CostAwareOrdering is enabled.
 // - Name: The name of the condition.
-// - Operator: The operator to be applied for comparison (e.g., equals, greaterThan).
-// - Value: The value to compare the fact to.
-// - Fact: The fact that is being evaluated in the condition.
+// - Operator: The operator to be applied for comparison (e.g., equals, greaterThan). A "!" prefix negates it, e.g. "!in".
+// - Negate: Negates the outcome of the operator of a leaf condition.
+// - Value: The value to compare the fact to. {"fact": "path"}, also as an array element, compares against another fact.
+// - ValueSet: The value was given, set when unmarshalling; needed for a null Value, which is the zero ValueNode.
+// - Fact: The fact that is being evaluated in the condition. {fact} segments, e.g. "limits.{currency}", are replaced with the
+// value of that fact; "{{" and "}}" are literal braces.
+// - ResolvedFact: The concrete path of a fact with {fact} segments, set once the condition was evaluated.
+// - MatchedFact: The path of the fact document the fact matched ignoring case, see RuleEngineOptions.CaseInsensitiveFactPaths.
+// - Path: Optional path applied to the fact value before the operator runs, e.g. "items.0.sku".
+// - Facts: The facts compared by a multi-fact operator, used instead of Fact.
 // - FactResult: The result of fact evaluation.
+// - FactResults: The resolved values of Facts, set when a multi-fact condition was evaluated.
 // - Result: The evaluation result of the condition (true/false).
+// - OperatorResult: The outcome of the operator before negation, reported when the condition is negated.
+// - Matches: The elements of an array fact that pass the operator on their own, unless it reports a MatchDetail.
+// - MatchDetail: What the operator reported as matched, e.g. regex capture groups, see NewDetailOperator.
 // - Params: Additional parameters that may affect the condition's evaluation.
 // - Condition: Raw condition string (for debugging or custom use cases).
+// - IfMissing: How a condition reference is handled when the named condition is not registered ("skip", "fail" or "false").
+// - MissingResolution: The IfMissing handling applied during evaluation, set when the referenced condition was missing.
 // - All, Any: Nested conditions that require all or any of the sub-conditions to be true.
+// - None: Nested conditions of which none may be true; evaluation stops at the first true one.
+// - AtLeast: The number of conditions of the 'any' group that must be true, e.g. 3 of 7 signals; 0 for one.
+// - MetCount: The number of conditions of an AtLeast group found true before the group was decided.
+// - MostOf: Nested conditions of which at least MinPassRatio of the evaluated ones must be true, e.g. 0.8 for 80%.
+// - MinPassRatio: The share of true conditions required by the 'mostOf' group, in (0, 1].
+// - PassRatio: The share of true conditions among those of the 'mostOf' group evaluated before it was decided.
+// - Duration: The evaluation time of a leaf condition, fact resolution included, recorded in trace mode.
+// - NamedGroups: Serialize All, Any and None as objects keyed by condition name instead of arrays.
+// - Shorthand: The condition was given as a bare condition name string and is serialized the same way.
 // - Not: A nested condition that negates its result.
+// - Ordered: Evaluate the 'all', 'any' and 'none' groups one condition at a time in declaration order, stopping at the first decisive one.
 type Condition struct {
 	Priority   *int
+	Cost       *int
 	Name       string
 	Operator   string
+	Negate     bool
 	Value      ValueNode
+	ValueSet   bool
 	Fact       string
+	Path       string
+	Facts      []string
 	FactResult Fact
 	Result     bool
-	Params     map[string]interface{}
-	Condition  string
-	All        []*Condition
-	Any        []*Condition
-	Not        *Condition
+	Matches    []ElementMatch
+	// MatchDetail is set when a detail operator passed, and is not kept for negated conditions
+	MatchDetail interface{}
+	Params      map[string]interface{}
+	Condition   string
+	IfMissing   string
+	All         []*Condition
+	Any         []*Condition
+	None        []*Condition
+	Not         *Condition
+	Ordered     bool
+	AtLeast     int
+	MetCount    int
+	// MostOf passes when the share of true conditions among the evaluated ones reaches MinPassRatio;
+	// skipped condition references are not counted
+	MostOf       []*Condition
+	MinPassRatio float64
+	PassRatio    float64
+	// FactResults holds the resolved values of Facts for multi-fact conditions
+	FactResults []*ValueNode
+	// ResolvedFact is the concrete path a fact with dynamic segments resolved to, e.g. "limits.EUR" for "limits.{currency}"
+	ResolvedFact string
+	// MatchedFact is the path of the fact document the fact matched ignoring case, e.g. "user.userID" for
+	// "user.userId", set with RuleEngineOptions.CaseInsensitiveFactPaths so rules can be fixed over time
+	MatchedFact string
+	// MissingResolution records how a missing condition reference was resolved during evaluation
+	MissingResolution string
+	// Transforms are applied in order to the resolved fact value before the operator runs, e.g. ["trim", "lower"],
+	// see DefaultTransforms and Engine.AddTransform
+	Transforms []string
+	// TransformedResult is the fact value after the transforms, the value the operator saw
+	TransformedResult *ValueNode
+	// FreshFact resolves the fact of the condition again instead of reading the fact caches of the run, e.g. for
+	// a counter deliberately read twice. Other conditions still read the cached value. See FactLookupOptions.Fresh
+	FreshFact bool
+	// FailureMessage replaces the line of the leaf condition in RuleResult.Explain when it failed, in the locale of
+	// the run, e.g. {"en": "{fact} must be set", "de": "{fact} fehlt"}. "{fact}", "{value}" and "{factResult}" are
+	// replaced with the fact, the value and the fact value the operator saw.
+	FailureMessage LocalizedMessage
+	// FreshResolution is set once the fact was resolved again for the condition, see FreshFact
+	FreshResolution bool
+	// Realized is the evaluated copy of the condition a condition reference resolved to, set during evaluation
+	Realized *Condition
+	// ValueResult is the value the operator compared against, with fact references in Value resolved
+	ValueResult *ValueNode
+	// NamedGroups is set when All, Any or None were given as objects of named conditions
+	NamedGroups bool
+	// Shorthand is set when the condition was given as a bare condition reference, e.g. "vipCustomer"
+	Shorthand bool
+	// OperatorResult holds the operator outcome before negation; negated records whether it was negated
+	OperatorResult bool
+	negated        bool
+	// Duration is the evaluation time of a leaf condition, including the facts it was first to resolve; set with RunOptions.Trace
+	Duration time.Duration
+	// origin is the registered condition a per-run copy was cloned from
+	origin *Condition
+	// parent and segment locate a per-run copy in its tree, see evaluationPath
+	parent  *Condition
+	segment string
 }
 
+// conditionShapes describes the accepted forms of a condition, used in unmarshalling errors
+const conditionShapes = `an object with "all", "any", "none", "mostOf" or "not", a condition reference {"condition": name}, ` +
+	`a fact condition {"fact", "operator", "value"}, or a condition name string`
+
+const (
+	// IfMissingSkip excludes a missing condition reference from its group
+	IfMissingSkip = "skip"
+	// IfMissingFail returns an error for a missing condition reference
+	IfMissingFail = "fail"
+	// IfMissingFalse evaluates a missing condition reference as false
+	IfMissingFalse = "false"
+)
+
+// errConditionSkipped signals that a condition was excluded from evaluation
+var errConditionSkipped = errors.New("condition skipped")
+
 // Validate checks if the Condition is valid based on business rules.
 // It verifies that if a value, fact, or operator are set, all three must be set.
 // It also ensures that if nested conditions (Any, All, Not) are provided, no value, fact, or operator is set.
 // Returns an error if the condition is invalid
 func (c *Condition) Validate() error {
+	if err := c.validateNode(); err != nil {
+		return err
+	}
+	for _, group := range []struct {
+		operator   string
+		conditions []*Condition
+	}{{"all", c.All}, {"any", c.Any}, {"none", c.None}, {"mostOf", c.MostOf}} {
+		for i, child := range group.conditions {
+			if child == nil {
+				return withConditionPath(c.groupPath(group.operator, i, child), errors.New("condition must not be null"))
+			}
+			if err := child.Validate(); err != nil {
+				return withConditionPath(c.groupPath(group.operator, i, child), err)
+			}
+		}
+	}
+	if c.Not != nil {
+		if err := c.Not.Validate(); err != nil {
+			return withConditionPath("not", err)
+		}
+	}
+	return nil
+}
+
+// groupPath is the JSON path segment of the i-th condition of a group, e.g. all[1], or all.name for named groups
+func (c *Condition) groupPath(operator string, i int, child *Condition) string {
+	if c.NamedGroups && child != nil && child.Name != "" {
+		return operator + "." + child.Name
+	}
+	return fmt.Sprintf("%s[%d]", operator, i)
+}
+
+// validateNode checks the condition itself, without its nested conditions
+func (c *Condition) validateNode() error {
 	// Validate priority (must be greater than 0 if set)
 	if c.Priority != nil && *c.Priority <= 0 {
 		return errors.New("priority must be greater than zero")
 	}
+	if c.Cost != nil && *c.Cost < 0 {
+		return errors.New("cost must not be negative")
+	}
 
-	valueExists := c.Value.Type != Null || (c.Value.Type != String && c.Value.String != "")
-	// Validate that if any of Value, Fact, or Operator are set, all three must be set
-	if valueExists || c.Operator != "" || c.Fact != "" {
+	// A null value is only distinguishable from an absent one by ValueSet; falsy values are always set
+	valueExists := c.ValueSet || c.Value.Type != Null
+	if len(c.Facts) > 0 {
+		// Multi-fact conditions need an operator, the value is optional
+		if c.Fact != "" {
+			return errors.New("fact and facts must not both be set")
+		}
+		if c.Operator == "" {
+			return errors.New("facts require an operator")
+		}
+		for _, fact := range c.Facts {
+			if fact == "" {
+				return errors.New("facts must not contain empty paths")
+			}
+		}
+	} else if valueExists || c.Operator != "" || c.Fact != "" {
+		// Validate that if any of Value, Fact, or Operator are set, all three must be set
 		if !valueExists || c.Operator == "" || c.Fact == "" {
 			return errors.New("if value, operator, or fact are set, all three must be provided")
 		}
 	}
-	// If Any, All, or Not are set, Value, Operator, and Fact must not be set
-	if (len(c.Any) > 0 || len(c.All) > 0 || c.Not != nil) && (valueExists || c.Operator != "" || c.Fact != "") {
+	// The range operators need a [low, high] value
+	if operator := strings.TrimPrefix(c.Operator, "!"); (operator == "between" || operator == "notBetween") && !hasFactReferences(&c.Value) {
+		if err := rangeCheck(&c.Value); err != nil {
+			return fmt.Errorf("operator %s: %w", c.Operator, err)
+		}
+	}
+	switch c.IfMissing {
+	case "", IfMissingSkip, IfMissingFail, IfMissingFalse:
+	default:
+		return fmt.Errorf("invalid ifMissing %q, expected %q, %q or %q", c.IfMissing, IfMissingSkip, IfMissingFail, IfMissingFalse)
+	}
+	// 'not' must negate an actual condition
+	if c.Not != nil && !c.Not.IsBooleanOperator() && !c.Not.IsConditionReference() && c.Not.Fact == "" && len(c.Not.Facts) == 0 {
+		return errors.New("not requires a condition")
+	}
+	if c.Path != "" && c.Fact == "" {
+		return errors.New("path requires a fact")
+	}
+	if len(c.Transforms) > 0 && (c.Fact == "" || len(c.Facts) > 0) {
+		return errors.New("transforms require a single fact condition")
+	}
+	if c.FreshFact && c.Fact == "" {
+		return errors.New("freshFact requires a single fact condition")
+	}
+	for _, name := range c.Transforms {
+		if name == "" {
+			return errors.New("transforms must not contain empty names")
+		}
+	}
+	if hasDynamicSegments(c.Fact) {
+		if err := validateDynamicPath(c.Fact); err != nil {
+			return err
+		}
+	}
+	if c.AtLeast < 0 || (c.AtLeast > 0 && c.AtLeast > len(c.Any)) {
+		return fmt.Errorf("atLeast %d requires an 'any' group with at least as many conditions", c.AtLeast)
+	}
+	if c.MinPassRatio != 0 && c.MostOf == nil {
+		return errors.New("minPassRatio requires a 'mostOf' group")
+	}
+	if c.MostOf != nil {
+		if !(c.MinPassRatio > 0 && c.MinPassRatio <= 1) {
+			return fmt.Errorf("minPassRatio %v must be in (0, 1]", c.MinPassRatio)
+		}
+		if len(c.MostOf) == 0 {
+			return errors.New("mostOf requires at least one condition")
+		}
+		// A ratio does not combine with the semantics of the other groups, they are nested instead
+		if c.All != nil || c.Any != nil || c.None != nil || c.Not != nil {
+			return errors.New("mostOf must not be combined with 'all', 'any', 'none' or 'not' in the same condition")
+		}
+	}
+	if c.Ordered && c.All == nil && c.Any == nil && c.None == nil && c.MostOf == nil {
+		return errors.New("ordered is only supported on 'all', 'any', 'none' and 'mostOf' groups")
+	}
+	// Groups and references are negated with 'not'
+	if c.Negate && (c.IsBooleanOperator() || c.IsConditionReference()) {
+		return errors.New("negate is only supported on fact conditions, use not to negate groups and condition references")
+	}
+	// If Any, All, None or Not are set, Value, Operator, and Fact must not be set
+	if (len(c.Any) > 0 || len(c.All) > 0 || len(c.None) > 0 || len(c.MostOf) > 0 || c.Not != nil) && (valueExists || c.Operator != "" || c.Fact != "" || len(c.Facts) > 0) {
 		return errors.New("value, operator, and fact must not be set if any, all, or not conditions are provided")
 	}
 
@@ -62,85 +275,299 @@ func (c *Condition) Validate() error {
 }
 
 // UnmarshalJSON is a custom JSON unmarshaller for the Condition struct.
+// A JSON string is shorthand for a condition reference: "vipCustomer" is {"condition": "vipCustomer"}.
+// The 'all' and 'any' groups may be arrays of conditions or objects of named conditions; in the object form
+// each key becomes the condition's Name and the conditions are ordered by key.
 // It validates the condition after unmarshalling to ensure it adheres to the rules.
 // Params:
 // - data: JSON data representing the condition.
 // Returns an error if the condition is invalid after unmarshalling.
 func (c *Condition) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+	if len(trimmed) > 0 && trimmed[0] == '"' {
+		var name string
+		if err := json.Unmarshal(trimmed, &name); err != nil {
+			return newInvalidConditionError(trimmed, err)
+		}
+		if name == "" {
+			return newInvalidConditionError(trimmed, errors.New("condition name must not be empty"))
+		}
+		c.Condition = name
+		c.Shorthand = true
+		return nil
+	}
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return newInvalidConditionError(trimmed, errors.New("unexpected JSON type"))
+	}
+
 	// Create a temporary struct to hold the incoming data
 	type Alias Condition // Alias to avoid infinite recursion inEvaluator UnmarshalJSON
 	temp := &struct {
 		*Alias
+		All    json.RawMessage `json:"all"`
+		Any    json.RawMessage `json:"any"`
+		None   json.RawMessage `json:"none"`
+		MostOf json.RawMessage `json:"mostOf"`
+		Not    json.RawMessage `json:"not"`
+		Value  json.RawMessage `json:"value"`
 	}{
 		Alias: (*Alias)(c),
 	}
 
 	// Unmarshal the JSON data into the temp struct
 	if err := json.Unmarshal(data, &temp); err != nil {
-		return err
+		return newInvalidConditionError(trimmed, err)
+	}
+	// The value is decoded separately to record its presence, as a null value decodes to the zero ValueNode
+	if temp.Value != nil {
+		if err := c.Value.UnmarshalJSON(temp.Value); err != nil {
+			return newInvalidConditionError(trimmed, err)
+		}
+		c.ValueSet = true
+	}
+	// Like encoding/json, groups absent from the data leave the current value untouched
+	for _, group := range []struct {
+		operator string
+		data     json.RawMessage
+		target   *[]*Condition
+	}{{"all", temp.All, &c.All}, {"any", temp.Any, &c.Any}, {"none", temp.None, &c.None}, {"mostOf", temp.MostOf, &c.MostOf}} {
+		if group.data == nil {
+			continue
+		}
+		conditions, named, err := unmarshalConditionGroup(group.operator, group.data)
+		if err != nil {
+			return newInvalidConditionError(trimmed, err)
+		}
+		*group.target = conditions
+		c.NamedGroups = c.NamedGroups || named
+	}
+	// 'not' is decoded separately so errors of the negated condition carry its path
+	if temp.Not != nil {
+		if bytes.Equal(bytes.TrimSpace(temp.Not), []byte("null")) {
+			c.Not = nil
+		} else {
+			not := &Condition{}
+			if err := json.Unmarshal(temp.Not, not); err != nil {
+				return withConditionPath("not", err)
+			}
+			c.Not = not
+		}
 	}
 
-	// Validate the condition after unmarshaling
-	if err := c.Validate(); err != nil {
+	// Validate the condition after unmarshaling, nested conditions were validated as they were decoded
+	if err := c.validateNode(); err != nil {
 		return err
 	}
 	return nil
 }
 
+// unmarshalConditionGroup parses an 'all', 'any' or 'none' group given either as an array of conditions
+// or as an object of named conditions. Returns whether the object form was used.
+// Errors of the conditions are prefixed with their path within the group, e.g. all[1].
+func unmarshalConditionGroup(operator string, data json.RawMessage) ([]*Condition, bool, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, false, nil
+	}
+	if data[0] != '{' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, false, err
+		}
+		group := make([]*Condition, len(items))
+		for i, item := range items {
+			path := fmt.Sprintf("%s[%d]", operator, i)
+			if bytes.Equal(bytes.TrimSpace(item), []byte("null")) {
+				return nil, false, withConditionPath(path, errors.New("condition must not be null"))
+			}
+			condition := &Condition{}
+			if err := json.Unmarshal(item, condition); err != nil {
+				return nil, false, withConditionPath(path, err)
+			}
+			group[i] = condition
+		}
+		return group, false, nil
+	}
+
+	var named map[string]json.RawMessage
+	if err := json.Unmarshal(data, &named); err != nil {
+		return nil, true, err
+	}
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	group := make([]*Condition, len(names))
+	for i, name := range names {
+		condition := &Condition{}
+		if err := json.Unmarshal(named[name], condition); err != nil {
+			return nil, true, withConditionPath(operator+"."+name, err)
+		}
+		condition.Name = name
+		group[i] = condition
+	}
+	return group, true, nil
+}
+
 // ToJSON converts the Condition instance to a JSON string representation.
 // Useful for serializing the condition for storage or transmission.
 func (c *Condition) ToJSON(stringify bool) (interface{}, error) {
+	return c.toJSON(stringify, nil)
+}
+
+// toJSON converts the condition like ToJSON, applying the serialization options to fact results
+func (c *Condition) toJSON(stringify bool, opts *SerializationOptions) (interface{}, error) {
 	props := map[string]interface{}{}
 	if c.Priority != nil {
 		props["priority"] = *c.Priority
 	}
+	if c.Cost != nil {
+		props["cost"] = *c.Cost
+	}
 	if c.Name != "" {
 		props["name"] = c.Name
 	}
+	if len(c.FailureMessage) > 0 {
+		props["failureMessage"] = c.FailureMessage
+	}
 	if oper := c.booleanOperator(); oper != "" {
 		if c.All != nil {
-			allConditions := make([]interface{}, len(c.All))
-			for i, condition := range c.All {
-				jsonCondition, err := condition.ToJSON(false)
-				if err != nil {
-					return nil, err
-				}
-				allConditions[i] = jsonCondition
+			allConditions, err := c.groupToJSON(c.All, opts)
+			if err != nil {
+				return nil, err
 			}
 			props["all"] = allConditions
 		}
 		if c.Any != nil {
-			anyConditions := make([]interface{}, len(c.Any))
-			for i, condition := range c.Any {
-				jsonCondition, err := condition.ToJSON(false)
-				if err != nil {
-					return nil, err
-				}
-				anyConditions[i] = jsonCondition
+			anyConditions, err := c.groupToJSON(c.Any, opts)
+			if err != nil {
+				return nil, err
 			}
 			props["any"] = anyConditions
+			if c.AtLeast > 0 {
+				props["atLeast"] = c.AtLeast
+				props["metCount"] = c.MetCount
+			}
+		}
+		if c.None != nil {
+			noneConditions, err := c.groupToJSON(c.None, opts)
+			if err != nil {
+				return nil, err
+			}
+			props["none"] = noneConditions
+		}
+		if c.MostOf != nil {
+			mostOfConditions, err := c.groupToJSON(c.MostOf, opts)
+			if err != nil {
+				return nil, err
+			}
+			props["mostOf"] = mostOfConditions
+			props["minPassRatio"] = c.MinPassRatio
+			props["passRatio"] = c.PassRatio
+		}
+		if c.Ordered {
+			props["ordered"] = true
 		}
 		if c.Not != nil {
-			jsonCondition, err := c.Not.ToJSON(false)
+			jsonCondition, err := c.Not.toJSON(false, opts)
 			if err != nil {
 				return nil, err
 			}
 			props["not"] = jsonCondition
 		}
 	} else if c.IsConditionReference() {
+		if c.Shorthand && c.Priority == nil && c.Name == "" && c.IfMissing == "" && c.MissingResolution == "" && c.Realized == nil {
+			return stringifyProps(c.Condition, stringify)
+		}
 		props["condition"] = c.Condition
+		if c.IfMissing != "" {
+			props["ifMissing"] = c.IfMissing
+		}
+		if c.MissingResolution != "" {
+			props["missingResolution"] = c.MissingResolution
+			props["result"] = c.Result
+		}
+		if c.Realized != nil {
+			realized, err := c.Realized.toJSON(false, opts)
+			if err != nil {
+				return nil, err
+			}
+			props["realized"] = realized
+			props["result"] = c.Result
+		}
 	} else {
 		props["operator"] = c.Operator
-		props["value"] = c.Value
-		props["fact"] = c.Fact
-		props["factResult"] = c.FactResult
+		props["value"] = c.Value.Raw()
+		if c.ValueResult != nil && !c.ValueResult.Equal(&c.Value) {
+			props["valueResult"] = c.ValueResult.Raw()
+		}
+		if c.Negate {
+			props["negate"] = true
+		}
+		if len(c.Facts) > 0 {
+			props["facts"] = c.Facts
+			if !opts.omitFactResults() && c.FactResults != nil {
+				factResults := make([]interface{}, len(c.FactResults))
+				for i, v := range c.FactResults {
+					factResults[i] = opts.factValue(v)
+				}
+				props["factResults"] = factResults
+			}
+		} else {
+			props["fact"] = c.Fact
+			if c.ResolvedFact != "" {
+				props["resolvedFact"] = c.ResolvedFact
+			}
+			if c.MatchedFact != "" {
+				props["matchedFact"] = c.MatchedFact
+			}
+			if c.Path != "" {
+				props["path"] = c.Path
+			}
+			if len(c.Transforms) > 0 {
+				props["transforms"] = c.Transforms
+			}
+			if c.FreshFact {
+				props["freshFact"] = true
+			}
+			if c.FreshResolution {
+				props["freshResolution"] = true
+			}
+			if !opts.omitFactResults() {
+				props["factResult"] = opts.factValue(c.FactResult.Value)
+				if c.TransformedResult != nil {
+					props["transformedResult"] = opts.factValue(c.TransformedResult)
+				}
+			}
+		}
 		props["result"] = c.Result
+		if c.negated {
+			props["operatorResult"] = c.OperatorResult
+		}
+		if len(c.Matches) > 0 {
+			props["matches"] = c.Matches
+		}
+		if c.MatchDetail != nil {
+			props["matchDetail"] = c.MatchDetail
+		}
+		if c.Duration > 0 {
+			props["durationNs"] = c.Duration
+		}
 
 		if c.Params != nil {
 			props["params"] = c.Params
 		}
 	}
 
+	return stringifyProps(props, stringify)
+}
+
+// stringifyProps returns the JSON-friendly form of a condition, encoded as a string when stringify is set
+func stringifyProps(props interface{}, stringify bool) (interface{}, error) {
 	if stringify {
 		jsonStr, err := json.Marshal(props)
 		if err != nil {
@@ -151,8 +578,42 @@ func (c *Condition) ToJSON(stringify bool) (interface{}, error) {
 	return props, nil
 }
 
-// Evaluate evaluates the condition against the given almanac and operator map
+// groupToJSON converts an 'all', 'any', 'none' or 'mostOf' group, as an object keyed by name when NamedGroups is set
+func (c *Condition) groupToJSON(group []*Condition, opts *SerializationOptions) (interface{}, error) {
+	if c.NamedGroups {
+		named := make(map[string]interface{}, len(group))
+		for _, condition := range group {
+			jsonCondition, err := condition.toJSON(false, opts)
+			if err != nil {
+				return nil, err
+			}
+			if props, ok := jsonCondition.(map[string]interface{}); ok {
+				delete(props, "name")
+			}
+			named[condition.Name] = jsonCondition
+		}
+		return named, nil
+	}
+	conditions := make([]interface{}, len(group))
+	for i, condition := range group {
+		jsonCondition, err := condition.toJSON(false, opts)
+		if err != nil {
+			return nil, err
+		}
+		conditions[i] = jsonCondition
+	}
+	return conditions, nil
+}
+
+// Evaluate evaluates the condition against the given almanac and operator map; transforms and operator decorators are
+// looked up among the built-in ones, see DefaultTransforms and DefaultOperatorDecorators
 func (c *Condition) Evaluate(almanac *Almanac, operatorMap map[string]Operator) (*EvaluationResult, error) {
+	return c.evaluate(almanac, operatorMap, defaultOperatorDecorators, defaultTransforms, almanac.FactValueWithOptions)
+}
+
+// evaluate evaluates the condition like Evaluate with the given decorators and transforms, resolving its facts with
+// the given lookup
+func (c *Condition) evaluate(almanac *Almanac, operatorMap map[string]Operator, decorators map[string]OperatorDecorator, transforms map[string]Transform, resolveFact factLookup) (*EvaluationResult, error) {
 	if reflect.ValueOf(almanac).IsZero() {
 		return nil, errors.New("almanac required")
 	}
@@ -163,41 +624,320 @@ func (c *Condition) Evaluate(almanac *Almanac, operatorMap map[string]Operator)
 		return nil, errors.New("Cannot evaluate() a boolean condition")
 	}
 
-	op, ok := operatorMap[c.Operator]
+	op, negated, ok := lookupOperator(operatorMap, decorators, c.Operator)
 	if !ok {
 		return nil, fmt.Errorf("Unknown operator: %s", c.Operator)
 	}
+	if len(c.Facts) > 0 || op.IsMultiFact() {
+		res, err := c.evaluateFacts(resolveFact, &op)
+		if err != nil {
+			return nil, err
+		}
+		return c.negate(res, negated), nil
+	}
 
-	rightHandSideValue := c.Value
-	leftHandSideValue, err := almanac.FactValue(c.Fact)
+	rightHandSideValue, err := resolveFactReferences(c.Value, resolveFact)
 	if err != nil {
 		return nil, err
 	}
+	if op.ValueCheck != nil {
+		if err := op.ValueCheck(&rightHandSideValue); err != nil {
+			return nil, fmt.Errorf("operator %s: %w", c.Operator, err)
+		}
+	}
+	// Dynamic segments, e.g. "limits.{currency}", are resolved first; the concrete path is cached like any other
+	factPath := c.Fact
+	undefinedSegment := false
+	if hasDynamicSegments(c.Fact) {
+		factPath, err = resolveDynamicPath(c.Fact, resolveFact)
+		if undefinedSegment = errors.Is(err, errDynamicSegmentUndefined); err != nil && !undefinedSegment {
+			return nil, err
+		}
+	}
+	var leftHandSideValue *Fact
+	if !undefinedSegment {
+		if leftHandSideValue, err = resolveFact(factPath, FactLookupOptions{Params: c.Params, Fresh: c.FreshFact}); err != nil {
+			return nil, err
+		}
+	}
+	matchedFact := ""
+	if almanac.caseInsensitive && leftHandSideValue != nil && leftHandSideValue.Path != factPath {
+		matchedFact = leftHandSideValue.Path
+	}
+	if c.Path != "" && leftHandSideValue != nil && leftHandSideValue.Value != nil {
+		if leftHandSideValue, err = c.applyPath(leftHandSideValue, almanac.allowUndefinedFacts); err != nil {
+			return nil, err
+		}
+	}
 
-	var result bool
+	// Undefined facts (only possible with AllowUndefinedFacts) participate in operators as Null,
+	// so negative operators such as notEqual pass while comparisons against the Null value fail
+	factValue := &ValueNode{Type: Null}
 	if leftHandSideValue != nil && leftHandSideValue.Value != nil {
-		result = op.Evaluate(leftHandSideValue.Value, &rightHandSideValue)
-		// TODO VALUE
-		Debug(fmt.Sprintf(`condition::evaluate <%v %s %v?> (%v)`, leftHandSideValue.Value.Raw(), c.Operator, rightHandSideValue, result))
+		factValue = leftHandSideValue.Value
+	}
+	var transformed *ValueNode
+	if len(c.Transforms) > 0 {
+		if factValue, err = c.applyTransforms(factValue, transforms); err != nil {
+			return nil, err
+		}
+		transformed = factValue
+	}
+	result, detail, err := op.evaluateDetail(factValue, &rightHandSideValue)
+	if err != nil {
+		return nil, fmt.Errorf("operator %s: %w", c.Operator, err)
 	}
+	Debug(fmt.Sprintf(`condition::evaluate <%v %s %v?> (%v)`, factValue.Raw(), c.Operator, rightHandSideValue, result))
 
 	res := &EvaluationResult{
 		Result:             result,
 		RightHandSideValue: rightHandSideValue,
 		Operator:           c.Operator,
+		TransformedValue:   transformed,
 	}
+	if factPath != c.Fact && !undefinedSegment {
+		res.ResolvedFact = factPath
+	}
+	res.FreshFact = c.FreshFact && !undefinedSegment
+	res.MatchedFact = matchedFact
 	if leftHandSideValue != nil {
 		res.LeftHandSideValue = *leftHandSideValue
 	}
+	c.negate(res, negated)
+	if res.Result && !res.Negated {
+		// Operators reporting what matched, e.g. anyElement, report it only as the match detail
+		res.MatchDetail = detail
+		if detail == nil && factValue.IsArray() {
+			res.Matches = matchingElements(&op, factValue, &rightHandSideValue)
+		}
+	}
 	return res, nil
 }
 
+// applyPath resolves the condition's path within a fact value. A path that does not resolve is handled like
+// an undefined fact: an error, or nil when undefined facts are allowed.
+func (c *Condition) applyPath(f *Fact, allowUndefinedFacts bool) (*Fact, error) {
+	value, ok := f.Value.Get(c.Path)
+	if !ok {
+		if allowUndefinedFacts {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("undefined fact: %s at path %s", c.Fact, c.Path)
+	}
+	return NewFact(c.Fact, *value, &FactOptions{Cache: false, Priority: f.Priority})
+}
+
+// negate records the operator outcome and applies the negation of the condition's negate flag and of a
+// "!" operator prefix; both together cancel out.
+func (c *Condition) negate(res *EvaluationResult, prefixNegated bool) *EvaluationResult {
+	res.OperatorResult = res.Result
+	res.Negated = c.Negate != prefixNegated
+	if res.Negated {
+		res.Result = !res.Result
+	}
+	return res
+}
+
+// evaluateFacts evaluates a multi-fact condition, resolving each of its facts and passing them to the operator
+func (c *Condition) evaluateFacts(resolveFact factLookup, op *Operator) (*EvaluationResult, error) {
+	if !op.IsMultiFact() {
+		return nil, fmt.Errorf("operator %s does not accept multiple facts", c.Operator)
+	}
+	if len(c.Facts) == 0 {
+		return nil, fmt.Errorf("operator %s requires facts", c.Operator)
+	}
+	if err := op.validateFactCount(len(c.Facts)); err != nil {
+		return nil, err
+	}
+
+	values := make([]*ValueNode, len(c.Facts))
+	for i, path := range c.Facts {
+		f, err := resolveFact(path, FactLookupOptions{})
+		if err != nil {
+			return nil, err
+		}
+		// Undefined facts participate as Null, like single fact conditions
+		values[i] = &ValueNode{Type: Null}
+		if f != nil && f.Value != nil {
+			values[i] = f.Value
+		}
+	}
+	rightHandSideValue, err := resolveFactReferences(c.Value, resolveFact)
+	if err != nil {
+		return nil, err
+	}
+	result := op.MultiFactCallback(values, &rightHandSideValue)
+	Debug(fmt.Sprintf(`condition::evaluate <%v %s %v?> (%v)`, c.Facts, c.Operator, rightHandSideValue, result))
+
+	return &EvaluationResult{
+		Result:             result,
+		RightHandSideValue: rightHandSideValue,
+		Operator:           c.Operator,
+		LeftHandSideValues: values,
+	}, nil
+}
+
+// applyEvaluationResult records the outcome of an evaluation on the condition
+func (c *Condition) applyEvaluationResult(evaluationResult *EvaluationResult) {
+	c.FactResult = evaluationResult.LeftHandSideValue
+	c.Result = evaluationResult.Result
+	c.OperatorResult = evaluationResult.OperatorResult
+	c.negated = evaluationResult.Negated
+	c.Matches = evaluationResult.Matches
+	c.MatchDetail = evaluationResult.MatchDetail
+	c.FactResults = evaluationResult.LeftHandSideValues
+	c.ResolvedFact = evaluationResult.ResolvedFact
+	c.MatchedFact = evaluationResult.MatchedFact
+	c.TransformedResult = evaluationResult.TransformedValue
+	c.FreshResolution = evaluationResult.FreshFact
+	if value, ok := evaluationResult.RightHandSideValue.(ValueNode); ok {
+		c.ValueResult = &value
+	}
+}
+
+// clone returns a deep copy of the condition tree, so a run can record results without affecting other runs.
+// Values, facts and params are shared since evaluation never modifies them.
+func (c *Condition) clone() *Condition {
+	if c == nil {
+		return nil
+	}
+	cp := *c
+	cp.origin = c.source()
+	cp.parent, cp.segment = nil, ""
+	cp.All = cp.cloneGroup("all", c.All)
+	cp.Any = cp.cloneGroup("any", c.Any)
+	cp.None = cp.cloneGroup("none", c.None)
+	cp.MostOf = cp.cloneGroup("mostOf", c.MostOf)
+	cp.Not = c.Not.clone()
+	if cp.Not != nil {
+		cp.Not.parent, cp.Not.segment = &cp, "not"
+	}
+	cp.Realized = c.Realized.clone()
+	if cp.Realized != nil {
+		cp.Realized.parent, cp.Realized.segment = &cp, c.Realized.segment
+	}
+	return &cp
+}
+
+// cloneGroup deep copies an 'all', 'any', 'none' or 'mostOf' group of the copy c, keeping nil and empty groups apart
+func (c *Condition) cloneGroup(operator string, group []*Condition) []*Condition {
+	if group == nil {
+		return nil
+	}
+	cloned := make([]*Condition, len(group))
+	for i, child := range group {
+		cloned[i] = child.clone()
+		if cloned[i] != nil {
+			cloned[i].parent, cloned[i].segment = c, c.groupPath(operator, i, child)
+		}
+	}
+	return cloned
+}
+
+// evaluationPath returns the path of a per-run copy of a condition within its rule, e.g. "conditions.all[1].any[0]".
+// Conditions evaluated for a reference continue the path of the reference with the condition name, e.g.
+// "conditions.all[0]{isAdult}.all[1]".
+func (c *Condition) evaluationPath() string {
+	var segments []string
+	for node := c; node != nil; node = node.parent {
+		if node.segment != "" {
+			segments = append(segments, node.segment)
+		}
+	}
+	path := "conditions"
+	for i := len(segments) - 1; i >= 0; i-- {
+		if strings.HasPrefix(segments[i], "{") {
+			path += segments[i]
+		} else {
+			path += "." + segments[i]
+		}
+	}
+	return path
+}
+
+// source returns the registered condition a copy was cloned from, or the condition itself
+func (c *Condition) source() *Condition {
+	if c.origin != nil {
+		return c.origin
+	}
+	return c
+}
+
+// matchingElements returns the elements of an array fact value that pass the condition's operator on their own.
+// Operators on array facts, e.g. contains, someFact:notEqual or custom operators without metadata, are evaluated with
+// a one-element array, operators on other facts with the element itself.
+func matchingElements(op *Operator, factValue, value *ValueNode) []ElementMatch {
+	arrayFact := op.Metadata == nil || op.Metadata.FactType == "array"
+	var matches []ElementMatch
+	for i := range factValue.Array {
+		element := &factValue.Array[i]
+		if arrayFact {
+			element = &ValueNode{Type: Array, Array: factValue.Array[i : i+1]}
+		}
+		if result, err := op.EvaluateE(element, value); err == nil && result {
+			matches = append(matches, ElementMatch{Index: i, Value: factValue.Array[i]})
+		}
+	}
+	return matches
+}
+
+// matchElements returns the elements of an array fact value that equal the condition value,
+// or that are contained in it when the condition value is itself an array.
+func matchElements(factValue, value *ValueNode) []ElementMatch {
+	var matches []ElementMatch
+	for i := range factValue.Array {
+		element := &factValue.Array[i]
+		if EvalEqual(element, value) || (value.IsArray() && EvalIn(element, value)) {
+			matches = append(matches, ElementMatch{Index: i, Value: *element})
+		}
+	}
+	return matches
+}
+
+// FindByName returns the first condition in the tree (including the condition itself) with the given name
+func (c *Condition) FindByName(name string) *Condition {
+	if c == nil {
+		return nil
+	}
+	if c.Name == name {
+		return c
+	}
+	for _, child := range c.All {
+		if found := child.FindByName(name); found != nil {
+			return found
+		}
+	}
+	for _, child := range c.Any {
+		if found := child.FindByName(name); found != nil {
+			return found
+		}
+	}
+	for _, child := range c.None {
+		if found := child.FindByName(name); found != nil {
+			return found
+		}
+	}
+	for _, child := range c.MostOf {
+		if found := child.FindByName(name); found != nil {
+			return found
+		}
+	}
+	if found := c.Not.FindByName(name); found != nil {
+		return found
+	}
+	return c.Realized.FindByName(name)
+}
+
 // booleanOperator returns the boolean operator for the condition
 func booleanOperator(condition *Condition) string {
 	if len(condition.Any) > 0 {
 		return "any"
 	} else if len(condition.All) > 0 {
 		return "all"
+	} else if len(condition.None) > 0 {
+		return "none"
+	} else if len(condition.MostOf) > 0 {
+		return "mostOf"
 	} else if condition.Not != nil {
 		return "not"
 	}
@@ -215,13 +955,19 @@ func (c *Condition) booleanOperator() string {
 	if c.Any != nil {
 		return "any"
 	}
+	if c.None != nil {
+		return "none"
+	}
+	if c.MostOf != nil {
+		return "mostOf"
+	}
 	if c.Not != nil {
 		return "not"
 	}
 	return ""
 }
 
-// IsBooleanOperator returns whether the operator is boolean ('all', 'any', 'not')
+// IsBooleanOperator returns whether the operator is boolean ('all', 'any', 'none', 'mostOf', 'not')
 func (c *Condition) IsBooleanOperator() bool {
 	return c.booleanOperator() != ""
 }
@@ -0,0 +1,138 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ConditionOption configures a condition created with NewLeafCondition or NewConditionReference
+type ConditionOption func(c *Condition)
+
+// WithPriority sets the priority of the condition, which must be greater than zero
+func WithPriority(priority int) ConditionOption {
+	return func(c *Condition) {
+		c.Priority = &priority
+	}
+}
+
+// WithName sets the name of the condition, reported on its results
+func WithName(name string) ConditionOption {
+	return func(c *Condition) {
+		c.Name = name
+	}
+}
+
+// WithParams sets the params passed to the calculated fact of the condition
+func WithParams(params map[string]interface{}) ConditionOption {
+	return func(c *Condition) {
+		c.Params = params
+	}
+}
+
+// WithPath sets the path applied to the fact value before the operator runs, e.g. "items.0.sku"
+func WithPath(path string) ConditionOption {
+	return func(c *Condition) {
+		c.Path = path
+	}
+}
+
+// WithNegate negates the outcome of the operator of a leaf condition
+func WithNegate() ConditionOption {
+	return func(c *Condition) {
+		c.Negate = true
+	}
+}
+
+// NewLeafCondition creates a condition comparing a fact to a value with an operator.
+// Params:
+// - fact: The path of the fact, e.g. "user.age".
+// - operator: The name of the operator, e.g. "greaterThan".
+// - value: The value to compare to: a ValueNode, or any value that encodes to JSON, nil for null.
+// - opts: Options for priority, name, params, path and negation.
+// Returns the condition, or an error when it is invalid.
+func NewLeafCondition(fact, operator string, value interface{}, opts ...ConditionOption) (*Condition, error) {
+	node, err := conditionValue(value)
+	if err != nil {
+		return nil, err
+	}
+	c := &Condition{Fact: fact, Operator: operator, Value: node, ValueSet: true}
+	return buildCondition(c, opts)
+}
+
+// NewAllCondition creates an 'all' group that passes when all of its conditions pass.
+// Returns an error when a condition is nil or invalid.
+func NewAllCondition(children ...*Condition) (*Condition, error) {
+	return buildCondition(&Condition{All: nonNilGroup(children)}, nil)
+}
+
+// NewAnyCondition creates an 'any' group that passes when at least one of its conditions passes.
+// Returns an error when a condition is nil or invalid.
+func NewAnyCondition(children ...*Condition) (*Condition, error) {
+	return buildCondition(&Condition{Any: nonNilGroup(children)}, nil)
+}
+
+// NewNoneCondition creates a 'none' group that passes when none of its conditions pass.
+// Returns an error when a condition is nil or invalid.
+func NewNoneCondition(children ...*Condition) (*Condition, error) {
+	return buildCondition(&Condition{None: nonNilGroup(children)}, nil)
+}
+
+// NewMostOfCondition creates a 'mostOf' group that passes when at least minPassRatio of its evaluated conditions pass,
+// e.g. 0.8 for 80%.
+// Returns an error when the ratio is not in (0, 1] or a condition is nil or invalid.
+func NewMostOfCondition(minPassRatio float64, children ...*Condition) (*Condition, error) {
+	return buildCondition(&Condition{MostOf: nonNilGroup(children), MinPassRatio: minPassRatio}, nil)
+}
+
+// NewNotCondition creates a condition negating the given condition.
+// Returns an error when the condition is nil or invalid.
+func NewNotCondition(child *Condition) (*Condition, error) {
+	if child == nil {
+		return nil, withConditionPath("not", errors.New("condition must not be null"))
+	}
+	return buildCondition(&Condition{Not: child}, nil)
+}
+
+// NewConditionReference creates a reference to a condition registered on the engine with AddCondition or SetCondition.
+// Returns an error when the name is empty or an option makes the reference invalid.
+func NewConditionReference(name string, opts ...ConditionOption) (*Condition, error) {
+	if name == "" {
+		return nil, errors.New("condition name must not be empty")
+	}
+	return buildCondition(&Condition{Condition: name}, opts)
+}
+
+// MarshalJSON encodes the definition of the condition in the rule JSON format, without evaluation results,
+// so conditions built in code can be stored and unmarshalled back. Use ToJSON to include evaluation results.
+func (c Condition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(conditionDefinition(&c, true))
+}
+
+// nonNilGroup returns an empty group for no conditions, as a nil group is no group at all
+func nonNilGroup(children []*Condition) []*Condition {
+	if children == nil {
+		return []*Condition{}
+	}
+	return children
+}
+
+// buildCondition applies the options to a new condition and validates it, nested conditions included
+func buildCondition(c *Condition, opts []ConditionOption) (*Condition, error) {
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// conditionValue converts a Go value to the value of a condition
+func conditionValue(value interface{}) (ValueNode, error) {
+	node, err := NewValue(value)
+	if err != nil {
+		return ValueNode{}, fmt.Errorf("condition %w", err)
+	}
+	return *node, nil
+}
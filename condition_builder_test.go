@@ -0,0 +1,121 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestConditionConstructors(t *testing.T) {
+	adult, err := NewLeafCondition("user.age", "greaterThanInclusive", 18, WithPriority(2), WithName("adult"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *adult.Priority != 2 || adult.Name != "adult" || adult.Value.Type != Number || adult.Value.Number != 18 {
+		t.Errorf("Unexpected leaf condition %+v", adult)
+	}
+	blocked, err := NewLeafCondition("user.country", "in", []string{"XX", "YY"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	notBlocked, err := NewNotCondition(blocked)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vip, err := NewConditionReference("vip")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	anyOf, err := NewAnyCondition(adult, vip)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	root, err := NewAllCondition(anyOf, notBlocked)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rule, err := NewRule(&RuleConfig{Name: "built", Conditions: *root, Event: EventConfig{Type: "built"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	engine := NewEngine(nil, &RuleEngineOptions{AllowUndefinedConditions: true})
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res, err := engine.Run(context.Background(), []byte(`{"user": {"age": 30, "country": "DE"}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if results := res.Results; len(results) != 1 {
+		t.Errorf("Expected the built rule to pass, got %d results", len(results))
+	}
+
+	t.Run("invalid conditions", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			build func() (*Condition, error)
+			err   string
+		}{
+			{"missing fact", func() (*Condition, error) { return NewLeafCondition("", "equal", 1) }, "if value, operator, or fact are set, all three must be provided"},
+			{"priority", func() (*Condition, error) { return NewLeafCondition("a", "equal", 1, WithPriority(0)) }, "priority must be greater than zero"},
+			{"negated reference", func() (*Condition, error) { return NewConditionReference("vip", WithNegate()) }, "negate is only supported on fact conditions, use not to negate groups and condition references"},
+			{"nil child", func() (*Condition, error) { return NewAllCondition(adult, nil) }, "all[1]: condition must not be null"},
+			{"nil not", func() (*Condition, error) { return NewNotCondition(nil) }, "not: condition must not be null"},
+			{"empty reference", func() (*Condition, error) { return NewConditionReference("") }, "condition name must not be empty"},
+			{"value", func() (*Condition, error) { return NewLeafCondition("a", "equal", func() {}) }, "condition value: json: unsupported type: func()"},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				c, err := tc.build()
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Expected %q, got %v (%+v)", tc.err, err, c)
+				}
+			})
+		}
+	})
+
+	t.Run("null and falsy values", func(t *testing.T) {
+		for _, value := range []interface{}{nil, false, 0, ""} {
+			if _, err := NewLeafCondition("a", "equal", value); err != nil {
+				t.Errorf("Expected %#v to be a valid value, got %v", value, err)
+			}
+		}
+	})
+}
+
+func TestConditionMarshalJSON(t *testing.T) {
+	adult, _ := NewLeafCondition("user.age", "greaterThanInclusive", 18, WithPriority(2), WithParams(map[string]interface{}{"unit": "years"}))
+	country, _ := NewLeafCondition("user", "equal", "DE", WithPath("country"), WithNegate())
+	vip, _ := NewConditionReference("vip")
+	vip.IfMissing = IfMissingFalse
+	blocked, _ := NewLeafCondition("user.country", "in", nil)
+	notBlocked, _ := NewNotCondition(blocked)
+	anyOf, _ := NewAnyCondition(adult, country, vip)
+	root, err := NewAllCondition(anyOf, notBlocked)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded Condition
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected the encoded condition to unmarshal, got %v: %s", err, data)
+	}
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("Expected the condition to round trip\n got: %s\nwant: %s", again, data)
+	}
+	expected := `{"all":[{"any":[{"fact":"user.age","operator":"greaterThanInclusive","params":{"unit":"years"},"priority":2,"value":18},` +
+		`{"fact":"user","negate":true,"operator":"equal","path":"country","value":"DE"},{"condition":"vip","ifMissing":"false"}]},` +
+		`{"not":{"fact":"user.country","operator":"in","value":null}}]}`
+	if string(data) != expected {
+		t.Errorf("Unexpected encoding\n got: %s\nwant: %s", data, expected)
+	}
+}
@@ -0,0 +1,63 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// conditionMemo holds the results of leaf conditions evaluated during a run, keyed by the condition's structure.
+// Entries are only valid while the almanac has not been mutated since they were recorded.
+type conditionMemo struct {
+	mu             sync.RWMutex
+	entries        map[string]conditionMemoEntry
+	hits           atomic.Int64
+	crossGroupHits atomic.Int64
+}
+
+type conditionMemoEntry struct {
+	result    *EvaluationResult
+	mutations uint64 // Almanac mutation counter when the result was recorded
+	group     int    // Priority group the result was recorded in
+}
+
+func newConditionMemo() *conditionMemo {
+	return &conditionMemo{entries: map[string]conditionMemoEntry{}}
+}
+
+// recall returns the memoized result of a leaf condition if the almanac was not mutated since it was recorded
+func (m *conditionMemo) recall(key string, almanac *Almanac) (*EvaluationResult, bool) {
+	if key == "" {
+		return nil, false
+	}
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+	if !ok || entry.mutations != almanac.mutations.Load() {
+		return nil, false
+	}
+	m.hits.Add(1)
+	if entry.group != almanac.priorityGroup {
+		m.crossGroupHits.Add(1)
+	}
+	return entry.result, true
+}
+
+// remember records the result of a leaf condition
+func (m *conditionMemo) remember(key string, almanac *Almanac, result *EvaluationResult) {
+	if key == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = conditionMemoEntry{result: result, mutations: almanac.mutations.Load(), group: almanac.priorityGroup}
+}
+
+// leafMemoKey returns the structural key of a leaf condition, or an empty string when it can not be memoized
+func leafMemoKey(c *Condition) string {
+	key, err := json.Marshal([]interface{}{c.Fact, c.Path, c.Facts, c.Transforms, c.Operator, c.Negate, c.Value.Raw(), c.Params})
+	if err != nil {
+		return ""
+	}
+	return string(key)
+}
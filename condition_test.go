@@ -1,8 +1,13 @@
 package rulesengine
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/tidwall/gjson"
 )
 
 func TestCondition(t *testing.T) {
@@ -194,3 +199,525 @@ func TestCondition(t *testing.T) {
 		}
 	})
 }
+
+func TestConditionUndefinedFact(t *testing.T) {
+	allowUndefined := true
+	almanac := NewAlmanac(gjson.Parse(`{}`), Options{AllowUndefinedFacts: &allowUndefined}, 0)
+	operators := map[string]Operator{}
+	for _, op := range DefaultOperators() {
+		operators[op.Name] = op
+	}
+
+	trueValue := ValueNode{Type: Bool, Bool: true}
+	numberValue := ValueNode{Type: Number, Number: 1}
+	stringValue := ValueNode{Type: String, String: "a"}
+	arrayValue := ValueNode{Type: Array, Array: []ValueNode{numberValue}}
+
+	testCases := []struct {
+		operator string
+		value    ValueNode
+		expected bool
+	}{
+		{"equal", trueValue, false},
+		{"equal", ValueNode{Type: Null}, true},
+		{"notEqual", trueValue, true},
+		{"notEqual", numberValue, true},
+		{"notEqual", ValueNode{Type: Null}, false},
+		{"in", arrayValue, false},
+		{"notIn", arrayValue, false},
+		{"contains", numberValue, false},
+		{"doesNotContain", numberValue, false},
+		{"lessThan", numberValue, false},
+		{"lessThanInclusive", numberValue, false},
+		{"greaterThan", numberValue, false},
+		{"greaterThanInclusive", numberValue, false},
+		{"startsWith", stringValue, false},
+		{"endsWith", stringValue, false},
+		{"includes", stringValue, false},
+		{"hasKey", stringValue, false},
+		{"notHasKey", stringValue, false},
+		{"keyCountGreaterThan", numberValue, false},
+		{"keyCountEqual", numberValue, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.operator, func(t *testing.T) {
+			condition := Condition{Fact: "optOut", Operator: tc.operator, Value: tc.value}
+			res, err := condition.Evaluate(almanac, operators)
+			if err != nil {
+				t.Fatalf("Expected evaluation to succeed, got error: %v", err)
+			}
+			if res.Result != tc.expected {
+				t.Errorf("Expected %s against an undefined fact to be %v, got %v", tc.operator, tc.expected, res.Result)
+			}
+		})
+	}
+}
+
+func TestConditionNamedGroups(t *testing.T) {
+	data := `{"all": {
+		"kycCheck": {"fact": "kyc", "operator": "equal", "value": true},
+		"ageCheck": {"fact": "age", "operator": "greaterThan", "value": 18}
+	}}`
+	var c Condition
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !c.NamedGroups {
+		t.Errorf("Expected NamedGroups to be set")
+	}
+	if len(c.All) != 2 || c.All[0].Name != "ageCheck" || c.All[1].Name != "kycCheck" {
+		t.Fatalf("Expected conditions named by key in sorted order, got %v", c.All)
+	}
+	if c.All[0].Fact != "age" || c.All[1].Fact != "kyc" {
+		t.Errorf("Expected condition bodies to be parsed, got %v and %v", c.All[0].Fact, c.All[1].Fact)
+	}
+
+	// Round trip preserves the object form
+	out, err := c.ToJSON(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	all, ok := out.(map[string]interface{})["all"].(map[string]interface{})
+	if !ok || len(all) != 2 || all["ageCheck"] == nil {
+		t.Errorf("Expected 'all' to serialize as an object of named conditions, got %v", out)
+	}
+
+	// Without the flag the array form is used
+	c.NamedGroups = false
+	out, _ = c.ToJSON(false)
+	if _, ok := out.(map[string]interface{})["all"].([]interface{}); !ok {
+		t.Errorf("Expected 'all' to serialize as an array, got %v", out)
+	}
+
+	var invalid Condition
+	if err := json.Unmarshal([]byte(`{"any": {"bad": {"fact": "age", "operator": "equal"}}}`), &invalid); err == nil {
+		t.Errorf("Expected an invalid named condition to be rejected")
+	}
+}
+
+func TestConditionReferenceShorthand(t *testing.T) {
+	var ruleConfig RuleConfig
+	if err := json.Unmarshal([]byte(`{"name": "vip", "conditions": "vipCustomer", "event": {"type": "vip"}}`), &ruleConfig); err != nil {
+		t.Fatalf("Expected the shorthand to be accepted, got %v", err)
+	}
+	if ruleConfig.Conditions.Condition != "vipCustomer" || !ruleConfig.Conditions.IsConditionReference() {
+		t.Errorf("Expected a reference to vipCustomer, got %+v", ruleConfig.Conditions)
+	}
+
+	engine := newTestEngine(t, `{"name": "vip", "conditions": "vipCustomer", "event": {"type": "vip"}}`, nil)
+	if err := engine.AddCondition("vipCustomer", &Condition{All: []*Condition{{Fact: "tier", Operator: "equal", Value: ValueNode{Type: String, String: "vip"}}}}); err != nil {
+		t.Fatalf("Failed to add condition: %v", err)
+	}
+	res, err := engine.Run(context.Background(), []byte(`{"tier": "vip"}`))
+	if err != nil || len(res.Results) != 1 {
+		t.Errorf("Expected the referenced condition to pass, got %v", err)
+	}
+
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"any": ["vipCustomer", {"fact": "age", "operator": "greaterThan", "value": 18}]}`), &c); err != nil {
+		t.Fatalf("Expected the shorthand to be accepted in groups, got %v", err)
+	}
+	out, err := c.ToJSON(true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.(string), `"any":["vipCustomer",`) {
+		t.Errorf("Expected the shorthand to round trip, got %s", out)
+	}
+}
+
+func TestConditionUnmarshalErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		snippet string
+	}{
+		{"number", `{"all": [42]}`, "42"},
+		{"array", `{"not": [{"fact": "a"}]}`, `[{"fact": "a"}]`},
+		{"wrong field type", `{"all": [{"fact": "a", "operator": "equal", "value": 1, "priority": "high"}]}`, `"priority": "high"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var c Condition
+			err := json.Unmarshal([]byte(tc.data), &c)
+			var invalid *InvalidConditionError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Expected an InvalidConditionError, got %v", err)
+			}
+			if !strings.Contains(invalid.Snippet, tc.snippet) {
+				t.Errorf("Expected the snippet to contain %s, got %s", tc.snippet, invalid.Snippet)
+			}
+			if !strings.Contains(err.Error(), "condition name string") {
+				t.Errorf("Expected the error to list the accepted shapes, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestConditionNegate(t *testing.T) {
+	almanac := NewAlmanac(gjson.Parse(`{"country": "DE"}`), Options{}, 0)
+	operators := map[string]Operator{}
+	for _, op := range DefaultOperators() {
+		operators[op.Name] = op
+	}
+
+	testCases := []struct {
+		name             string
+		data             string
+		expected         bool
+		expectedOperator bool
+	}{
+		{"negate flag", `{"fact": "country", "operator": "in", "value": ["US", "CA"], "negate": true}`, true, false},
+		{"operator prefix", `{"fact": "country", "operator": "!in", "value": ["US", "CA"]}`, true, false},
+		{"prefix and flag cancel out", `{"fact": "country", "operator": "!in", "value": ["US", "CA"], "negate": true}`, false, false},
+		{"registered operator names win", `{"fact": "country", "operator": "!=", "value": "DE"}`, false, false},
+		{"negated passing operator", `{"fact": "country", "operator": "equal", "value": "DE", "negate": true}`, false, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var condition Condition
+			if err := json.Unmarshal([]byte(tc.data), &condition); err != nil {
+				t.Fatalf("Failed to unmarshal condition: %v", err)
+			}
+			res, err := condition.Evaluate(almanac, operators)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Result != tc.expected || res.OperatorResult != tc.expectedOperator {
+				t.Errorf("Expected result %v and operator result %v, got %v and %v", tc.expected, tc.expectedOperator, res.Result, res.OperatorResult)
+			}
+		})
+	}
+
+	t.Run("result tree shows the operator outcome", func(t *testing.T) {
+		condition := Condition{Fact: "country", Operator: "in", Value: ValueNode{Type: Array, Array: []ValueNode{{Type: String, String: "US"}}}, Negate: true}
+		res, err := condition.Evaluate(almanac, operators)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		condition.applyEvaluationResult(res)
+		out, err := condition.ToJSON(false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		props := out.(map[string]interface{})
+		if props["negate"] != true || props["result"] != true || props["operatorResult"] != false {
+			t.Errorf("Expected negate, result and operatorResult in %v", props)
+		}
+	})
+
+	t.Run("unknown negated operator", func(t *testing.T) {
+		condition := Condition{Fact: "country", Operator: "!unknown", Value: ValueNode{Type: String, String: "DE"}}
+		if _, err := condition.Evaluate(almanac, operators); err == nil {
+			t.Errorf("Expected an unknown operator error")
+		}
+	})
+
+	t.Run("negate is rejected on groups", func(t *testing.T) {
+		for _, data := range []string{
+			`{"all": [{"fact": "country", "operator": "equal", "value": "DE"}], "negate": true}`,
+			`{"condition": "european", "negate": true}`,
+		} {
+			var condition Condition
+			if err := json.Unmarshal([]byte(data), &condition); err == nil || !strings.Contains(err.Error(), "use not") {
+				t.Errorf("Expected negate to be rejected for %s, got %v", data, err)
+			}
+		}
+	})
+}
+
+func TestConditionFactReferenceValue(t *testing.T) {
+	facts := gjson.Parse(`{"shipping": {"country": "DE"}, "billing": {"country": "DE"}, "home": "FR", "limit": 100, "amount": 120}`)
+	almanac := NewAlmanac(facts, Options{}, 0)
+	operators := map[string]Operator{}
+	for _, op := range DefaultOperators() {
+		operators[op.Name] = op
+	}
+
+	testCases := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{"equal facts", `{"fact": "shipping.country", "operator": "equal", "value": {"fact": "billing.country"}}`, true},
+		{"unequal facts", `{"fact": "shipping.country", "operator": "equal", "value": {"fact": "home"}}`, false},
+		{"number comparison", `{"fact": "amount", "operator": "greaterThan", "value": {"fact": "limit"}}`, true},
+		{"in with fact elements", `{"fact": "shipping.country", "operator": "in", "value": [{"fact": "home"}, {"fact": "billing.country"}]}`, true},
+		{"in with mixed elements", `{"fact": "home", "operator": "in", "value": ["US", {"fact": "billing.country"}]}`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var condition Condition
+			if err := json.Unmarshal([]byte(tc.data), &condition); err != nil {
+				t.Fatalf("Failed to unmarshal condition: %v", err)
+			}
+			res, err := condition.Evaluate(almanac, operators)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, res.Result)
+			}
+		})
+	}
+
+	condition := Condition{Fact: "home", Operator: "notEqual", Value: ValueNode{Type: Object, Object: map[string]ValueNode{"fact": {Type: String, String: "missing"}}}}
+	if _, err := condition.Evaluate(almanac, operators); err == nil {
+		t.Errorf("Expected an undefined referenced fact to fail the condition")
+	}
+	allowUndefined := true
+	lenient := NewAlmanac(facts, Options{AllowUndefinedFacts: &allowUndefined}, 0)
+	if res, err := condition.Evaluate(lenient, operators); err != nil || !res.Result || res.RightHandSideValue.(ValueNode).Type != Null {
+		t.Errorf("Expected an undefined referenced fact to compare as null, got %v, %v", res, err)
+	}
+
+	engine := newTestEngine(t, `{"name": "r", "conditions": {"all": [{"any": [
+		{"fact": "home", "operator": "in", "value": [{"fact": "shipping.country"}]},
+		{"fact": "shipping.country", "operator": "equal", "value": {"fact": "billing.country"}}
+	]}]}, "event": {"type": "r"}}`, nil)
+	res, err := engine.Run(context.Background(), []byte(facts.Raw))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Errorf("Expected fact references to resolve inside nested groups")
+	}
+}
+
+func TestConditionPath(t *testing.T) {
+	newPathEngine := func(t *testing.T, conditions string, options *RuleEngineOptions) *Engine {
+		t.Helper()
+		engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, options)
+		err := engine.AddCalculatedFact("order", func(a *Almanac, params ...interface{}) *ValueNode {
+			return NewValueFromGjson(gjson.Parse(`{"items": [{"sku": "ABC", "qty": 2}]}`))
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		return engine
+	}
+	facts := []byte(`{"customer": {"addresses": [{"country": "CH"}]}}`)
+
+	testCases := []struct {
+		name, conditions string
+		passes           bool
+	}{
+		{"calculated fact", `{"all": [{"fact": "order", "path": "items.0.sku", "operator": "equal", "value": "ABC"}]}`, true},
+		{"raw fact", `{"all": [{"fact": "customer", "path": "addresses.0.country", "operator": "equal", "value": "CH"}]}`, true},
+		{"different paths of one fact", `{"all": [
+			{"fact": "order", "path": "items.0.sku", "operator": "equal", "value": "ABC"},
+			{"fact": "order", "path": "items.0.qty", "operator": "equal", "value": 2}
+		]}`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newPathEngine(t, tc.conditions, nil).Run(context.Background(), facts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if passed := len(res.Results) == 1; passed != tc.passes {
+				t.Errorf("Expected %v, got %v", tc.passes, passed)
+			}
+		})
+	}
+
+	missing := `{"all": [{"fact": "order", "path": "items.5.sku", "operator": "notEqual", "value": "ABC"}]}`
+	if _, err := newPathEngine(t, missing, nil).Run(context.Background(), facts); err == nil || !strings.Contains(err.Error(), "undefined fact") {
+		t.Errorf("Expected an unresolvable path to be an undefined fact, got %v", err)
+	}
+	res, err := newPathEngine(t, missing, &RuleEngineOptions{AllowUndefinedFacts: true}).Run(context.Background(), facts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Errorf("Expected an unresolvable path to compare as null when undefined facts are allowed")
+	}
+}
+
+func TestConditionDynamicFactPath(t *testing.T) {
+	facts := []byte(`{"amount": 110, "currency": "USD", "limits": {"EUR": 100, "USD": 120, "{raw}": 5, "a.b": 1}}`)
+	testCases := []struct {
+		name, conditions string
+		passes           bool
+	}{
+		{"key from another fact", `{"all": [{"fact": "limits.{currency}", "operator": "greaterThanInclusive", "value": {"fact": "amount"}}]}`, true},
+		{"literal braces", `{"all": [{"fact": "limits.{{raw}}", "operator": "equal", "value": 5}]}`, true},
+		{"key with a path separator", `{"all": [{"fact": "limits.{key}", "operator": "equal", "value": 1}]}`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newTestEngine(t, `{"name": "r", "facts": {"key": "a.b"}, "conditions": `+tc.conditions+`, "event": {"type": "r"}}`, nil)
+			res, err := engine.Run(context.Background(), facts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if passed := len(res.Results) == 1; passed != tc.passes {
+				t.Errorf("Expected %v, got %v", tc.passes, passed)
+			}
+		})
+	}
+
+	t.Run("resolved path in the result", func(t *testing.T) {
+		engine := newTestEngine(t, `{"name": "r", "conditions": {"all": [{"fact": "limits.{currency}", "operator": "equal", "value": 120}]}, "event": {"type": "r"}}`, nil)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		results := res.Results
+		if len(results) != 1 || results[0].Conditions.All[0].ResolvedFact != "limits.USD" {
+			t.Fatalf("Expected the concrete path limits.USD, got %v", results)
+		}
+		out, err := results[0].Conditions.All[0].ToJSON(true)
+		if err != nil || !strings.Contains(out.(string), `"resolvedFact":"limits.USD"`) {
+			t.Errorf("Expected resolvedFact in the JSON, got %v, %v", out, err)
+		}
+	})
+
+	t.Run("missing key fact", func(t *testing.T) {
+		conditions := `{"all": [{"fact": "limits.{region}", "operator": "notEqual", "value": 1}]}`
+		if _, err := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, nil).Run(context.Background(), facts); err == nil || !strings.Contains(err.Error(), "undefined fact: region") {
+			t.Errorf("Expected an undefined fact error, got %v", err)
+		}
+		engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, &RuleEngineOptions{AllowUndefinedFacts: true})
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 {
+			t.Errorf("Expected the fact to be handled as undefined")
+		}
+	})
+
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"fact": "limits.{currency", "operator": "equal", "value": 1}`), &c); err == nil || !strings.Contains(err.Error(), "unterminated") {
+		t.Errorf("Expected an unterminated segment to be rejected, got %v", err)
+	}
+}
+
+func TestConditionFalsyValues(t *testing.T) {
+	testCases := []struct {
+		value, facts string
+	}{
+		{`0`, `{"a": 0}`},
+		{`false`, `{"a": false}`},
+		{`""`, `{"a": ""}`},
+		{`null`, `{"a": null}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			var c Condition
+			if err := json.Unmarshal([]byte(`{"fact": "a", "operator": "equal", "value": `+tc.value+`}`), &c); err != nil {
+				t.Fatalf("Expected value %s to be accepted, got %v", tc.value, err)
+			}
+			if !c.ValueSet {
+				t.Errorf("Expected ValueSet")
+			}
+			engine := newTestEngine(t, `{"name": "r", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": `+tc.value+`}]}, "event": {"type": "r"}}`, nil)
+			res, err := engine.Run(context.Background(), []byte(tc.facts))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(res.Results) != 1 {
+				t.Errorf("Expected the fact to equal %s", tc.value)
+			}
+		})
+	}
+
+	var missing Condition
+	if err := json.Unmarshal([]byte(`{"fact": "a", "operator": "equal"}`), &missing); err == nil {
+		t.Errorf("Expected a condition without value to be rejected")
+	}
+}
+
+func TestConditionNestedValidation(t *testing.T) {
+	missingFact := "conditions.all[1].any[0]: if value, operator, or fact are set, all three must be provided"
+
+	t.Run("rules built in code", func(t *testing.T) {
+		_, err := NewRule(&RuleConfig{
+			Name: "nested",
+			Conditions: Condition{All: []*Condition{
+				{Fact: "age", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 18}},
+				{Any: []*Condition{{Operator: "equal", Value: ValueNode{Type: String, String: "DE"}}}},
+			}},
+			Event: EventConfig{Type: "nested"},
+		})
+		if err == nil || err.Error() != missingFact {
+			t.Fatalf("Expected %q, got %v", missingFact, err)
+		}
+		var pathErr *ConditionPathError
+		if !errors.As(err, &pathErr) || pathErr.Path != "conditions.all[1].any[0]" {
+			t.Errorf("Expected a ConditionPathError, got %#v", err)
+		}
+	})
+
+	testCases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"nested groups", `{"all": [{"fact": "age", "operator": "greaterThan", "value": 18}, {"any": [{"operator": "equal", "value": "DE"}]}]}`, missingFact},
+		{"named groups", `{"any": {"adult": {"fact": "age", "operator": "greaterThan"}}}`, "conditions.any.adult: if value, operator, or fact are set, all three must be provided"},
+		{"not", `{"not": {"all": [{"fact": "age", "path": "$.x"}]}}`, "conditions.not.all[0]: if value, operator, or fact are set, all three must be provided"},
+		{"null condition", `{"none": [null]}`, "conditions.none[0]: condition must not be null"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var config RuleConfig
+			err := json.Unmarshal([]byte(`{"name": "nested", "conditions": `+tc.data+`, "event": {"type": "nested"}}`), &config)
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("Expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	t.Run("root errors are not prefixed", func(t *testing.T) {
+		cond := Condition{Any: []*Condition{{Fact: "age"}}}
+		if err := cond.Validate(); err == nil || err.Error() != "any[0]: if value, operator, or fact are set, all three must be provided" {
+			t.Errorf("Expected the path relative to the condition, got %v", err)
+		}
+	})
+}
+
+func TestConditionFreshFact(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "counter",
+		"conditions": {"ordered": true, "all": [
+			{"fact": "counter", "operator": "equal", "value": 1},
+			{"fact": "counter", "freshFact": true, "operator": "equal", "value": 2},
+			{"fact": "counter", "operator": "equal", "value": 1}
+		]},
+		"event": {"type": "counter"}
+	}`, nil)
+	calls := 0
+	err := engine.AddCalculatedFact("counter", func(a *Almanac, params ...interface{}) *ValueNode {
+		calls++
+		return &ValueNode{Type: Number, Number: float64(calls)}
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+
+	options := DefaultRunOptions()
+	options.MemoizeConditions = true
+	res, err := engine.RunWithOptions(context.Background(), []byte(`{}`), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 || calls != 2 {
+		t.Fatalf("Expected the fresh read not to replace the cached value, got %d calculations and failures %v", calls, res.FailureResults)
+	}
+	conditions := res.Results[0].Conditions.All
+	if conditions[0].FreshResolution || !conditions[1].FreshResolution || conditions[2].FreshResolution {
+		t.Errorf("Expected only the second condition to be flagged fresh")
+	}
+	out, err := res.Results[0].Conditions.ToJSON(true)
+	if err != nil || strings.Count(out.(string), `"freshResolution":true`) != 1 {
+		t.Errorf("Expected a single freshResolution in the result tree, got %v, %v", out, err)
+	}
+
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"all": [{"freshFact": true, "facts": ["a", "b"], "operator": "subsetOf"}]}`), &c); err == nil {
+		t.Errorf("Expected freshFact without a single fact to be rejected")
+	}
+}
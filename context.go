@@ -2,24 +2,138 @@ package rulesengine
 
 import (
 	"context"
+	"sync"
 )
 
 // ExecutionContext holds metadata and control flags for rule execution.
+// It is shared by all goroutines of a run; use Stop, Stopped, AddError and GetErrors
+// rather than the fields directly when the context is in use.
 type ExecutionContext struct {
 	context.Context
-	Cancel    context.CancelFunc
+	Cancel context.CancelFunc
+	// StopEarly is set by Stop, i.e. by Engine.Stop. Groups deciding early, e.g. an 'any' group with a true condition,
+	// only stop their own remaining conditions and never stop the run.
 	StopEarly bool
 	Message   string
 	Errors    []error
+	Values    *Values
+	mu        sync.Mutex
+	stopOnce  sync.Once
+	// stopProcessing is set by StopProcessing; the priority group being evaluated still completes
+	stopProcessing bool
 }
 
+// NewEvaluationContext creates a cancellable ExecutionContext derived from ctx.
+// A nil ctx is treated as context.Background().
 func NewEvaluationContext(ctx context.Context) *ExecutionContext {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return newExecutionContext(ctx, cancel, NewValues(nil))
+}
+
+// newExecutionContext creates an ExecutionContext around an already cancellable context
+func newExecutionContext(ctx context.Context, cancel context.CancelFunc, values *Values) *ExecutionContext {
+	if cancel == nil {
+		cancel = func() {}
+	}
 	return &ExecutionContext{
 		Context: ctx,
+		Cancel:  cancel,
 		Errors:  []error{},
+		Values:  values,
 	}
 }
 
+// Stop marks the execution as stopped early with the given message and cancels the context.
+// It is safe to call from multiple goroutines; only the first call records its message.
+func (c *ExecutionContext) Stop(message string) {
+	c.stopOnce.Do(func() {
+		c.mu.Lock()
+		c.StopEarly = true
+		c.Message = message
+		c.mu.Unlock()
+		if c.Cancel != nil {
+			c.Cancel()
+		}
+	})
+}
+
+// Stopped reports whether the execution was stopped early
+func (c *ExecutionContext) Stopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.StopEarly
+}
+
+// StopProcessing stops the run after the priority group being evaluated: its rules complete and their events are
+// published, the rules of lower priority groups are not evaluated. Unlike Stop and Engine.Stop, it only affects the run
+// of this context and never interrupts a group, so the outcome does not depend on the interleaving of rules.
+// Event handlers and calculated facts reach it through Almanac.ExecutionContext.
+func (c *ExecutionContext) StopProcessing() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopProcessing = true
+}
+
+// ProcessingStopped reports whether StopProcessing was called
+func (c *ExecutionContext) ProcessingStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopProcessing
+}
+
+// AddError records an error that occurred during execution
 func (c *ExecutionContext) AddError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.Errors = append(c.Errors, err)
 }
+
+// GetErrors returns a copy of the errors recorded during execution
+func (c *ExecutionContext) GetErrors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	errs := make([]error, len(c.Errors))
+	copy(errs, c.Errors)
+	return errs
+}
+
+// Values is a concurrency-safe, run-scoped key/value store for side-channel data
+// such as a tenant ID or locale shared between handlers, operators and fact resolvers.
+// It is not an input to condition logic; conditions only ever read facts.
+type Values struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// NewValues creates a Values store seeded with a copy of the given map (can be nil).
+func NewValues(initial map[string]interface{}) *Values {
+	values := make(map[string]interface{}, len(initial))
+	for k, v := range initial {
+		values[k] = v
+	}
+	return &Values{values: values}
+}
+
+// Set stores a value under the given key. Setting a value on a nil store is a no-op.
+func (v *Values) Set(key string, value interface{}) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[key] = value
+}
+
+// Get returns the value stored under the given key and whether it exists
+func (v *Values) Get(key string) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[key]
+	return value, ok
+}
@@ -0,0 +1,100 @@
+package rulesengine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestNewEvaluationContext(t *testing.T) {
+	t.Run("Stop is idempotent and cancels the context", func(t *testing.T) {
+		ctx := NewEvaluationContext(context.Background())
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx.Stop("stopped")
+			}()
+		}
+		wg.Wait()
+		if !ctx.Stopped() || ctx.Message != "stopped" {
+			t.Errorf("Expected context to be stopped with message, got %v %q", ctx.Stopped(), ctx.Message)
+		}
+		if ctx.Err() == nil {
+			t.Errorf("Expected the underlying context to be cancelled")
+		}
+	})
+
+	t.Run("Nil parent context and cancel are usable", func(t *testing.T) {
+		ctx := NewEvaluationContext(nil)
+		ctx.Cancel()
+		ctx.Stop("stopped")
+
+		bare := &ExecutionContext{Context: context.Background()}
+		bare.Stop("stopped")
+		if !bare.Stopped() {
+			t.Errorf("Expected a context without Cancel to stop")
+		}
+	})
+
+	t.Run("Errors are collected concurrently", func(t *testing.T) {
+		ctx := NewEvaluationContext(context.Background())
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx.AddError(errors.New("failed"))
+			}()
+		}
+		wg.Wait()
+		if n := len(ctx.GetErrors()); n != 50 {
+			t.Errorf("Expected 50 errors, got %d", n)
+		}
+	})
+
+	t.Run("Failing all group does not panic", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "nested",
+			"conditions": {"any": [{"all": [
+				{"fact": "a", "operator": "equal", "value": 1},
+				{"fact": "b", "operator": "equal", "value": 1}
+			]}]},
+			"event": {"type": "nested"}
+		}`, nil)
+		almanac := NewAlmanac(gjson.Parse(`{"a": 1, "b": 2}`), Options{}, 0)
+		if _, err := engine.Rules[0].Evaluate(NewEvaluationContext(context.Background()), almanac); err != nil {
+			t.Errorf("Expected evaluation to succeed, got error: %v", err)
+		}
+	})
+}
+
+func TestValues(t *testing.T) {
+	t.Run("Nil store ignores sets and has no values", func(t *testing.T) {
+		var values *Values
+		values.Set("tenant", "acme")
+		if _, ok := values.Get("tenant"); ok {
+			t.Errorf("Expected a nil store to have no values")
+		}
+	})
+
+	t.Run("Almanac values are ready without a run", func(t *testing.T) {
+		almanac := NewAlmanac(gjson.Parse(`{}`), Options{}, 0)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				almanac.Values().Set("tenant", "acme")
+			}()
+		}
+		wg.Wait()
+		if tenant, _ := almanac.Values().Get("tenant"); tenant != "acme" {
+			t.Errorf("Expected the value to be stored, got %v", tenant)
+		}
+	})
+}
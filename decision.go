@@ -0,0 +1,72 @@
+package rulesengine
+
+import (
+	"sort"
+)
+
+// Decision returns the event of the highest priority successful rule of a run, together with its result.
+// Rules of equal priority are ordered by the order in which they were added to the engine, so the decision
+// is deterministic even though rules of a priority group are evaluated concurrently.
+// Events dropped to resolve an exclusive event conflict are never chosen.
+// Params:
+// - results: The successful rule results of a run, RunResult.Results.
+// Returns false when no rule emitted an event.
+func Decision(results []*RuleResult) (*Event, *RuleResult, bool) {
+	ordered := decisionResults(results)
+	if len(ordered) == 0 {
+		return nil, nil, false
+	}
+	return &ordered[0].Event, ordered[0], true
+}
+
+// DecisionsByType returns the events of the given type emitted by successful rules, in decision order.
+// Params:
+// - results: The successful rule results of a run, RunResult.Results.
+// - eventType: The event type of interest.
+func DecisionsByType(results []*RuleResult, eventType string) []*Event {
+	var events []*Event
+	for _, ruleResult := range decisionResults(results) {
+		if ruleResult.Event.Type == eventType {
+			events = append(events, &ruleResult.Event)
+		}
+	}
+	return events
+}
+
+// decisionResults returns the results that emitted an event, ordered by priority and declaration order
+func decisionResults(results []*RuleResult) []*RuleResult {
+	candidates := make([]*RuleResult, 0, len(results))
+	var rules []*Rule
+	for _, ruleResult := range results {
+		if ruleResult == nil || ruleResult.Result == nil || !*ruleResult.Result || ruleResult.Dropped || ruleResult.EventSuppressed {
+			continue
+		}
+		if rules == nil && ruleResult.rule != nil && ruleResult.rule.Engine != nil {
+			rules = ruleResult.rule.Engine.GetRules()
+		}
+		candidates = append(candidates, ruleResult)
+	}
+	sortByPriority(candidates, rules)
+	return candidates
+}
+
+// sortByPriority orders rule results by descending priority, and rules of equal priority by their position
+// in rules, i.e. the order in which they were added to the engine. Results of unknown rules keep their order.
+func sortByPriority(results []*RuleResult, rules []*Rule) {
+	position := make(map[*Rule]int, len(rules))
+	for i, r := range rules {
+		position[r] = i
+	}
+	positionOf := func(ruleResult *RuleResult) int {
+		if p, ok := position[ruleResult.rule]; ok {
+			return p
+		}
+		return len(rules)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Priority != results[j].Priority {
+			return results[i].Priority > results[j].Priority
+		}
+		return positionOf(results[i]) < positionOf(results[j])
+	})
+}
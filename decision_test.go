@@ -0,0 +1,61 @@
+package rulesengine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDecision(t *testing.T) {
+	engine := newScoreRulesEngine(t, nil,
+		scoreRule{"review", "review", 1},
+		scoreRule{"approveA", "approve", 5},
+		scoreRule{"approveB", "approve", 5},
+		scoreRule{"decline", "decline", 5},
+	)
+
+	// Rules of a priority group finish in any order, the decision must not depend on it
+	for i := 0; i < 20; i++ {
+		res, err := engine.Run(context.Background(), []byte(`{"score": 50}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		results := res.Results
+		event, ruleResult, ok := Decision(results)
+		if !ok || event.Type != "approve" || ruleResult.Name != "approveA" {
+			t.Fatalf("Expected approveA to decide, got %v %v", event, ruleResult)
+		}
+		approvals := DecisionsByType(results, "approve")
+		if len(approvals) != 2 {
+			t.Errorf("Expected 2 approve events, got %d", len(approvals))
+		}
+		if len(DecisionsByType(results, "unknown")) != 0 {
+			t.Errorf("Expected no events of an unknown type")
+		}
+	}
+
+	t.Run("no successful rule", func(t *testing.T) {
+		res, err := engine.Run(context.Background(), []byte(`{"score": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, _, ok := Decision(res.Results); ok {
+			t.Errorf("Expected no decision")
+		}
+	})
+}
+
+func TestDecisionSkipsDroppedEvents(t *testing.T) {
+	engine := newConflictTestEngine(t, ConflictHighestPriorityWins)
+	engine.DeclareExclusiveEvents("notify", "approve")
+	res, err := engine.Run(context.Background(), []byte(`{"score": 50}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := res.Results
+	if event, _, ok := Decision(results); !ok || event.Type != "approve" {
+		t.Errorf("Expected approve to decide, got %v", event)
+	}
+	if events := DecisionsByType(results, "decline"); len(events) != 0 {
+		t.Errorf("Expected the dropped decline event to be excluded, got %v", events)
+	}
+}
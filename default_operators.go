@@ -1,18 +1,24 @@
 package rulesengine
 
 import (
+	"container/list"
+	"fmt"
+	"regexp"
 	"strings"
+	"sync"
 )
 
 // EvalEqual checks if two ValueNode instances are equal.
 // It compares their types first, and if they match, it evaluates their values.
-// Supported types: String, Number, Bool, Array.
+// Supported types: Null, String, Number, Bool, Array, Object.
 // Returns true if both nodes have the same type and value, false otherwise.
 func EvalEqual(a, b *ValueNode) bool {
 	if !a.SameType(b) {
 		return false
 	}
 	switch a.Type {
+	case Null:
+		return true
 	case String:
 		return a.String == b.String
 	case Number:
@@ -29,6 +35,17 @@ func EvalEqual(a, b *ValueNode) bool {
 			}
 		}
 		return true
+	case Object:
+		if len(a.Object) != len(b.Object) {
+			return false
+		}
+		for key, av := range a.Object {
+			bv, ok := b.Object[key]
+			if !ok || !EvalEqual(&av, &bv) {
+				return false
+			}
+		}
+		return true
 	default:
 		return false
 	}
@@ -70,6 +87,29 @@ func EvalNotIn(a, b *ValueNode) bool {
 	return !EvalIn(a, b)
 }
 
+// EvalInFact checks if a ValueNode instance is a member of a set held by another fact, an element of an array or,
+// for strings, a key of an object. Empty sets and Null, e.g. an undefined fact, contain nothing.
+// Returns true if 'a' is in the set 'b', false otherwise.
+func EvalInFact(a, b *ValueNode) bool {
+	switch b.Type {
+	case Array:
+		return EvalIn(a, b)
+	case Object:
+		if !a.IsString() {
+			return false
+		}
+		_, ok := b.Object[a.String]
+		return ok
+	}
+	return false
+}
+
+// EvalNotInFact checks if a ValueNode instance is not a member of a set held by another fact.
+// It returns the negation of EvalInFact, so it is true for empty sets.
+func EvalNotInFact(a, b *ValueNode) bool {
+	return !EvalInFact(a, b)
+}
+
 // EvalLessThan checks if the first ValueNode is less than the second.
 // Both 'a' and 'b' must be numbers for the comparison to be valid.
 // Returns true if 'a' is less than 'b', false otherwise.
@@ -110,6 +150,24 @@ func EvalGreaterOrEqual(a, b *ValueNode) bool {
 	return a.Number >= b.Number
 }
 
+// EvalBetween checks if the number in the first ValueNode lies within the [low, high] range in the second ValueNode,
+// bounds included. Returns false for non-number facts and invalid ranges.
+func EvalBetween(a, b *ValueNode) bool {
+	if !a.IsNumber() || rangeCheck(b) != nil {
+		return false
+	}
+	return a.Number >= b.Array[0].Number && a.Number <= b.Array[1].Number
+}
+
+// EvalNotBetween checks if the number in the first ValueNode lies outside the [low, high] range in the second ValueNode.
+// Returns false for non-number facts and invalid ranges.
+func EvalNotBetween(a, b *ValueNode) bool {
+	if !a.IsNumber() || rangeCheck(b) != nil {
+		return false
+	}
+	return a.Number < b.Array[0].Number || a.Number > b.Array[1].Number
+}
+
 // EvalStartsWith checks if the string in the first ValueNode starts with the string in the second ValueNode.
 // Both 'a' and 'b' must be strings for the comparison to be valid.
 // Returns true if 'a' starts with 'b', false otherwise.
@@ -140,6 +198,258 @@ func EvalIncludes(a, b *ValueNode) bool {
 	return strings.Contains(a.String, b.String)
 }
 
+// EvalHasKey checks if the object in the first ValueNode contains the key in the second ValueNode.
+// Nested keys can be addressed using dot syntax (e.g. "consent.marketing").
+// Returns true if 'a' is an object containing the key 'b', false otherwise.
+func EvalHasKey(a, b *ValueNode) bool {
+	if !a.IsObject() || !b.IsString() {
+		return false
+	}
+	current := a
+	for _, key := range strings.Split(b.String, ".") {
+		if !current.IsObject() {
+			return false
+		}
+		child, ok := current.Object[key]
+		if !ok {
+			return false
+		}
+		current = &child
+	}
+	return true
+}
+
+// EvalNotHasKey checks if the object in the first ValueNode does not contain the key in the second ValueNode.
+// It returns the negation of EvalHasKey for objects.
+// Returns true if 'a' is an object without the key 'b', false otherwise.
+func EvalNotHasKey(a, b *ValueNode) bool {
+	if !a.IsObject() || !b.IsString() {
+		return false
+	}
+	return !EvalHasKey(a, b)
+}
+
+// EvalKeyCountGreaterThan checks if the object in the first ValueNode has more keys than the number in the second ValueNode.
+// Returns true if 'a' is an object with more than 'b' keys, false otherwise.
+func EvalKeyCountGreaterThan(a, b *ValueNode) bool {
+	if !a.IsObject() || !b.IsNumber() {
+		return false
+	}
+	return float64(len(a.Object)) > b.Number
+}
+
+// EvalKeyCountEqual checks if the object in the first ValueNode has exactly the number of keys in the second ValueNode.
+// Returns true if 'a' is an object with 'b' keys, false otherwise.
+func EvalKeyCountEqual(a, b *ValueNode) bool {
+	if !a.IsObject() || !b.IsNumber() {
+		return false
+	}
+	return float64(len(a.Object)) == b.Number
+}
+
+// RegexMatch is the match detail of the regex operator
+type RegexMatch struct {
+	Groups []string          `json:"groups"`          // The whole match followed by the capture groups
+	Named  map[string]string `json:"named,omitempty"` // The named capture groups
+}
+
+// compiledPattern is a cached compilation of a regular expression, err is set for invalid patterns
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// maxCompiledPatterns bounds the pattern cache, so rules or facts with ever new patterns cannot grow it without limit
+const maxCompiledPatterns = 1024
+
+// compiledPatterns caches the compiled patterns of the regex operators, keyed by pattern
+var compiledPatterns = newPatternCache(maxCompiledPatterns)
+
+// patternCache is a bounded LRU cache of compiled patterns
+type patternCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type patternCacheEntry struct {
+	pattern  string
+	compiled compiledPattern
+}
+
+// newPatternCache creates a cache holding at most capacity patterns
+func newPatternCache(capacity int) *patternCache {
+	return &patternCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// get returns the compilation of a pattern if it is cached
+func (c *patternCache) get(pattern string) (compiledPattern, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[pattern]
+	if !ok {
+		return compiledPattern{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*patternCacheEntry).compiled, true
+}
+
+// put stores the compilation of a pattern, evicting the least recently used pattern when the cache is full
+func (c *patternCache) put(pattern string, compiled compiledPattern) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[pattern]; ok {
+		el.Value.(*patternCacheEntry).compiled = compiled
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[pattern] = c.order.PushFront(&patternCacheEntry{pattern: pattern, compiled: compiled})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*patternCacheEntry).pattern)
+	}
+}
+
+// len returns the number of cached patterns
+func (c *patternCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// compilePattern returns the compiled regular expression for a pattern, or nil when it is invalid
+func compilePattern(pattern string) *regexp.Regexp {
+	re, _ := compilePatternErr(pattern)
+	return re
+}
+
+// compilePatternErr returns the compiled regular expression for a pattern, or the compilation error
+func compilePatternErr(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := compiledPatterns.get(pattern); ok {
+		return compiled.re, compiled.err
+	}
+	re, err := regexp.Compile(pattern)
+	compiledPatterns.put(pattern, compiledPattern{re: re, err: err})
+	return re, err
+}
+
+// EvalMatches checks if the string in the first ValueNode matches the regular expression in the second ValueNode.
+// Returns false for non-string operands and invalid patterns.
+func EvalMatches(a, b *ValueNode) bool {
+	if !a.IsString() || !b.IsString() {
+		return false
+	}
+	re := compilePattern(b.String)
+	return re != nil && re.MatchString(a.String)
+}
+
+// EvalDoesNotMatch checks if the string in the first ValueNode does not match the regular expression in the second ValueNode.
+// Returns false for non-string operands and invalid patterns.
+func EvalDoesNotMatch(a, b *ValueNode) bool {
+	if !a.IsString() || !b.IsString() {
+		return false
+	}
+	re := compilePattern(b.String)
+	return re != nil && !re.MatchString(a.String)
+}
+
+// EvalRegex checks if the string in the first ValueNode matches the regular expression in the second ValueNode.
+// The match detail is a RegexMatch with the capture groups of the first match.
+// Returns false for non-string operands and invalid patterns.
+func EvalRegex(a, b *ValueNode) (bool, interface{}) {
+	if !a.IsString() || !b.IsString() {
+		return false, nil
+	}
+	re := compilePattern(b.String)
+	if re == nil {
+		return false, nil
+	}
+	groups := re.FindStringSubmatch(a.String)
+	if groups == nil {
+		return false, nil
+	}
+	detail := &RegexMatch{Groups: groups}
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if detail.Named == nil {
+			detail.Named = map[string]string{}
+		}
+		detail.Named[name] = groups[i]
+	}
+	return true, detail
+}
+
+// EvalAnyElement checks if the array in the first ValueNode has an element equal to the second ValueNode,
+// or contained in it when the second ValueNode is an array.
+// The match detail is the ElementMatch of the first matching element.
+func EvalAnyElement(a, b *ValueNode) (bool, interface{}) {
+	if !a.IsArray() {
+		return false, nil
+	}
+	matches := matchElements(a, b)
+	if len(matches) == 0 {
+		return false, nil
+	}
+	return true, matches[0]
+}
+
+func regexValidator(value *ValueNode) bool {
+	return value.IsString() && compilePattern(value.String) != nil
+}
+
+// rangeCheck reports why a condition value is not a [low, high] range of numbers
+func rangeCheck(value *ValueNode) error {
+	if !value.IsArray() || len(value.Array) != 2 {
+		return fmt.Errorf("range must be an array of two numbers [low, high], got %s", value.Type)
+	}
+	low, high := &value.Array[0], &value.Array[1]
+	if !low.IsNumber() || !high.IsNumber() {
+		return fmt.Errorf("range must be an array of two numbers [low, high], got [%s, %s]", low.Type, high.Type)
+	}
+	if low.Number > high.Number {
+		return fmt.Errorf("range low %v is greater than high %v", low.Number, high.Number)
+	}
+	return nil
+}
+
+func rangeValidator(value *ValueNode) bool {
+	return rangeCheck(value) == nil
+}
+
+// patternCheck reports why a condition value cannot be used as a regular expression
+func patternCheck(value *ValueNode) error {
+	if !value.IsString() {
+		return fmt.Errorf("pattern must be a string, got %s", value.Type)
+	}
+	if _, err := compilePatternErr(value.String); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", value.String, err)
+	}
+	return nil
+}
+
+// factReferenceValidator accepts fact references only, e.g. {"fact": "tenant.allowedCountries"}
+func factReferenceValidator(value *ValueNode) bool {
+	_, ok := factReference(value)
+	return ok
+}
+
+// factSetCheck checks the set resolved from the fact reference of inFact and notInFact, Null for undefined facts
+func factSetCheck(value *ValueNode) error {
+	switch value.Type {
+	case Array, Object, Null:
+		return nil
+	}
+	return fmt.Errorf("set must be an array or object fact, got %s", value.Type)
+}
+
 // **************************************************************************************
 // FACT VALIDATOR FUNCTIONS
 func exists(a *ValueNode) bool {
@@ -158,6 +468,68 @@ func stringValidator(a *ValueNode) bool {
 	return a.Type == String
 }
 
+func objectValidator(a *ValueNode) bool {
+	return a.Type == Object
+}
+
+// EvalSubsetOf is a multi-fact operator checking that every element of the first fact is contained in the second fact.
+// A scalar first fact is treated as a single element. The condition value is not used.
+func EvalSubsetOf(facts []*ValueNode, _ *ValueNode) bool {
+	if len(facts) != 2 || !facts[1].IsArray() {
+		return false
+	}
+	if !facts[0].IsArray() {
+		return facts[0].Type != Null && EvalIn(facts[0], facts[1])
+	}
+	for i := range facts[0].Array {
+		if !EvalIn(&facts[0].Array[i], facts[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// typeValidator returns the validator for a type hint, or nil for "any"
+func typeValidator(typeHint string) func(*ValueNode) bool {
+	switch typeHint {
+	case "number":
+		return numberValidator
+	case "string":
+		return stringValidator
+	case "array":
+		return isArray
+	case "object":
+		return objectValidator
+	case "fact":
+		return factReferenceValidator
+	default:
+		return nil
+	}
+}
+
+// defaultOperatorMetadata holds the operand type hints of the default operators and their aliases
+var defaultOperatorMetadata = func() map[string]OperatorMetadata {
+	metadata := map[string]OperatorMetadata{}
+	describe := func(factType, valueType string, names ...string) {
+		for _, name := range names {
+			metadata[name] = OperatorMetadata{FactType: factType, ValueType: valueType, Arity: 2}
+		}
+	}
+	describe("any", "any", "equal", "=", "eq", "notEqual", "ne", "!=")
+	describe("any", "array", "in", "notIn")
+	describe("any", "fact", "inFact", "notInFact")
+	describe("array", "any", "contains", "doesNotContain")
+	describe("number", "number", "lessThan", "<", "lt", "lessThanInclusive", "<=", "lte")
+	describe("number", "number", "greaterThan", ">", "gt", "greaterThanInclusive", ">=", "gte")
+	describe("number", "array", "between", "notBetween")
+	describe("string", "string", "startsWith", "endsWith", "includes")
+	describe("object", "string", "hasKey", "notHasKey")
+	describe("object", "number", "keyCountGreaterThan", "keyCountEqual")
+	describe("string", "string", "regex", "matches", "doesNotMatch")
+	describe("array", "any", "anyElement")
+	return metadata
+}()
+
 // DefaultOperators returns a slice of default operators
 func DefaultOperators() []Operator {
 	var operators []Operator
@@ -179,13 +551,20 @@ func DefaultOperators() []Operator {
 	operators = append(operators, *notEqual)
 
 	// IN OPERATOR
-	in, _ := NewOperator("in", EvalIn, isArray)
+	in, _ := NewOperator("in", EvalIn, exists)
 	operators = append(operators, *in)
 
 	// NOT IN OPERATOR
-	notIn, _ := NewOperator("notIn", EvalNotIn, isArray)
+	notIn, _ := NewOperator("notIn", EvalNotIn, exists)
 	operators = append(operators, *notIn)
 
+	// IN FACT OPERATORS, the set is the array or object held by the fact referenced as value
+	inFact, _ := NewOperator("inFact", EvalInFact, exists)
+	operators = append(operators, *inFact)
+
+	notInFact, _ := NewOperator("notInFact", EvalNotInFact, exists)
+	operators = append(operators, *notInFact)
+
 	// CONTAINS OPERATOR
 	contains, _ := NewOperator("contains", EvalIn, isArray)
 	operators = append(operators, *contains)
@@ -228,6 +607,13 @@ func DefaultOperators() []Operator {
 	greaterThanInclusive, _ = NewOperator("gte", EvalGreaterOrEqual, numberValidator)
 	operators = append(operators, *greaterThanInclusive)
 
+	// RANGE OPERATORS
+	between, _ := NewOperator("between", EvalBetween, numberValidator)
+	operators = append(operators, *between)
+
+	notBetween, _ := NewOperator("notBetween", EvalNotBetween, numberValidator)
+	operators = append(operators, *notBetween)
+
 	// STARTS WITH
 	startsWith, _ := NewOperator("startsWith", EvalStartsWith, stringValidator)
 	operators = append(operators, *startsWith)
@@ -238,5 +624,55 @@ func DefaultOperators() []Operator {
 	includes, _ := NewOperator("includes", EvalIncludes, stringValidator)
 	operators = append(operators, *includes)
 
+	// OBJECT KEY OPERATORS
+	hasKey, _ := NewOperator("hasKey", EvalHasKey, objectValidator)
+	operators = append(operators, *hasKey)
+
+	notHasKey, _ := NewOperator("notHasKey", EvalNotHasKey, objectValidator)
+	operators = append(operators, *notHasKey)
+
+	keyCountGreaterThan, _ := NewOperator("keyCountGreaterThan", EvalKeyCountGreaterThan, objectValidator)
+	operators = append(operators, *keyCountGreaterThan)
+
+	keyCountEqual, _ := NewOperator("keyCountEqual", EvalKeyCountEqual, objectValidator)
+	operators = append(operators, *keyCountEqual)
+
+	// REGULAR EXPRESSION OPERATORS
+	matches, _ := NewOperator("matches", EvalMatches, stringValidator)
+	operators = append(operators, *matches)
+
+	doesNotMatch, _ := NewOperator("doesNotMatch", EvalDoesNotMatch, stringValidator)
+	operators = append(operators, *doesNotMatch)
+
+	// MATCH DETAIL OPERATORS
+	regex, _ := NewDetailOperator("regex", EvalRegex, stringValidator)
+	operators = append(operators, *regex)
+
+	anyElement, _ := NewDetailOperator("anyElement", EvalAnyElement, isArray)
+	operators = append(operators, *anyElement)
+
+	// MULTI-FACT OPERATORS
+	subsetOf, _ := NewMultiFactOperator("subsetOf", 2, EvalSubsetOf)
+	operators = append(operators, *subsetOf)
+
+	for i := range operators {
+		if metadata, ok := defaultOperatorMetadata[operators[i].Name]; ok {
+			operators[i].Metadata = &metadata
+			operators[i].ValueValidator = typeValidator(metadata.ValueType)
+		}
+	}
+	for i := range operators {
+		switch operators[i].Name {
+		case "regex", "matches", "doesNotMatch":
+			operators[i].ValueValidator = regexValidator
+			operators[i].ValueCheck = patternCheck
+		case "between", "notBetween":
+			operators[i].ValueValidator = rangeValidator
+			operators[i].ValueCheck = rangeCheck
+		case "inFact", "notInFact":
+			operators[i].ValueCheck = factSetCheck
+		}
+	}
+
 	return operators
 }
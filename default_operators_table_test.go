@@ -0,0 +1,147 @@
+package rulesengine_test
+
+import (
+	"testing"
+	"time"
+
+	rulesengine "github.com/nimbit-software/gojson-rules-engine"
+	"github.com/nimbit-software/gojson-rules-engine/optest"
+)
+
+// defaultOperator returns the default operator of the given name
+func defaultOperator(t testing.TB, name string) rulesengine.Operator {
+	t.Helper()
+	for _, op := range rulesengine.DefaultOperators() {
+		if op.Name == name {
+			return op
+		}
+	}
+	t.Fatalf("no default operator %s", name)
+	return rulesengine.Operator{}
+}
+
+func TestObjectKeyOperators(t *testing.T) {
+	metadata := map[string]interface{}{"consent": map[string]interface{}{"marketing": true}, "source": "web", "tags": []interface{}{}}
+
+	optest.Run(t, defaultOperator(t, "hasKey"), []optest.Case{
+		{Name: "top level", Fact: metadata, Value: "consent", Want: true},
+		{Name: "nested", Fact: metadata, Value: "consent.marketing", Want: true},
+		{Name: "missing nested", Fact: metadata, Value: "consent.email", Want: false},
+		{Name: "through non object", Fact: metadata, Value: "source.web", Want: false},
+		{Name: "fact not an object", Fact: "consent", Value: "consent", Want: false},
+		{Name: "value not a string", Fact: metadata, Value: 1, Rejected: true},
+	})
+	optest.Run(t, defaultOperator(t, "notHasKey"), []optest.Case{
+		{Name: "missing", Fact: metadata, Value: "campaign", Want: true},
+		{Name: "present", Fact: metadata, Value: "source", Want: false},
+	})
+	optest.Run(t, defaultOperator(t, "keyCountGreaterThan"), []optest.Case{
+		{Name: "more keys", Fact: metadata, Value: 2, Want: true},
+		{Name: "as many keys", Fact: metadata, Value: 3, Want: false},
+	})
+	optest.Run(t, defaultOperator(t, "keyCountEqual"), []optest.Case{
+		{Name: "equal", Fact: metadata, Value: 3, Want: true},
+		{Name: "value not a number", Fact: metadata, Value: "3", Rejected: true},
+	})
+}
+
+func TestEvalSubsetOf(t *testing.T) {
+	optest.Run(t, defaultOperator(t, "subsetOf"), []optest.Case{
+		{Name: "subset", Fact: []interface{}{[]int{1, 3}, []int{1, 2, 3}}, Want: true},
+		{Name: "empty subset", Fact: []interface{}{[]int{}, []int{1}}, Want: true},
+		{Name: "not a subset", Fact: []interface{}{[]int{1, 4}, []int{1, 2, 3}}, Want: false},
+		{Name: "scalar element", Fact: []interface{}{2, []int{1, 2}}, Want: true},
+		{Name: "undefined first fact", Fact: []interface{}{nil, []int{1, 2}}, Want: false},
+		{Name: "second fact not an array", Fact: []interface{}{[]int{1}, 1}, Want: false},
+		{Name: "wrong number of facts", Fact: []interface{}{[]int{1}}, Want: false},
+	})
+}
+
+func TestRangeOperators(t *testing.T) {
+	between := defaultOperator(t, "between")
+	notBetween := defaultOperator(t, "notBetween")
+	for _, tc := range []struct {
+		fact                interface{}
+		between, notBetween bool
+	}{
+		{10, true, false},
+		{15, true, false},
+		{20, true, false},
+		{9.5, false, true},
+		{21, false, true},
+		{"15", false, false},
+	} {
+		optest.Run(t, between, []optest.Case{{Fact: tc.fact, Value: []int{10, 20}, Want: tc.between}})
+		optest.Run(t, notBetween, []optest.Case{{Fact: tc.fact, Value: []int{10, 20}, Want: tc.notBetween}})
+	}
+
+	var invalid []optest.Case
+	for _, value := range []interface{}{[]int{1}, []int{1, 2, 3}, []interface{}{1, "2"}, 5, []int{20, 10}} {
+		invalid = append(invalid, optest.Case{Fact: 15, Value: value, Rejected: true})
+	}
+	optest.Run(t, between, invalid)
+}
+
+func TestRegexOperators(t *testing.T) {
+	for _, name := range []string{"matches", "doesNotMatch", "regex"} {
+		optest.Run(t, defaultOperator(t, name), []optest.Case{
+			{Name: "invalid pattern", Fact: "jane", Value: "(", Rejected: true},
+			{Name: "pattern not a string", Fact: "jane", Value: 1, Rejected: true},
+		})
+	}
+}
+
+func TestInFactOperators(t *testing.T) {
+	// The value is the set held by the referenced fact, nil for an undefined fact
+	allowed := []interface{}{"DE", "CH"}
+	regions := map[string]interface{}{"CH": "emea", "US": "amer"}
+	optest.Run(t, defaultOperator(t, "inFact"), []optest.Case{
+		{Name: "in array", Fact: "CH", Value: allowed, Want: true},
+		{Name: "not in array", Fact: "US", Value: allowed, Want: false},
+		{Name: "empty set", Fact: "CH", Value: []interface{}{}, Want: false},
+		{Name: "object key", Fact: "CH", Value: regions, Want: true},
+		{Name: "object value", Fact: "emea", Value: regions, Want: false},
+		{Name: "undefined set", Fact: "CH", Value: nil, Want: false},
+		{Name: "set not an array or object", Fact: "CH", Value: "CH", Rejected: true},
+	})
+	optest.Run(t, defaultOperator(t, "notInFact"), []optest.Case{
+		{Name: "in array", Fact: "CH", Value: allowed, Want: false},
+		{Name: "not in array", Fact: "US", Value: allowed, Want: true},
+		{Name: "empty set", Fact: "CH", Value: []interface{}{}, Want: true},
+		{Name: "object key", Fact: "CH", Value: regions, Want: false},
+		{Name: "undefined set", Fact: "CH", Value: nil, Want: true},
+		{Name: "set not an array or object", Fact: "CH", Value: 1, Rejected: true},
+	})
+}
+
+func TestDefaultOperatorsNoPanic(t *testing.T) {
+	for _, op := range rulesengine.DefaultOperators() {
+		optest.NoPanic(t, op, 500, 1)
+	}
+}
+
+func FuzzBetween(f *testing.F) {
+	optest.Fuzz(f, defaultOperator(f, "between"))
+}
+
+func TestOperatorE(t *testing.T) {
+	op, err := rulesengine.NewOperatorE("afterDate", func(a, b *rulesengine.ValueNode) (bool, error) {
+		fact, err := time.Parse(time.DateOnly, a.String)
+		if err != nil {
+			return false, err
+		}
+		value, err := time.Parse(time.DateOnly, b.String)
+		if err != nil {
+			return false, err
+		}
+		return fact.After(value), nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+	optest.Run(t, *op, []optest.Case{
+		{Name: "after", Fact: "2024-02-03", Value: "2024-01-31", Want: true},
+		{Name: "before", Fact: "2024-01-03", Value: "2024-01-31", Want: false},
+		{Name: "invalid fact", Fact: "03/02/2024", Value: "2024-01-31", Err: true},
+	})
+}
@@ -0,0 +1,190 @@
+package rulesengine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestEvalRegex(t *testing.T) {
+	str := func(s string) *ValueNode { return &ValueNode{Type: String, String: s} }
+
+	ok, detail := EvalRegex(str("jane@example.com"), str(`^(?P<user>[^@]+)@(.+)$`))
+	match, isMatch := detail.(*RegexMatch)
+	if !ok || !isMatch {
+		t.Fatalf("Expected a match with detail, got %v %v", ok, detail)
+	}
+	if !reflect.DeepEqual(match.Groups, []string{"jane@example.com", "jane", "example.com"}) || match.Named["user"] != "jane" {
+		t.Errorf("Unexpected capture groups %+v", match)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		fact, expr *ValueNode
+	}{
+		{"no match", str("jane"), str(`@`)},
+		{"invalid pattern", str("jane"), str(`(`)},
+		{"not a string", &ValueNode{Type: Number, Number: 1}, str(`1`)},
+	} {
+		if ok, detail := EvalRegex(tc.fact, tc.expr); ok || detail != nil {
+			t.Errorf("%s: expected no match, got %v %v", tc.name, ok, detail)
+		}
+	}
+}
+
+func TestMatchesOperators(t *testing.T) {
+	operators := map[string]Operator{}
+	for _, op := range DefaultOperators() {
+		operators[op.Name] = op
+	}
+	almanac := NewAlmanac(gjson.Parse(`{"email": "jane@example.com", "age": 30}`), Options{}, 0)
+
+	for _, tc := range []struct {
+		fact, operator, pattern string
+		expected                bool
+	}{
+		{"email", "matches", `^[^@]+@example\.com$`, true},
+		{"email", "matches", `^bob@`, false},
+		{"email", "doesNotMatch", `^bob@`, true},
+		{"email", "doesNotMatch", `@example`, false},
+		{"age", "matches", `30`, false},
+		{"age", "doesNotMatch", `31`, false},
+	} {
+		c := &Condition{Fact: tc.fact, Operator: tc.operator, Value: ValueNode{Type: String, String: tc.pattern}}
+		res, err := c.Evaluate(almanac, operators)
+		if err != nil {
+			t.Fatalf("%s %s %s: unexpected error: %v", tc.fact, tc.operator, tc.pattern, err)
+		}
+		if res.Result != tc.expected {
+			t.Errorf("%s %s %s: expected %v, got %v", tc.fact, tc.operator, tc.pattern, tc.expected, res.Result)
+		}
+	}
+
+	c := &Condition{Fact: "email", Operator: "matches", Value: ValueNode{Type: String, String: `(`}}
+	if _, err := c.Evaluate(almanac, operators); err == nil {
+		t.Errorf("Expected an invalid pattern to fail the evaluation")
+	}
+	if compilePattern(`^[^@]+@example\.com$`) != compilePattern(`^[^@]+@example\.com$`) {
+		t.Errorf("Expected compiled patterns to be cached")
+	}
+}
+
+func TestPatternCacheIsBounded(t *testing.T) {
+	cache := newPatternCache(2)
+	a, b, c := compiledPattern{re: regexp.MustCompile("a")}, compiledPattern{re: regexp.MustCompile("b")}, compiledPattern{re: regexp.MustCompile("c")}
+	cache.put("a", a)
+	cache.put("b", b)
+	// Using a makes b the least recently used pattern
+	if got, ok := cache.get("a"); !ok || got.re != a.re {
+		t.Fatalf("Expected a to be cached")
+	}
+	cache.put("c", c)
+	if cache.len() != 2 {
+		t.Errorf("Expected the cache to hold 2 patterns, got %d", cache.len())
+	}
+	if _, ok := cache.get("b"); ok {
+		t.Errorf("Expected the least recently used pattern to be evicted")
+	}
+	for _, pattern := range []string{"a", "c"} {
+		if _, ok := cache.get(pattern); !ok {
+			t.Errorf("Expected %s to be cached", pattern)
+		}
+	}
+
+	for i := 0; i < maxCompiledPatterns+10; i++ {
+		compilePattern(fmt.Sprintf("^id-%d$", i))
+	}
+	if n := compiledPatterns.len(); n != maxCompiledPatterns {
+		t.Errorf("Expected the pattern cache to stay at %d patterns, got %d", maxCompiledPatterns, n)
+	}
+}
+
+func TestEvalAnyElement(t *testing.T) {
+	items := &ValueNode{Type: Array, Array: []ValueNode{{Type: String, String: "a"}, {Type: String, String: "b"}}}
+	ok, detail := EvalAnyElement(items, &ValueNode{Type: String, String: "b"})
+	if !ok || !reflect.DeepEqual(detail, ElementMatch{Index: 1, Value: items.Array[1]}) {
+		t.Errorf("Expected the second element to match, got %v %v", ok, detail)
+	}
+	if ok, detail := EvalAnyElement(items, &ValueNode{Type: String, String: "c"}); ok || detail != nil {
+		t.Errorf("Expected no match, got %v %v", ok, detail)
+	}
+}
+
+func TestInFactOperatorsInRules(t *testing.T) {
+	facts := `{"country": "CH", "tenant": {"allowedCountries": ["DE", "CH"], "regions": {"CH": "emea"}, "name": "acme"}}`
+	t.Run("sets are resolved from the referenced facts", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "country",
+			"conditions": {"all": [
+				{"fact": "country", "operator": "inFact", "value": {"fact": "tenant.allowedCountries"}},
+				{"fact": "country", "operator": "inFact", "value": {"fact": "tenant.regions"}},
+				{"fact": "country", "operator": "notInFact", "value": {"fact": "tenant.missing"}}
+			]},
+			"event": {"type": "country"}
+		}`, &RuleEngineOptions{AllowUndefinedFacts: true})
+		res, err := engine.Run(context.Background(), []byte(facts))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 {
+			t.Errorf("Expected the rule to pass, got %v", res.FailureResults)
+		}
+	})
+
+	t.Run("sets that are no array or object fail the condition", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "country",
+			"conditions": {"all": [{"fact": "country", "operator": "inFact", "value": {"fact": "tenant.name"}}]},
+			"event": {"type": "country"}
+		}`, nil)
+		if _, err := engine.Run(context.Background(), []byte(facts)); err == nil || !strings.Contains(err.Error(), "array or object") {
+			t.Errorf("Expected the set to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("literal values are rejected", func(t *testing.T) {
+		rule, err := NewRule(&RuleConfig{Name: "r", Conditions: Condition{All: []*Condition{
+			{Fact: "country", Operator: "inFact", Value: ValueNode{Type: Array, Array: []ValueNode{{Type: String, String: "CH"}}}},
+		}}, Event: EventConfig{Type: "r"}})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := NewEngine(nil, nil).AddRule(rule); err == nil || !strings.Contains(err.Error(), "fact reference") {
+			t.Errorf("Expected a literal set to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("calculated sets are resolved once per run", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "country",
+			"conditions": {"all": [
+				{"fact": "country", "operator": "inFact", "value": {"fact": "allowed"}},
+				{"fact": "origin", "operator": "inFact", "value": {"fact": "allowed"}}
+			]},
+			"event": {"type": "country"}
+		}`, nil)
+		var calls atomic.Int32
+		err := engine.AddCalculatedFact("allowed", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls.Add(1)
+			return &ValueNode{Type: Array, Array: []ValueNode{{Type: String, String: "CH"}, {Type: String, String: "DE"}}}
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		for run := 1; run <= 2; run++ {
+			res, err := engine.Run(context.Background(), []byte(`{"country": "CH", "origin": "DE"}`))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(res.Results) != 1 || calls.Load() != int32(run) {
+				t.Errorf("Run %d: expected the rule to pass with the set calculated once, got %d calculations", run, calls.Load())
+			}
+		}
+	})
+}
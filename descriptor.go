@@ -0,0 +1,134 @@
+package rulesengine
+
+import (
+	"sort"
+	"strings"
+)
+
+// EngineDescriptor is a JSON-serializable description of what an engine supports.
+// It is assembled from the engine registries and intended for rule authoring tools.
+type EngineDescriptor struct {
+	Operators []OperatorDescriptor `json:"operators"`
+	Facts     []FactDescriptor     `json:"facts"`
+	// Constants are the static facts loaded from the constants of a bundle, they are not listed under Facts
+	Constants  []FactDescriptor `json:"constants"`
+	Conditions []string         `json:"conditions"`
+	// Events are the event types emitted by rules, registered with RegisterEventTypes or handled by event handlers
+	Events []string `json:"events"`
+	// Handlers are the topics with event handlers: "success", "failure" and event types
+	Handlers []string `json:"handlers"`
+	Rules    []string `json:"rules"`
+}
+
+// OperatorDescriptor describes a registered operator and its operand type hints.
+type OperatorDescriptor struct {
+	Name string `json:"name"`
+	OperatorMetadata
+}
+
+// FactDescriptor describes a registered fact.
+// Type is the data type of static facts, or "calculated" for calculated facts.
+type FactDescriptor struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Dynamic  bool   `json:"dynamic"`
+	Cached   bool   `json:"cached"`
+	Priority int    `json:"priority"`
+}
+
+// Descriptor returns a description of the operators, facts, constants, named conditions, event types, event handlers and
+// rules registered on the engine. All lists are sorted by name so the output is stable.
+func (e *Engine) Descriptor() EngineDescriptor {
+	operators := e.Operators()
+	d := EngineDescriptor{
+		Operators:  make([]OperatorDescriptor, 0, len(operators)),
+		Facts:      []FactDescriptor{},
+		Constants:  []FactDescriptor{},
+		Conditions: []string{},
+		Events:     []string{},
+		Handlers:   []string{},
+		Rules:      []string{},
+	}
+
+	for name, op := range operators {
+		od := OperatorDescriptor{
+			Name:             name,
+			OperatorMetadata: OperatorMetadata{FactType: "any", ValueType: "any", Arity: 2},
+		}
+		if op.Metadata != nil {
+			od.OperatorMetadata = *op.Metadata
+		}
+		d.Operators = append(d.Operators, od)
+	}
+	sort.Slice(d.Operators, func(i, j int) bool { return d.Operators[i].Name < d.Operators[j].Name })
+
+	e.mu.Lock()
+	constants := make(map[string]struct{}, len(e.constants))
+	for path := range e.constants {
+		constants[path] = struct{}{}
+	}
+	e.mu.Unlock()
+	e.Facts.Range(func(path string, f *Fact) bool {
+		fd := FactDescriptor{
+			Path:     path,
+			Type:     "calculated",
+			Dynamic:  f.Dynamic,
+			Cached:   f.Cached,
+			Priority: f.Priority,
+		}
+		if !f.Dynamic && f.Value != nil {
+			fd.Type = f.Value.Type.String()
+		}
+		if _, ok := constants[path]; ok {
+			d.Constants = append(d.Constants, fd)
+		} else {
+			d.Facts = append(d.Facts, fd)
+		}
+		return true
+	})
+	sort.Slice(d.Facts, func(i, j int) bool { return d.Facts[i].Path < d.Facts[j].Path })
+	sort.Slice(d.Constants, func(i, j int) bool { return d.Constants[i].Path < d.Constants[j].Path })
+
+	e.Conditions.Range(func(key, _ interface{}) bool {
+		d.Conditions = append(d.Conditions, key.(string))
+		return true
+	})
+	sort.Strings(d.Conditions)
+
+	seenEvents := map[string]struct{}{}
+	addEvent := func(eventType string) {
+		if _, ok := seenEvents[eventType]; !ok {
+			seenEvents[eventType] = struct{}{}
+			d.Events = append(d.Events, eventType)
+		}
+	}
+	for _, r := range e.GetRules() {
+		d.Rules = append(d.Rules, r.Name)
+		addEvent(r.RuleEvent.Type)
+	}
+	for eventType := range e.registeredEventTypes() {
+		addEvent(eventType)
+	}
+	seenHandlers := map[string]struct{}{}
+	e.handlerTopics.Range(func(key, _ interface{}) bool {
+		topic := key.(string)
+		// Handlers may have been removed since, and internal topics like diagnostics are no events
+		if topic == diagnosticTopic || !e.bus.HasCallback(topic) {
+			return true
+		}
+		handler := strings.TrimPrefix(topic, eventTopic(""))
+		if _, ok := seenHandlers[handler]; !ok {
+			seenHandlers[handler] = struct{}{}
+			d.Handlers = append(d.Handlers, handler)
+		}
+		if handler != "success" && handler != "failure" {
+			addEvent(handler)
+		}
+		return true
+	})
+	sort.Strings(d.Rules)
+	sort.Strings(d.Events)
+	sort.Strings(d.Handlers)
+
+	return d
+}
@@ -0,0 +1,74 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEngineDescriptor(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "adult",
+		"conditions": {"all": [{"fact": "age", "operator": "greaterThan", "value": 18}]},
+		"event": {"type": "adult"}
+	}`, nil)
+	if err := engine.AddFact("country", &ValueNode{Type: String, String: "CH"}, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	if err := engine.AddCalculatedFact("score", func(a *Almanac, params ...interface{}) *ValueNode {
+		return &ValueNode{Type: Number, Number: 1}
+	}, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+
+	if err := engine.LoadBundle([]byte(`{"constants": {"limit": 5}}`)); err != nil {
+		t.Fatalf("Failed to load bundle: %v", err)
+	}
+	engine.RegisterEventTypes("approve")
+	notify := func(Event, *Almanac, *RuleResult) {}
+	removed := func(Event, *Almanac, *RuleResult) {}
+	for _, err := range []error{
+		engine.On("notify", notify),
+		engine.On("removed", removed),
+		engine.OnEvent("audit", func(map[string]interface{}, *Almanac, *RuleResult) {}),
+		engine.OnSuccess(func(Event, *Almanac, *RuleResult) {}),
+		engine.OnDiagnostic(func(Diagnostic, *Almanac) {}),
+		engine.Off("removed", removed),
+	} {
+		if err != nil {
+			t.Fatalf("Failed to register handlers: %v", err)
+		}
+	}
+
+	d := engine.Descriptor()
+
+	var greaterThan *OperatorDescriptor
+	for i := range d.Operators {
+		if d.Operators[i].Name == "greaterThan" {
+			greaterThan = &d.Operators[i]
+		}
+	}
+	if greaterThan == nil || greaterThan.FactType != "number" || greaterThan.ValueType != "number" {
+		t.Errorf("Expected greaterThan to describe number operands, got %+v", greaterThan)
+	}
+
+	if len(d.Facts) != 2 || d.Facts[0].Path != "country" || d.Facts[0].Type != "string" || d.Facts[1].Type != "calculated" {
+		t.Errorf("Unexpected fact descriptors: %+v", d.Facts)
+	}
+	if len(d.Constants) != 1 || d.Constants[0].Path != "limit" || d.Constants[0].Type != "number" {
+		t.Errorf("Expected the bundle constant apart from the facts, got %+v", d.Constants)
+	}
+	if expected := []string{"adult", "approve", "audit", "notify"}; !reflect.DeepEqual(d.Events, expected) {
+		t.Errorf("Expected the rule, registered and handled event types %v, got %v", expected, d.Events)
+	}
+	if expected := []string{"audit", "notify", "success"}; !reflect.DeepEqual(d.Handlers, expected) {
+		t.Errorf("Expected the handler topics %v, got %v", expected, d.Handlers)
+	}
+	if len(d.Rules) != 1 || d.Rules[0] != "adult" {
+		t.Errorf("Unexpected rules: %v", d.Rules)
+	}
+
+	if _, err := json.Marshal(d); err != nil {
+		t.Errorf("Expected descriptor to be JSON serializable, got error: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package rulesengine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shadowCopy returns a copy of the almanac for evaluating a rule a second time, see RunOptions.CheckDeterminism.
+// The copy shares the fact document, the engine and runtime facts and the run values, but not the results, events,
+// budget or the values of calculated facts, which are calculated again. Its events are never published.
+func (a *Almanac) shadowCopy() *Almanac {
+	shadow := NewAlmanac(a.rawFacts, Options{
+		AllowUndefinedFacts:      &a.allowUndefinedFacts,
+		MaxCachedFactBytes:       a.maxCachedFactBytes,
+		LazyArrayThreshold:       a.lazyArrayThreshold,
+		CaseInsensitiveFactPaths: a.caseInsensitive,
+	}, 1)
+	a.factMap.Range(func(key string, f *Fact) bool {
+		shadow.factMap.Set(key, f)
+		return true
+	})
+	shadow.values = a.values
+	shadow.replay = a.replay
+	// Session-cached values may be older than the run, recalculating them would report stale values as differences
+	shadow.session = a.session
+	shadow.execCtx = a.execCtx
+	shadow.priorityGroup = a.priorityGroup
+	shadow.noPriorityBarriers = a.noPriorityBarriers
+	shadow.quiet = true
+	shadow.shadow = true
+	return shadow
+}
+
+// checkDeterminism evaluates the rule again against a shadow copy of the almanac and emits a
+// DiagnosticNonDeterministicRule diagnostic when its outcome differs from the first evaluation
+func (e *Engine) checkDeterminism(ctx *ExecutionContext, almanac *Almanac, r *Rule, first *RuleResult) {
+	second, err := r.Evaluate(ctx, almanac.shadowCopy())
+	diagnostic := Diagnostic{Type: DiagnosticNonDeterministicRule, Rule: r.Name}
+	switch {
+	case err != nil:
+		diagnostic.Error = fmt.Sprintf("second evaluation failed: %v", err)
+	case errorText(first.Error) != errorText(second.Error):
+		diagnostic.Error = fmt.Sprintf("evaluations failed differently: %q, then %q", errorText(first.Error), errorText(second.Error))
+	default:
+		diagnostic.Paths = diffConditions(&first.Conditions, &second.Conditions, "")
+		if boolValue(first.Result) != boolValue(second.Result) {
+			diagnostic.Error = fmt.Sprintf("rule evaluated to %v, then %v", boolValue(first.Result), boolValue(second.Result))
+		} else if len(diagnostic.Paths) > 0 {
+			diagnostic.Error = "conditions evaluated differently: " + strings.Join(diagnostic.Paths, ", ")
+		} else {
+			return
+		}
+	}
+	e.diagnose(almanac, diagnostic)
+}
+
+// diffConditions returns the paths of the leaf conditions whose result or fact value differs between two evaluations
+// of the same condition tree. Leaf conditions that only one evaluation reached are not compared, as concurrent
+// evaluation may short-circuit groups differently.
+func diffConditions(a, b *Condition, path string) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+	var paths []string
+	if a.Operator != "" && evaluated(a) && evaluated(b) {
+		if a.Result != b.Result || !factValuesEqual(a, b) {
+			paths = append(paths, path)
+		}
+	}
+	for _, group := range []struct {
+		operator string
+		a, b     []*Condition
+	}{{"all", a.All, b.All}, {"any", a.Any, b.Any}, {"none", a.None, b.None}, {"mostOf", a.MostOf, b.MostOf}} {
+		for i := 0; i < len(group.a) && i < len(group.b); i++ {
+			paths = append(paths, diffConditions(group.a[i], group.b[i], joinConditionPath(path, fmt.Sprintf("%s[%d]", group.operator, i)))...)
+		}
+	}
+	paths = append(paths, diffConditions(a.Not, b.Not, joinConditionPath(path, "not"))...)
+	return append(paths, diffConditions(a.Realized, b.Realized, path+"{"+a.Condition+"}")...)
+}
+
+// evaluated reports whether the facts of a leaf condition were resolved
+func evaluated(c *Condition) bool {
+	return c.FactResult.Value != nil || c.FactResults != nil
+}
+
+// factValuesEqual reports whether two evaluations of a leaf condition resolved the same fact values
+func factValuesEqual(a, b *Condition) bool {
+	if (a.FactResult.Value == nil) != (b.FactResult.Value == nil) || len(a.FactResults) != len(b.FactResults) {
+		return false
+	}
+	if a.FactResult.Value != nil && !a.FactResult.Value.Equal(b.FactResult.Value) {
+		return false
+	}
+	for i := range a.FactResults {
+		if (a.FactResults[i] == nil) != (b.FactResults[i] == nil) || (a.FactResults[i] != nil && !a.FactResults[i].Equal(b.FactResults[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// errorText returns the message of an error, empty for nil
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// boolValue dereferences a result, false for nil
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
@@ -0,0 +1,102 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckDeterminism(t *testing.T) {
+	var calls atomic.Int32
+	engine := newTestEngine(t, `{
+		"name": "flaky",
+		"conditions": {"all": [
+			{"fact": "age", "operator": "greaterThan", "value": 17},
+			{"fact": "counter", "operator": "equal", "value": 1}
+		]},
+		"event": {"type": "flaky"}
+	}`, nil)
+	var config RuleConfig
+	if err := json.Unmarshal([]byte(`{
+		"name": "stable",
+		"conditions": {"all": [{"fact": "age", "operator": "greaterThan", "value": 17}]},
+		"event": {"type": "stable"}
+	}`), &config); err != nil {
+		t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+	}
+	if err := engine.AddRuleFromMap(&config); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	// State kept across calls, like a package-level variable, makes the fact differ on every calculation
+	engine.AddCalculatedFact("counter", func(a *Almanac, params ...interface{}) *ValueNode {
+		return &ValueNode{Type: Number, Number: float64(calls.Add(1))}
+	}, nil)
+	var published atomic.Int32
+	if err := engine.OnSuccess(func(Event, *Almanac, *RuleResult) { published.Add(1) }); err != nil {
+		t.Fatalf("Failed to register success handler: %v", err)
+	}
+	facts := []byte(`{"age": 30}`)
+
+	t.Run("non-deterministic rules are reported", func(t *testing.T) {
+		calls.Store(0)
+		published.Store(0)
+		options := DefaultRunOptions()
+		options.CheckDeterminism = true
+		res, err := engine.RunWithOptions(context.Background(), facts, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Diagnostics) != 1 {
+			t.Fatalf("Expected a single diagnostic, got %+v", res.Diagnostics)
+		}
+		d := res.Diagnostics[0]
+		if d.Type != DiagnosticNonDeterministicRule || d.Rule != "flaky" || len(d.Paths) != 1 || d.Paths[0] != "all[1]" {
+			t.Errorf("Expected the counter condition of the flaky rule, got %+v", d)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("Expected the calculated fact to be calculated again, got %d calls", calls.Load())
+		}
+		if len(res.Results) != 2 || published.Load() != 2 {
+			t.Errorf("Expected the results and events of the first evaluation only, got %d results, %d events", len(res.Results), published.Load())
+		}
+	})
+
+	t.Run("rules are evaluated once by default", func(t *testing.T) {
+		calls.Store(0)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Diagnostics) != 0 || calls.Load() != 1 {
+			t.Errorf("Expected no diagnostics and a single calculation, got %+v and %d calls", res.Diagnostics, calls.Load())
+		}
+	})
+
+	t.Run("deterministic facts pass", func(t *testing.T) {
+		engine.AddCalculatedFact("counter", func(a *Almanac, params ...interface{}) *ValueNode {
+			return &ValueNode{Type: Number, Number: 1}
+		}, nil)
+		options := DefaultRunOptions()
+		options.CheckDeterminism = true
+		res, err := engine.RunWithOptions(context.Background(), facts, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Diagnostics) != 0 {
+			t.Errorf("Expected no diagnostics, got %+v", res.Diagnostics)
+		}
+	})
+
+	t.Run("condition diffs", func(t *testing.T) {
+		leaf := func(result bool, value float64) *Condition {
+			return &Condition{Fact: "a", Operator: "equal", Result: result, FactResult: Fact{Value: &ValueNode{Type: Number, Number: value}}}
+		}
+		a := &Condition{All: []*Condition{leaf(true, 1), {Any: []*Condition{leaf(true, 2), leaf(false, 3)}}, {Not: leaf(true, 4)}}}
+		b := &Condition{All: []*Condition{leaf(true, 1), {Any: []*Condition{leaf(true, 5), {Fact: "a", Operator: "equal"}}}, {Not: leaf(false, 4)}}}
+		paths := diffConditions(a, b, "")
+		if len(paths) != 2 || paths[0] != "all[1].any[0]" || paths[1] != "all[2].not" {
+			t.Errorf("Expected the differing leaf conditions reached by both evaluations, got %v", paths)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package rulesengine
+
+import (
+	"errors"
+	"time"
+)
+
+// Types of the diagnostics emitted by the engine. They are prefixed with "__" to keep them apart from business events.
+const (
+	// DiagnosticRuleTimeout reports a rule whose evaluation took longer than RunOptions.RuleTimeout
+	DiagnosticRuleTimeout = "__rule_timeout"
+	// DiagnosticRunBudgetExceeded reports a run that passed its soft deadline or exhausted its evaluation budget
+	DiagnosticRunBudgetExceeded = "__run_budget_exceeded"
+	// DiagnosticRuleError reports a rule whose evaluation failed, whether or not the engine continues on error
+	DiagnosticRuleError = "__rule_error"
+	// DiagnosticNonDeterministicRule reports a rule evaluating differently twice in a row, see RunOptions.CheckDeterminism
+	DiagnosticNonDeterministicRule = "__nondeterministic_rule"
+	// DiagnosticUndefinedCondition reports the first reference of a rule to a missing condition that was evaluated as
+	// false or skipped instead of failing the rule, see RunStats.UndefinedConditions
+	DiagnosticUndefinedCondition = "__undefined_condition"
+	// DiagnosticFactError reports a condition evaluated as false because the callback of its calculated fact failed,
+	// see FactErrorFalse
+	DiagnosticFactError = "__fact_error"
+)
+
+// diagnosticTopic is the bus topic diagnostics are published on
+const diagnosticTopic = "__diagnostic"
+
+// Diagnostic is a synthetic engine event explaining why a run did less than usual, e.g. for dashboards.
+// Diagnostics are never part of the run's events, see RunResult.Diagnostics and Engine.OnDiagnostic.
+type Diagnostic struct {
+	Type string `json:"type"`
+	// Rule is the rule the diagnostic is about, empty for run diagnostics without a rule
+	Rule string `json:"rule,omitempty"`
+	// Duration is the evaluation time of the rule, or the time the run had taken, for run diagnostics
+	Duration time.Duration `json:"durationNs"`
+	// Limit is the exceeded rule timeout or soft deadline, 0 for errors and evaluation budgets
+	Limit time.Duration `json:"limitNs,omitempty"`
+	// Error is the error of the rule or the exhausted evaluation budget, or what differed for non-deterministic rules
+	Error string `json:"error,omitempty"`
+	// Paths are the condition paths of a non-deterministic rule that evaluated differently, e.g. "all[1]"
+	Paths []string `json:"paths,omitempty"`
+}
+
+// DiagnosticHandler handles the diagnostics of the engine, see Engine.OnDiagnostic.
+type DiagnosticHandler func(diagnostic Diagnostic, almanac *Almanac)
+
+// OnDiagnostic registers a handler called for every diagnostic emitted by a run, see Diagnostic.
+// Params:
+// - handler: The handler, called synchronously before the run returns, including for runs that fail.
+// Returns an error if the handler is nil.
+func (e *Engine) OnDiagnostic(handler DiagnosticHandler) error {
+	if handler == nil {
+		return errors.New("engine: diagnostic handler is required")
+	}
+	return e.subscribe(diagnosticTopic, handler)
+}
+
+// diagnose records a diagnostic on the almanac and publishes it unless the run is quiet.
+// Safe to call from the goroutines evaluating rules.
+func (e *Engine) diagnose(almanac *Almanac, diagnostic Diagnostic) {
+	almanac.diagnosticsMu.Lock()
+	almanac.diagnostics = append(almanac.diagnostics, diagnostic)
+	almanac.diagnosticsMu.Unlock()
+	if !almanac.quiet {
+		e.bus.Publish(diagnosticTopic, diagnostic, almanac)
+	}
+}
+
+// diagnoseRule emits the diagnostics of an evaluated rule: its error and exceeding the rule timeout.
+// Evaluation budget errors are reported once for the run instead.
+func (e *Engine) diagnoseRule(almanac *Almanac, r *Rule, duration time.Duration, err error) {
+	if err != nil && !errors.Is(err, ErrEvaluationBudgetExceeded) {
+		e.diagnose(almanac, Diagnostic{Type: DiagnosticRuleError, Rule: r.Name, Duration: duration, Error: err.Error()})
+	}
+	if almanac.ruleTimeout > 0 && duration > almanac.ruleTimeout {
+		e.diagnose(almanac, Diagnostic{Type: DiagnosticRuleTimeout, Rule: r.Name, Duration: duration, Limit: almanac.ruleTimeout})
+	}
+}
+
+// diagnoseBudget emits the run diagnostic of an exhausted evaluation budget, if any
+func (e *Engine) diagnoseBudget(almanac *Almanac, started time.Time) {
+	err := almanac.budget.err()
+	if err == nil {
+		return
+	}
+	diagnostic := Diagnostic{Type: DiagnosticRunBudgetExceeded, Duration: time.Since(started), Error: err.Error()}
+	var budgetErr *EvaluationBudgetExceededError
+	if errors.As(err, &budgetErr) {
+		diagnostic.Rule = budgetErr.Rule
+	}
+	e.diagnose(almanac, diagnostic)
+}
+
+// Diagnostics returns the diagnostics emitted so far by the run, in emission order.
+func (a *Almanac) Diagnostics() []Diagnostic {
+	a.diagnosticsMu.Lock()
+	defer a.diagnosticsMu.Unlock()
+	return append([]Diagnostic(nil), a.diagnostics...)
+}
@@ -0,0 +1,105 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineDiagnostics(t *testing.T) {
+	newEngine := func(t *testing.T, operator string) (*Engine, *[]Diagnostic) {
+		t.Helper()
+		engine := newPriorityTestEngine(t, operator)
+		engine.AddOperator("slowEqual", func(a, b *ValueNode) bool {
+			time.Sleep(20 * time.Millisecond)
+			return EvalEqual(a, b)
+		})
+		var published []Diagnostic
+		if err := engine.OnDiagnostic(func(diagnostic Diagnostic, almanac *Almanac) {
+			published = append(published, diagnostic)
+		}); err != nil {
+			t.Fatalf("Failed to register diagnostic handler: %v", err)
+		}
+		return engine, &published
+	}
+	facts := []byte(`{"a": 1}`)
+
+	t.Run("no diagnostics for a normal run", func(t *testing.T) {
+		engine, published := newEngine(t, "equal")
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Diagnostics) != 0 || len(*published) != 0 {
+			t.Errorf("Expected no diagnostics, got %v and %v", res.Diagnostics, *published)
+		}
+		data, err := json.Marshal(res)
+		if err != nil || !strings.Contains(string(data), `"diagnostics":[]`) {
+			t.Errorf("Expected an empty list of diagnostics, got %s (%v)", data, err)
+		}
+	})
+
+	t.Run("rule timeout and soft deadline", func(t *testing.T) {
+		engine, published := newEngine(t, "slowEqual")
+		options := DefaultRunOptions()
+		options.RuleTimeout = 5 * time.Millisecond
+		options.SoftDeadline = 5 * time.Millisecond
+		res, err := engine.RunWithOptions(context.Background(), facts, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		diagnostics := res.Diagnostics
+		if len(diagnostics) != 2 {
+			t.Fatalf("Expected a rule timeout and a run budget diagnostic, got %v", diagnostics)
+		}
+		if d := diagnostics[0]; d.Type != DiagnosticRuleTimeout || d.Rule != "first" || d.Duration < 20*time.Millisecond || d.Limit != options.RuleTimeout {
+			t.Errorf("Unexpected rule timeout diagnostic %+v", d)
+		}
+		if d := diagnostics[1]; d.Type != DiagnosticRunBudgetExceeded || d.Rule != "" || d.Duration < 20*time.Millisecond || d.Limit != options.SoftDeadline {
+			t.Errorf("Unexpected run budget diagnostic %+v", d)
+		}
+		if !reflect.DeepEqual(*published, diagnostics) {
+			t.Errorf("Expected the diagnostics to be published before the run returns, got %v", *published)
+		}
+		if len(res.Events) != 1 || res.Events[0].Type != "first" {
+			t.Errorf("Expected the business events only, got %v", res.Events)
+		}
+	})
+
+	t.Run("rule errors", func(t *testing.T) {
+		engine, published := newEngine(t, "unknown")
+		engine.ContinueOnError = true
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Diagnostics) != 1 || res.Diagnostics[0].Type != DiagnosticRuleError || res.Diagnostics[0].Rule != "first" || !strings.Contains(res.Diagnostics[0].Error, "unknown") {
+			t.Errorf("Expected a rule error diagnostic for the first rule, got %v", res.Diagnostics)
+		}
+
+		// Failing runs return no result, the handlers still learn why
+		engine.ContinueOnError = false
+		*published = nil
+		if _, err := engine.Run(context.Background(), facts); err == nil {
+			t.Fatalf("Expected the run to fail")
+		}
+		if len(*published) != 1 || (*published)[0].Type != DiagnosticRuleError || (*published)[0].Rule != "first" {
+			t.Errorf("Expected a rule error diagnostic for the failed run, got %v", *published)
+		}
+	})
+
+	t.Run("evaluation budget", func(t *testing.T) {
+		engine, published := newEngine(t, "equal")
+		options := DefaultRunOptions()
+		options.MaxConditionEvaluations = 1
+		if _, err := engine.RunWithOptions(context.Background(), facts, options); err == nil {
+			t.Fatalf("Expected the run to exceed its budget")
+		}
+		if len(*published) != 1 || (*published)[0].Type != DiagnosticRunBudgetExceeded || (*published)[0].Rule != "second" || !strings.Contains((*published)[0].Error, "conditionEvaluations") {
+			t.Errorf("Expected a single run budget diagnostic, got %v", *published)
+		}
+	})
+}
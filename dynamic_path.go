@@ -0,0 +1,105 @@
+package rulesengine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hasDynamicSegments reports whether a fact path contains {fact} segments or escaped braces
+func hasDynamicSegments(path string) bool {
+	return strings.ContainsAny(path, "{}")
+}
+
+// parseDynamicPath splits a fact path into literal text and the facts of its {fact} segments, e.g. "limits.{currency}".
+// "{{" and "}}" stand for literal braces. Calls segment for every literal part and fact in order.
+func parseDynamicPath(path string, literal func(text string), segment func(fact string) error) error {
+	var text strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '{' && i+1 < len(path) && path[i+1] == '{', c == '}' && i+1 < len(path) && path[i+1] == '}':
+			text.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(path[i+1:], '}')
+			if end < 0 {
+				return fmt.Errorf("unterminated segment in fact path %q", path)
+			}
+			fact := path[i+1 : i+1+end]
+			if fact == "" || strings.ContainsAny(fact, "{") {
+				return fmt.Errorf("invalid segment {%s} in fact path %q", fact, path)
+			}
+			literal(text.String())
+			text.Reset()
+			if err := segment(fact); err != nil {
+				return err
+			}
+			i += end + 1
+		case c == '}':
+			return fmt.Errorf("unmatched } in fact path %q, use }} for a literal brace", path)
+		default:
+			text.WriteByte(c)
+		}
+	}
+	literal(text.String())
+	return nil
+}
+
+// validateDynamicPath checks the syntax of the {fact} segments of a fact path
+func validateDynamicPath(path string) error {
+	return parseDynamicPath(path, func(string) {}, func(string) error { return nil })
+}
+
+// errDynamicSegmentUndefined is returned by resolveDynamicPath when the fact of a segment is undefined
+// and undefined facts are allowed
+var errDynamicSegmentUndefined = errors.New("dynamic segment undefined")
+
+// resolveDynamicPath replaces the {fact} segments of a fact path with the values of their facts, giving the concrete path.
+// Segment values must be strings or numbers; characters with a meaning in paths are escaped so the value is one key.
+func resolveDynamicPath(path string, resolveFact factLookup) (string, error) {
+	var resolved strings.Builder
+	err := parseDynamicPath(path, func(text string) {
+		// gjson reads braces as multipath syntax, literal ones are escaped
+		resolved.WriteString(literalBraces.Replace(text))
+	}, func(fact string) error {
+		f, err := resolveFact(fact, FactLookupOptions{})
+		if err != nil {
+			return fmt.Errorf("fact path %s: %w", path, err)
+		}
+		if f == nil || f.Value == nil {
+			return errDynamicSegmentUndefined
+		}
+		switch f.Value.Type {
+		case String:
+			resolved.WriteString(escapePathKey(f.Value.String))
+		case Number:
+			resolved.WriteString(escapePathKey(strconv.FormatFloat(f.Value.Number, 'f', -1, 64)))
+		default:
+			return fmt.Errorf("fact path %s: segment {%s} is a %s, expected a string or number", path, fact, f.Value.Type)
+		}
+		return nil
+	})
+	return resolved.String(), err
+}
+
+// literalBraces escapes the literal braces of a fact path
+var literalBraces = strings.NewReplacer("{", `\{`, "}", `\}`)
+
+// pathSpecialChars are the characters gjson paths interpret within a key
+const pathSpecialChars = `.*?|#@!\{}`
+
+// escapePathKey escapes the characters of a key that gjson paths interpret
+func escapePathKey(key string) string {
+	if !strings.ContainsAny(key, pathSpecialChars) {
+		return key
+	}
+	var escaped strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(pathSpecialChars, r) {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
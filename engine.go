@@ -8,6 +8,7 @@ import (
 	"github.com/tidwall/gjson"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/asaskevich/EventBus"
 )
@@ -18,7 +19,9 @@ func DefaultRuleEngineOptions() *RuleEngineOptions {
 	return &RuleEngineOptions{
 		AllowUndefinedFacts:       false,
 		AllowUndefinedConditions:  false,
+		AllowUnknownOperators:     false,
 		ReplaceFactsInEventParams: false,
+		RejectEmptyGroups:         true,
 	}
 }
 
@@ -35,23 +38,50 @@ func NewEngine(rules []*Rule, options *RuleEngineOptions) *Engine {
 
 	engine := &Engine{
 		Rules:                     []*Rule{},
-		Operators:                 make(map[string]Operator),
 		Status:                    READY,
 		bus:                       EventBus.New(),
 		AllowUndefinedConditions:  options.AllowUndefinedConditions,
+		AllowUnknownOperators:     options.AllowUnknownOperators,
 		AllowUndefinedFacts:       options.AllowUndefinedFacts,
 		ReplaceFactsInEventParams: options.ReplaceFactsInEventParams,
+		ContinueOnError:           options.ContinueOnError,
+		EventConflictPolicy:       options.EventConflictPolicy,
+		RejectEmptyGroups:         options.RejectEmptyGroups,
+		scheduler:                 options.Scheduler,
+		CostAwareOrdering:         options.CostAwareOrdering,
+		EphemeralRulesFact:        options.EphemeralRulesFact,
+		EventFilter:               options.EventFilter,
+		StrictEventTypes:          options.StrictEventTypes,
+		StrictFactTypes:           options.StrictFactTypes,
+		SynchronousEvents:         options.SynchronousEvents,
+		ForwardChaining:           options.ForwardChaining,
+		SlowConditionThreshold:    options.SlowConditionThreshold,
+		OnSlowCondition:           options.OnSlowCondition,
+		CaseInsensitiveFactPaths:  options.CaseInsensitiveFactPaths,
+	}
+	if engine.scheduler == nil {
+		engine.scheduler = goroutineScheduler{}
+	}
+
+	if options.ResultCacheSize > 0 {
+		engine.resultCache = newResultCache(options.ResultCacheSize, options.ResultCacheTTL)
 	}
 
+	for _, o := range DefaultOperators() {
+		engine.AddOperator(o, nil)
+	}
+	for name, transform := range DefaultTransforms() {
+		engine.transforms.store(name, transform)
+	}
+	for name, decorator := range DefaultOperatorDecorators() {
+		engine.decorators.store(name, decorator)
+	}
 	for _, r := range rules {
 		err := engine.AddRule(r)
 		if err != nil {
 			return nil
 		}
 	}
-	for _, o := range DefaultOperators() {
-		engine.AddOperator(o, nil)
-	}
 	return engine
 }
 
@@ -61,16 +91,60 @@ func NewEngine(rules []*Rule, options *RuleEngineOptions) *Engine {
 // - rule: The rule to be added to the engine.
 // Returns an error if the rule is invalid or cannot be added.
 func (e *Engine) AddRule(rule *Rule) error {
-	if rule == nil {
-		return errors.New("engine: rule is required")
+	if err := e.validateRule(rule); err != nil {
+		return err
 	}
 
 	rule.SetEngine(e)
-	e.Rules = append(e.Rules, rule)
-	e.prioritizedRules = nil
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.setRules(append(e.Rules, rule))
+	return nil
+}
+
+// validateRule checks a rule before it is added to the engine
+func (e *Engine) validateRule(rule *Rule) error {
+	if rule == nil {
+		return errors.New("engine: rule is required")
+	}
+	if err := e.validateRuleValues(rule); err != nil {
+		return err
+	}
+	if e.StrictEventTypes && !e.isRegisteredEventType(rule.RuleEvent.Type) {
+		return fmt.Errorf("engine: rule %q: %w %q", rule.Name, ErrUnregisteredEventType, rule.RuleEvent.Type)
+	}
+	if e.StrictFactTypes {
+		if mismatches := e.factTypeMismatches(rule, nil, nil); len(mismatches) > 0 {
+			return newFactTypeError(rule.Name, mismatches)
+		}
+	}
+	if e.RejectEmptyGroups {
+		if path := emptyGroupPath(&rule.Conditions, ""); path != "" {
+			return fmt.Errorf("engine: rule %q: empty condition group %s", rule.Name, path)
+		}
+	}
 	return nil
 }
 
+// setRules replaces the rule list and drops the prioritized cache, the caller must hold e.mu.
+// The rule list is never modified in place, so slices handed out by GetRules stay valid.
+func (e *Engine) setRules(rules []*Rule) {
+	e.Rules = rules
+	e.prioritizedRules.Store(nil)
+	e.configVersion.Add(1)
+}
+
+// withoutRules returns a copy of the rule list without the rules matching the predicate, the caller must hold e.mu
+func (e *Engine) withoutRules(match func(r *Rule) bool) []*Rule {
+	rules := make([]*Rule, 0, len(e.Rules))
+	for _, r := range e.Rules {
+		if !match(r) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
 // AddRuleFromMap adds a rule to the engine from a configuration map.
 // The rule is created from the map and then added to the engine.
 // Params:
@@ -81,11 +155,125 @@ func (e *Engine) AddRuleFromMap(rp *RuleConfig) error {
 		return errors.New("engine: AddRuleFromMap invalid configuration")
 	}
 
-	r, _ := NewRule(rp)
-	r.SetEngine(e)
-	e.Rules = append(e.Rules, r)
-	e.prioritizedRules = nil
-	return nil
+	r, err := NewRule(rp)
+	if err != nil {
+		return err
+	}
+	return e.AddRule(r)
+}
+
+// validateRuleValues checks that the operators of the rule's leaf conditions are registered, unless AllowUnknownOperators
+// is set, their values against the value validators of the operators, and that multi-fact operators are used with
+// the right number of facts. Conditions using operators that are not registered or have no value validator are not checked.
+func (e *Engine) validateRuleValues(rule *Rule) error {
+	operators, decorators := e.Operators(), e.OperatorDecorators()
+	if !e.AllowUnknownOperators {
+		if unknown := unknownOperators(&rule.Conditions, operators, decorators, "conditions"); len(unknown) > 0 {
+			return NewUnknownOperatorsError(rule.Name, unknown)
+		}
+	}
+	if unknown := unknownTransforms(&rule.Conditions, e.Transforms(), "conditions"); len(unknown) > 0 {
+		return NewUnknownTransformsError(rule.Name, unknown)
+	}
+	var validate func(c *Condition) error
+	validate = func(c *Condition) error {
+		if c == nil {
+			return nil
+		}
+		if op, _, ok := lookupOperator(operators, decorators, c.Operator); ok && c.Operator != "" {
+			switch {
+			case len(c.Facts) > 0 && !op.IsMultiFact():
+				return fmt.Errorf("engine: rule %q: operator %q is not a multi-fact operator", rule.Name, c.Operator)
+			case op.IsMultiFact() && len(c.Facts) == 0:
+				return fmt.Errorf("engine: rule %q: condition on fact %q: operator %q requires facts", rule.Name, c.Fact, c.Operator)
+			case op.IsMultiFact():
+				if err := op.validateFactCount(len(c.Facts)); err != nil {
+					return fmt.Errorf("engine: rule %q: %w", rule.Name, err)
+				}
+			}
+		}
+		if c.Operator != "" {
+			if op, _, ok := lookupOperator(operators, decorators, c.Operator); ok && !hasFactReferences(&c.Value) && !op.ValidateValue(&c.Value) {
+				expected := "a valid value"
+				if op.Metadata != nil && op.Metadata.ValueType == "fact" {
+					expected = `a fact reference {"fact": "path"}`
+				} else if op.Metadata != nil {
+					expected = "a value of type " + op.Metadata.ValueType
+				}
+				return fmt.Errorf("engine: rule %q: condition on fact %q: operator %q expects %s, got %s", rule.Name, c.Fact, c.Operator, expected, c.Value.Type)
+			}
+		}
+		for _, child := range c.All {
+			if err := validate(child); err != nil {
+				return err
+			}
+		}
+		for _, child := range c.Any {
+			if err := validate(child); err != nil {
+				return err
+			}
+		}
+		for _, child := range c.None {
+			if err := validate(child); err != nil {
+				return err
+			}
+		}
+		for _, child := range c.MostOf {
+			if err := validate(child); err != nil {
+				return err
+			}
+		}
+		return validate(c.Not)
+	}
+	return validate(&rule.Conditions)
+}
+
+// unknownOperators returns every leaf condition of the tree whose operator is not registered, as its path and operator,
+// e.g. `conditions.all[1] "graterThan"`
+func unknownOperators(c *Condition, operators map[string]Operator, decorators map[string]OperatorDecorator, path string) []string {
+	if c == nil {
+		return nil
+	}
+	var unknown []string
+	if c.Operator != "" {
+		if _, _, ok := lookupOperator(operators, decorators, c.Operator); !ok {
+			unknown = append(unknown, fmt.Sprintf("%s %q", path, c.Operator))
+		}
+	}
+	for _, group := range []struct {
+		operator   string
+		conditions []*Condition
+	}{{"all", c.All}, {"any", c.Any}, {"none", c.None}, {"mostOf", c.MostOf}} {
+		for i, child := range group.conditions {
+			unknown = append(unknown, unknownOperators(child, operators, decorators, fmt.Sprintf("%s.%s[%d]", path, group.operator, i))...)
+		}
+	}
+	return append(unknown, unknownOperators(c.Not, operators, decorators, path+".not")...)
+}
+
+// emptyGroupPath returns the path of the first empty 'all', 'any' or 'none' group in the condition tree, e.g. "any[1].all",
+// or an empty string when there is none
+func emptyGroupPath(c *Condition, path string) string {
+	if c == nil {
+		return ""
+	}
+	for _, group := range []struct {
+		operator   string
+		conditions []*Condition
+	}{{"all", c.All}, {"any", c.Any}, {"none", c.None}, {"mostOf", c.MostOf}} {
+		if group.conditions == nil {
+			continue
+		}
+		if len(group.conditions) == 0 {
+			return path + group.operator
+		}
+		for i, child := range group.conditions {
+			if found := emptyGroupPath(child, fmt.Sprintf("%s%s[%d].", path, group.operator, i)); found != "" {
+				return found
+			}
+		}
+	}
+	return emptyGroupPath(c.Not, path+"not.")
 }
 
 // AddRules adds multiple rules to the engine in a single operation.
@@ -109,24 +297,26 @@ func (e *Engine) AddRules(rules []*Rule) error {
 // - r: The updated rule.
 // Returns an error if the rule cannot be found or updated.
 func (e *Engine) UpdateRule(r *Rule) error {
-	ruleIndex := -1
-	for i, ruleInEngine := range e.Rules {
-		if ruleInEngine.Name == r.Name {
-			ruleIndex = i
-			break
-		}
+	if err := e.validateRule(r); err != nil {
+		return err
 	}
 
-	if ruleIndex > -1 {
-		e.Rules = append(e.Rules[:ruleIndex], e.Rules[ruleIndex+1:]...)
-		err := e.AddRule(r)
-		if err != nil {
-			return err
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	replaced := false
+	rules := e.withoutRules(func(ruleInEngine *Rule) bool {
+		if !replaced && ruleInEngine.Name == r.Name {
+			replaced = true
+			return true
 		}
-		e.prioritizedRules = nil
-		return nil
+		return false
+	})
+	if !replaced {
+		return errors.New("engine: updateRule() rule not found")
 	}
-	return errors.New("engine: updateRule() rule not found")
+	r.SetEngine(e)
+	e.setRules(append(rules, r))
+	return nil
 }
 
 // RemoveRule removes an existing rule in the engine.
@@ -134,20 +324,20 @@ func (e *Engine) UpdateRule(r *Rule) error {
 // - r: The updated rule.
 // Returns an error if the rule cannot be found or updated.
 func (e *Engine) RemoveRule(rule *Rule) bool {
-	index := -1
-	for i, r := range e.Rules {
-		if r == rule {
-			index = i
-			break
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	removed := false
+	rules := e.withoutRules(func(r *Rule) bool {
+		if !removed && r == rule {
+			removed = true
+			return true
 		}
+		return false
+	})
+	if removed {
+		e.setRules(rules)
 	}
-
-	if index > -1 {
-		e.Rules = append(e.Rules[:index], e.Rules[index+1:]...)
-		e.prioritizedRules = nil // reset prioritized rules
-		return true
-	}
-	return false
+	return removed
 }
 
 // RemoveRuleByName removes an existing rule in the engine by its name.
@@ -155,28 +345,85 @@ func (e *Engine) RemoveRule(rule *Rule) bool {
 // - name: The name of the rule to be removed.
 // Returns true if the rule was removed, false if it was not found.
 func (e *Engine) RemoveRuleByName(name string) bool {
-	var filteredRules []*Rule
-	for _, r := range e.Rules {
-		if r.Name != name {
-			filteredRules = append(filteredRules, r)
-		}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rules := e.withoutRules(func(r *Rule) bool { return r.Name == name })
+	if len(rules) == len(e.Rules) {
+		return false
 	}
+	e.setRules(rules)
+	return true
+}
 
-	if len(filteredRules) != len(e.Rules) {
-		e.Rules = filteredRules
-		e.prioritizedRules = nil // reset prioritized rules
-		return true
+// ClearRules removes all rules from the engine.
+// Operators, facts and named conditions are kept; a run started afterwards evaluates no rules.
+func (e *Engine) ClearRules() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clearRules()
+}
+
+// Reset removes all rules, named conditions and bundle constants from the engine.
+// Operators, registered facts, event handlers and the engine options are kept.
+func (e *Engine) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clearRules()
+	e.Conditions.Clear()
+	for path := range e.constants {
+		e.Facts.Delete(path)
 	}
-	return false
+	if len(e.constants) > 0 {
+		e.factsVersion.Add(1)
+	}
+	e.constants = nil
+}
+
+// clearRules drops the rules and the prioritized cache, the caller must hold e.mu
+func (e *Engine) clearRules() {
+	e.setRules(nil)
 }
 
 // GetRules returns all rules in the engine.
 // Returns a slice of all rules in the engine.
 func (e *Engine) GetRules() []*Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	return e.Rules
 }
 
-// TODO ADD CONDITION THAT CAN BE REUSED IN RULES
+// AddCondition adds a named condition that can be referenced from rules with {"condition": name}
+// Params:
+// - name: The name of the condition.
+// - condition: The condition to be added.
+// Returns an error if the name is empty or the condition is invalid.
+func (e *Engine) AddCondition(name string, condition *Condition) error {
+	if name == "" {
+		return errors.New("engine: condition name is required")
+	}
+	if condition == nil {
+		return errors.New("engine: condition is required")
+	}
+	if err := condition.Validate(); err != nil {
+		return err
+	}
+	e.Conditions.Store(name, *condition)
+	e.configVersion.Add(1)
+	return nil
+}
+
+// SetCondition registers a named condition like AddCondition, requiring a group or a condition reference at its root
+// so the name stands for a reusable block rather than a single leaf, e.g. {"all": [...]} for rules using {"condition": "isAdult"}.
+// Params:
+// - name: The name of the condition.
+// - cond: The condition to be registered.
+// Returns an error if the name is empty or the condition is invalid.
+func (e *Engine) SetCondition(name string, cond Condition) error {
+	if cond.All == nil && cond.Any == nil && cond.None == nil && cond.MostOf == nil && cond.Not == nil && cond.Condition == "" {
+		return fmt.Errorf("engine: condition %q must have an 'all', 'any', 'none', 'mostOf', 'not' or 'condition' root", name)
+	}
+	return e.AddCondition(name, &cond)
+}
 
 // RemoveCondition removes a condition that has previously been added to this engine
 // Params:
@@ -186,25 +433,92 @@ func (e *Engine) RemoveCondition(name string) bool {
 	_, ok := e.Conditions.Load(name)
 	if ok {
 		e.Conditions.Delete(name)
+		e.configVersion.Add(1)
 	}
 	return ok
 }
 
-// AddOperator adds a custom operator definition
+// AddOperator adds a custom operator definition, replacing an operator of the same name
 // Params:
-// - operatorOrName: The operator to be added, or the name of the operator.
-// - cb: The callback function to be executed when the operator is evaluated.
-func (e *Engine) AddOperator(operatorOrName interface{}, cb func(*ValueNode, *ValueNode) bool) {
+// - operatorOrName: The operator to be added, as an Operator or *Operator, or the name of the operator.
+// - cb: The callback function to be executed when the operator is evaluated, used with a name.
+// Returns an error if the operator has no name or callback, or operatorOrName is of another type.
+func (e *Engine) AddOperator(operatorOrName interface{}, cb func(*ValueNode, *ValueNode) bool) error {
 	var op Operator
 	switch v := operatorOrName.(type) {
 	case Operator:
 		op = v
+	case *Operator:
+		if v == nil {
+			return errors.New("engine: operator is nil")
+		}
+		op = *v
 	case string:
-		newOpp, _ := NewOperator(v, cb, nil)
-		op = *newOpp
+		newOp, err := NewOperator(v, cb, nil)
+		if err != nil {
+			return fmt.Errorf("engine: operator %q: %w", v, err)
+		}
+		op = *newOp
+	default:
+		return fmt.Errorf("engine: unsupported operator type %T", operatorOrName)
+	}
+	if op.Name == "" {
+		return errors.New("engine: operator name is required")
+	}
+	if op.Callback == nil && op.DetailCallback == nil && op.ErrorCallback == nil && op.MultiFactCallback == nil {
+		return fmt.Errorf("engine: operator %q has no callback", op.Name)
 	}
 	Debug(fmt.Sprintf("engine::addOperator name:%s", op.Name))
-	e.Operators[op.Name] = op
+	e.operators.store(op)
+	e.configVersion.Add(1)
+	return nil
+}
+
+// OperatorInfo describes a registered operator, see Engine.ListOperators
+type OperatorInfo struct {
+	Name string `json:"name"`
+	// Default is set for the operators of DefaultOperators, also when they were replaced by an operator of the same name
+	Default     bool   `json:"default"`
+	Description string `json:"description,omitempty"`
+}
+
+// ListOperators returns the registered operators sorted by name, e.g. for rule authoring tools.
+// Decorated and "!" negated names are not listed, see HasOperator.
+func (e *Engine) ListOperators() []OperatorInfo {
+	operators := e.Operators()
+	infos := make([]OperatorInfo, 0, len(operators))
+	for name, op := range operators {
+		_, builtin := builtinOperatorNames[name]
+		infos = append(infos, OperatorInfo{Name: name, Default: builtin, Description: op.Description})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// HasOperator reports whether conditions can use the operator name: a registered operator, or one negated with "!"
+// or wrapped in registered decorators, e.g. "!in" or "everyFact:greaterThan".
+func (e *Engine) HasOperator(name string) bool {
+	_, _, ok := lookupOperator(e.Operators(), e.OperatorDecorators(), name)
+	return ok
+}
+
+// Operators returns a snapshot of the registered operators by name.
+// The map is shared and must not be modified, use AddOperator and RemoveOperator instead.
+func (e *Engine) Operators() map[string]Operator {
+	return e.operators.load()
+}
+
+// AddOperatorE adds a custom operator whose callback can fail, see NewOperatorE
+// Params:
+// - name: The name of the operator.
+// - cb: The callback function to be executed when the operator is evaluated, returning the outcome or an error.
+// Returns an error if the name is empty or the callback is nil.
+func (e *Engine) AddOperatorE(name string, cb func(a, b *ValueNode) (bool, error)) error {
+	op, err := NewOperatorE(name, cb, nil)
+	if err != nil {
+		return err
+	}
+	return e.AddOperator(*op, nil)
 }
 
 // RemoveOperator removes a custom operator definition
@@ -219,9 +533,9 @@ func (e *Engine) RemoveOperator(operatorOrName interface{}) bool {
 	case string:
 		operatorName = v
 	}
-	_, ok := e.Operators[operatorName]
+	ok := e.operators.remove(operatorName)
 	if ok {
-		delete(e.Operators, operatorName)
+		e.configVersion.Add(1)
 	}
 	return ok
 }
@@ -229,16 +543,17 @@ func (e *Engine) RemoveOperator(operatorOrName interface{}) bool {
 // AddFact adds a fact definition to the engine
 // Params:
 // path: The path of the fact.
-// value: The value of the fact.
+// value: The value of the fact, copied so later changes to its arrays and objects do not reach the engine.
 // options: Additional options for the fact.
 // Returns an error if the fact cannot be added.
 func (e *Engine) AddFact(path string, value *ValueNode, options *FactOptions) error {
-	fact, err := NewFact(path, *value, options)
+	fact, err := NewFact(path, value.deepCopy(), options)
 	if err != nil {
 		return err
 	}
 	Debug(fmt.Sprintf("engine::addFact id:%s", fact.Path))
 	e.Facts.Set(fact.Path, fact)
+	e.factsVersion.Add(1)
 	return nil
 }
 
@@ -252,6 +567,102 @@ func (e *Engine) AddCalculatedFact(path string, method DynamicFactCallback, opti
 	fact := NewCalculatedFact(path, method, options)
 	Debug(fmt.Sprintf("engine::addFact id:%s", fact.Path))
 	e.Facts.Set(fact.Path, fact)
+	e.factsVersion.Add(1)
+	return nil
+}
+
+// AddCalculatedFactE adds a calculated fact whose callback receives the context of the run and can fail, see
+// NewCalculatedFactE. An error fails the rule referencing the fact with a CalculatedFactError, or evaluates its
+// conditions as false with FactOptions{OnError: FactErrorFalse}.
+// Params:
+// path: The path of the fact.
+// method: The callback function to be executed when the fact is evaluated.
+// options: Additional options for the fact.
+// Returns an error if the path is empty, the callback is nil or OnError is not supported.
+func (e *Engine) AddCalculatedFactE(path string, method FactCallbackE, options *FactOptions) error {
+	if path == "" {
+		return errors.New("engine: fact path is required")
+	}
+	if method == nil {
+		return fmt.Errorf("engine: calculated fact %s is nil", path)
+	}
+	if options != nil && options.OnError != "" && options.OnError != FactErrorFail && options.OnError != FactErrorFalse {
+		return fmt.Errorf("engine: calculated fact %s: unsupported onError %q", path, options.OnError)
+	}
+	fact := NewCalculatedFactE(path, method, options)
+	Debug(fmt.Sprintf("engine::addFact id:%s", fact.Path))
+	e.Facts.Set(fact.Path, fact)
+	e.factsVersion.Add(1)
+	return nil
+}
+
+// AddFacts adds static facts in bulk, converting each value with NewValue, e.g. 42, "a" or map[string]interface{}{"a": 1}.
+// Paths that are already registered conflict unless options.Override is set, as do paths stored under the same key of
+// the fact map. Either every fact is added or, on an error, none.
+// Params:
+// values: The values of the facts by path.
+// options: Additional options for every fact.
+// Returns a FactPathConflictError (ErrFactPathConflict) listing every conflict, or the error of a path or value that
+// cannot be converted.
+func (e *Engine) AddFacts(values map[string]interface{}, options *FactOptions) error {
+	facts := make([]*Fact, 0, len(values))
+	for _, path := range sortedKeys(values) {
+		value, err := NewValue(values[path])
+		if err != nil {
+			return fmt.Errorf("engine: fact %s: %w", path, err)
+		}
+		fact, err := NewFact(path, value.deepCopy(), options)
+		if err != nil {
+			return err
+		}
+		facts = append(facts, fact)
+	}
+	return e.addFacts(facts, options)
+}
+
+// AddCalculatedFacts adds calculated facts in bulk, with the conflict checks of AddFacts.
+// Params:
+// methods: The callbacks of the facts by path.
+// options: Additional options for every fact.
+// Returns a FactPathConflictError (ErrFactPathConflict) listing every conflict, or an error for a nil callback.
+func (e *Engine) AddCalculatedFacts(methods map[string]DynamicFactCallback, options *FactOptions) error {
+	facts := make([]*Fact, 0, len(methods))
+	for _, path := range sortedKeys(methods) {
+		if methods[path] == nil {
+			return fmt.Errorf("engine: calculated fact %s is nil", path)
+		}
+		facts = append(facts, NewCalculatedFact(path, methods[path], options))
+	}
+	return e.addFacts(facts, options)
+}
+
+// addFacts registers a batch of facts sorted by path once none of them conflicts
+func (e *Engine) addFacts(facts []*Fact, options *FactOptions) error {
+	override := options != nil && options.Override
+	var conflicts []FactPathConflict
+	batch := make(map[uint64]string, len(facts))
+	for _, f := range facts {
+		if f.Path == "" {
+			return errors.New("engine: fact path is required")
+		}
+		key := HashString(f.Path)
+		if other, ok := batch[key]; ok {
+			conflicts = append(conflicts, FactPathConflict{Path: f.Path, With: other})
+			continue
+		}
+		batch[key] = f.Path
+		if existing, ok := e.Facts.Load(f.Path); ok && (existing.Path != f.Path || !override) {
+			conflicts = append(conflicts, FactPathConflict{Path: f.Path, With: existing.Path, Registered: true})
+		}
+	}
+	if len(conflicts) > 0 {
+		return &FactPathConflictError{Conflicts: conflicts}
+	}
+	for _, f := range facts {
+		Debug(fmt.Sprintf("engine::addFact id:%s", f.Path))
+		e.Facts.Set(f.Path, f)
+	}
+	e.factsVersion.Add(1)
 	return nil
 }
 
@@ -263,6 +674,7 @@ func (e *Engine) RemoveFact(path string) bool {
 	_, ok := e.Facts.Load(path)
 	if ok {
 		e.Facts.Delete(path)
+		e.factsVersion.Add(1)
 	}
 	return ok
 }
@@ -272,44 +684,91 @@ func (e *Engine) RemoveFact(path string) bool {
 // path: The path of the fact to be retrieved.
 // Returns the fact if it exists, or nil if it does not.
 func (e *Engine) GetFact(path string) *Fact {
-	f, _ := e.Facts.Load(path)
-	if &f == nil {
+	f, ok := e.Facts.Load(path)
+	if !ok {
 		return nil
 	}
 	return f
 }
 
 // PrioritizeRules iterates over the engine rules, organizing them by highest -> lowest priority
-// Returns a 2D slice of rules, where each inner slice contains rules of the same priority
+// Returns a 2D slice of rules, where each inner slice contains rules of the same priority.
+// The result is a snapshot shared by concurrent runs and must not be modified; rules added or removed
+// afterwards only affect the snapshots taken later.
 func (e *Engine) PrioritizeRules() [][]*Rule {
-	if e.prioritizedRules == nil {
-		ruleSets := make(map[int][]*Rule)
-		for _, r := range e.Rules {
-			priority := r.GetPriority()
-			ruleSets[priority] = append(ruleSets[priority], r)
-		}
+	if sets := e.prioritizedRules.Load(); sets != nil {
+		return *sets
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if sets := e.prioritizedRules.Load(); sets != nil {
+		return *sets
+	}
 
-		var keys []int
-		for k := range ruleSets {
-			keys = append(keys, k)
-		}
+	ruleSets := make(map[int][]*Rule)
+	for _, r := range e.Rules {
+		priority := r.GetPriority()
+		ruleSets[priority] = append(ruleSets[priority], r)
+	}
 
-		sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+	var keys []int
+	for k := range ruleSets {
+		keys = append(keys, k)
+	}
 
-		for _, k := range keys {
-			e.prioritizedRules = append(e.prioritizedRules, ruleSets[k])
-		}
+	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+
+	sets := make([][]*Rule, 0, len(keys))
+	for _, k := range keys {
+		sets = append(sets, ruleSets[k])
 	}
-	return e.prioritizedRules
+	e.prioritizedRules.Store(&sets)
+	return sets
 }
 
-// Stop stops the rules engine from running the next priority set of Rules
+// Stop stops all active runs of the rules engine from running their next priority set of Rules, cancelling
+// their contexts. Runs started after Stop are not affected. To stop a single run, e.g. from an event handler,
+// use ExecutionContext.StopProcessing from Almanac.ExecutionContext instead.
 // Returns the engine instance
 func (e *Engine) Stop() *Engine {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
 	e.Status = FINISHED
+	for ctx := range e.activeRuns {
+		ctx.Stop("engine stopped")
+	}
 	return e
 }
 
+// GetStatus returns the status of the engine: READY, RUNNING while a run is active, or FINISHED.
+// Unlike the Status field, it is safe to call while runs are active.
+func (e *Engine) GetStatus() string {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	return e.Status
+}
+
+// startRun registers the execution context of a new run and marks the engine as running
+func (e *Engine) startRun(ctx *ExecutionContext) {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	if e.activeRuns == nil {
+		e.activeRuns = make(map[*ExecutionContext]struct{})
+	}
+	e.activeRuns[ctx] = struct{}{}
+	e.Status = RUNNING
+}
+
+// finishRun unregisters the execution context of a run and marks the engine as finished once no other run is active
+func (e *Engine) finishRun(ctx *ExecutionContext) {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	delete(e.activeRuns, ctx)
+	if len(e.activeRuns) == 0 {
+		e.Status = FINISHED
+	}
+}
+
 // EvaluateRules runs an array of rules
 // Params:
 // - rules: The rules to be evaluated.
@@ -317,9 +776,9 @@ func (e *Engine) Stop() *Engine {
 // - ctx: The execution context for the rules.
 // Returns an error if any rule evaluation fails.
 func (e *Engine) EvaluateRules(rules []*Rule, almanac *Almanac, ctx *ExecutionContext) error {
-	// CHECK STATE OF ENGINE
-	if e.Status != RUNNING {
-		Debug(fmt.Sprintf("engine::run status:%s; skipping remaining rules", e.Status))
+	// CHECK STATE OF THE RUN
+	if ctx.Stopped() || ctx.ProcessingStopped() {
+		Debug("engine::run stopped; skipping remaining rules")
 		return nil
 	}
 
@@ -328,12 +787,13 @@ func (e *Engine) EvaluateRules(rules []*Rule, almanac *Almanac, ctx *ExecutionCo
 	results := make(chan *RuleResult, len(rules))
 
 	for _, r := range rules {
-		if ctx.StopEarly {
+		if ctx.Stopped() {
 			break
 		}
 
 		wg.Add(1)
-		go func(rule *Rule) {
+		rule := r
+		e.schedule(func() {
 			defer wg.Done()
 
 			select {
@@ -341,17 +801,27 @@ func (e *Engine) EvaluateRules(rules []*Rule, almanac *Almanac, ctx *ExecutionCo
 				Debug("Context cancelled inEvaluator goroutine")
 				return
 			default:
+				started := time.Now()
 				ruleResult, err := rule.Evaluate(ctx, almanac)
+				almanac.ruleTimings.record(rule, started)
+				ruleErr := err
+				if err == nil {
+					ruleErr = ruleResult.Error
+				}
+				e.diagnoseRule(almanac, rule, time.Since(started), ruleErr)
 				if err != nil {
 					errs <- err
 					return
 				}
+				if almanac.checkDeterminism {
+					e.checkDeterminism(ctx, almanac, rule, ruleResult)
+				}
 
 				Debug(fmt.Sprintf("engine::run ruleResult:%v", ruleResult.Result))
 				results <- ruleResult
 				Debug("Result sent to results channel inEvaluator goroutine")
 			}
-		}(r)
+		})
 	}
 
 	// Close results and errors channels after all goroutines complete
@@ -362,25 +832,35 @@ func (e *Engine) EvaluateRules(rules []*Rule, almanac *Almanac, ctx *ExecutionCo
 		close(errs)
 	}()
 
-	// Collect results
+	// Collect results; with SynchronousEvents the events are published once the group completed
+	var pending, chained []*RuleResult
 	for ruleResult := range results {
 		Debug("Received result from results channel")
 		almanac.AddResult(ruleResult)
+		if ruleResult.EventSuppressed {
+			// The match is recorded, the event is neither collected nor published
+			continue
+		}
+		outcome := Failure
 		if ruleResult.Result != nil && *ruleResult.Result {
-			err := almanac.AddEvent(ruleResult.Event, "success")
-			if err != nil {
-				Debug(fmt.Sprintf("Error adding success event: %v", err))
-				return err
-			}
-			e.bus.Publish("success", ruleResult.Event, almanac, ruleResult)
-			e.bus.Publish(ruleResult.Event.Type, ruleResult.Event.Params, almanac, ruleResult)
-		} else {
-			err := almanac.AddEvent(ruleResult.Event, "failure")
-			if err != nil {
-				Debug(fmt.Sprintf("Error adding failure event: %v", err))
-				return err
-			}
-			e.bus.Publish("failure", ruleResult.Event, almanac, ruleResult)
+			outcome = Success
+		}
+		if err := almanac.AddEvent(ruleResult.Event, outcome); err != nil {
+			Debug(fmt.Sprintf("Error adding %s event: %v", outcome, err))
+			return err
+		}
+		if e.ForwardChaining && outcome == Success {
+			chained = append(chained, ruleResult)
+		}
+		if almanac.quiet {
+			continue
+		}
+		if e.SynchronousEvents {
+			pending = append(pending, ruleResult)
+			continue
+		}
+		if err := e.publishResult(almanac, ruleResult); err != nil {
+			return err
 		}
 	}
 
@@ -390,70 +870,330 @@ func (e *Engine) EvaluateRules(rules []*Rule, almanac *Almanac, ctx *ExecutionCo
 		return err
 	}
 
+	sortByRulePosition(pending, rules)
+	for _, ruleResult := range pending {
+		if err := e.publishResult(almanac, ruleResult); err != nil {
+			return err
+		}
+	}
+	// Facts are set once the whole group completed, so the rules of the group do not see each other's facts
+	sortByRulePosition(chained, rules)
+	for _, ruleResult := range chained {
+		if err := setEventFacts(almanac, ruleResult); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (e *Engine) Run(ctx context.Context, input []byte) (map[string]interface{}, error) {
-	return e.runInternal(ctx, input)
+// sortByRulePosition sorts rule results by the position of their rule in rules
+func sortByRulePosition(results []*RuleResult, rules []*Rule) {
+	if len(results) < 2 {
+		return
+	}
+	position := make(map[*Rule]int, len(rules))
+	for i, r := range rules {
+		position[r] = i
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return position[results[i].rule] < position[results[j].rule]
+	})
 }
 
-func (e *Engine) RunWithMap(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+// publishResult publishes the event of a rule result to the engine handlers, and with SynchronousEvents to the
+// callbacks of the rule. A panicking handler is recovered and reported as an error naming the rule.
+func (e *Engine) publishResult(almanac *Almanac, ruleResult *RuleResult) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("engine: event handler of rule %q panicked: %v", ruleResult.Name, r)
+		}
+	}()
+	outcome := "failure"
+	if ruleResult.Result != nil && *ruleResult.Result {
+		outcome = "success"
+		e.bus.Publish("success", ruleResult.Event, almanac, ruleResult)
+		e.bus.Publish(ruleResult.Event.Type, ruleResult.Event.Params, almanac, ruleResult)
+		e.bus.Publish(eventTopic(ruleResult.Event.Type), ruleResult.Event, almanac, ruleResult)
+	} else {
+		e.bus.Publish("failure", ruleResult.Event, almanac, ruleResult)
+	}
+	if e.SynchronousEvents && ruleResult.rule != nil {
+		ruleResult.rule.bus.Publish(outcome, ruleResult)
+	}
+	return nil
+}
+
+// Run evaluates the rules against the JSON facts.
+// Params:
+// - ctx: The context of the run; when it is cancelled, the rules not evaluated yet are skipped.
+// - input: The facts as raw JSON.
+// Returns the outcome of the run, or an error when the run failed.
+func (e *Engine) Run(ctx context.Context, input []byte) (*RunResult, error) {
+	return e.runInternal(ctx, input, nil)
+}
+
+// RunWithMap evaluates the rules against facts given as a map, which is encoded to JSON first.
+// Params:
+// - ctx: The context of the run.
+// - input: The facts.
+// Returns the outcome of the run, or an error when the facts are nil or cannot be encoded, or the run failed.
+func (e *Engine) RunWithMap(ctx context.Context, input map[string]interface{}) (*RunResult, error) {
+	if input == nil {
+		return nil, &InvalidFactsJSONError{Reason: "facts map is nil"}
+	}
 	factBytes, err := json.Marshal(input)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling input map: %v", err)
 	}
-	return e.runInternal(ctx, factBytes)
+	return e.runInternal(ctx, factBytes, nil)
+}
+
+// RunWithOptions runs the rules engine with run-scoped options such as evaluation budgets.
+// If options is nil, DefaultRunOptions are used.
+func (e *Engine) RunWithOptions(ctx context.Context, input []byte, options *RunOptions) (*RunResult, error) {
+	return e.runInternal(ctx, input, options)
+}
+
+// configurationVersion returns a version that changes whenever rules, operators, named conditions or facts change
+func (e *Engine) configurationVersion() uint64 {
+	return e.configVersion.Load() + e.factsVersion.Load()
+}
+
+// PurgeResultCache removes all cached run results
+func (e *Engine) PurgeResultCache() {
+	if e.resultCache != nil {
+		e.resultCache.purge()
+	}
+}
+
+// runInternal serves the run from the result cache when enabled, evaluating the rules on a miss
+func (e *Engine) runInternal(ctx context.Context, facts []byte, options *RunOptions) (res *RunResult, err error) {
+	defer func(started time.Time) {
+		e.counters.record(started, err)
+	}(time.Now())
+
+	if options == nil || !options.AllowFactFragments {
+		if err := validateFactsJSON(facts); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.resultCache == nil || e.hasEphemeralRules(options) {
+		return e.evaluate(ctx, facts, options)
+	}
+
+	key, cacheable := resultCacheKey(facts, options)
+	if !cacheable {
+		return e.evaluate(ctx, facts, options)
+	}
+	version := e.configurationVersion()
+	if cached, ok := e.resultCache.get(key, version); ok {
+		Debug(fmt.Sprintf("engine::run result cache hit key:%s", key))
+		var serialization *SerializationOptions
+		var locale string
+		if options != nil {
+			serialization, locale = options.Serialization, options.Locale
+		}
+		return cached.cachedCopy(serialization, locale), nil
+	}
+
+	res, err = e.evaluate(ctx, facts, options)
+	if err == nil && res != nil && !res.Partial {
+		e.resultCache.put(key, version, res)
+	}
+	return res, err
 }
 
-// Run runs the rules engine
-func (e *Engine) runInternal(ctx context.Context, facts []byte) (map[string]interface{}, error) {
-	var err error
+// validateFactsJSON checks that the fact input of a run is a JSON object
+func validateFactsJSON(facts []byte) error {
+	if !gjson.ValidBytes(facts) {
+		var syntaxErr *json.SyntaxError
+		var value interface{}
+		if err := json.Unmarshal(facts, &value); errors.As(err, &syntaxErr) {
+			return &InvalidFactsJSONError{Offset: syntaxErr.Offset, Reason: syntaxErr.Error()}
+		} else if err != nil {
+			return &InvalidFactsJSONError{Reason: err.Error()}
+		}
+		return &InvalidFactsJSONError{Reason: "malformed JSON"}
+	}
+	if parsed := gjson.ParseBytes(facts); !parsed.IsObject() {
+		return &InvalidFactsJSONError{Reason: fmt.Sprintf("facts must be an object, got %s", parsed.Type)}
+	}
+	return nil
+}
+
+// evaluate runs the rules engine
+func (e *Engine) evaluate(ctx context.Context, facts []byte, options *RunOptions) (res *RunResult, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("engine::run recovered from panic: %v", r)
+			res, err = nil, fmt.Errorf("engine::run recovered from panic: %v", r)
 		}
 	}()
 
 	Debug("engine::run started")
-	e.Status = RUNNING
 
 	parsedFacts := gjson.ParseBytes(facts)
 
+	if options == nil {
+		options = DefaultRunOptions()
+	}
+	// The rule sets are taken once, so rules cleared or added during the run do not affect it
+	orderedSets := e.PrioritizeRules()
+	ephemeral, err := e.ephemeralRules(parsedFacts, options)
+	if err != nil {
+		return nil, err
+	}
+	orderedSets = mergeRuleSets(orderedSets, ephemeral)
+	ruleCount := 0
+	for _, set := range orderedSets {
+		ruleCount += len(set)
+	}
 	almanacInstance := NewAlmanac(parsedFacts, Options{
-		AllowUndefinedFacts: &e.AllowUndefinedFacts,
-	}, len(e.Rules))
+		AllowUndefinedFacts:      &e.AllowUndefinedFacts,
+		MaxCachedFactBytes:       options.MaxCachedFactBytes,
+		LazyArrayThreshold:       options.LazyArrayThreshold,
+		CaseInsensitiveFactPaths: e.CaseInsensitiveFactPaths,
+	}, ruleCount)
+	almanacInstance.budget = newEvaluationBudget(options)
+	values := NewValues(options.Values)
+	almanacInstance.values = values
+	if options.MemoizeConditions {
+		almanacInstance.conditionMemo = newConditionMemo()
+	}
+	almanacInstance.quiet = options.quiet
+	almanacInstance.trace = options.Trace
+	almanacInstance.ruleTimings = options.ruleTimings
+	if options.TrackFactAccess {
+		almanacInstance.accessed = newFactAccessLog()
+	}
+	almanacInstance.replay = options.replay
+	almanacInstance.session = options.session
+	almanacInstance.ruleTimeout = options.RuleTimeout
+	almanacInstance.checkDeterminism = options.CheckDeterminism
 
+	// Calculated facts are computed lazily, when a condition first references them, into the almanac of the run;
+	// the engine's facts hold their definitions only
 	e.Facts.Range(func(key string, f *Fact) bool {
-		if f.Dynamic {
-			f.Calculate(almanacInstance)
-		}
 		almanacInstance.AddFact(key, f)
 		return true
 
 	})
 
+	callerCtx := ctx
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	// Run Context
-	execCtx := &ExecutionContext{
-		Context: ctx,
-		Cancel:  cancel,
-	}
+	execCtx := newExecutionContext(ctx, cancel, values)
+	almanacInstance.execCtx = execCtx
+	e.startRun(execCtx)
+	defer e.finishRun(execCtx)
 
-	orderedSets := e.PrioritizeRules()
-	for _, set := range orderedSets {
-		if err := e.EvaluateRules(set, almanacInstance, execCtx); err != nil {
-			return nil, err
+	started := time.Now()
+	deadlineExceeded := false
+	var noDataResults []*RuleResult
+	if options.IgnorePriorityBarriers {
+		// All rules share a single evaluation group; results are put back in priority order afterwards
+		almanacInstance.noPriorityBarriers = true
+		position := make(map[*Rule]int, ruleCount)
+		all := make([]*Rule, 0, ruleCount)
+		for _, set := range orderedSets {
+			for _, r := range set {
+				position[r] = len(all)
+				all = append(all, r)
+			}
 		}
-		if execCtx.StopEarly {
-			break
+		if options.SkipRulesWithoutFacts {
+			all, noDataResults = e.withoutNoDataRules(all, almanacInstance)
+		}
+		if callerCtx.Err() == nil {
+			if err := e.EvaluateRules(all, almanacInstance, execCtx); err != nil {
+				e.diagnoseBudget(almanacInstance, started)
+				return nil, err
+			}
+		}
+		if almanacInstance.barrierViolation.Load() {
+			return nil, ErrPriorityBarriersRequired
+		}
+		almanacInstance.orderResults(position)
+	} else {
+		for i, set := range orderedSets {
+			if callerCtx.Err() != nil {
+				break
+			}
+			if options.SoftDeadline > 0 && time.Since(started) >= options.SoftDeadline {
+				Debug(fmt.Sprintf("engine::run soft deadline of %s exceeded, skipping %d priority groups", options.SoftDeadline, len(orderedSets)-i))
+				deadlineExceeded = true
+				e.diagnose(almanacInstance, Diagnostic{Type: DiagnosticRunBudgetExceeded, Duration: time.Since(started), Limit: options.SoftDeadline})
+				break
+			}
+			almanacInstance.priorityGroup = i
+			groupStarted := time.Now()
+			if options.SkipRulesWithoutFacts {
+				// Checked per group, as facts added by earlier groups can provide data
+				var skipped []*RuleResult
+				set, skipped = e.withoutNoDataRules(set, almanacInstance)
+				noDataResults = append(noDataResults, skipped...)
+			}
+			if err := e.EvaluateRules(set, almanacInstance, execCtx); err != nil {
+				e.diagnoseBudget(almanacInstance, started)
+				return nil, err
+			}
+			almanacInstance.groupDurations = append(almanacInstance.groupDurations, time.Since(groupStarted))
+			if execCtx.Stopped() || execCtx.ProcessingStopped() {
+				break
+			}
 		}
 	}
 
-	e.Status = FINISHED
+	if err := almanacInstance.budget.err(); err != nil {
+		e.diagnoseBudget(almanacInstance, started)
+		return nil, err
+	}
+	if err := e.resolveEventConflicts(almanacInstance); err != nil {
+		return nil, err
+	}
+
 	Debug("engine::run completed")
 
+	// When the caller cancelled the run or its soft deadline passed, rules that did not complete are reported as skipped
+	skippedResults := noDataResults
+	partial := callerCtx.Err() != nil || deadlineExceeded
+	if partial {
+		reason := SkippedBudget
+		if callerCtx.Err() != nil {
+			Debug(fmt.Sprintf("engine::run cancelled: %v", callerCtx.Err()))
+			reason = SkippedCancelled
+		}
+		completed := make(map[*Rule]struct{}, len(almanacInstance.GetResults()))
+		for _, ruleResult := range almanacInstance.GetResults() {
+			completed[ruleResult.rule] = struct{}{}
+		}
+		for _, ruleResult := range noDataResults {
+			completed[ruleResult.rule] = struct{}{}
+		}
+		for _, set := range orderedSets {
+			for _, r := range set {
+				if _, ok := completed[r]; !ok {
+					skippedResults = append(skippedResults, newSkippedResult(r, reason))
+				}
+			}
+		}
+	}
+
 	ruleResults := almanacInstance.GetResults()
+	if options.Serialization != nil {
+		for _, ruleResult := range ruleResults {
+			ruleResult.Serialization = options.Serialization
+		}
+	}
+	if options.Locale != "" {
+		for _, ruleResult := range ruleResults {
+			ruleResult.Locale = options.Locale
+		}
+		for _, ruleResult := range skippedResults {
+			ruleResult.Locale = options.Locale
+		}
+	}
 	var results []*RuleResult
 	var failureResults []*RuleResult
 
@@ -462,18 +1202,25 @@ func (e *Engine) runInternal(ctx context.Context, facts []byte) (map[string]inte
 		for _, ruleResult := range ruleResults {
 			// Safely check if ruleResult.Result is not nil and true
 			if ruleResult.Result != nil && *ruleResult.Result {
-				results = append(results, &ruleResult)
+				results = append(results, ruleResult)
 			} else {
-				failureResults = append(failureResults, &ruleResult)
+				failureResults = append(failureResults, ruleResult)
 			}
 		}
 	}
 
-	return map[string]interface{}{
-		"almanac":        almanacInstance,
-		"results":        results,
-		"failureResults": failureResults,
-		"events":         almanacInstance.GetEvents("success"),
-		"failureEvents":  almanacInstance.GetEvents("failure"),
+	return &RunResult{
+		Almanac:          almanacInstance,
+		Results:          results,
+		FailureResults:   failureResults,
+		Events:           *almanacInstance.GetEvents("success"),
+		FailureEvents:    *almanacInstance.GetEvents("failure"),
+		DroppedEvents:    almanacInstance.droppedEvents,
+		Stats:            almanacInstance.Stats(),
+		Partial:          partial,
+		DeadlineExceeded: deadlineExceeded,
+		SkippedResults:   skippedResults,
+		Diagnostics:      almanacInstance.Diagnostics(),
+		Error:            callerCtx.Err(),
 	}, err
 }
@@ -0,0 +1,1083 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestEngine creates an engine with a single rule parsed from JSON
+func newTestEngine(t *testing.T, ruleJSON string, options *RuleEngineOptions) *Engine {
+	t.Helper()
+	var ruleConfig RuleConfig
+	if err := json.Unmarshal([]byte(ruleJSON), &ruleConfig); err != nil {
+		t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+	}
+	rule, err := NewRule(&ruleConfig)
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	engine := NewEngine(nil, options)
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	return engine
+}
+
+// scoreRule describes a rule of newScoreRulesEngine
+type scoreRule struct {
+	name, event string
+	priority    int
+}
+
+// newScoreRulesEngine creates an engine with rules emitting the given events, in order, all passing for {"score": 50}
+func newScoreRulesEngine(t *testing.T, options *RuleEngineOptions, rules ...scoreRule) *Engine {
+	t.Helper()
+	engine := NewEngine(nil, options)
+	for _, config := range rules {
+		priority := config.priority
+		rule, err := NewRule(&RuleConfig{
+			Name:       config.name,
+			Priority:   &priority,
+			Conditions: Condition{All: []*Condition{{Fact: "score", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 10}}}},
+			Event:      EventConfig{Type: config.event},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+	return engine
+}
+
+func TestEngineEvaluationBudget(t *testing.T) {
+	ruleJSON := `{
+		"name": "budget",
+		"conditions": {
+			"all": [
+				{"fact": "a", "operator": "equal", "value": 1},
+				{"fact": "b", "operator": "equal", "value": 2}
+			]
+		},
+		"event": {"type": "budget"}
+	}`
+	facts := []byte(`{"a": 1, "b": 2}`)
+
+	t.Run("Within budget reports stats", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, nil)
+		res, err := engine.RunWithOptions(context.Background(), facts, nil)
+		if err != nil {
+			t.Fatalf("Expected run to succeed, got error: %v", err)
+		}
+		stats := res.Stats
+		if stats.ConditionEvaluations != 2 {
+			t.Errorf("Expected 2 condition evaluations, got %d", stats.ConditionEvaluations)
+		}
+		if stats.FactResolutions != 2 {
+			t.Errorf("Expected 2 fact resolutions, got %d", stats.FactResolutions)
+		}
+	})
+
+	t.Run("Condition budget exceeded", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, nil)
+		_, err := engine.RunWithOptions(context.Background(), facts, &RunOptions{MaxConditionEvaluations: 1})
+		if !errors.Is(err, ErrEvaluationBudgetExceeded) {
+			t.Fatalf("Expected ErrEvaluationBudgetExceeded, got %v", err)
+		}
+		var budgetErr *EvaluationBudgetExceededError
+		if !errors.As(err, &budgetErr) || budgetErr.Rule != "budget" {
+			t.Errorf("Expected budget error identifying rule 'budget', got %v", err)
+		}
+	})
+
+	t.Run("Fact budget exceeded by a condition names its rule", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, nil)
+		_, err := engine.RunWithOptions(context.Background(), facts, &RunOptions{MaxFactResolutions: 1})
+		var budgetErr *EvaluationBudgetExceededError
+		if !errors.As(err, &budgetErr) || budgetErr.Budget != "factResolutions" || budgetErr.Rule != "budget" {
+			t.Errorf("Expected a fact budget error identifying rule 'budget', got %v", err)
+		}
+	})
+
+	t.Run("Rules wrap budget errors charged outside of them", func(t *testing.T) {
+		shared := &EvaluationBudgetExceededError{Budget: "factResolutions", Limit: 1, Fact: "a"}
+		var wg sync.WaitGroup
+		for _, name := range []string{"first", "second"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r := &Rule{Name: name}
+				var budgetErr *EvaluationBudgetExceededError
+				err := r.annotateError(fmt.Errorf("a equal > %w", shared))
+				if !errors.As(err, &budgetErr) || budgetErr != shared || !strings.HasPrefix(err.Error(), fmt.Sprintf("rule %q: ", name)) {
+					t.Errorf("Expected the error to name rule %s, got %v", name, err)
+				}
+			}()
+		}
+		wg.Wait()
+		if shared.Rule != "" {
+			t.Errorf("Expected the shared budget error to stay unannotated, got rule %q", shared.Rule)
+		}
+	})
+
+	t.Run("Recursive fact resolution budget exceeded", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, nil)
+		var resolve func(a *Almanac) *ValueNode
+		resolve = func(a *Almanac) *ValueNode {
+			if _, err := a.FactValue("b"); err != nil {
+				return &ValueNode{Type: Null}
+			}
+			return resolve(a)
+		}
+		err := engine.AddCalculatedFact("a", func(a *Almanac, params ...interface{}) *ValueNode {
+			return resolve(a)
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		_, err = engine.RunWithOptions(context.Background(), facts, &RunOptions{MaxFactResolutions: 100})
+		if !errors.Is(err, ErrEvaluationBudgetExceeded) {
+			t.Fatalf("Expected ErrEvaluationBudgetExceeded, got %v", err)
+		}
+	})
+}
+
+func TestEngineRunValues(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "tenant",
+		"conditions": {"all": [{"fact": "tenantTier", "operator": "equal", "value": "gold"}]},
+		"event": {"type": "tenant"}
+	}`, nil)
+	err := engine.AddCalculatedFact("tenantTier", func(a *Almanac, params ...interface{}) *ValueNode {
+		tenant, _ := a.Values().Get("tenant")
+		if tenant == "acme" {
+			return &ValueNode{Type: String, String: "gold"}
+		}
+		return &ValueNode{Type: String, String: "basic"}
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+
+	res, err := engine.RunWithOptions(context.Background(), []byte(`{}`), &RunOptions{Values: map[string]interface{}{"tenant": "acme"}})
+	if err != nil {
+		t.Fatalf("Expected run to succeed, got error: %v", err)
+	}
+	if events := res.Events; len(events) != 1 {
+		t.Errorf("Expected the fact resolver to read the run values, got %d events", len(events))
+	}
+}
+
+func TestEngineAddRuleValidatesValues(t *testing.T) {
+	newRule := func(t *testing.T, conditions string) *Rule {
+		t.Helper()
+		var ruleConfig RuleConfig
+		if err := json.Unmarshal([]byte(`{"name": "values", "conditions": `+conditions+`, "event": {"type": "values"}}`), &ruleConfig); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		rule, err := NewRule(&ruleConfig)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		return rule
+	}
+
+	testCases := []struct {
+		name       string
+		conditions string
+		valid      bool
+	}{
+		{"numeric operator with number", `{"all": [{"fact": "age", "operator": "greaterThan", "value": 18}]}`, true},
+		{"numeric operator with string", `{"all": [{"fact": "age", "operator": "greaterThan", "value": "18"}]}`, false},
+		{"in with array", `{"all": [{"fact": "country", "operator": "in", "value": ["CH"]}]}`, true},
+		{"in with string nested", `{"any": [{"all": [{"fact": "country", "operator": "in", "value": "CH"}]}]}`, false},
+		{"custom operator is permissive", `{"all": [{"fact": "age", "operator": "custom", "value": "18"}]}`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := NewEngine(nil, nil)
+			engine.AddOperator("custom", func(a, b *ValueNode) bool { return true })
+			err := engine.AddRule(newRule(t, tc.conditions))
+			if tc.valid && err != nil {
+				t.Errorf("Expected rule to be accepted, got error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Expected rule to be rejected")
+			}
+		})
+	}
+}
+
+// newPriorityTestEngine creates an engine with a high priority rule "first" and a low priority rule "second",
+// both checking the fact "a" with the given operator, which may be registered afterwards
+func newPriorityTestEngine(t *testing.T, operator string) *Engine {
+	t.Helper()
+	options := DefaultRuleEngineOptions()
+	options.AllowUnknownOperators = true
+	engine := NewEngine(nil, options)
+	for _, ruleJSON := range []string{
+		`{"name": "first", "priority": 10, "conditions": {"all": [{"fact": "a", "operator": "` + operator + `", "value": 1}]}, "event": {"type": "first"}}`,
+		`{"name": "second", "priority": 1, "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "second"}}`,
+	} {
+		var ruleConfig RuleConfig
+		if err := json.Unmarshal([]byte(ruleJSON), &ruleConfig); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		rule, err := NewRule(&ruleConfig)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+	return engine
+}
+
+// assertPartial checks a cancelled run reported the expected completed and skipped rules
+func assertPartial(t *testing.T, res *RunResult, completed, skipped []string) {
+	t.Helper()
+	if !res.Partial {
+		t.Fatalf("Expected a partial result, got %v", res.Partial)
+	}
+	if !errors.Is(res.Error, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", res.Error)
+	}
+	var gotCompleted []string
+	for _, rr := range res.Results {
+		gotCompleted = append(gotCompleted, rr.Name)
+	}
+	for _, rr := range res.FailureResults {
+		gotCompleted = append(gotCompleted, rr.Name)
+	}
+	if len(gotCompleted) != len(completed) {
+		t.Errorf("Expected completed rules %v, got %v", completed, gotCompleted)
+	}
+	skippedResults := res.SkippedResults
+	if len(skippedResults) != len(skipped) {
+		t.Fatalf("Expected skipped rules %v, got %d", skipped, len(skippedResults))
+	}
+	for i, rr := range skippedResults {
+		if rr.Name != skipped[i] || rr.Skipped != SkippedCancelled || rr.Result != nil {
+			t.Errorf("Unexpected skipped result %s (%s)", rr.Name, rr.Skipped)
+		}
+	}
+}
+
+func TestEngineAddRuleRejectsUnknownOperators(t *testing.T) {
+	ruleJSON := `{"name": "typos", "conditions": {"all": [
+		{"fact": "age", "operator": "graterThan", "value": 18},
+		{"any": [{"fact": "country", "operator": "!in", "value": ["CH"]}, {"not": {"fact": "tier", "operator": "custom", "value": "gold"}}]}
+	]}, "event": {"type": "typos"}}`
+	var ruleConfig RuleConfig
+	if err := json.Unmarshal([]byte(ruleJSON), &ruleConfig); err != nil {
+		t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+	}
+	rule, err := NewRule(&ruleConfig)
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	engine := NewEngine(nil, nil)
+	err = engine.AddRule(rule)
+	var invalid *InvalidRuleError
+	if !errors.As(err, &invalid) || invalid.Code != "UNKNOWN_OPERATOR" {
+		t.Fatalf("Expected an InvalidRuleError, got %v", err)
+	}
+	expected := `rule "typos": unknown operators: conditions.all[0] "graterThan", conditions.all[1].any[1].not "custom"`
+	if invalid.Message != expected {
+		t.Errorf("Expected %q, got %q", expected, invalid.Message)
+	}
+	if len(engine.GetRules()) != 0 {
+		t.Errorf("Expected the rule not to be added")
+	}
+
+	t.Run("opt out for operators registered later", func(t *testing.T) {
+		engine := NewEngine(nil, &RuleEngineOptions{AllowUnknownOperators: true})
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Expected the rule to be accepted, got %v", err)
+		}
+		if _, err := engine.Run(context.Background(), []byte(`{"age": 30, "country": "DE", "tier": "gold"}`)); err == nil || !strings.Contains(err.Error(), "Unknown operator: graterThan") {
+			t.Errorf("Expected the unknown operator to fail the run, got %v", err)
+		}
+	})
+}
+
+func TestEngineRunPartialResults(t *testing.T) {
+	t.Run("cancelled before run", func(t *testing.T) {
+		engine := newPriorityTestEngine(t, "equal")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		res, err := engine.Run(ctx, []byte(`{"a": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assertPartial(t, res, nil, []string{"first", "second"})
+	})
+
+	t.Run("cancelled within a priority group", func(t *testing.T) {
+		engine := newPriorityTestEngine(t, "cancels")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		engine.AddOperator("cancels", func(a, b *ValueNode) bool {
+			cancel()
+			return EvalEqual(a, b)
+		})
+		res, err := engine.Run(ctx, []byte(`{"a": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assertPartial(t, res, []string{"first"}, []string{"second"})
+	})
+
+	t.Run("cancelled during result collection", func(t *testing.T) {
+		engine := newPriorityTestEngine(t, "equal")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := engine.bus.Subscribe("success", func(_ Event, _ *Almanac, _ *RuleResult) { cancel() }); err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		res, err := engine.Run(ctx, []byte(`{"a": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assertPartial(t, res, []string{"first"}, []string{"second"})
+	})
+
+	t.Run("not cancelled", func(t *testing.T) {
+		engine := newPriorityTestEngine(t, "equal")
+		res, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if res.Partial || res.Error != nil || len(res.SkippedResults) != 0 {
+			t.Errorf("Expected a complete result, got partial=%v error=%v", res.Partial, res.Error)
+		}
+	})
+}
+
+func TestEngineRunSoftDeadline(t *testing.T) {
+	engine := newPriorityTestEngine(t, "slowEqual")
+	engine.AddOperator("slowEqual", func(a, b *ValueNode) bool {
+		time.Sleep(20 * time.Millisecond)
+		return EvalEqual(a, b)
+	})
+	options := DefaultRunOptions()
+	options.SoftDeadline = 5 * time.Millisecond
+
+	res, err := engine.RunWithOptions(context.Background(), []byte(`{"a": 1}`), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !res.Partial || !res.DeadlineExceeded || res.Error != nil {
+		t.Fatalf("Expected a partial result without error, got partial=%v deadlineExceeded=%v error=%v", res.Partial, res.DeadlineExceeded, res.Error)
+	}
+	// The slow group completes although it overran the deadline
+	if results := res.Results; len(results) != 1 || results[0].Name != "first" {
+		t.Errorf("Expected the first priority group to complete, got %v", results)
+	}
+	skipped := res.SkippedResults
+	if len(skipped) != 1 || skipped[0].Name != "second" || skipped[0].Skipped != SkippedBudget {
+		t.Errorf("Expected the second rule to be skipped for the budget, got %v", skipped)
+	}
+	durations := res.Stats.PriorityGroupDurations
+	if len(durations) != 1 || durations[0] < 20*time.Millisecond {
+		t.Errorf("Expected the duration of the evaluated group, got %v", durations)
+	}
+
+	options.SoftDeadline = time.Second
+	res, err = engine.RunWithOptions(context.Background(), []byte(`{"a": 1}`), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if res.Partial || len(res.Results) != 2 || len(res.Stats.PriorityGroupDurations) != 2 {
+		t.Errorf("Expected a complete run within the deadline, got %v", res)
+	}
+}
+
+func TestEngineIgnorePriorityBarriers(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	for i := 1; i <= 20; i++ {
+		rule, err := NewRule(&RuleConfig{
+			Name:     fmt.Sprintf("rule-%02d", i),
+			Priority: &i,
+			Conditions: Condition{All: []*Condition{
+				{Fact: "a", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: float64(i % 3)}},
+			}},
+			Event: EventConfig{Type: fmt.Sprintf("event-%02d", i)},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+
+	expected, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for n := 0; n < 5; n++ {
+		res, err := engine.RunWithOptions(context.Background(), []byte(`{"a": 1}`), &RunOptions{IgnorePriorityBarriers: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for key, lists := range map[string][2][]*RuleResult{
+			"results":        {res.Results, expected.Results},
+			"failureResults": {res.FailureResults, expected.FailureResults},
+		} {
+			got, want := lists[0], lists[1]
+			if len(got) != len(want) {
+				t.Fatalf("Expected %d %s, got %d", len(want), key, len(got))
+			}
+			for i := range want {
+				if got[i].Name != want[i].Name {
+					t.Errorf("Expected %s[%d] to be %s, got %s", key, i, want[i].Name, got[i].Name)
+				}
+			}
+		}
+		events := res.Events
+		for i := 1; i < len(events); i++ {
+			if events[i-1].Type < events[i].Type {
+				t.Errorf("Expected events in priority order, got %s before %s", events[i-1].Type, events[i].Type)
+			}
+		}
+	}
+}
+
+func TestEngineIgnorePriorityBarriersRejectsRuntimeFacts(t *testing.T) {
+	engine := newPriorityTestEngine(t, "equal")
+	if err := engine.bus.Subscribe("success", func(_ Event, almanac *Almanac, _ *RuleResult) {
+		_ = almanac.AddRuntimeFact("b", ValueNode{Type: Number, Number: 1})
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	_, err := engine.RunWithOptions(context.Background(), []byte(`{"a": 1}`), &RunOptions{IgnorePriorityBarriers: true})
+	if !errors.Is(err, ErrPriorityBarriersRequired) {
+		t.Errorf("Expected ErrPriorityBarriersRequired, got %v", err)
+	}
+	if _, err := engine.Run(context.Background(), []byte(`{"a": 1}`)); err != nil {
+		t.Errorf("Expected runtime facts to be allowed with priority barriers, got %v", err)
+	}
+}
+
+func TestEngineMultiFactOperators(t *testing.T) {
+	ruleJSON := `{
+		"name": "known items",
+		"conditions": {"all": [{"facts": ["order.itemIds", "catalog.ids"], "operator": "subsetOf"}]},
+		"event": {"type": "known"}
+	}`
+
+	t.Run("Operands are resolved and reported", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, nil)
+		res, err := engine.Run(context.Background(), []byte(`{"order": {"itemIds": [1, 3]}, "catalog": {"ids": [1, 2, 3]}}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		results := res.Results
+		if len(results) != 1 {
+			t.Fatalf("Expected the rule to pass, got %v", res.FailureResults)
+		}
+		condition := results[0].Conditions.All[0]
+		if len(condition.FactResults) != 2 || len(condition.FactResults[0].Array) != 2 || len(condition.FactResults[1].Array) != 3 {
+			t.Errorf("Expected both resolved operands on the result, got %v", condition.FactResults)
+		}
+
+		res, err = engine.Run(context.Background(), []byte(`{"order": {"itemIds": [1, 4]}, "catalog": {"ids": [1, 2, 3]}}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 0 {
+			t.Errorf("Expected the rule to fail for an unknown item")
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		tests := []struct {
+			name, conditions, message string
+		}{
+			{"wrong arity", `{"all": [{"facts": ["a", "b", "c"], "operator": "subsetOf"}]}`, "expects 2 facts"},
+			{"binary operator", `{"all": [{"facts": ["a", "b"], "operator": "equal", "value": 1}]}`, "not a multi-fact operator"},
+			{"missing facts", `{"all": [{"fact": "a", "operator": "subsetOf", "value": 1}]}`, "requires facts"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var ruleConfig RuleConfig
+				if err := json.Unmarshal([]byte(`{"name": "r", "conditions": `+tt.conditions+`, "event": {"type": "r"}}`), &ruleConfig); err != nil {
+					t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+				}
+				rule, err := NewRule(&ruleConfig)
+				if err != nil {
+					t.Fatalf("Failed to create rule: %v", err)
+				}
+				if err := NewEngine(nil, nil).AddRule(rule); err == nil || !strings.Contains(err.Error(), tt.message) {
+					t.Errorf("Expected error containing %q, got %v", tt.message, err)
+				}
+			})
+		}
+	})
+}
+
+func TestEngineConditionMemo(t *testing.T) {
+	newMemoEngine := func(t *testing.T) (*Engine, *int) {
+		t.Helper()
+		engine := NewEngine(nil, nil)
+		calls := 0
+		engine.AddOperator("counted", func(a, b *ValueNode) bool {
+			calls++
+			return EvalEqual(a, b)
+		})
+		for i, name := range []string{"high", "low"} {
+			priority := 10 - i
+			rule, err := NewRule(&RuleConfig{
+				Name:       name,
+				Priority:   &priority,
+				Conditions: Condition{All: []*Condition{{Fact: "a", Operator: "counted", Value: ValueNode{Type: Number, Number: 1}}}},
+				Event:      EventConfig{Type: name},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create rule: %v", err)
+			}
+			if err := engine.AddRule(rule); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+		}
+		return engine, &calls
+	}
+	facts := []byte(`{"a": 1}`)
+
+	t.Run("Identical conditions are evaluated once across priority groups", func(t *testing.T) {
+		engine, calls := newMemoEngine(t)
+		res, err := engine.RunWithOptions(context.Background(), facts, &RunOptions{MemoizeConditions: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *calls != 1 {
+			t.Errorf("Expected 1 evaluation, got %d", *calls)
+		}
+		stats := res.Stats
+		if stats.ConditionMemoHits != 1 || stats.CrossGroupMemoHits != 1 {
+			t.Errorf("Expected 1 cross group memo hit, got %+v", stats)
+		}
+		for _, rr := range res.Results {
+			if !rr.Conditions.All[0].Result || rr.Conditions.All[0].FactResult.Value.Number != 1 {
+				t.Errorf("Expected memoized results on the condition tree of %s", rr.Name)
+			}
+		}
+	})
+
+	t.Run("Adding a runtime fact invalidates the memo", func(t *testing.T) {
+		engine, calls := newMemoEngine(t)
+		if err := engine.bus.Subscribe("success", func(_ Event, almanac *Almanac, _ *RuleResult) {
+			_ = almanac.AddRuntimeFact("b", ValueNode{Type: Number, Number: 1})
+		}); err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		res, err := engine.RunWithOptions(context.Background(), facts, &RunOptions{MemoizeConditions: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *calls != 2 || res.Stats.ConditionMemoHits != 0 {
+			t.Errorf("Expected the memo to be invalidated, got %d evaluations", *calls)
+		}
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		engine, calls := newMemoEngine(t)
+		if _, err := engine.Run(context.Background(), facts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *calls != 2 {
+			t.Errorf("Expected 2 evaluations, got %d", *calls)
+		}
+	})
+}
+
+func TestEngineClearRules(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "r", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "r"}}`, nil)
+	facts := []byte(`{"a": 1}`)
+	if res, err := engine.Run(context.Background(), facts); err != nil || len(res.Results) != 1 {
+		t.Fatalf("Expected the rule to pass, got %v", err)
+	}
+
+	engine.ClearRules()
+	if len(engine.GetRules()) != 0 {
+		t.Errorf("Expected no rules, got %d", len(engine.GetRules()))
+	}
+	res, err := engine.Run(context.Background(), facts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 0 || len(res.FailureResults) != 0 {
+		t.Errorf("Expected an empty result after ClearRules, got %v", res)
+	}
+}
+
+func TestEngineReset(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	engine.AddOperator("divisibleBy", func(a, b *ValueNode) bool {
+		return b.Number != 0 && int(a.Number)%int(b.Number) == 0
+	})
+	if err := engine.AddFact("registered", &ValueNode{Type: Number, Number: 4}, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	err := engine.LoadBundle([]byte(`{
+		"constants": {"limit": 3},
+		"conditions": {"even": {"all": [{"fact": "registered", "operator": "divisibleBy", "value": 2}]}},
+		"rules": [{"name": "r", "conditions": {"all": [{"condition": "even"}]}, "event": {"type": "r"}}]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to load bundle: %v", err)
+	}
+
+	engine.Reset()
+	if len(engine.GetRules()) != 0 {
+		t.Errorf("Expected no rules, got %d", len(engine.GetRules()))
+	}
+	if _, ok := engine.Conditions.Load("even"); ok {
+		t.Errorf("Expected named conditions to be cleared")
+	}
+	if _, ok := engine.Facts.Load("limit"); ok {
+		t.Errorf("Expected constants to be cleared")
+	}
+	if _, ok := engine.Facts.Load("registered"); !ok {
+		t.Errorf("Expected registered facts to be kept")
+	}
+	if _, ok := engine.Operators()["divisibleBy"]; !ok {
+		t.Errorf("Expected operators to be kept")
+	}
+
+	// The engine is usable again with the kept operator and fact
+	rule := `{"name": "r", "conditions": {"all": [{"fact": "registered", "operator": "divisibleBy", "value": 2}]}, "event": {"type": "r"}}`
+	var config RuleConfig
+	if err := json.Unmarshal([]byte(rule), &config); err != nil {
+		t.Fatalf("Failed to unmarshal rule: %v", err)
+	}
+	r, err := NewRule(&config)
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	if err := engine.AddRule(r); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	res, err := engine.Run(context.Background(), []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Errorf("Expected the rule to pass after Reset")
+	}
+}
+
+func TestEngineOperatorsConcurrentMutation(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "r", "conditions": {"all": [
+		{"fact": "age", "operator": "greaterThan", "value": 18},
+		{"fact": "country", "operator": "startsWith", "value": "D"}
+	]}, "event": {"type": "adult"}}`, nil)
+	facts := []byte(`{"age": 30, "country": "DE"}`)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := engine.Run(context.Background(), facts)
+			if err == nil && len(res.Results) != 1 {
+				err = errors.New("expected the rule to pass")
+			}
+			if err != nil {
+				errs <- err
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("custom%d", i)
+		engine.AddOperator(name, func(a, b *ValueNode) bool { return true })
+		if i%2 == 0 {
+			engine.RemoveOperator(name)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Unexpected run error: %v", err)
+	}
+	if _, ok := engine.Operators()["custom1"]; !ok {
+		t.Errorf("Expected custom1 to be registered")
+	}
+	if _, ok := engine.Operators()["custom0"]; ok {
+		t.Errorf("Expected custom0 to be removed")
+	}
+}
+
+func TestEngineRulesConcurrentMutation(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "base", "conditions": {"all": [{"fact": "age", "operator": "greaterThan", "value": 18}]}, "event": {"type": "adult"}}`, nil)
+	facts := []byte(`{"age": 30}`)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := engine.Run(context.Background(), facts)
+			if err != nil {
+				errs <- err
+				return
+			}
+			found := false
+			for _, r := range res.Results {
+				found = found || r.Name == "base"
+			}
+			if !found {
+				errs <- errors.New("expected the base rule to pass")
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		priority := i%5 + 1
+		rule, err := NewRule(&RuleConfig{
+			Name:       fmt.Sprintf("temp%d", i),
+			Priority:   &priority,
+			Conditions: Condition{All: []*Condition{{Fact: "age", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 18}}}},
+			Event:      EventConfig{Type: "temp"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+		if i%2 == 0 {
+			engine.RemoveRuleByName(rule.Name)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Unexpected run error: %v", err)
+	}
+	if len(engine.GetRules()) != 26 {
+		t.Errorf("Expected 26 rules, got %d", len(engine.GetRules()))
+	}
+}
+
+func TestEngineSetCondition(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "adult", "conditions": {"all": [{"condition": "isAdult"}]}, "event": {"type": "adult"}}`, nil)
+	leaf := Condition{Fact: "age", Operator: "greaterThanInclusive", Value: ValueNode{Type: Number, Number: 18}}
+	if err := engine.SetCondition("isAdult", leaf); err == nil {
+		t.Errorf("Expected an error for a condition without group at its root")
+	}
+	if err := engine.SetCondition("isAdult", Condition{All: []*Condition{&leaf}}); err != nil {
+		t.Fatalf("Failed to set condition: %v", err)
+	}
+
+	// The reference must survive evaluation, so every run resolves it again
+	for i, age := range []int{20, 12, 30} {
+		res, err := engine.Run(context.Background(), []byte(fmt.Sprintf(`{"age": %d}`, age)))
+		if err != nil {
+			t.Fatalf("Run %d: unexpected error: %v", i, err)
+		}
+		if passed := len(res.Results) == 1; passed != (age >= 18) {
+			t.Errorf("Run %d: expected the rule to pass for age %d: %v", i, age, age >= 18)
+		}
+	}
+	if ref := engine.GetRules()[0].Conditions.All[0]; ref.Condition != "isAdult" {
+		t.Errorf("Expected the rule to keep its condition reference, got %q", ref.Condition)
+	}
+}
+
+func TestEngineConcurrentRuns(t *testing.T) {
+	engine := NewEngine(nil, &RuleEngineOptions{AllowUndefinedFacts: true, ReplaceFactsInEventParams: true})
+	if err := engine.SetCondition("isAdult", Condition{All: []*Condition{{Fact: "user.age", Operator: "greaterThanInclusive", Value: ValueNode{Type: Number, Number: 18}}}}); err != nil {
+		t.Fatalf("Failed to set condition: %v", err)
+	}
+	engine.AddCalculatedFact("doubled", func(a *Almanac, params ...interface{}) *ValueNode {
+		f, err := a.FactValue("user.age")
+		if err != nil || f == nil {
+			return nil
+		}
+		return &ValueNode{Type: Number, Number: f.Value.Number * 2}
+	}, nil)
+	for _, ruleJSON := range []string{
+		`{"name": "adult", "priority": 2, "conditions": {"all": [{"condition": "isAdult"}, {"any": [{"fact": "doubled", "operator": "greaterThan", "value": 40}, {"fact": "user.vip", "operator": "equal", "value": true}]}]}, "event": {"type": "adult", "params": {"id": {"fact": "user.id"}}}}`,
+		`{"name": "flagged", "priority": 1, "conditions": {"all": [{"fact": "flag", "operator": "equal", "value": true}]}, "event": {"type": "flagged"}}`,
+	} {
+		config := ephemeralRuleConfig(t, ruleJSON)
+		rule, err := NewRule(&config)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+	// The first priority group of every run flags adults for the second one
+	engine.OnSuccess(func(event Event, almanac *Almanac, _ *RuleResult) {
+		if event.Type == "adult" {
+			_ = almanac.AddRuntimeFact("flag", ValueNode{Type: Bool, Bool: true})
+		}
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 500)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			age := 10 + i // Runs 8 and up are adults, and their doubled age is above 40 from run 11
+			adult := age >= 18 && (age*2 > 40 || i%2 == 0)
+			for n := 0; n < 10; n++ {
+				facts := fmt.Sprintf(`{"user": {"id": %d, "age": %d, "vip": %t}}`, i, age, i%2 == 0)
+				res, err := engine.Run(context.Background(), []byte(facts))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !adult {
+					if len(res.Results) != 0 || len(res.FailureResults) != 2 {
+						errs <- fmt.Errorf("run %d: expected both rules to fail, got %d results", i, len(res.Results))
+					}
+					continue
+				}
+				if len(res.Results) != 2 || res.Results[0].Name != "adult" || res.Results[1].Name != "flagged" {
+					errs <- fmt.Errorf("run %d: expected both rules to pass, got %d results", i, len(res.Results))
+					continue
+				}
+				if id := res.Events[0].Params["id"]; id != float64(i) {
+					errs <- fmt.Errorf("run %d: expected the event of its own facts, got id %v", i, id)
+				}
+				// The doubled age is not evaluated when the vip condition decided the group first
+				if doubled := res.Results[0].Conditions.All[1].Any[0].FactResult.Value; doubled != nil && doubled.Number != float64(age*2) {
+					errs <- fmt.Errorf("run %d: expected the fact results of its own evaluation, got %v", i, doubled.Number)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if status := engine.GetStatus(); status != FINISHED {
+		t.Errorf("Expected the engine to be finished, got %s", status)
+	}
+}
+
+func TestEngineStop(t *testing.T) {
+	engine := newPriorityTestEngine(t, "stopping")
+	stop := true
+	engine.AddOperator("stopping", func(a, b *ValueNode) bool {
+		if stop {
+			engine.Stop()
+		}
+		return EvalEqual(a, b)
+	})
+
+	// Stop ends the active run after its current priority group
+	res, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 || res.Results[0].Name != "first" {
+		t.Errorf("Expected only the first priority group to run, got %v", res.Results)
+	}
+
+	// Runs started after Stop are not affected
+	stop = false
+	res, err = engine.Run(context.Background(), []byte(`{"a": 1}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 2 {
+		t.Errorf("Expected both rules to pass, got %d", len(res.Results))
+	}
+}
+
+func TestEngineConditionEarlyExitIsLocal(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	for _, ruleJSON := range []string{
+		// The any group is decided by its first condition, leaving the slow one unfinished
+		`{"name": "short", "priority": 10, "conditions": {"any": [
+			{"fact": "a", "operator": "equal", "value": 1},
+			{"fact": "slow", "operator": "equal", "value": 1}
+		]}, "event": {"type": "short"}}`,
+		`{"name": "full", "priority": 10, "conditions": {"all": [
+			{"fact": "a", "operator": "equal", "value": 1},
+			{"fact": "slow", "operator": "equal", "value": 1},
+			{"any": [{"fact": "b", "operator": "equal", "value": 3}, {"fact": "b", "operator": "equal", "value": 2}]}
+		]}, "event": {"type": "full"}}`,
+		`{"name": "later", "priority": 1, "conditions": {"all": [{"fact": "slow", "operator": "equal", "value": 1}]}, "event": {"type": "later"}}`,
+	} {
+		var ruleConfig RuleConfig
+		if err := json.Unmarshal([]byte(ruleJSON), &ruleConfig); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		if err := engine.AddRuleFromMap(&ruleConfig); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+	err := engine.AddCalculatedFact("slow", func(a *Almanac, params ...interface{}) *ValueNode {
+		time.Sleep(5 * time.Millisecond)
+		return &ValueNode{Type: Number, Number: 1}
+	}, &FactOptions{Cache: false, Priority: 1})
+	if err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		res, err := engine.Run(context.Background(), []byte(`{"a": 1, "b": 2}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 3 {
+			t.Fatalf("Expected all rules to pass despite the short-circuit, got %v and failures %v", res.Results, res.FailureResults)
+		}
+		if res.Almanac.ExecutionContext().Stopped() {
+			t.Errorf("Expected the short-circuit not to stop the run")
+		}
+	}
+}
+
+func TestEngineListOperators(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	if err := engine.AddOperator(Operator{Name: "divisibleBy", Description: "the fact is a multiple of the value", Callback: func(a, b *ValueNode) bool {
+		return b.Number != 0 && int(a.Number)%int(b.Number) == 0
+	}}, nil); err != nil {
+		t.Fatalf("Failed to add operator: %v", err)
+	}
+
+	infos := engine.ListOperators()
+	if len(infos) != len(DefaultOperators())+1 {
+		t.Errorf("Expected the default operators and divisibleBy, got %d operators", len(infos))
+	}
+	for i, info := range infos {
+		if i > 0 && infos[i-1].Name >= info.Name {
+			t.Errorf("Expected operators sorted by name, got %s after %s", info.Name, infos[i-1].Name)
+		}
+		switch info.Name {
+		case "divisibleBy":
+			if info.Default || info.Description != "the fact is a multiple of the value" {
+				t.Errorf("Expected a described custom operator, got %+v", info)
+			}
+		case "equal":
+			if !info.Default {
+				t.Errorf("Expected equal to be a default operator")
+			}
+		}
+	}
+
+	for name, want := range map[string]bool{"divisibleBy": true, "!divisibleBy": true, "everyFact:divisibleBy": true, "divisible": false, "": false} {
+		if got := engine.HasOperator(name); got != want {
+			t.Errorf("HasOperator(%q): expected %v, got %v", name, want, got)
+		}
+	}
+
+	for _, invalid := range []struct {
+		operator interface{}
+		cb       func(a, b *ValueNode) bool
+	}{
+		{"noCallback", nil},
+		{42, EvalEqual},
+		{Operator{Name: "empty"}, nil},
+		{(*Operator)(nil), nil},
+	} {
+		if err := engine.AddOperator(invalid.operator, invalid.cb); err == nil {
+			t.Errorf("Expected %v to be rejected", invalid.operator)
+		}
+	}
+	if engine.HasOperator("noCallback") || engine.HasOperator("empty") {
+		t.Errorf("Expected rejected operators not to be registered")
+	}
+}
+
+func TestEngineRunInvalidFactsJSON(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "adult",
+		"conditions": {"all": [{"fact": "age", "operator": "greaterThanInclusive", "value": 18}]},
+		"event": {"type": "adult"}
+	}`, &RuleEngineOptions{AllowUndefinedFacts: true})
+
+	for _, tc := range []struct {
+		name   string
+		input  string
+		offset int64
+	}{
+		{"empty", ``, 0},
+		{"truncated", `{"age": 20`, 10},
+		{"malformed", `{"age": 20,, "name": "ada"}`, 12},
+		{"array", `[{"age": 20}]`, 0},
+		{"number", `20`, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := engine.Run(context.Background(), []byte(tc.input))
+			var invalid *InvalidFactsJSONError
+			if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidFactsJSON) {
+				t.Fatalf("Expected an invalid facts error, got %v", err)
+			}
+			if invalid.Offset != tc.offset {
+				t.Errorf("Expected offset %d, got %d: %v", tc.offset, invalid.Offset, err)
+			}
+		})
+	}
+
+	t.Run("fragments", func(t *testing.T) {
+		res, err := engine.RunWithOptions(context.Background(), []byte(`{"age": 20`), &RunOptions{AllowFactFragments: true})
+		if err != nil {
+			t.Fatalf("Expected fragments to be evaluated, got %v", err)
+		}
+		if len(res.Results) != 1 {
+			t.Errorf("Expected the fragment's fact to be read")
+		}
+	})
+
+	t.Run("nil map", func(t *testing.T) {
+		if _, err := engine.RunWithMap(context.Background(), nil); !errors.Is(err, ErrInvalidFactsJSON) {
+			t.Errorf("Expected a nil map to be rejected, got %v", err)
+		}
+		if _, err := engine.RunWithMap(context.Background(), map[string]interface{}{}); err != nil {
+			t.Errorf("Expected an empty map to run, got %v", err)
+		}
+	})
+}
+
+func TestEngineCaseInsensitiveFactPaths(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "known",
+		"conditions": {"all": [{"fact": "user.userId", "operator": "equal", "value": 7}]},
+		"event": {"type": "known"}
+	}`, &RuleEngineOptions{CaseInsensitiveFactPaths: true})
+	res, err := engine.Run(context.Background(), []byte(`{"user": {"userID": 7}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Fatalf("Expected the rule to match the key ignoring case, got %v", res.FailureResults)
+	}
+	out, err := res.Results[0].ToJSON(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	leaf := out.(map[string]interface{})["conditions"].(map[string]interface{})["all"].([]interface{})[0].(map[string]interface{})
+	if leaf["matchedFact"] != "user.userID" {
+		t.Errorf("Expected the matched path in the result, got %v", leaf)
+	}
+
+	res, err = engine.Run(context.Background(), []byte(`{"user": {"userId": 7}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 || res.Results[0].Conditions.All[0].MatchedFact != "" {
+		t.Errorf("Expected an exact match not to be reported")
+	}
+}
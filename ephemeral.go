@@ -0,0 +1,138 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// DefaultMaxEphemeralRules is the default number of ephemeral rules allowed in a single run
+	DefaultMaxEphemeralRules = 16
+	// DefaultMaxEphemeralConditions is the default number of conditions allowed in a single ephemeral rule
+	DefaultMaxEphemeralConditions = 64
+)
+
+// ephemeralRules compiles the ephemeral rules of a run: those passed in the run options followed by those
+// embedded in the fact document at the engine's EphemeralRulesFact path. They are validated like rules added
+// to the engine, and limited in number and size since they usually come from an untrusted source.
+func (e *Engine) ephemeralRules(facts gjson.Result, options *RunOptions) ([]*Rule, error) {
+	configs := append([]RuleConfig(nil), options.EphemeralRules...)
+	if e.EphemeralRulesFact != "" {
+		embedded := facts.Get(e.EphemeralRulesFact)
+		if embedded.Exists() {
+			if !embedded.IsArray() {
+				return nil, fmt.Errorf("engine: ephemeral rules at %q must be an array", e.EphemeralRulesFact)
+			}
+			for i, raw := range embedded.Array() {
+				var config RuleConfig
+				if err := json.Unmarshal([]byte(raw.Raw), &config); err != nil {
+					return nil, fmt.Errorf("engine: ephemeral rule %s[%d]: %w", e.EphemeralRulesFact, i, err)
+				}
+				configs = append(configs, config)
+			}
+		}
+	}
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	maxRules := options.MaxEphemeralRules
+	if maxRules == 0 {
+		maxRules = DefaultMaxEphemeralRules
+	}
+	if maxRules > 0 && len(configs) > maxRules {
+		return nil, fmt.Errorf("engine: %d ephemeral rules exceed the limit of %d", len(configs), maxRules)
+	}
+	maxConditions := options.MaxEphemeralConditions
+	if maxConditions == 0 {
+		maxConditions = DefaultMaxEphemeralConditions
+	}
+
+	rules := make([]*Rule, len(configs))
+	for i := range configs {
+		r, err := e.compileEphemeralRule(&configs[i], maxConditions)
+		if err != nil {
+			return nil, fmt.Errorf("engine: ephemeral rule %d (%s): %w", i, configs[i].Name, err)
+		}
+		rules[i] = r
+	}
+	return rules, nil
+}
+
+// compileEphemeralRule creates and validates an ephemeral rule, linking it to the engine without adding it
+func (e *Engine) compileEphemeralRule(config *RuleConfig, maxConditions int) (*Rule, error) {
+	if count := countConditions(&config.Conditions); maxConditions > 0 && count > maxConditions {
+		return nil, fmt.Errorf("%d conditions exceed the limit of %d", count, maxConditions)
+	}
+	r, err := NewRule(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.validateRuleValues(r); err != nil {
+		return nil, err
+	}
+	if e.RejectEmptyGroups {
+		if path := emptyGroupPath(&r.Conditions, ""); path != "" {
+			return nil, fmt.Errorf("empty condition group %s", path)
+		}
+	}
+	r.SetEngine(e)
+	r.ephemeral = true
+	return r, nil
+}
+
+// countConditions returns the number of conditions in a condition tree, groups included
+func countConditions(c *Condition) int {
+	if c == nil {
+		return 0
+	}
+	count := 1
+	for _, child := range c.All {
+		count += countConditions(child)
+	}
+	for _, child := range c.Any {
+		count += countConditions(child)
+	}
+	for _, child := range c.None {
+		count += countConditions(child)
+	}
+	for _, child := range c.MostOf {
+		count += countConditions(child)
+	}
+	return count + countConditions(c.Not)
+}
+
+// mergeRuleSets adds rules to prioritized rule sets, keeping the sets ordered by priority.
+// Within a priority the added rules follow the existing ones.
+func mergeRuleSets(sets [][]*Rule, rules []*Rule) [][]*Rule {
+	if len(rules) == 0 {
+		return sets
+	}
+	byPriority := map[int][]*Rule{}
+	for _, set := range sets {
+		if len(set) > 0 {
+			byPriority[set[0].Priority] = append([]*Rule(nil), set...)
+		}
+	}
+	for _, r := range rules {
+		byPriority[r.Priority] = append(byPriority[r.Priority], r)
+	}
+	priorities := make([]int, 0, len(byPriority))
+	for priority := range byPriority {
+		priorities = append(priorities, priority)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+	merged := make([][]*Rule, len(priorities))
+	for i, priority := range priorities {
+		merged[i] = byPriority[priority]
+	}
+	return merged
+}
+
+// hasEphemeralRules reports whether a run may evaluate ephemeral rules, whose results must not be cached
+func (e *Engine) hasEphemeralRules(options *RunOptions) bool {
+	return e.EphemeralRulesFact != "" || (options != nil && len(options.EphemeralRules) > 0)
+}
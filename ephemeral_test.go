@@ -0,0 +1,108 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func ephemeralRuleConfig(t *testing.T, ruleJSON string) RuleConfig {
+	t.Helper()
+	var config RuleConfig
+	if err := json.Unmarshal([]byte(ruleJSON), &config); err != nil {
+		t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+	}
+	return config
+}
+
+func TestRunWithEphemeralRules(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "stored", "priority": 1, "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "stored"}}`, nil)
+	options := DefaultRunOptions()
+	options.EphemeralRules = []RuleConfig{
+		ephemeralRuleConfig(t, `{"name": "adhoc", "priority": 5, "conditions": {"all": [{"fact": "b", "operator": "greaterThan", "value": 2}]}, "event": {"type": "adhoc"}}`),
+	}
+
+	res, err := engine.RunWithOptions(context.Background(), []byte(`{"a": 1, "b": 3}`), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := res.Results
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 passing rules, got %d", len(results))
+	}
+	if results[0].Name != "adhoc" || !results[0].Ephemeral {
+		t.Errorf("Expected the higher priority ephemeral rule first and flagged, got %s (ephemeral %v)", results[0].Name, results[0].Ephemeral)
+	}
+	if results[1].Ephemeral {
+		t.Errorf("Expected the stored rule not to be flagged ephemeral")
+	}
+	if props, _ := results[0].ToJSON(false); props.(map[string]interface{})["ephemeral"] != true {
+		t.Errorf("Expected ephemeral in the serialized result, got %v", props)
+	}
+	if len(engine.GetRules()) != 1 {
+		t.Errorf("Expected ephemeral rules not to be stored, got %d rules", len(engine.GetRules()))
+	}
+
+	res, err = engine.Run(context.Background(), []byte(`{"a": 1, "b": 3}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Errorf("Expected the ephemeral rule not to outlive its run")
+	}
+}
+
+func TestEphemeralRuleLimits(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	rule := `{"name": "adhoc", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}, {"fact": "b", "operator": "equal", "value": 2}]}, "event": {"type": "adhoc"}}`
+
+	options := DefaultRunOptions()
+	options.MaxEphemeralRules = 1
+	options.EphemeralRules = []RuleConfig{ephemeralRuleConfig(t, rule), ephemeralRuleConfig(t, rule)}
+	if _, err := engine.RunWithOptions(context.Background(), []byte(`{}`), options); err == nil || !strings.Contains(err.Error(), "exceed the limit") {
+		t.Errorf("Expected the rule limit to be enforced, got %v", err)
+	}
+
+	options = DefaultRunOptions()
+	options.MaxEphemeralConditions = 2
+	options.EphemeralRules = []RuleConfig{ephemeralRuleConfig(t, rule)}
+	if _, err := engine.RunWithOptions(context.Background(), []byte(`{}`), options); err == nil || !strings.Contains(err.Error(), "3 conditions") {
+		t.Errorf("Expected the condition limit to be enforced, got %v", err)
+	}
+
+	invalid := ephemeralRuleConfig(t, strings.Replace(rule, "adhoc", "bad", 1))
+	priority := -1
+	invalid.Priority = &priority
+	options = DefaultRunOptions()
+	options.EphemeralRules = []RuleConfig{invalid}
+	if _, err := engine.RunWithOptions(context.Background(), []byte(`{}`), options); err == nil || !strings.Contains(err.Error(), "ephemeral rule 0 (bad)") {
+		t.Errorf("Expected an invalid ephemeral rule to fail the run, got %v", err)
+	}
+}
+
+func TestEphemeralRulesFromFacts(t *testing.T) {
+	engine := NewEngine(nil, &RuleEngineOptions{EphemeralRulesFact: "_rules", ResultCacheSize: 8})
+	facts := []byte(`{
+		"amount": 120,
+		"_rules": [{"name": "large", "conditions": {"all": [{"fact": "amount", "operator": "greaterThan", "value": 100}]}, "event": {"type": "large"}}]
+	}`)
+
+	for i := 0; i < 2; i++ {
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		results := res.Results
+		if len(results) != 1 || results[0].Name != "large" || !results[0].Ephemeral {
+			t.Fatalf("Expected the embedded rule to pass, got %v", results)
+		}
+		if res.Cached {
+			t.Errorf("Expected runs with ephemeral rules to bypass the result cache")
+		}
+	}
+
+	if _, err := engine.Run(context.Background(), []byte(`{"_rules": {"name": "x"}}`)); err == nil {
+		t.Errorf("Expected an error when the embedded rules are not an array")
+	}
+}
@@ -1,6 +1,10 @@
 package rulesengine
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // UndefinedFactError represents an error for an undefined fact
 type UndefinedFactError struct {
@@ -49,3 +53,293 @@ func NewInvalidPriorityValueError() *InvalidRuleError {
 func NewPriorityNotSetError() *InvalidRuleError {
 	return NewInvalidRuleError("Priority not set", "PRIORITY_NOT_SET")
 }
+
+// NewUnknownOperatorsError reports the conditions of a rule using operators that are not registered,
+// each given as its condition path and operator
+func NewUnknownOperatorsError(rule string, conditions []string) *InvalidRuleError {
+	return NewInvalidRuleError(fmt.Sprintf("rule %q: unknown operators: %s", rule, strings.Join(conditions, ", ")), "UNKNOWN_OPERATOR")
+}
+
+// NewUnknownTransformsError reports the conditions of a rule using transforms that are not registered,
+// each given as its condition path and transform
+func NewUnknownTransformsError(rule string, conditions []string) *InvalidRuleError {
+	return NewInvalidRuleError(fmt.Sprintf("rule %q: unknown transforms: %s", rule, strings.Join(conditions, ", ")), "UNKNOWN_TRANSFORM")
+}
+
+// ErrEvaluationBudgetExceeded is returned (wrapped in an EvaluationBudgetExceededError) when a run
+// exceeds its fact resolution or condition evaluation budget
+var ErrEvaluationBudgetExceeded = errors.New("evaluation budget exceeded")
+
+// ErrPriorityBarriersRequired is returned when a run with IgnorePriorityBarriers chains rules through runtime facts,
+// which needs each priority group to complete before the next one starts
+var ErrPriorityBarriersRequired = errors.New("runtime fact chaining requires priority barriers")
+
+// ErrConflictingEvents is returned (wrapped in a ConflictingEventsError) when a run emits
+// more than one event of an exclusive event group and the engine is configured to fail
+var ErrConflictingEvents = errors.New("conflicting events")
+
+// ErrUnregisteredEventType is returned when an engine with StrictEventTypes is given a rule whose event type
+// was not registered with Engine.RegisterEventTypes
+var ErrUnregisteredEventType = errors.New("unregistered event type")
+
+// ErrUnsupportedSchemaVersion is returned (wrapped in an UnsupportedSchemaVersionError) for rules declaring
+// a schemaVersion newer than the engine supports
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// UnsupportedSchemaVersionError reports a rule schema version newer than SchemaVersion,
+// listing the constructs of the rule this engine does not understand
+type UnsupportedSchemaVersionError struct {
+	Version    int
+	Supported  int
+	Constructs []string
+}
+
+func (e *UnsupportedSchemaVersionError) Error() string {
+	msg := fmt.Sprintf("%s %d, this engine supports up to %d", ErrUnsupportedSchemaVersion, e.Version, e.Supported)
+	if len(e.Constructs) > 0 {
+		msg += "; unsupported constructs: " + strings.Join(e.Constructs, ", ")
+	}
+	return msg
+}
+
+// Unwrap allows errors.Is(err, ErrUnsupportedSchemaVersion)
+func (e *UnsupportedSchemaVersionError) Unwrap() error {
+	return ErrUnsupportedSchemaVersion
+}
+
+// ErrPathShapeMismatch is returned (wrapped in a PathShapeMismatchError) when a fact path does not fit the shape of
+// the fact document, e.g. it queries into a string or indexes past the end of an array
+var ErrPathShapeMismatch = errors.New("path shape mismatch")
+
+// PathShapeMismatchError reports a fact path that does not fit the document, as opposed to a fact that is simply absent.
+// ValidPath is the deepest prefix of Path found in the document, empty when the first segment already mismatched.
+type PathShapeMismatchError struct {
+	Path      string
+	ValidPath string
+	Reason    string
+}
+
+func (e *PathShapeMismatchError) Error() string {
+	return fmt.Sprintf("%s: %s: %s after %q", ErrPathShapeMismatch, e.Path, e.Reason, e.ValidPath)
+}
+
+// Unwrap allows errors.Is(err, ErrPathShapeMismatch)
+func (e *PathShapeMismatchError) Unwrap() error {
+	return ErrPathShapeMismatch
+}
+
+// ErrFactPathConflict is returned (wrapped in a FactPathConflictError) when facts registered in bulk claim a path
+// that is already taken
+var ErrFactPathConflict = errors.New("fact path conflict")
+
+// FactPathConflict is a path of a bulk registration that is already taken. With is the path it conflicts with: the
+// same path for a registered fact, or another path stored under the same key of the engine's fact map.
+type FactPathConflict struct {
+	Path       string
+	With       string
+	Registered bool
+}
+
+func (c FactPathConflict) String() string {
+	switch {
+	case c.Path == c.With:
+		return fmt.Sprintf("%q is already registered", c.Path)
+	case c.Registered:
+		return fmt.Sprintf("%q collides with registered fact %q", c.Path, c.With)
+	}
+	return fmt.Sprintf("%q collides with %q", c.Path, c.With)
+}
+
+// FactPathConflictError lists every conflicting path of a bulk fact registration, sorted by path.
+// None of the facts of the batch were registered.
+type FactPathConflictError struct {
+	Conflicts []FactPathConflict
+}
+
+func (e *FactPathConflictError) Error() string {
+	conflicts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		conflicts[i] = c.String()
+	}
+	return fmt.Sprintf("%s: %s", ErrFactPathConflict, strings.Join(conflicts, ", "))
+}
+
+// Unwrap allows errors.Is(err, ErrFactPathConflict)
+func (e *FactPathConflictError) Unwrap() error {
+	return ErrFactPathConflict
+}
+
+// ErrCalculatedFact is returned (wrapped in a CalculatedFactError) when the callback of a calculated fact added with
+// Engine.AddCalculatedFactE fails
+var ErrCalculatedFact = errors.New("calculated fact failed")
+
+// CalculatedFactError reports the failed callback of a calculated fact with the rule and the path of the condition
+// that referenced it, e.g. "conditions.all[1]". errors.Is matches both ErrCalculatedFact and the callback's error.
+type CalculatedFactError struct {
+	Path      string
+	Rule      string
+	Condition string
+	Err       error
+}
+
+func (e *CalculatedFactError) Error() string {
+	return fmt.Sprintf("%s: %s, requested by rule %q at %s: %v", ErrCalculatedFact, e.Path, e.Rule, e.Condition, e.Err)
+}
+
+// Unwrap allows errors.Is(err, ErrCalculatedFact) and errors.Is with the callback's error, e.g. context.Canceled
+func (e *CalculatedFactError) Unwrap() []error {
+	return []error{ErrCalculatedFact, e.Err}
+}
+
+// factCalculationError is the error of a calculated fact's callback until the condition that referenced it turns it
+// into a CalculatedFactError; it is cached with the fact, so it carries no rule
+type factCalculationError struct {
+	path    string
+	onError string
+	err     error
+}
+
+func (e *factCalculationError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", ErrCalculatedFact, e.path, e.err)
+}
+
+func (e *factCalculationError) Unwrap() []error {
+	return []error{ErrCalculatedFact, e.err}
+}
+
+// ErrInvalidFactsJSON is returned (wrapped in an InvalidFactsJSONError) when the fact input of a run is not a JSON
+// object, see RunOptions.AllowFactFragments
+var ErrInvalidFactsJSON = errors.New("invalid facts JSON")
+
+// InvalidFactsJSONError reports fact input that is empty, malformed or not an object. Offset is the byte offset the
+// parser stopped at, or 0 for a well-formed value that is not an object.
+type InvalidFactsJSONError struct {
+	Offset int64
+	Reason string
+}
+
+func (e *InvalidFactsJSONError) Error() string {
+	return fmt.Sprintf("%s at offset %d: %s", ErrInvalidFactsJSON, e.Offset, e.Reason)
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidFactsJSON)
+func (e *InvalidFactsJSONError) Unwrap() error {
+	return ErrInvalidFactsJSON
+}
+
+// ConflictingEventsError lists the events of an exclusive group emitted in one run and the rules that emitted them
+type ConflictingEventsError struct {
+	Events []string
+	Rules  []string
+}
+
+func (e *ConflictingEventsError) Error() string {
+	return fmt.Sprintf("%s: %v emitted by rules %q", ErrConflictingEvents, e.Events, e.Rules)
+}
+
+// Unwrap allows errors.Is(err, ErrConflictingEvents)
+func (e *ConflictingEventsError) Unwrap() error {
+	return ErrConflictingEvents
+}
+
+// InvalidConditionError is returned when a condition can not be unmarshalled.
+// Snippet holds the beginning of the offending JSON and Expected the accepted condition shapes.
+type InvalidConditionError struct {
+	Snippet  string
+	Expected string
+	Err      error
+}
+
+func (e *InvalidConditionError) Error() string {
+	return fmt.Sprintf("invalid condition %s: %v (expected %s)", e.Snippet, e.Err, e.Expected)
+}
+
+// Unwrap returns the underlying error
+func (e *InvalidConditionError) Unwrap() error {
+	return e.Err
+}
+
+// maxConditionSnippet is the number of bytes of JSON quoted in an InvalidConditionError
+const maxConditionSnippet = 80
+
+// newInvalidConditionError wraps an unmarshalling error with the offending JSON.
+// Errors of nested conditions are returned unchanged, so the innermost offending condition is reported.
+func newInvalidConditionError(data []byte, err error) error {
+	var invalid *InvalidConditionError
+	var nested *ConditionPathError
+	if errors.As(err, &invalid) || errors.As(err, &nested) {
+		return err
+	}
+	snippet := string(data)
+	if len(snippet) > maxConditionSnippet {
+		snippet = strings.ToValidUTF8(snippet[:maxConditionSnippet], "") + "…"
+	}
+	return &InvalidConditionError{Snippet: snippet, Expected: conditionShapes, Err: err}
+}
+
+// ConditionPathError identifies the nested condition that failed validation by its JSON path,
+// e.g. "conditions.all[1].any[0]: if value, operator, or fact are set, all three must be provided".
+type ConditionPathError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConditionPathError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the offending condition
+func (e *ConditionPathError) Unwrap() error {
+	return e.Err
+}
+
+// withConditionPath prefixes the path of an error of a nested condition with the path segment of its parent
+func withConditionPath(segment string, err error) error {
+	if nested, ok := err.(*ConditionPathError); ok {
+		return &ConditionPathError{Path: segment + "." + nested.Path, Err: nested.Err}
+	}
+	return &ConditionPathError{Path: segment, Err: err}
+}
+
+// conditionsError prefixes the path of an error of a nested rule condition with "conditions", the key of the
+// rule's conditions; errors of the root condition are returned unchanged
+func conditionsError(err error) error {
+	if _, ok := err.(*ConditionPathError); ok {
+		return withConditionPath("conditions", err)
+	}
+	return err
+}
+
+// EvaluationBudgetExceededError identifies the budget that ran out and where it happened
+type EvaluationBudgetExceededError struct {
+	Budget string
+	Limit  int64
+	Rule   string
+	Fact   string
+}
+
+func (e *EvaluationBudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: %s limit of %d reached (rule: %q, fact: %q)", ErrEvaluationBudgetExceeded, e.Budget, e.Limit, e.Rule, e.Fact)
+}
+
+// Unwrap allows errors.Is(err, ErrEvaluationBudgetExceeded)
+func (e *EvaluationBudgetExceededError) Unwrap() error {
+	return ErrEvaluationBudgetExceeded
+}
+
+// BundleError identifies the section and key of a bundle that failed to load
+type BundleError struct {
+	Section string
+	Key     string
+	Err     error
+}
+
+// Error implements the error interface for BundleError
+func (e *BundleError) Error() string {
+	return fmt.Sprintf("bundle %s %q: %v", e.Section, e.Key, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *BundleError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,155 @@
+package rulesengine
+
+// EventConflictPolicy decides how a run resolves an exclusive event group of which more than one event fired
+type EventConflictPolicy string
+
+const (
+	// ConflictHighestPriorityWins keeps the event of the highest priority rule and drops the others.
+	// Rules of equal priority are ordered by the order in which they were added to the engine.
+	ConflictHighestPriorityWins EventConflictPolicy = "highestPriorityWins"
+	// ConflictFail fails the run with a ConflictingEventsError
+	ConflictFail EventConflictPolicy = "fail"
+)
+
+// EventConflict records how a violation of an exclusive event group was resolved
+type EventConflict struct {
+	Events  []string `json:"events"`  // The group's events that fired
+	Rules   []string `json:"rules"`   // The rules that emitted them, in priority order
+	Winner  string   `json:"winner"`  // The rule whose event was kept
+	Dropped []string `json:"dropped"` // The rules whose events were dropped
+}
+
+// DeclareExclusiveEvents declares a group of event types of which at most one may be emitted per run.
+// Violations are detected at the end of a run and resolved according to the engine's EventConflictPolicy,
+// which defaults to ConflictHighestPriorityWins. Handlers subscribed to the events have already been called
+// by then; dropped events are removed from the run result and reported in RunResult.DroppedEvents.
+// Params:
+// - eventTypes: The mutually exclusive event types.
+func (e *Engine) DeclareExclusiveEvents(eventTypes ...string) {
+	if len(eventTypes) < 2 {
+		return
+	}
+	group := append([]string(nil), eventTypes...)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	current := e.exclusiveEventGroups()
+	groups := append(make([][]string, 0, len(current)+1), current...)
+	groups = append(groups, group)
+	e.exclusiveEvents.Store(&groups)
+	e.configVersion.Add(1)
+}
+
+// exclusiveEventGroups returns the groups declared with DeclareExclusiveEvents, the slice must not be modified
+func (e *Engine) exclusiveEventGroups() [][]string {
+	if groups := e.exclusiveEvents.Load(); groups != nil {
+		return *groups
+	}
+	return nil
+}
+
+// RegisterEventTypes registers the event types consumers handle.
+// With StrictEventTypes, rules emitting any other event type are refused; otherwise Lint reports them
+// once at least one event type is registered.
+// Params:
+// - eventTypes: The event types to register.
+func (e *Engine) RegisterEventTypes(eventTypes ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	current := e.registeredEventTypes()
+	next := make(map[string]struct{}, len(current)+len(eventTypes))
+	for eventType := range current {
+		next[eventType] = struct{}{}
+	}
+	for _, eventType := range eventTypes {
+		next[eventType] = struct{}{}
+	}
+	e.eventTypes.Store(&next)
+}
+
+// registeredEventTypes returns the event types registered with RegisterEventTypes, the map must not be modified
+func (e *Engine) registeredEventTypes() map[string]struct{} {
+	if eventTypes := e.eventTypes.Load(); eventTypes != nil {
+		return *eventTypes
+	}
+	return nil
+}
+
+// isRegisteredEventType reports whether the event type was registered with RegisterEventTypes
+func (e *Engine) isRegisteredEventType(eventType string) bool {
+	_, ok := e.registeredEventTypes()[eventType]
+	return ok
+}
+
+// resolveEventConflicts checks the successful rule results of a run against the exclusive event groups
+func (e *Engine) resolveEventConflicts(almanac *Almanac) error {
+	groups := e.exclusiveEventGroups()
+	if len(groups) == 0 {
+		return nil
+	}
+	dropped := map[*RuleResult]struct{}{}
+	for _, group := range groups {
+		inGroup := make(map[string]struct{}, len(group))
+		for _, eventType := range group {
+			inGroup[eventType] = struct{}{}
+		}
+		var fired []*RuleResult
+		for _, ruleResult := range almanac.GetResults() {
+			if _, ok := inGroup[ruleResult.Event.Type]; ok && ruleResult.Result != nil && *ruleResult.Result && !ruleResult.EventSuppressed {
+				fired = append(fired, ruleResult)
+			}
+		}
+		if !conflicting(fired) {
+			continue
+		}
+		sortByPriority(fired, e.GetRules())
+
+		conflict := EventConflict{Winner: fired[0].Name}
+		seen := map[string]struct{}{}
+		for _, ruleResult := range fired {
+			conflict.Rules = append(conflict.Rules, ruleResult.Name)
+			if _, ok := seen[ruleResult.Event.Type]; !ok {
+				seen[ruleResult.Event.Type] = struct{}{}
+				conflict.Events = append(conflict.Events, ruleResult.Event.Type)
+			}
+		}
+		if e.EventConflictPolicy == ConflictFail {
+			return &ConflictingEventsError{Events: conflict.Events, Rules: conflict.Rules}
+		}
+		for _, ruleResult := range fired[1:] {
+			if ruleResult.Event.Type == fired[0].Event.Type {
+				continue
+			}
+			ruleResult.Dropped = true
+			dropped[ruleResult] = struct{}{}
+			conflict.Dropped = append(conflict.Dropped, ruleResult.Name)
+		}
+		almanac.eventConflicts = append(almanac.eventConflicts, conflict)
+	}
+
+	if len(dropped) == 0 {
+		return nil
+	}
+	kept := make([]Event, 0, len(almanac.events[Success]))
+	for _, ruleResult := range almanac.GetResults() {
+		if ruleResult.Result == nil || !*ruleResult.Result {
+			continue
+		}
+		if _, ok := dropped[ruleResult]; ok {
+			almanac.droppedEvents = append(almanac.droppedEvents, ruleResult.Event)
+			continue
+		}
+		kept = append(kept, ruleResult.Event)
+	}
+	almanac.events[Success] = kept
+	return nil
+}
+
+// conflicting reports whether the rule results emitted more than one distinct event type
+func conflicting(fired []*RuleResult) bool {
+	for _, ruleResult := range fired[min(1, len(fired)):] {
+		if ruleResult.Event.Type != fired[0].Event.Type {
+			return true
+		}
+	}
+	return false
+}
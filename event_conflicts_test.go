@@ -0,0 +1,168 @@
+package rulesengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// newConflictTestEngine creates an engine where "approve" (priority 5) and "decline" (priority 1) both fire for {"score": 50}
+func newConflictTestEngine(t *testing.T, policy EventConflictPolicy) *Engine {
+	t.Helper()
+	engine := newScoreRulesEngine(t, &RuleEngineOptions{EventConflictPolicy: policy},
+		scoreRule{"approver", "approve", 5},
+		scoreRule{"decliner", "decline", 1},
+		scoreRule{"notifier", "notify", 1},
+	)
+	engine.DeclareExclusiveEvents("approve", "decline")
+	return engine
+}
+
+func TestEngineExclusiveEventsHighestPriorityWins(t *testing.T) {
+	engine := newConflictTestEngine(t, ConflictHighestPriorityWins)
+	res, err := engine.Run(context.Background(), []byte(`{"score": 50}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events := res.Events
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %v", events)
+	}
+	for _, event := range events {
+		if event.Type == "decline" {
+			t.Errorf("Expected the decline event to be dropped")
+		}
+	}
+	dropped := res.DroppedEvents
+	if len(dropped) != 1 || dropped[0].Type != "decline" {
+		t.Errorf("Expected decline in droppedEvents, got %v", dropped)
+	}
+	for _, rr := range res.Results {
+		if rr.Dropped != (rr.Name == "decliner") {
+			t.Errorf("Unexpected Dropped flag %v on rule %s", rr.Dropped, rr.Name)
+		}
+	}
+
+	conflicts := res.Stats.EventConflicts
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict in stats, got %v", conflicts)
+	}
+	if conflicts[0].Winner != "approver" || len(conflicts[0].Dropped) != 1 || conflicts[0].Dropped[0] != "decliner" {
+		t.Errorf("Unexpected conflict resolution %+v", conflicts[0])
+	}
+}
+
+func TestEngineExclusiveEventsFail(t *testing.T) {
+	engine := newConflictTestEngine(t, ConflictFail)
+	_, err := engine.Run(context.Background(), []byte(`{"score": 50}`))
+	var conflictErr *ConflictingEventsError
+	if !errors.As(err, &conflictErr) || !errors.Is(err, ErrConflictingEvents) {
+		t.Fatalf("Expected a ConflictingEventsError, got %v", err)
+	}
+	if len(conflictErr.Rules) != 2 || conflictErr.Rules[0] != "approver" || conflictErr.Rules[1] != "decliner" {
+		t.Errorf("Expected rules approver and decliner, got %v", conflictErr.Rules)
+	}
+
+	// No conflict when only one event of the group fires
+	res, err := engine.Run(context.Background(), []byte(`{"score": 5}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Stats.EventConflicts) != 0 {
+		t.Errorf("Expected no conflicts")
+	}
+}
+
+func TestEngineDeclareExclusiveEventsDuringRuns(t *testing.T) {
+	engine := newConflictTestEngine(t, ConflictHighestPriorityWins)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := engine.Run(context.Background(), []byte(`{"score": 50}`)); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		engine.DeclareExclusiveEvents("notify", fmt.Sprintf("other%d", i))
+	}
+	wg.Wait()
+	if groups := engine.exclusiveEventGroups(); len(groups) != 21 {
+		t.Errorf("Expected 21 exclusive event groups, got %d", len(groups))
+	}
+}
+
+func TestEngineRegisterEventTypes(t *testing.T) {
+	newRule := func(eventType string) *Rule {
+		rule, err := NewRule(&RuleConfig{
+			Name:       eventType + "Rule",
+			Conditions: Condition{All: []*Condition{{Fact: "score", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 10}}}},
+			Event:      EventConfig{Type: eventType},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		return rule
+	}
+
+	t.Run("missing event type", func(t *testing.T) {
+		if _, err := NewRule(&RuleConfig{Name: "noEvent"}); err == nil {
+			t.Errorf("Expected an error for a rule without event type")
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		engine := NewEngine(nil, &RuleEngineOptions{StrictEventTypes: true})
+		engine.RegisterEventTypes("approve")
+		if err := engine.AddRule(newRule("approve")); err != nil {
+			t.Errorf("Expected the registered event type to be accepted, got %v", err)
+		}
+		if err := engine.AddRule(newRule("decline")); !errors.Is(err, ErrUnregisteredEventType) {
+			t.Errorf("Expected ErrUnregisteredEventType, got %v", err)
+		}
+	})
+
+	t.Run("lint", func(t *testing.T) {
+		engine := NewEngine(nil, nil)
+		for _, eventType := range []string{"approve", "decline"} {
+			if err := engine.AddRule(newRule(eventType)); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+		}
+		if issues := engine.Lint(); len(issues) != 0 {
+			t.Errorf("Expected no issues without registered event types, got %v", issues)
+		}
+		engine.RegisterEventTypes("approve")
+		issues := engine.Lint()
+		if len(issues) != 1 || issues[0].Rule != "declineRule" || issues[0].Analyzer != unregisteredEventTypeName {
+			t.Errorf("Expected an issue for the unregistered event type, got %v", issues)
+		}
+	})
+	t.Run("concurrent registration", func(t *testing.T) {
+		engine := NewEngine(nil, &RuleEngineOptions{StrictEventTypes: true})
+		engine.RegisterEventTypes("approve")
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := engine.AddRule(newRule("approve")); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				engine.Lint()
+			}()
+		}
+		for i := 0; i < 20; i++ {
+			engine.RegisterEventTypes(fmt.Sprintf("other%d", i))
+		}
+		wg.Wait()
+		if eventTypes := engine.registeredEventTypes(); len(eventTypes) != 21 {
+			t.Errorf("Expected 21 registered event types, got %d", len(eventTypes))
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package rulesengine
+
+import "context"
+
+// EventFilter decides whether the event of a rule whose conditions passed is emitted, e.g. to honour an exhausted
+// budget or muted notifications. Returning false suppresses the event: it is not collected in the run's events nor
+// published, while the rule result keeps Result true and is flagged EventSuppressed.
+// Filters run synchronously on the goroutine evaluating the rule, before its result is collected.
+type EventFilter func(ctx context.Context, result *RuleResult) bool
+
+// allowEvent applies the engine's and the rule's event filters to a passing rule result
+func (r *Rule) allowEvent(ctx context.Context, ruleResult *RuleResult) bool {
+	if r.Engine != nil && r.Engine.EventFilter != nil && !r.Engine.EventFilter(ctx, ruleResult) {
+		return false
+	}
+	return r.EventFilter == nil || r.EventFilter(ctx, ruleResult)
+}
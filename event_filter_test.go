@@ -0,0 +1,63 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestEventFilter(t *testing.T) {
+	engine := NewEngine(nil, &RuleEngineOptions{
+		EventFilter: func(ctx context.Context, result *RuleResult) bool {
+			return result.Event.Type != "notify"
+		},
+	})
+	for _, ruleJSON := range []string{
+		`{"name": "notify", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "notify"}}`,
+		`{"name": "budget", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "spend"}}`,
+		`{"name": "audit", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "audit"}}`,
+	} {
+		var config RuleConfig
+		if err := json.Unmarshal([]byte(ruleJSON), &config); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		if config.Name == "budget" {
+			config.EventFilter = func(ctx context.Context, result *RuleResult) bool { return false }
+		}
+		rule, err := NewRule(&config)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+
+	published := 0
+	if err := engine.bus.Subscribe("success", func(_ Event, _ *Almanac, _ *RuleResult) { published++ }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	res, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results := res.Results
+	if len(results) != 3 {
+		t.Fatalf("Expected all rules to be reported as passed, got %d", len(results))
+	}
+	for _, result := range results {
+		if suppressed := result.Name != "audit"; result.EventSuppressed != suppressed {
+			t.Errorf("%s: expected EventSuppressed %v", result.Name, suppressed)
+		}
+	}
+	if events := res.Events; len(events) != 1 || events[0].Type != "audit" {
+		t.Errorf("Expected only the audit event, got %v", events)
+	}
+	if published != 1 {
+		t.Errorf("Expected one published success event, got %d", published)
+	}
+	if event, _, ok := Decision(results); !ok || event.Type != "audit" {
+		t.Errorf("Expected suppressed events to be left out of the decision, got %v", event)
+	}
+}
@@ -0,0 +1,177 @@
+package rulesengine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	eventHandlerType     = reflect.TypeOf(EventHandler(nil))
+	eventTypeHandlerType = reflect.TypeOf(EventTypeHandler(nil))
+)
+
+// OnSuccess registers a handler called for the event of every rule that passed.
+// Params:
+// - handler: The handler, called synchronously while the run collects results.
+// Returns an error if the handler is nil.
+func (e *Engine) OnSuccess(handler EventHandler) error {
+	if handler == nil {
+		return errors.New("engine: success handler is required")
+	}
+	return e.subscribe("success", handler)
+}
+
+// OnFailure registers a handler called for the event of every rule that failed.
+// Params:
+// - handler: The handler, called synchronously while the run collects results.
+// Returns an error if the handler is nil.
+func (e *Engine) OnFailure(handler EventHandler) error {
+	if handler == nil {
+		return errors.New("engine: failure handler is required")
+	}
+	return e.subscribe("failure", handler)
+}
+
+// OnEvent registers a handler called with the params of every event of the given type emitted by a passing rule.
+// Params:
+// - eventType: The event type.
+// - handler: The handler, called synchronously while the run collects results.
+// Returns an error if the event type is empty or the handler is nil.
+func (e *Engine) OnEvent(eventType string, handler EventTypeHandler) error {
+	if eventType == "" {
+		return errors.New("engine: event type is required")
+	}
+	if handler == nil {
+		return fmt.Errorf("engine: handler for event type %q is required", eventType)
+	}
+	return e.subscribe(eventType, handler)
+}
+
+// On registers a handler for a topic, like engine.on in json-rules-engine: "success" and "failure" are handled like
+// OnSuccess and OnFailure, any other topic is an event type and the handler is called with the event of every passing
+// rule emitting it. Handlers are called synchronously, so their side effects are done when the run returns.
+// Params:
+// - eventType: "success", "failure" or an event type.
+// - handler: The handler.
+// Returns an error if the event type is empty or the handler is nil.
+func (e *Engine) On(eventType string, handler EventHandler) error {
+	if eventType == "" {
+		return errors.New("engine: event type is required")
+	}
+	if handler == nil {
+		return fmt.Errorf("engine: handler for %q is required", eventType)
+	}
+	return e.subscribe(handlerTopic(eventType), handler)
+}
+
+// Off removes a handler registered with On, or with OnSuccess and OnFailure for "success" and "failure".
+// Handlers are told apart by their function, so of several closures created by the same function literal the first
+// registered is removed; keep the handler to remove in a variable. Off must not be called from a handler.
+// Params:
+// - eventType: The topic the handler was registered for.
+// - handler: The handler.
+// Returns an error if no handler was registered for the topic.
+func (e *Engine) Off(eventType string, handler EventHandler) error {
+	if handler == nil {
+		return fmt.Errorf("engine: handler for %q is required", eventType)
+	}
+	return e.unsubscribe(handlerTopic(eventType), handler)
+}
+
+// Unsubscribe removes a handler registered with Subscribe or OnEvent, see Off.
+// Params:
+// - topic: The topic the handler was registered for.
+// - handler: The handler.
+// Returns an error if no handler was registered for the topic.
+func (e *Engine) Unsubscribe(topic string, handler interface{}) error {
+	if handler == nil {
+		return fmt.Errorf("engine: handler for %q is required", topic)
+	}
+	return e.unsubscribe(topic, normalizeHandler(topic, handler))
+}
+
+// subscribe registers a handler on the bus, recording the topic for Descriptor
+func (e *Engine) subscribe(topic string, handler interface{}) error {
+	if err := e.bus.Subscribe(topic, handler); err != nil {
+		return err
+	}
+	e.handlerTopics.Store(topic, struct{}{})
+	return nil
+}
+
+func (e *Engine) unsubscribe(topic string, handler interface{}) error {
+	if !e.bus.HasCallback(topic) {
+		return fmt.Errorf("engine: no handler registered for %q", topic)
+	}
+	return e.bus.Unsubscribe(topic, handler)
+}
+
+// handlerTopic returns the bus topic of handlers registered with On
+func handlerTopic(eventType string) string {
+	if eventType == "success" || eventType == "failure" {
+		return eventType
+	}
+	return eventTopic(eventType)
+}
+
+// eventTopic returns the bus topic publishing the events of a type, kept apart from the topic of OnEvent handlers,
+// which take the event params
+func eventTopic(eventType string) string {
+	return "event:" + eventType
+}
+
+// Subscribe registers a handler of any function type for a topic: "success", "failure" or an event type.
+// Unlike the underlying event bus, which fails when publishing to a handler with a different signature,
+// the handler is checked against the arguments published on the topic when it is registered.
+// Success and failure handlers take (Event, *Almanac, *RuleResult), event type handlers
+// (map[string]interface{}, *Almanac, *RuleResult); parameters may be interfaces the arguments satisfy.
+// Params:
+// - topic: The topic to subscribe to.
+// - handler: The handler function.
+// Returns an error describing the mismatch if the handler does not accept the published arguments.
+func (e *Engine) Subscribe(topic string, handler interface{}) error {
+	if topic == "" {
+		return errors.New("engine: topic is required")
+	}
+	want := topicHandlerType(topic)
+	if err := checkHandlerSignature(reflect.TypeOf(handler), want); err != nil {
+		return fmt.Errorf("engine: handler for %q: %w", topic, err)
+	}
+	return e.subscribe(topic, normalizeHandler(topic, handler))
+}
+
+// topicHandlerType returns the handler type of the arguments published on a topic
+func topicHandlerType(topic string) reflect.Type {
+	if topic == "success" || topic == "failure" {
+		return eventHandlerType
+	}
+	return eventTypeHandlerType
+}
+
+// normalizeHandler converts a handler of the exact signature of its topic to EventHandler or EventTypeHandler,
+// so it is found by Unsubscribe however it was registered
+func normalizeHandler(topic string, handler interface{}) interface{} {
+	want := topicHandlerType(topic)
+	value := reflect.ValueOf(handler)
+	if value.Kind() == reflect.Func && value.Type().ConvertibleTo(want) {
+		return value.Convert(want).Interface()
+	}
+	return handler
+}
+
+// checkHandlerSignature checks that a handler of type got can be called with the arguments of want
+func checkHandlerSignature(got, want reflect.Type) error {
+	if got == nil || got.Kind() != reflect.Func {
+		return fmt.Errorf("expected a function %s, got %v", want, got)
+	}
+	if got.IsVariadic() || got.NumIn() != want.NumIn() {
+		return fmt.Errorf("expected a function %s, got %s", want, got)
+	}
+	for i := 0; i < want.NumIn(); i++ {
+		if !want.In(i).AssignableTo(got.In(i)) {
+			return fmt.Errorf("parameter %d is %s, expected %s in %s", i+1, got.In(i), want.In(i), want)
+		}
+	}
+	return nil
+}
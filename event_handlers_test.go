@@ -0,0 +1,269 @@
+package rulesengine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEngineEventHandlers(t *testing.T) {
+	newEngine := func(t *testing.T) *Engine {
+		t.Helper()
+		return newTestEngine(t, `{"name": "adult", "conditions": {"all": [{"fact": "age", "operator": "greaterThanInclusive", "value": 18}]}, "event": {"type": "adult", "params": {"discount": 10}}}`, nil)
+	}
+
+	t.Run("typed handlers", func(t *testing.T) {
+		engine := newEngine(t)
+		var successes, failures []string
+		var discount, age interface{}
+		if err := engine.OnSuccess(func(event Event, almanac *Almanac, ruleResult *RuleResult) {
+			age, _ = almanac.GetValue("age")
+			successes = append(successes, event.Type+":"+ruleResult.Name)
+		}); err != nil {
+			t.Fatalf("Failed to register success handler: %v", err)
+		}
+		if err := engine.OnFailure(func(event Event, almanac *Almanac, ruleResult *RuleResult) {
+			failures = append(failures, ruleResult.Name)
+		}); err != nil {
+			t.Fatalf("Failed to register failure handler: %v", err)
+		}
+		if err := engine.OnEvent("adult", func(params map[string]interface{}, almanac *Almanac, ruleResult *RuleResult) {
+			discount = params["discount"]
+		}); err != nil {
+			t.Fatalf("Failed to register event handler: %v", err)
+		}
+		for _, facts := range []string{`{"age": 20}`, `{"age": 12}`} {
+			if _, err := engine.Run(context.Background(), []byte(facts)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if len(successes) != 1 || successes[0] != "adult:adult" || age != float64(20) {
+			t.Errorf("Unexpected success handler calls: %v, age %v", successes, age)
+		}
+		if len(failures) != 1 || failures[0] != "adult" || discount != float64(10) {
+			t.Errorf("Unexpected handler calls: failures=%v discount=%v", failures, discount)
+		}
+	})
+
+	t.Run("nil handlers", func(t *testing.T) {
+		engine := newEngine(t)
+		if engine.OnSuccess(nil) == nil || engine.OnFailure(nil) == nil || engine.OnEvent("adult", nil) == nil || engine.OnEvent("", func(map[string]interface{}, *Almanac, *RuleResult) {}) == nil {
+			t.Errorf("Expected nil handlers and empty event types to be rejected")
+		}
+	})
+
+	t.Run("subscribe", func(t *testing.T) {
+		engine := newEngine(t)
+		called := 0
+		if err := engine.Subscribe("success", func(event Event, almanac interface{}, ruleResult *RuleResult) { called++ }); err != nil {
+			t.Fatalf("Expected a matching handler to be accepted, got %v", err)
+		}
+		if err := engine.Subscribe("adult", func(params map[string]interface{}, almanac *Almanac, ruleResult *RuleResult) { called++ }); err != nil {
+			t.Fatalf("Expected a matching handler to be accepted, got %v", err)
+		}
+		if _, err := engine.Run(context.Background(), []byte(`{"age": 20}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if called != 2 {
+			t.Errorf("Expected both handlers to be called, got %d calls", called)
+		}
+	})
+
+	t.Run("on and off", func(t *testing.T) {
+		engine := newEngine(t)
+		var calls []string
+		adult := func(event Event, almanac *Almanac, ruleResult *RuleResult) {
+			calls = append(calls, "adult:"+event.Type)
+		}
+		success := func(event Event, almanac *Almanac, ruleResult *RuleResult) {
+			calls = append(calls, "success:"+ruleResult.Name)
+		}
+		failure := func(event Event, almanac *Almanac, ruleResult *RuleResult) {
+			calls = append(calls, "failure:"+ruleResult.Name)
+		}
+		params := func(params map[string]interface{}, almanac *Almanac, ruleResult *RuleResult) {
+			calls = append(calls, "params")
+		}
+		for topic, handler := range map[string]EventHandler{"adult": adult, "success": success, "failure": failure} {
+			if err := engine.On(topic, handler); err != nil {
+				t.Fatalf("Failed to register %s handler: %v", topic, err)
+			}
+		}
+		if err := engine.OnEvent("adult", params); err != nil {
+			t.Fatalf("Failed to register event handler: %v", err)
+		}
+		run := func(facts string) {
+			t.Helper()
+			calls = nil
+			if _, err := engine.Run(context.Background(), []byte(facts)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		run(`{"age": 20}`)
+		if strings.Join(calls, " ") != "success:adult params adult:adult" {
+			t.Errorf("Expected the handlers to be called before Run returns, got %v", calls)
+		}
+		run(`{"age": 12}`)
+		if strings.Join(calls, " ") != "failure:adult" {
+			t.Errorf("Expected the failure handler only, got %v", calls)
+		}
+
+		if err := engine.Off("adult", adult); err != nil {
+			t.Fatalf("Failed to remove handler: %v", err)
+		}
+		if err := engine.Off("success", success); err != nil {
+			t.Fatalf("Failed to remove handler: %v", err)
+		}
+		if err := engine.Unsubscribe("adult", params); err != nil {
+			t.Fatalf("Failed to remove handler: %v", err)
+		}
+		run(`{"age": 20}`)
+		if len(calls) != 0 {
+			t.Errorf("Expected removed handlers not to be called, got %v", calls)
+		}
+		if engine.Off("", adult) == nil || engine.Off("other", adult) == nil || engine.Off("adult", nil) == nil || engine.On("", adult) == nil {
+			t.Errorf("Expected unknown topics and nil handlers to be rejected")
+		}
+	})
+
+	t.Run("subscribe rejects wrong signatures", func(t *testing.T) {
+		engine := newEngine(t)
+		testCases := []struct {
+			name, topic string
+			handler     interface{}
+			message     string
+		}{
+			{"not a function", "success", "handler", "expected a function"},
+			{"wrong almanac type", "success", func(Event, string, *RuleResult) {}, "parameter 2"},
+			{"missing parameter", "failure", func(Event, *Almanac) {}, "expected a function"},
+			{"event for event type", "adult", func(Event, *Almanac, *RuleResult) {}, "parameter 1"},
+			{"variadic", "adult", func(params map[string]interface{}, rest ...interface{}) {}, "expected a function"},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				err := engine.Subscribe(tc.topic, tc.handler)
+				if err == nil || !strings.Contains(err.Error(), tc.message) {
+					t.Errorf("Expected an error containing %q, got %v", tc.message, err)
+				}
+			})
+		}
+	})
+}
+
+func TestEngineStopProcessingPerRun(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	for _, ruleJSON := range []string{
+		`{"name": "block", "priority": 2, "conditions": {"all": [{"fact": "blocked", "operator": "equal", "value": true}]}, "event": {"type": "block"}}`,
+		`{"name": "allow", "priority": 1, "conditions": {"all": [{"fact": "blocked", "operator": "equal", "value": false}]}, "event": {"type": "allow"}}`,
+	} {
+		config := ephemeralRuleConfig(t, ruleJSON)
+		rule, err := NewRule(&config)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+	if err := engine.OnEvent("block", func(_ map[string]interface{}, almanac *Almanac, _ *RuleResult) {
+		almanac.ExecutionContext().StopProcessing()
+	}); err != nil {
+		t.Fatalf("Failed to register handler: %v", err)
+	}
+
+	// Runs stopping themselves must not stop the runs in flight next to them
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(blocked bool) {
+			defer wg.Done()
+			res, err := engine.Run(context.Background(), []byte(fmt.Sprintf(`{"blocked": %t}`, blocked)))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if blocked && len(res.FailureResults) != 0 {
+				errs <- fmt.Errorf("expected the allow rule to be skipped after the block, got %d failures", len(res.FailureResults))
+			}
+			if !blocked && (len(res.Results) != 1 || res.Results[0].Name != "allow") {
+				errs <- fmt.Errorf("expected the allow rule to pass, got %v", res.Results)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestEngineSynchronousEvents(t *testing.T) {
+	var order []string
+	newEngine := func(t *testing.T) *Engine {
+		t.Helper()
+		engine := NewEngine(nil, &RuleEngineOptions{SynchronousEvents: true})
+		engine.AddCalculatedFact("slow", func(a *Almanac, params ...interface{}) *ValueNode {
+			time.Sleep(10 * time.Millisecond)
+			return &ValueNode{Type: Number, Number: 1}
+		}, nil)
+		for _, ruleJSON := range []string{
+			`{"name": "slow", "priority": 2, "conditions": {"all": [{"fact": "slow", "operator": "equal", "value": 1}]}, "event": {"type": "slow"}}`,
+			`{"name": "fast", "priority": 2, "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "fast"}}`,
+			`{"name": "failing", "priority": 2, "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 2}]}, "event": {"type": "failing"}}`,
+			`{"name": "last", "priority": 1, "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "last"}}`,
+		} {
+			config := ephemeralRuleConfig(t, ruleJSON)
+			config.OnSuccess = func(result *RuleResult) interface{} {
+				order = append(order, "rule:"+result.Name)
+				return nil
+			}
+			rule, err := NewRule(&config)
+			if err != nil {
+				t.Fatalf("Failed to create rule: %v", err)
+			}
+			if err := engine.AddRule(rule); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+		}
+		return engine
+	}
+
+	engine := newEngine(t)
+	record := func(event Event, almanac *Almanac, ruleResult *RuleResult) {
+		order = append(order, ruleResult.Name)
+	}
+	if err := engine.OnSuccess(record); err != nil {
+		t.Fatalf("Failed to register handler: %v", err)
+	}
+	if err := engine.OnFailure(record); err != nil {
+		t.Fatalf("Failed to register handler: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		order = nil
+		if _, err := engine.Run(context.Background(), []byte(`{"a": 1}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// The slow rule completes last in its group but is published first, callbacks included
+		if got := strings.Join(order, " "); got != "slow rule:slow fast rule:fast failing last rule:last" {
+			t.Fatalf("Expected the events in rule order before Run returns, got %s", got)
+		}
+	}
+
+	t.Run("panicking handlers fail the run", func(t *testing.T) {
+		engine := newEngine(t)
+		if err := engine.OnEvent("fast", func(map[string]interface{}, *Almanac, *RuleResult) {
+			panic("boom")
+		}); err != nil {
+			t.Fatalf("Failed to register handler: %v", err)
+		}
+		_, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+		if err == nil || !strings.Contains(err.Error(), `rule "fast"`) || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("Expected the panic as an error naming the rule, got %v", err)
+		}
+	})
+}
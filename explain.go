@@ -0,0 +1,367 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// MessageCatalog provides the localized strings of rule result explanations, see RuleResult.Explain.
+// Keys are operator names, e.g. "greaterThan", operator decorator names, e.g. "someFact", and the structural
+// phrases MessageAllOf, MessageAnyOf, MessageIsNot and the other Message constants.
+// Message returns false for keys without a string, which fall back to EnglishCatalog.
+type MessageCatalog interface {
+	Message(key string) (string, bool)
+}
+
+// MapCatalog is a MessageCatalog backed by a map of keys to localized strings
+type MapCatalog map[string]string
+
+// Message implements MessageCatalog
+func (c MapCatalog) Message(key string) (string, bool) {
+	message, ok := c[key]
+	return message, ok
+}
+
+// The structural phrases of explanations, used as MessageCatalog keys
+const (
+	MessageAllOf  = "all of"
+	MessageAnyOf  = "any of"
+	MessageNoneOf = "none of"
+	MessageMostOf = "most of"
+	// MessageAtLeast introduces an 'any' group with atLeast, "{count}" is replaced with the number of conditions
+	MessageAtLeast = "at least {count} of"
+	MessageIs      = "is"
+	MessageIsNot   = "is not"
+	MessagePassed  = "passed"
+	MessageFailed  = "failed"
+	MessageSkipped = "skipped"
+)
+
+// DefaultLocale is the locale of EnglishCatalog, used when a run sets no RunOptions.Locale
+const DefaultLocale = "en"
+
+// EnglishCatalog is the built-in catalog of the default operators and decorators and the structural phrases.
+// Operator strings complete "<fact> is", e.g. "greater than" for "age is greater than 17".
+var EnglishCatalog = MapCatalog{
+	MessageAllOf:   "all of",
+	MessageAnyOf:   "any of",
+	MessageNoneOf:  "none of",
+	MessageMostOf:  "most of",
+	MessageAtLeast: "at least {count} of",
+	MessageIs:      "is",
+	MessageIsNot:   "is not",
+	MessagePassed:  "passed",
+	MessageFailed:  "failed",
+	MessageSkipped: "skipped",
+
+	"equal": "equal to", "=": "equal to", "eq": "equal to",
+	"notEqual": "not equal to", "ne": "not equal to", "!=": "not equal to",
+	"in": "in", "notIn": "not in", "inFact": "in", "notInFact": "not in",
+	"contains": "containing", "doesNotContain": "not containing",
+	"lessThan": "less than", "<": "less than", "lt": "less than",
+	"lessThanInclusive": "at most", "<=": "at most", "lte": "at most",
+	"greaterThan": "greater than", ">": "greater than", "gt": "greater than",
+	"greaterThanInclusive": "at least", ">=": "at least", "gte": "at least",
+	"between": "between", "notBetween": "not between",
+	"startsWith": "starting with", "endsWith": "ending with", "includes": "including",
+	"hasKey": "having the key", "notHasKey": "not having the key",
+	"keyCountGreaterThan": "having more keys than", "keyCountEqual": "having a key count of",
+	"matches": "matching", "doesNotMatch": "not matching", "regex": "matching",
+	"anyElement": "having an element matching", "subsetOf": "a subset",
+
+	"everyFact": "for every element", "someFact": "for some element",
+	"everyValue": "for every value", "someValue": "for some value",
+}
+
+// LocalizedMessage is a message in several locales, keyed by locale, e.g. {"en": "...", "de": "..."}.
+// It unmarshals from such an object or from a plain string, stored under the empty locale.
+type LocalizedMessage map[string]string
+
+// UnmarshalJSON accepts a string or an object of locales to strings
+func (m *LocalizedMessage) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		*m = LocalizedMessage{"": message}
+		return nil
+	}
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return errors.New("failureMessage must be a string or an object of locales to strings")
+	}
+	*m = messages
+	return nil
+}
+
+// MarshalJSON writes a message without locales as a plain string
+func (m LocalizedMessage) MarshalJSON() ([]byte, error) {
+	if message, ok := m[""]; ok && len(m) == 1 {
+		return json.Marshal(message)
+	}
+	return json.Marshal(map[string]string(m))
+}
+
+// forLocale returns the message of the locale, falling back to its language, e.g. "de" for "de-CH", then to
+// DefaultLocale and then to the message without locale
+func (m LocalizedMessage) forLocale(locale string) (string, bool) {
+	for _, candidate := range append(localeFallbacks(locale), DefaultLocale, "") {
+		if message, ok := m[candidate]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// localeFallbacks returns the locale and its language, e.g. ["de-CH", "de"]
+func localeFallbacks(locale string) []string {
+	if locale == "" {
+		return nil
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		return []string{locale, locale[:i]}
+	}
+	return []string{locale}
+}
+
+// catalogRegistry holds the message catalogs of an engine; the map is replaced on every change, so readers need no lock
+type catalogRegistry struct {
+	mu      sync.Mutex
+	current atomic.Pointer[map[string]MessageCatalog]
+}
+
+// load returns the current catalogs, the map must not be modified
+func (r *catalogRegistry) load() map[string]MessageCatalog {
+	if m := r.current.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// store adds or replaces a catalog
+func (r *catalogRegistry) store(locale string, catalog MessageCatalog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.load()
+	next := make(map[string]MessageCatalog, len(current)+1)
+	for l, existing := range current {
+		next[l] = existing
+	}
+	next[locale] = catalog
+	r.current.Store(&next)
+}
+
+// AddMessageCatalog registers the message catalog of a locale for RuleResult.Explain, replacing a catalog of the
+// same locale, the built-in English one included.
+// Params:
+// - locale: The locale runs select the catalog with, see RunOptions.Locale, e.g. "de" or "de-CH".
+// - catalog: The catalog; keys it has no string for fall back to EnglishCatalog.
+// Returns an error if the locale is empty or the catalog is nil.
+func (e *Engine) AddMessageCatalog(locale string, catalog MessageCatalog) error {
+	if locale == "" {
+		return errors.New("engine: message catalog locale is required")
+	}
+	if catalog == nil {
+		return fmt.Errorf("engine: message catalog %s is nil", locale)
+	}
+	Debug(fmt.Sprintf("engine::addMessageCatalog locale:%s", locale))
+	e.catalogs.store(locale, catalog)
+	return nil
+}
+
+// MessageCatalog returns the catalog registered for the locale or its language, e.g. "de" for "de-CH",
+// and EnglishCatalog when there is none
+func (e *Engine) MessageCatalog(locale string) MessageCatalog {
+	catalogs := e.catalogs.load()
+	for _, candidate := range localeFallbacks(locale) {
+		if catalog, ok := catalogs[candidate]; ok {
+			return catalog
+		}
+	}
+	if catalog, ok := catalogs[DefaultLocale]; ok {
+		return catalog
+	}
+	return EnglishCatalog
+}
+
+// Explain renders the rule result as human readable lines in the locale of its run, see RunOptions.Locale: the
+// outcome of the rule, then one indented line per condition, marked "✓" when it passed, "✗" when it failed and "-"
+// when it was not evaluated. Failed conditions with a failureMessage show the message of the locale instead.
+func (rr *RuleResult) Explain() string {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	x := explainer{catalog: EnglishCatalog, locale: rr.Locale}
+	if rr.rule != nil && rr.rule.Engine != nil {
+		x.catalog = rr.rule.Engine.MessageCatalog(rr.Locale)
+	}
+	status := MessageFailed
+	switch {
+	case rr.Skipped != "":
+		status = MessageSkipped
+	case rr.Result != nil && *rr.Result:
+		status = MessagePassed
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", rr.Name, x.message(status))
+	if rr.Skipped == "" {
+		x.condition(&b, &rr.Conditions, 1)
+	}
+	return b.String()
+}
+
+// explainer renders condition trees with the strings of a catalog
+type explainer struct {
+	catalog MessageCatalog
+	locale  string
+}
+
+// lookup returns the string of the key from the catalog, falling back to EnglishCatalog
+func (x *explainer) lookup(key string) (string, bool) {
+	if message, ok := x.catalog.Message(key); ok {
+		return message, true
+	}
+	return EnglishCatalog.Message(key)
+}
+
+// message returns the string of the key, or the key itself when no catalog has it
+func (x *explainer) message(key string) string {
+	if message, ok := x.lookup(key); ok {
+		return message
+	}
+	return key
+}
+
+// condition writes the lines of a condition and its nested conditions at the given depth
+func (x *explainer) condition(b *strings.Builder, c *Condition, depth int) {
+	if c == nil {
+		return
+	}
+	indent := strings.Repeat("  ", depth)
+	if c.IsConditionReference() {
+		evaluated := c.Realized != nil || c.MissingResolution != ""
+		fmt.Fprintf(b, "%s%s %s\n", indent, marker(c.Result, evaluated), c.Condition)
+		x.condition(b, c.Realized, depth+1)
+		return
+	}
+	if c.IsBooleanOperator() {
+		for _, group := range []struct {
+			phrase     string
+			conditions []*Condition
+		}{{MessageAllOf, c.All}, {MessageAnyOf, c.Any}, {MessageNoneOf, c.None}, {MessageMostOf, c.MostOf}} {
+			if group.conditions == nil {
+				continue
+			}
+			header := x.message(group.phrase)
+			if group.phrase == MessageAnyOf && c.AtLeast > 0 {
+				header = strings.ReplaceAll(x.message(MessageAtLeast), "{count}", strconv.Itoa(c.AtLeast))
+			}
+			fmt.Fprintf(b, "%s%s:\n", indent, header)
+			for _, child := range group.conditions {
+				x.condition(b, child, depth+1)
+			}
+		}
+		if c.Not != nil {
+			fmt.Fprintf(b, "%s%s:\n", indent, x.message(MessageIsNot))
+			x.condition(b, c.Not, depth+1)
+		}
+		return
+	}
+	evaluated := c.ValueResult != nil || c.FactResult.Value != nil || c.FactResults != nil
+	fmt.Fprintf(b, "%s%s %s\n", indent, marker(c.Result, evaluated), x.leaf(c, evaluated))
+}
+
+// leaf renders a leaf condition, e.g. `user.age is greater than 17 [12]` for an age of 12,, or its failure message
+func (x *explainer) leaf(c *Condition, evaluated bool) string {
+	fact := c.Fact
+	if c.ResolvedFact != "" {
+		fact = c.ResolvedFact
+	}
+	if len(c.Facts) > 0 {
+		fact = strings.Join(c.Facts, ", ")
+	}
+	if c.Path != "" {
+		fact += "." + c.Path
+	}
+	value := formatExplainValue(&c.Value)
+	if c.ValueResult != nil {
+		value = formatExplainValue(c.ValueResult)
+	}
+	var actual string
+	switch {
+	case c.TransformedResult != nil:
+		actual = formatExplainValue(c.TransformedResult)
+	case c.FactResult.Value != nil:
+		actual = formatExplainValue(c.FactResult.Value)
+	case c.FactResults != nil:
+		values := make([]string, len(c.FactResults))
+		for i, v := range c.FactResults {
+			values[i] = formatExplainValue(v)
+		}
+		actual = strings.Join(values, ", ")
+	}
+
+	if evaluated && !c.Result {
+		if template, ok := c.FailureMessage.forLocale(x.locale); ok {
+			return strings.NewReplacer("{fact}", fact, "{value}", value, "{factResult}", actual).Replace(template)
+		}
+	}
+
+	negated := c.Negate
+	name := c.Operator
+	if base, ok := strings.CutPrefix(name, "!"); ok && base != "" {
+		negated, name = !negated, base
+	}
+	phrase, ok := x.lookup(name)
+	var qualifiers []string
+	if !ok {
+		// A decorated operator, e.g. "someFact:equal", is its operator qualified by its decorators
+		parts := strings.Split(name, ":")
+		phrase = x.message(parts[len(parts)-1])
+		for _, decorator := range parts[:len(parts)-1] {
+			if decorator == "not" {
+				negated = !negated
+				continue
+			}
+			qualifiers = append(qualifiers, x.message(decorator))
+		}
+	}
+	copula := x.message(MessageIs)
+	if negated {
+		copula = x.message(MessageIsNot)
+	}
+	line := fact + " " + copula + " " + phrase
+	if len(c.Facts) == 0 {
+		line += " " + value
+	}
+	if len(qualifiers) > 0 {
+		line += " (" + strings.Join(qualifiers, ", ") + ")"
+	}
+	if evaluated && actual != "" {
+		line += " [" + actual + "]"
+	}
+	return line
+}
+
+// marker returns the mark of a condition line
+func marker(result, evaluated bool) string {
+	switch {
+	case result:
+		return "✓"
+	case evaluated:
+		return "✗"
+	default:
+		return "-"
+	}
+}
+
+// formatExplainValue renders a value as JSON, e.g. "ios" quoted and arrays in brackets
+func formatExplainValue(v *ValueNode) string {
+	data, err := json.Marshal(v.Raw())
+	if err != nil {
+		return fmt.Sprint(v.Raw())
+	}
+	return string(data)
+}
@@ -0,0 +1,194 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const explainRule = `{
+	"name": "checkout",
+	"conditions": {"ordered": true, "all": [
+		{"fact": "user.age", "operator": "greaterThanInclusive", "value": 18},
+		{"fact": "user.country", "operator": "in", "value": ["CH", "DE"], "negate": true},
+		{"fact": "user.email", "operator": "notEqual", "value": "", "failureMessage": {"en": "{fact} is missing", "de": "{fact} fehlt"}},
+		{"ordered": true, "any": [
+			{"fact": "cart.items", "operator": "someFact:greaterThan", "value": 100},
+			{"fact": "user.vip", "operator": "equal", "value": true}
+		]}
+	]},
+	"event": {"type": "checkout"}
+}`
+
+func TestRuleResultExplain(t *testing.T) {
+	engine := newTestEngine(t, explainRule, nil)
+
+	res, err := engine.Run(context.Background(), []byte(`{"user": {"age": 30, "country": "FR", "email": ""}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.FailureResults) != 1 {
+		t.Fatalf("Expected the rule to fail, got %v", res.Results)
+	}
+	expected := `checkout: failed
+  all of:
+    ✓ user.age is at least 18 [30]
+    ✓ user.country is not in ["CH","DE"] ["FR"]
+    ✗ user.email is missing
+    any of:
+      - cart.items is greater than 100 (for some element)
+      - user.vip is equal to true
+`
+	if got := res.FailureResults[0].Explain(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+
+	res, err = engine.Run(context.Background(), []byte(`{"user": {"age": 30, "country": "FR", "email": "a@x.com"}, "cart": {"items": [5, 120]}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Fatalf("Expected the rule to pass, got %v", res.FailureResults)
+	}
+	expected = `checkout: passed
+  all of:
+    ✓ user.age is at least 18 [30]
+    ✓ user.country is not in ["CH","DE"] ["FR"]
+    ✓ user.email is not equal to "" ["a@x.com"]
+    any of:
+      ✓ cart.items is greater than 100 (for some element) [[5,120]]
+      - user.vip is equal to true
+`
+	if got := res.Results[0].Explain(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestRuleResultExplainLocale(t *testing.T) {
+	engine := newTestEngine(t, explainRule, nil)
+	german := MapCatalog{
+		MessageAllOf:           "alle von",
+		MessageAnyOf:           "eine von",
+		MessageIs:              "ist",
+		MessageIsNot:           "ist nicht",
+		MessageFailed:          "nicht erfüllt",
+		"greaterThanInclusive": "mindestens",
+	}
+	if err := engine.AddMessageCatalog("de", german); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := engine.AddMessageCatalog("", german); err == nil {
+		t.Errorf("Expected a catalog without locale to be rejected")
+	}
+	if err := engine.AddMessageCatalog("fr", nil); err == nil {
+		t.Errorf("Expected a nil catalog to be rejected")
+	}
+	facts := []byte(`{"user": {"age": 16}}`)
+
+	options := DefaultRunOptions()
+	options.Locale = "de-CH"
+	res, err := engine.RunWithOptions(context.Background(), facts, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.FailureResults) != 1 {
+		t.Fatalf("Expected the rule to fail, got %v", res.Results)
+	}
+	result := res.FailureResults[0]
+	// Keys the German catalog has no string for fall back to English
+	expected := `checkout: nicht erfüllt
+  alle von:
+    ✗ user.age ist mindestens 18 [16]
+    - user.country ist nicht in ["CH","DE"]
+    - user.email ist not equal to ""
+    eine von:
+      - cart.items ist greater than 100 (for some element)
+      - user.vip ist equal to true
+`
+	if got := result.Explain(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+
+	t.Run("failure messages are chosen at render time", func(t *testing.T) {
+		res, err := engine.RunWithOptions(context.Background(), []byte(`{"user": {"age": 30, "country": "FR", "email": ""}}`), options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result := res.FailureResults[0]
+		if line := explainLine(result.Explain(), 4); line != "    ✗ user.email fehlt" {
+			t.Errorf("Expected the German failure message, got %q", line)
+		}
+		result.Locale = "it"
+		if line := explainLine(result.Explain(), 4); line != "    ✗ user.email is missing" {
+			t.Errorf("Expected the English failure message for a locale without one, got %q", line)
+		}
+	})
+
+	t.Run("cached results use the locale of the run", func(t *testing.T) {
+		engine := newTestEngine(t, explainRule, &RuleEngineOptions{ResultCacheSize: 8})
+		if err := engine.AddMessageCatalog("de", german); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := engine.Run(context.Background(), facts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res, err := engine.RunWithOptions(context.Background(), facts, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !res.Cached || res.FailureResults[0].Locale != "de-CH" {
+			t.Errorf("Expected a cached result in the locale of the run, got cached %v locale %q", res.Cached, res.FailureResults[0].Locale)
+		}
+	})
+}
+
+func TestConditionFailureMessageJSON(t *testing.T) {
+	for _, tt := range []struct {
+		name, json string
+		expected   LocalizedMessage
+	}{
+		{"plain", `"{fact} is missing"`, LocalizedMessage{"": "{fact} is missing"}},
+		{"per locale", `{"de":"{fact} fehlt","en":"{fact} is missing"}`, LocalizedMessage{"en": "{fact} is missing", "de": "{fact} fehlt"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Condition
+			if err := json.Unmarshal([]byte(`{"fact": "a", "operator": "equal", "value": 1, "failureMessage": `+tt.json+`}`), &c); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(c.FailureMessage) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, c.FailureMessage)
+			}
+			for locale, message := range tt.expected {
+				if c.FailureMessage[locale] != message {
+					t.Errorf("Expected %v, got %v", tt.expected, c.FailureMessage)
+				}
+			}
+			out, err := c.ToJSON(true)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var props map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(out.(string)), &props); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(props["failureMessage"]) != tt.json {
+				t.Errorf("Expected the failure message to serialize as %s, got %s", tt.json, props["failureMessage"])
+			}
+		})
+	}
+
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"fact": "a", "operator": "equal", "value": 1, "failureMessage": 1}`), &c); err == nil {
+		t.Errorf("Expected a failure message that is not a string or object to be rejected")
+	}
+}
+
+// explainLine returns a line of an explanation
+func explainLine(explanation string, i int) string {
+	lines := strings.Split(explanation, "\n")
+	if i >= len(lines) {
+		return ""
+	}
+	return lines[i]
+}
@@ -1,8 +1,12 @@
 package rulesengine
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/tidwall/gjson"
 	"sync"
+	"time"
 )
 
 // FactMap is a thread-safe map used to store and manage facts in the rules engine.
@@ -67,12 +71,43 @@ func (m *FactMap) Range(f func(key string, value *Fact) bool) {
 	})
 }
 
+// MaxValueDepth limits how deeply nested arrays and objects are converted into ValueNodes.
+// Values nested deeper than this are converted to Null to protect against pathological documents.
+const MaxValueDepth = 64
+
+// NewValue converts a Go value into a ValueNode, e.g. 42, "a", []interface{}{1, 2} or map[string]interface{}{"a": 1}.
+// Values are converted through their JSON encoding; ValueNodes are returned as they are.
+// Params:
+// - value: The value to be converted, nil for Null.
+// Returns an error if the value cannot be encoded as JSON.
+func NewValue(value interface{}) (*ValueNode, error) {
+	switch v := value.(type) {
+	case ValueNode:
+		return &v, nil
+	case *ValueNode:
+		if v == nil {
+			return &ValueNode{Type: Null}, nil
+		}
+		return v, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("value: %w", err)
+	}
+	return NewValueFromGjson(gjson.ParseBytes(data)), nil
+}
+
 // NewValueFromGjson converts a gjson.Result into a ValueNode.
-// It handles various data types such as null, string, number, boolean, and arrays.
+// It handles various data types such as null, string, number, boolean, arrays and objects.
 // Params:
 // - result: The gjson.Result to be converted.
 // Returns a pointer to a ValueNode representing the result.
 func NewValueFromGjson(result gjson.Result) *ValueNode {
+	return newValueFromGjson(result, 0)
+}
+
+// newValueFromGjson converts a gjson.Result into a ValueNode, tracking the nesting depth.
+func newValueFromGjson(result gjson.Result, depth int) *ValueNode {
 	switch result.Type {
 	case gjson.Null:
 		return &ValueNode{Type: Null}
@@ -83,18 +118,25 @@ func NewValueFromGjson(result gjson.Result) *ValueNode {
 	case gjson.True, gjson.False:
 		return &ValueNode{Type: Bool, Bool: result.Bool()}
 	case gjson.JSON:
+		if depth >= MaxValueDepth {
+			Debug(fmt.Sprintf("fact::newValueFromGjson max depth %d reached", MaxValueDepth))
+			return &ValueNode{Type: Null}
+		}
 		if result.IsArray() {
 			arrayValues := make([]ValueNode, 0)
 			result.ForEach(func(_, value gjson.Result) bool {
-				v := NewValueFromGjson(value)
+				v := newValueFromGjson(value, depth+1)
 				arrayValues = append(arrayValues, *v)
 				return true // Continue iteration
 			})
 			return &ValueNode{Type: Array, Array: arrayValues}
-		} else {
-			// Handle objects if needed
-			return &ValueNode{Type: Null}
 		}
+		objectValues := make(map[string]ValueNode)
+		result.ForEach(func(key, value gjson.Result) bool {
+			objectValues[key.String()] = *newValueFromGjson(value, depth+1)
+			return true // Continue iteration
+		})
+		return &ValueNode{Type: Object, Object: objectValues}
 	default:
 		return &ValueNode{Type: Null}
 	}
@@ -108,9 +150,26 @@ type Fact struct {
 	CalculationMethod DynamicFactCallback
 	Cached            bool
 	Priority          int
+	Cost              int
 	Dynamic           bool
+	// ValueType is the type of the fact's values: the type of a static fact's value, or the type declared
+	// for a calculated fact with FactOptions.ValueType; Null when unknown
+	ValueType DataType
+	// SessionCache is the time the values of a calculated fact are shared between the runs of a Session, see FactOptions
+	SessionCache time.Duration
+	// CalculationMethodE is set instead of CalculationMethod for facts created with NewCalculatedFactE
+	CalculationMethodE FactCallbackE
+	// OnError is how conditions handle an error of CalculationMethodE, see FactOptions.OnError
+	OnError string
 }
 
+const (
+	// FactErrorFail fails the rule referencing a calculated fact whose callback failed
+	FactErrorFail = "fail"
+	// FactErrorFalse evaluates the conditions referencing a calculated fact whose callback failed as false
+	FactErrorFalse = "false"
+)
+
 // NewCalculatedFact creates a new Fact instance with a dynamic calculation method.
 // Params:
 // path: The path identifying the fact.
@@ -124,13 +183,31 @@ func NewCalculatedFact(path string, method DynamicFactCallback, options *FactOpt
 
 	return &Fact{
 		Priority:          options.Priority,
+		Cost:              options.Cost,
 		Cached:            options.Cache,
 		Path:              path,
 		CalculationMethod: method,
 		Dynamic:           true,
+		ValueType:         options.ValueType,
+		SessionCache:      options.SessionCache,
 	}
 }
 
+// NewCalculatedFactE creates a calculated fact whose callback receives the context of the run and can fail, e.g. for
+// facts queried from a database. See FactOptions.OnError for how conditions handle its errors.
+// Params:
+// path: The path identifying the fact.
+// method: The method to calculate the fact value.
+// options: Optional configuration options for the fact.
+func NewCalculatedFactE(path string, method FactCallbackE, options *FactOptions) *Fact {
+	f := NewCalculatedFact(path, nil, options)
+	f.CalculationMethodE = method
+	if options != nil {
+		f.OnError = options.OnError
+	}
+	return f
+}
+
 // NewFact creates a new Fact instance with a static value.
 // Params:
 // path: The path identifying the fact.
@@ -143,24 +220,57 @@ func NewFact(path string, value ValueNode, options *FactOptions) (*Fact, error)
 	}
 
 	return &Fact{
-		Value:    &value,
-		Priority: options.Priority,
-		Cached:   options.Cache,
-		Dynamic:  false,
-		Path:     path,
+		Value:     &value,
+		Priority:  options.Priority,
+		Cost:      options.Cost,
+		Cached:    options.Cache,
+		Dynamic:   false,
+		Path:      path,
+		ValueType: value.Type,
 	}, nil
 }
 
 // Calculate evaluates the fact value using the provided Almanac and optional parameters.
-// If the fact is dynamic, it uses the calculation method to determine the value.
+// Calculated facts return a copy holding the calculated value and leave the fact itself unchanged, so a fact
+// registered on an engine holds its definition only and can be shared by concurrent runs. Static facts are returned as is.
 // Params:
 // almanac: The Almanac instance to use for calculation.
 // params: Optional parameters to pass to the calculation method.
+// Facts created with NewCalculatedFactE receive the context of the almanac's run and a map as the first param; their
+// errors leave the value nil.
 func (f *Fact) Calculate(almanac *Almanac, params ...interface{}) *Fact {
-	if f.Dynamic {
-		f.Value = f.CalculationMethod(almanac, params...)
+	if !f.Dynamic {
 		return f
 	}
-	// TODO USE ALMANAC TO CALCULATE FACT VALUE
-	return f
+	calculated := *f
+	if f.CalculationMethodE != nil {
+		var factParams map[string]interface{}
+		if len(params) > 0 {
+			factParams, _ = params[0].(map[string]interface{})
+		}
+		calculated.Value, _ = f.calculate(almanac, factParams)
+	} else {
+		calculated.Value = f.CalculationMethod(almanac, params...)
+	}
+	return &calculated
+}
+
+// calculate runs the calculation method of a calculated fact, passing params when there are any.
+// Errors of CalculationMethodE are returned as a factCalculationError.
+func (f *Fact) calculate(almanac *Almanac, params map[string]interface{}) (*ValueNode, error) {
+	if f.CalculationMethodE != nil {
+		var ctx context.Context = context.Background()
+		if execCtx := almanac.ExecutionContext(); execCtx != nil {
+			ctx = execCtx
+		}
+		value, err := f.CalculationMethodE(ctx, almanac, params)
+		if err != nil {
+			return nil, &factCalculationError{path: f.Path, onError: f.OnError, err: err}
+		}
+		return value, nil
+	}
+	if params != nil {
+		return f.CalculationMethod(almanac, params), nil
+	}
+	return f.CalculationMethod(almanac), nil
 }
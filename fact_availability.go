@@ -0,0 +1,235 @@
+package rulesengine
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+// ReferencedFacts returns the fact paths read by the rule's conditions, sorted and without duplicates: the facts of
+// leaf and multi-fact conditions, facts referenced by condition values and the facts of dynamic path segments.
+// Conditions referenced by name contribute the facts of their currently registered condition.
+func (r *Rule) ReferencedFacts() []string {
+	facts, _ := r.referencedFacts()
+	return facts
+}
+
+// referencedFacts collects the fact paths of the rule's conditions.
+// complete is false when a referenced condition is not registered, so not all facts are known.
+func (r *Rule) referencedFacts() (facts []string, complete bool) {
+	seen := map[string]struct{}{}
+	add := func(path string) {
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			facts = append(facts, path)
+		}
+	}
+	complete = true
+	realized := map[string]struct{}{}
+	var walk func(c *Condition)
+	walk = func(c *Condition) {
+		if c == nil {
+			return
+		}
+		if c.IsConditionReference() {
+			if _, ok := realized[c.Condition]; ok {
+				return
+			}
+			realized[c.Condition] = struct{}{}
+			if r.Engine == nil {
+				complete = false
+				return
+			}
+			cond, ok := r.Engine.Conditions.Load(c.Condition)
+			if !ok {
+				complete = false
+				return
+			}
+			walk(&cond)
+			return
+		}
+		if c.Fact != "" {
+			add(c.Fact)
+			if hasDynamicSegments(c.Fact) {
+				_ = parseDynamicPath(c.Fact, func(string) {}, func(fact string) error {
+					add(fact)
+					return nil
+				})
+			}
+		}
+		for _, fact := range c.Facts {
+			add(fact)
+		}
+		if fact, ok := factReference(&c.Value); ok {
+			add(fact)
+		} else if c.Value.Type == Array {
+			for i := range c.Value.Array {
+				if fact, ok := factReference(&c.Value.Array[i]); ok {
+					add(fact)
+				}
+			}
+		}
+		for _, group := range [][]*Condition{c.All, c.Any, c.None, c.MostOf} {
+			for _, child := range group {
+				walk(child)
+			}
+		}
+		walk(c.Not)
+	}
+	walk(&r.Conditions)
+	sort.Strings(facts)
+	return facts, complete
+}
+
+// factNamespace returns the top-level key of a fact path as a path, e.g. "device" for "device.os.version".
+// ok is false when the key is not a plain key, e.g. a wildcard, a modifier or a dynamic segment.
+func factNamespace(path string) (string, bool) {
+	key := path
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '.', '|':
+			key = path[:i]
+			i = len(path)
+		case '*', '?', '#', '@', '!', '{', '}':
+			return "", false
+		}
+	}
+	return key, key != ""
+}
+
+// noDataProbe records whether a rule is known to fail when none of its fact namespaces hold data.
+// It is valid for the engine configuration it was computed against.
+type noDataProbe struct {
+	version    noDataVersion
+	namespaces []string
+	skippable  bool
+}
+
+// noDataVersion identifies the engine configuration a noDataProbe depends on
+type noDataVersion struct {
+	config              uint64
+	facts               uint64
+	allowUndefinedFacts bool
+}
+
+// noData returns the fact namespaces of the rule and whether the rule can be skipped when all of them are absent,
+// computing it when the engine configuration changed since it was last computed.
+// A rule is only skippable when evaluating it without any of its facts fails without an error, so rules passing on
+// undefined facts, e.g. with notEqual, not or none, and rules erroring on them without AllowUndefinedFacts are kept.
+// Rules reading engine or rule-local facts always have data.
+func (r *Rule) noData() ([]string, bool) {
+	version := noDataVersion{
+		config:              r.Engine.configVersion.Load(),
+		facts:               r.Engine.factsVersion.Load(),
+		allowUndefinedFacts: r.Engine.AllowUndefinedFacts,
+	}
+	r.mu.Lock()
+	probe := r.noDataProbe
+	r.mu.Unlock()
+	if probe != nil && probe.version == version {
+		return probe.namespaces, probe.skippable
+	}
+
+	probe = &noDataProbe{version: version}
+	facts, complete := r.referencedFacts()
+	probe.skippable = complete && len(facts) > 0
+	provided := r.providedNamespaces()
+	seen := map[string]struct{}{}
+	for _, fact := range facts {
+		namespace, ok := factNamespace(fact)
+		if _, local := provided[namespace]; !ok || local {
+			probe.skippable = false
+			break
+		}
+		if _, ok := seen[namespace]; !ok {
+			seen[namespace] = struct{}{}
+			probe.namespaces = append(probe.namespaces, namespace)
+		}
+	}
+	if probe.skippable {
+		allowUndefinedFacts := version.allowUndefinedFacts
+		almanac := NewAlmanac(gjson.Parse(`{}`), Options{AllowUndefinedFacts: &allowUndefinedFacts}, 1)
+		almanac.quiet = true
+		result, err := r.evaluateRoot(NewEvaluationContext(context.Background()), almanac, r.Conditions.clone())
+		probe.skippable = err == nil && !result
+	}
+
+	r.mu.Lock()
+	r.noDataProbe = probe
+	r.mu.Unlock()
+	return probe.namespaces, probe.skippable
+}
+
+// providedNamespaces returns the namespaces of the engine facts and the rule-local facts, which are available
+// regardless of the fact document
+func (r *Rule) providedNamespaces() map[string]struct{} {
+	provided := map[string]struct{}{}
+	r.Engine.Facts.Range(func(path string, _ *Fact) bool {
+		if namespace, ok := factNamespace(path); ok {
+			provided[namespace] = struct{}{}
+		}
+		return true
+	})
+	for path := range r.Facts {
+		if namespace, ok := factNamespace(path); ok {
+			provided[namespace] = struct{}{}
+		}
+	}
+	return provided
+}
+
+// withoutNoDataRules removes the rules without data in the almanac from a rule set, see RunOptions.SkipRulesWithoutFacts.
+// Returns the rules to evaluate and the results of the skipped rules, marked SkippedNoData.
+func (e *Engine) withoutNoDataRules(rules []*Rule, almanac *Almanac) ([]*Rule, []*RuleResult) {
+	var runtime map[string]struct{}
+	var evaluate []*Rule
+	var skipped []*RuleResult
+	for _, r := range rules {
+		namespaces, skippable := r.noData()
+		if skippable && runtime == nil {
+			// Facts added during the run, e.g. by event handlers of earlier priority groups, provide data too
+			runtime = map[string]struct{}{}
+			almanac.factMap.Range(func(path string, _ *Fact) bool {
+				if namespace, ok := factNamespace(path); ok {
+					runtime[namespace] = struct{}{}
+				}
+				return true
+			})
+		}
+		if !skippable || hasData(namespaces, almanac, runtime) {
+			evaluate = append(evaluate, r)
+			continue
+		}
+		Debug("engine::run no data for rule " + r.Name + ", skipping")
+		skipped = append(skipped, newSkippedResult(r, SkippedNoData))
+	}
+	return evaluate, skipped
+}
+
+// hasData reports whether any of the namespaces exists in the fact document, among the almanac's facts
+// or among the facts of a replayed snapshot. With CaseInsensitiveFactPaths a namespace differing from a key of the
+// fact document only in case has data too
+func hasData(namespaces []string, almanac *Almanac, runtime map[string]struct{}) bool {
+	for _, namespace := range namespaces {
+		if _, ok := runtime[namespace]; ok {
+			return true
+		}
+		if almanac.replay != nil {
+			if _, ok := almanac.replay.namespaces[namespace]; ok {
+				return true
+			}
+		}
+		if almanac.rawFacts.Get(namespace).Exists() {
+			return true
+		}
+		if almanac.caseInsensitive {
+			if _, ok := almanac.foldPath(namespace); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
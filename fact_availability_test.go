@@ -0,0 +1,134 @@
+package rulesengine
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRuleReferencedFacts(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "referenced",
+		"conditions": {"all": [
+			{"fact": "limits.{currency}", "operator": "greaterThan", "value": {"fact": "order.total"}},
+			{"facts": ["a", "b"], "operator": "subsetOf"},
+			{"not": {"fact": "user.country", "operator": "in", "value": [{"fact": "blocked.country"}, "XX"]}},
+			{"condition": "isAdult"}
+		]},
+		"event": {"type": "referenced"}
+	}`, &RuleEngineOptions{AllowUndefinedConditions: true})
+	rule := engine.GetRules()[0]
+
+	expected := []string{"a", "b", "blocked.country", "currency", "limits.{currency}", "order.total", "user.country"}
+	if got := rule.ReferencedFacts(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if err := engine.SetCondition("isAdult", Condition{All: []*Condition{{Fact: "user.age", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 17}}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = []string{"a", "b", "blocked.country", "currency", "limits.{currency}", "order.total", "user.age", "user.country"}
+	if got := rule.ReferencedFacts(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the facts of the registered condition, got %v", got)
+	}
+}
+
+func TestRunSkipRulesWithoutFacts(t *testing.T) {
+	rules := []string{
+		`{"name": "ios", "priority": 2, "conditions": {"all": [{"fact": "device.os", "operator": "equal", "value": "ios"}, {"fact": "device.version", "operator": "greaterThan", "value": 16}]}, "event": {"type": "ios"}}`,
+		`{"name": "notAndroid", "priority": 2, "conditions": {"all": [{"fact": "device.os", "operator": "notEqual", "value": "android"}]}, "event": {"type": "notAndroid"}}`,
+		`{"name": "noDevice", "priority": 2, "conditions": {"none": [{"fact": "device.os", "operator": "equal", "value": "ios"}]}, "event": {"type": "noDevice"}}`,
+		`{"name": "adult", "priority": 2, "conditions": {"all": [{"fact": "user.age", "operator": "greaterThan", "value": 17}]}, "event": {"type": "adult"}}`,
+		`{"name": "tier", "priority": 2, "conditions": {"all": [{"fact": "settings.tier", "operator": "equal", "value": "gold"}]}, "event": {"type": "tier"}}`,
+		`{"name": "session", "priority": 1, "conditions": {"all": [{"fact": "session.id", "operator": "equal", "value": "s1"}]}, "event": {"type": "session"}}`,
+	}
+	newEngine := func(allowUndefinedFacts bool) *Engine {
+		engine := NewEngine(nil, &RuleEngineOptions{AllowUndefinedFacts: allowUndefinedFacts})
+		for _, ruleJSON := range rules {
+			config := ephemeralRuleConfig(t, ruleJSON)
+			rule, err := NewRule(&config)
+			if err != nil {
+				t.Fatalf("Failed to create rule: %v", err)
+			}
+			if err := engine.AddRule(rule); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+		}
+		if err := engine.AddFact("settings.tier", &ValueNode{Type: String, String: "gold"}, nil); err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		return engine
+	}
+	options := DefaultRunOptions()
+	options.SkipRulesWithoutFacts = true
+
+	t.Run("rules failing without data are skipped", func(t *testing.T) {
+		engine := newEngine(true)
+		// A handler of the first priority group provides the session, so the later group has data
+		engine.OnSuccess(func(event Event, almanac *Almanac, _ *RuleResult) {
+			if event.Type == "adult" {
+				_ = almanac.AddRuntimeFact("session.id", ValueNode{Type: String, String: "s1"})
+			}
+		})
+		res, err := engine.RunWithOptions(context.Background(), []byte(`{"user": {"age": 30}}`), options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var passed []string
+		for _, rr := range res.Results {
+			passed = append(passed, rr.Name)
+		}
+		sort.Strings(passed)
+		if expected := []string{"adult", "noDevice", "notAndroid", "session", "tier"}; !reflect.DeepEqual(passed, expected) {
+			t.Errorf("Expected %v to pass, got %v", expected, passed)
+		}
+		if failed := res.FailureResults; len(failed) != 0 {
+			t.Errorf("Expected no failures, got %d", len(failed))
+		}
+		skipped := res.SkippedResults
+		if len(skipped) != 1 || skipped[0].Name != "ios" || skipped[0].Skipped != SkippedNoData || skipped[0].Result != nil {
+			t.Fatalf("Expected the ios rule to be skipped without data, got %v", skipped)
+		}
+		if res.Partial {
+			t.Errorf("Expected skipping without data not to make the result partial")
+		}
+
+		res, err = engine.RunWithOptions(context.Background(), []byte(`{"device": {"os": "ios", "version": 17}}`), options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		skipped = res.SkippedResults
+		if len(skipped) != 2 || skipped[0].Name != "adult" || skipped[1].Name != "session" {
+			t.Errorf("Expected the user and session rules to be skipped, got %d skipped", len(skipped))
+		}
+	})
+
+	t.Run("undefined fact errors are kept", func(t *testing.T) {
+		engine := newEngine(false)
+		if _, err := engine.RunWithOptions(context.Background(), []byte(`{"user": {"age": 30}}`), options); err == nil {
+			t.Errorf("Expected the undefined device fact to fail the run")
+		}
+	})
+}
+
+func TestRunSkipRulesWithoutFactsCaseInsensitive(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "ios",
+		"conditions": {"all": [{"fact": "Device.os", "operator": "equal", "value": "ios"}]},
+		"event": {"type": "ios"}
+	}`, &RuleEngineOptions{CaseInsensitiveFactPaths: true})
+	options := DefaultRunOptions()
+	options.SkipRulesWithoutFacts = true
+
+	res, err := engine.RunWithOptions(context.Background(), []byte(`{"device": {"os": "ios"}}`), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.SkippedResults) != 0 {
+		t.Fatalf("Expected the namespace to match ignoring case, got %d skipped", len(res.SkippedResults))
+	}
+	if len(res.Results) != 1 || res.Results[0].Name != "ios" {
+		t.Errorf("Expected the ios rule to pass, got %v", res.Results)
+	}
+}
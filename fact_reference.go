@@ -0,0 +1,63 @@
+package rulesengine
+
+// factReference returns the fact path of a condition value of the form {"fact": "path"}
+func factReference(v *ValueNode) (string, bool) {
+	if v.Type != Object {
+		return "", false
+	}
+	fact, ok := v.Object["fact"]
+	if !ok || fact.Type != String || fact.String == "" {
+		return "", false
+	}
+	return fact.String, true
+}
+
+// hasFactReferences reports whether a condition value is a fact reference or an array containing one
+func hasFactReferences(v *ValueNode) bool {
+	if _, ok := factReference(v); ok {
+		return true
+	}
+	if v.Type == Array {
+		for i := range v.Array {
+			if _, ok := factReference(&v.Array[i]); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveFactReferences resolves a condition value referencing facts, e.g. {"fact": "billingAddress.country"},
+// to the values of those facts. References may be the value itself or elements of an array value, as used with "in".
+// Undefined facts, when allowed, resolve to Null. Values without references are returned as is.
+func resolveFactReferences(v ValueNode, resolveFact factLookup) (ValueNode, error) {
+	resolve := func(path string) (ValueNode, error) {
+		f, err := resolveFact(path, FactLookupOptions{})
+		if err != nil {
+			return ValueNode{}, err
+		}
+		if f == nil || f.Value == nil {
+			return ValueNode{Type: Null}, nil
+		}
+		return *f.Value, nil
+	}
+
+	if path, ok := factReference(&v); ok {
+		return resolve(path)
+	}
+	if v.Type != Array || !hasFactReferences(&v) {
+		return v, nil
+	}
+	elements := make([]ValueNode, len(v.Array))
+	for i := range v.Array {
+		elements[i] = v.Array[i]
+		if path, ok := factReference(&v.Array[i]); ok {
+			value, err := resolve(path)
+			if err != nil {
+				return ValueNode{}, err
+			}
+			elements[i] = value
+		}
+	}
+	return ValueNode{Type: Array, Array: elements}, nil
+}
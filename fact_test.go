@@ -0,0 +1,346 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestNewValueFromGjson(t *testing.T) {
+	t.Run("Object facts resolve to Object", func(t *testing.T) {
+		almanac := NewAlmanac(gjson.Parse(`{"metadata": {"consent": true, "count": 2}}`), Options{}, 0)
+		fact, err := almanac.FactValue("metadata")
+		if err != nil {
+			t.Fatalf("Expected fact to resolve, got error: %v", err)
+		}
+		if fact.Value.Type != Object {
+			t.Fatalf("Expected Object type, got %v", fact.Value.Type)
+		}
+		if len(fact.Value.Object) != 2 || !fact.Value.Object["consent"].Bool || fact.Value.Object["count"].Number != 2 {
+			t.Errorf("Unexpected object value: %v", fact.Value.Raw())
+		}
+	})
+
+	t.Run("Deeply nested values are truncated", func(t *testing.T) {
+		doc := strings.Repeat(`{"a":`, MaxValueDepth+10) + "1" + strings.Repeat("}", MaxValueDepth+10)
+		value := NewValueFromGjson(gjson.Parse(doc))
+		depth := 0
+		for value.Type == Object {
+			child := value.Object["a"]
+			value = &child
+			depth++
+		}
+		if depth != MaxValueDepth || value.Type != Null {
+			t.Errorf("Expected conversion to stop at depth %d with Null, got depth %d and type %v", MaxValueDepth, depth, value.Type)
+		}
+	})
+
+	t.Run("Object facts flow through conditions and event params", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "object-fact",
+			"conditions": {
+				"all": [
+					{"fact": "metadata", "operator": "equal", "value": {"consent": true, "source": "web"}},
+					{"fact": "metadata", "operator": "hasKey", "value": "consent"}
+				]
+			},
+			"event": {"type": "object", "params": {"metadata": {"fact": "metadata"}}}
+		}`, &RuleEngineOptions{ReplaceFactsInEventParams: true})
+
+		res, err := engine.Run(context.Background(), []byte(`{"metadata": {"consent": true, "source": "web"}}`))
+		if err != nil {
+			t.Fatalf("Expected run to succeed, got error: %v", err)
+		}
+		events := res.Events
+		if len(events) != 1 {
+			t.Fatalf("Expected 1 success event, got %d", len(events))
+		}
+		metadata, ok := events[0].Params["metadata"].(map[string]ValueNode)
+		if !ok || metadata["source"].String != "web" {
+			t.Errorf("Expected metadata object in event params, got %v", events[0].Params["metadata"])
+		}
+	})
+}
+
+func TestCalculatedFactParams(t *testing.T) {
+	var calls atomic.Int32
+	engine := NewEngine(nil, nil)
+	err := engine.AddCalculatedFact("accountBalance", func(a *Almanac, params ...interface{}) *ValueNode {
+		calls.Add(1)
+		balances := map[string]float64{"EUR": 150, "USD": 50}
+		if len(params) == 0 {
+			return &ValueNode{Type: Number, Number: 0}
+		}
+		currency, _ := params[0].(map[string]interface{})["currency"].(string)
+		return &ValueNode{Type: Number, Number: balances[currency]}
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to add calculated fact: %v", err)
+	}
+	for _, ruleJSON := range []string{
+		`{"name": "eur", "conditions": {"all": [{"fact": "accountBalance", "params": {"currency": "EUR"}, "operator": "greaterThan", "value": 100}]}, "event": {"type": "eur"}}`,
+		`{"name": "eurAgain", "conditions": {"all": [{"fact": "accountBalance", "params": {"currency": "EUR"}, "operator": "lessThan", "value": 200}]}, "event": {"type": "eurAgain"}}`,
+		`{"name": "usd", "conditions": {"all": [{"fact": "accountBalance", "params": {"currency": "USD"}, "operator": "greaterThan", "value": 100}]}, "event": {"type": "usd"}}`,
+		`{"name": "none", "conditions": {"all": [{"fact": "accountBalance", "operator": "equal", "value": 0}]}, "event": {"type": "none"}}`,
+	} {
+		var config RuleConfig
+		if err := json.Unmarshal([]byte(ruleJSON), &config); err != nil {
+			t.Fatalf("Failed to unmarshal rule: %v", err)
+		}
+		if err := engine.AddRuleFromMap(&config); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+
+	res, err := engine.Run(context.Background(), []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	passed := map[string]bool{}
+	for _, rr := range res.Results {
+		passed[rr.Name] = true
+	}
+	if len(passed) != 3 || !passed["eur"] || !passed["eurAgain"] || !passed["none"] {
+		t.Errorf("Expected eur, eurAgain and none to pass, got %v", passed)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected one calculation per distinct set of params, got %d", n)
+	}
+}
+
+func TestEngineAddFactCopiesValue(t *testing.T) {
+	value := &ValueNode{Type: Object, Object: map[string]ValueNode{
+		"tiers": {Type: Array, Array: []ValueNode{{Type: String, String: "gold"}}},
+	}}
+	engine := NewEngine(nil, nil)
+	if err := engine.AddFact("config", value, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	value.Object["tiers"].Array[0] = ValueNode{Type: String, String: "silver"}
+	value.Object["added"] = ValueNode{Type: Bool, Bool: true}
+
+	stored := engine.GetFact("config").Value
+	if tier := stored.Object["tiers"].Array[0].String; tier != "gold" {
+		t.Errorf("Expected the stored fact to be unaffected, got %s", tier)
+	}
+	if _, ok := stored.Object["added"]; ok {
+		t.Errorf("Expected the stored object not to share the caller's map")
+	}
+}
+
+func TestEngineAddFactsInBulk(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	err := engine.AddFacts(map[string]interface{}{
+		"limit":  100,
+		"tier":   "gold",
+		"limits": map[string]interface{}{"eur": 10},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to add facts: %v", err)
+	}
+	if f := engine.GetFact("limits"); f == nil || f.Value.Type != Object {
+		t.Errorf("Expected the object fact, got %v", f)
+	}
+
+	calculated := func(a *Almanac, params ...interface{}) *ValueNode { return &ValueNode{Type: Number, Number: 1} }
+	err = engine.AddCalculatedFacts(map[string]DynamicFactCallback{"score": calculated, "tier": calculated, "limit": calculated}, nil)
+	var conflict *FactPathConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrFactPathConflict) {
+		t.Fatalf("Expected a fact path conflict, got %v", err)
+	}
+	if len(conflict.Conflicts) != 2 || conflict.Conflicts[0].Path != "limit" || conflict.Conflicts[1].Path != "tier" {
+		t.Errorf("Expected every conflict sorted by path, got %v", conflict.Conflicts)
+	}
+	if engine.GetFact("score") != nil || engine.GetFact("tier").Dynamic {
+		t.Errorf("Expected a conflicting batch to register nothing")
+	}
+
+	if err := engine.AddCalculatedFacts(map[string]DynamicFactCallback{"score": nil}, nil); err == nil {
+		t.Errorf("Expected a nil callback to be rejected")
+	}
+	err = engine.AddCalculatedFacts(map[string]DynamicFactCallback{"score": calculated, "tier": calculated}, &FactOptions{Cache: true, Priority: 1, Override: true})
+	if err != nil {
+		t.Fatalf("Expected the override to replace the fact, got %v", err)
+	}
+	if !engine.GetFact("tier").Dynamic || !engine.GetFact("score").Dynamic {
+		t.Errorf("Expected the calculated facts to be registered")
+	}
+}
+
+func TestCalculatedFactE(t *testing.T) {
+	type tenantKey struct{}
+	errUnknownCurrency := errors.New("unknown currency")
+	balance := func(ctx context.Context, a *Almanac, params map[string]interface{}) (*ValueNode, error) {
+		if ctx.Value(tenantKey{}) != "acme" {
+			return nil, errors.New("missing tenant")
+		}
+		if params["currency"] != "EUR" {
+			return nil, errUnknownCurrency
+		}
+		return &ValueNode{Type: Number, Number: 500}, nil
+	}
+	ruleJSON := `{
+		"name": "rich",
+		"conditions": {"all": [{"fact": "balance", "params": {"currency": "%s"}, "operator": "greaterThan", "value": 100}]},
+		"event": {"type": "rich"}
+	}`
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	engine := newTestEngine(t, fmt.Sprintf(ruleJSON, "EUR"), nil)
+	if err := engine.AddCalculatedFactE("balance", balance, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	if engine.AddCalculatedFactE("balance", nil, nil) == nil || engine.AddCalculatedFactE("balance", balance, &FactOptions{OnError: "skip"}) == nil {
+		t.Errorf("Expected nil callbacks and unsupported onError values to be rejected")
+	}
+	res, err := engine.Run(ctx, []byte(`{}`))
+	if err != nil || len(res.Results) != 1 {
+		t.Fatalf("Expected the rule to pass with the run's context, got %v, %v", res, err)
+	}
+
+	engine = newTestEngine(t, fmt.Sprintf(ruleJSON, "XXX"), nil)
+	if err := engine.AddCalculatedFactE("balance", balance, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	_, err = engine.Run(ctx, []byte(`{}`))
+	var factErr *CalculatedFactError
+	if !errors.As(err, &factErr) || !errors.Is(err, ErrCalculatedFact) || !errors.Is(err, errUnknownCurrency) {
+		t.Fatalf("Expected a calculated fact error, got %v", err)
+	}
+	if factErr.Path != "balance" || factErr.Rule != "rich" || factErr.Condition != "conditions.all[0]" {
+		t.Errorf("Expected the fact, rule and condition in the error, got %+v", factErr)
+	}
+
+	engine = newTestEngine(t, fmt.Sprintf(ruleJSON, "XXX"), nil)
+	if err := engine.AddCalculatedFactE("balance", balance, &FactOptions{Cache: true, Priority: 1, OnError: FactErrorFalse}); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	res, err = engine.Run(ctx, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Expected the condition to evaluate as false, got %v", err)
+	}
+	if len(res.Results) != 0 || len(res.Diagnostics) != 1 || res.Diagnostics[0].Type != DiagnosticFactError {
+		t.Errorf("Expected the rule to fail with a fact error diagnostic, got %v", res.Diagnostics)
+	}
+}
+
+func TestCalculatedFactCacheOption(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cache bool
+		calls int32
+	}{
+		{"cached once per params", true, 2},
+		{"uncached on every reference", false, 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newTestEngine(t, `{
+				"name": "balances",
+				"conditions": {"all": [
+					{"fact": "balance", "params": {"currency": "EUR"}, "operator": "greaterThan", "value": 10},
+					{"fact": "balance", "params": {"currency": "EUR"}, "operator": "lessThan", "value": 1000},
+					{"fact": "balance", "params": {"currency": "USD"}, "operator": "greaterThan", "value": 10},
+					{"fact": "balance", "params": {"currency": "USD"}, "operator": "lessThan", "value": 1000}
+				]},
+				"event": {"type": "balances"}
+			}`, nil)
+			var calls, unreferenced atomic.Int32
+			err := engine.AddCalculatedFact("balance", func(a *Almanac, params ...interface{}) *ValueNode {
+				calls.Add(1)
+				return &ValueNode{Type: Number, Number: 100}
+			}, &FactOptions{Cache: tc.cache, Priority: 1})
+			if err != nil {
+				t.Fatalf("Failed to add fact: %v", err)
+			}
+			err = engine.AddCalculatedFact("unreferenced", func(a *Almanac, params ...interface{}) *ValueNode {
+				unreferenced.Add(1)
+				return nil
+			}, &FactOptions{Cache: tc.cache, Priority: 1})
+			if err != nil {
+				t.Fatalf("Failed to add fact: %v", err)
+			}
+
+			for run := 1; run <= 2; run++ {
+				res, err := engine.Run(context.Background(), []byte(`{}`))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(res.Results) != 1 {
+					t.Fatalf("Expected the rule to pass, got %v", res.FailureResults)
+				}
+				// Values are cached per run, so every run calculates again
+				if got := calls.Load(); got != tc.calls*int32(run) {
+					t.Errorf("Run %d: expected %d calculations, got %d", run, tc.calls*int32(run), got)
+				}
+			}
+			if unreferenced.Load() != 0 {
+				t.Errorf("Expected facts no condition references never to be calculated")
+			}
+		})
+	}
+}
+
+func TestCalculatedFactsScopedPerRun(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "tier",
+		"conditions": {"all": [{"fact": "tier", "operator": "equal", "value": "gold"}]},
+		"event": {"type": "tier"}
+	}`, nil)
+	err := engine.AddCalculatedFact("tier", func(a *Almanac, params ...interface{}) *ValueNode {
+		points, err := a.FactValue("points")
+		if err != nil || points.Value == nil || points.Value.Number < 100 {
+			return &ValueNode{Type: String, String: "silver"}
+		}
+		return &ValueNode{Type: String, String: "gold"}
+	}, &FactOptions{Cache: true, Priority: 1})
+	if err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		gold := i%2 == 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			facts := `{"points": 10}`
+			if gold {
+				facts = `{"points": 500}`
+			}
+			res, err := engine.Run(context.Background(), []byte(facts))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if passed := len(res.Results) == 1; passed != gold {
+				errs <- fmt.Errorf("run with %s: expected the rule to pass %v, got %v", facts, gold, passed)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	fact := engine.GetFact("tier")
+	if fact == nil || fact.Value != nil {
+		t.Fatalf("Expected the engine to keep the fact definition only, got %+v", fact)
+	}
+	almanac := NewAlmanac(gjson.Parse(`{"points": 500}`), Options{}, 0)
+	if calculated := fact.Calculate(almanac); calculated == fact || calculated.Value == nil || calculated.Value.String != "gold" {
+		t.Errorf("Expected Calculate to return the calculated value, got %+v", calculated)
+	}
+	if fact.Value != nil {
+		t.Errorf("Expected Calculate to leave the fact definition unchanged")
+	}
+}
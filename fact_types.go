@@ -0,0 +1,157 @@
+package rulesengine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// factTypeAnalyzerName is the analyzer name of issues for operators used with facts of the wrong type
+const factTypeAnalyzerName = "factType"
+
+// FactTypeAnalyzer flags leaf conditions whose operator expects another type than the fact they reference,
+// e.g. greaterThan on a string fact, which can never pass. Only facts of known type are checked: rule-local facts,
+// static engine facts and calculated facts declaring FactOptions.ValueType.
+var FactTypeAnalyzer = LintAnalyzer{
+	Name: factTypeAnalyzerName,
+	Run: func(r *Rule) []LintIssue {
+		if r.Engine == nil {
+			return nil
+		}
+		var issues []LintIssue
+		for _, mismatch := range r.Engine.factTypeMismatches(r, nil, nil) {
+			issues = append(issues, LintIssue{
+				Rule:     r.Name,
+				Analyzer: factTypeAnalyzerName,
+				Message:  mismatch.message(),
+				Paths:    []string{mismatch.path},
+			})
+		}
+		return issues
+	},
+}
+
+// factTypeMismatch is a leaf condition whose operator expects another fact type than the fact's
+type factTypeMismatch struct {
+	path     string // The path of the condition within the rule, e.g. "all[0]"
+	fact     string
+	operator string
+	expected string
+	got      DataType
+}
+
+func (m factTypeMismatch) message() string {
+	return fmt.Sprintf("operator %q expects a fact of type %s, %q is of type %s", m.operator, m.expected, m.fact, m.got)
+}
+
+// factTypeMismatches returns the leaf conditions of the rule whose operator expects another type than the fact they
+// reference. Facts without a known type, e.g. facts of the fact document, are only checked against the sample
+// document when one is given. Conditions with a path, transforms or a dynamic fact path are not checked.
+func (e *Engine) factTypeMismatches(r *Rule, sample *gjson.Result, pending map[string]*Fact) []factTypeMismatch {
+	operators := e.Operators()
+	var mismatches []factTypeMismatch
+	var walk func(c *Condition, path string)
+	walk = func(c *Condition, path string) {
+		if c == nil {
+			return
+		}
+		if c.Operator != "" && c.Fact != "" && c.Path == "" && len(c.Transforms) == 0 && !hasDynamicSegments(c.Fact) {
+			if op, _, ok := lookupOperator(operators, e.OperatorDecorators(), c.Operator); ok && op.Metadata != nil && op.Metadata.FactType != "any" {
+				if got, known := e.factType(r, c.Fact, sample, pending); known && got != Null && got.String() != op.Metadata.FactType {
+					mismatches = append(mismatches, factTypeMismatch{path: path, fact: c.Fact, operator: c.Operator, expected: op.Metadata.FactType, got: got})
+				}
+			}
+		}
+		for _, group := range []struct {
+			operator   string
+			conditions []*Condition
+		}{{"all", c.All}, {"any", c.Any}, {"none", c.None}, {"mostOf", c.MostOf}} {
+			for i, child := range group.conditions {
+				walk(child, joinConditionPath(path, fmt.Sprintf("%s[%d]", group.operator, i)))
+			}
+		}
+		walk(c.Not, joinConditionPath(path, "not"))
+	}
+	walk(&r.Conditions, "")
+	return mismatches
+}
+
+// joinConditionPath appends a segment to a condition path
+func joinConditionPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+// factType returns the type of a fact as resolved for the rule: rule-local facts first, then pending facts about to
+// replace engine facts, e.g. those of a bundle, then engine facts and then the sample document. known is false when
+// the type cannot be told, e.g. for calculated facts without ValueType.
+func (e *Engine) factType(r *Rule, path string, sample *gjson.Result, pending map[string]*Fact) (DataType, bool) {
+	for base, rest := path, ""; ; {
+		if value, ok := r.Facts[base]; ok {
+			if resolved, found := value.Get(rest); found {
+				return resolved.Type, true
+			}
+			break
+		}
+		i := strings.LastIndexByte(base, '.')
+		if i < 0 {
+			break
+		}
+		if rest == "" {
+			rest = base[i+1:]
+		} else {
+			rest = base[i+1:] + "." + rest
+		}
+		base = base[:i]
+	}
+	if f, ok := pending[path]; ok {
+		return f.ValueType, f.ValueType != Null
+	}
+	if f, ok := e.Facts.Load(path); ok {
+		return f.ValueType, f.ValueType != Null
+	}
+	if sample != nil {
+		if result := sample.Get(path); result.Exists() {
+			return NewValueFromGjson(result).Type, true
+		}
+	}
+	return Null, false
+}
+
+// newFactTypeError reports the fact type mismatches of a rule
+func newFactTypeError(rule string, mismatches []factTypeMismatch) *InvalidRuleError {
+	messages := make([]string, len(mismatches))
+	for i, mismatch := range mismatches {
+		messages[i] = "conditions." + mismatch.path + ": " + mismatch.message()
+	}
+	return NewInvalidRuleError(fmt.Sprintf("rule %q: %s", rule, strings.Join(messages, "; ")), "FACT_TYPE_MISMATCH")
+}
+
+// Validate checks the rules of the engine against the types of the facts they reference, like StrictFactTypes does
+// when rules are added, but with the facts registered now.
+// Params:
+// - sample: An optional representative fact document, nil for none. Facts found in it are checked too, unless an
+// engine or rule-local fact of the same path takes precedence.
+// Returns an InvalidRuleError with code FACT_TYPE_MISMATCH for every rule using an operator on a fact of
+// the wrong type, joined, or nil.
+func (e *Engine) Validate(sample []byte) error {
+	var document *gjson.Result
+	if sample != nil {
+		if !gjson.ValidBytes(sample) {
+			return errors.New("engine: sample document is not valid JSON")
+		}
+		parsed := gjson.ParseBytes(sample)
+		document = &parsed
+	}
+	var errs []error
+	for _, r := range e.GetRules() {
+		if mismatches := e.factTypeMismatches(r, document, nil); len(mismatches) > 0 {
+			errs = append(errs, newFactTypeError(r.Name, mismatches))
+		}
+	}
+	return errors.Join(errs...)
+}
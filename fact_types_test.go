@@ -0,0 +1,87 @@
+package rulesengine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFactTypeValidation(t *testing.T) {
+	ruleJSON := `{
+		"name": "typed",
+		"conditions": {"all": [
+			{"fact": "country", "operator": "greaterThan", "value": 10},
+			{"any": [{"fact": "risk", "operator": "startsWith", "value": "hi"}, {"fact": "limits.max", "operator": "lessThan", "value": 5}]},
+			{"fact": "user.age", "operator": "greaterThan", "value": 17},
+			{"fact": "country", "operator": "equal", "value": "DE"}
+		]},
+		"facts": {"limits": {"max": "ten"}},
+		"event": {"type": "typed"}
+	}`
+	newEngine := func(t *testing.T, options *RuleEngineOptions) *Engine {
+		t.Helper()
+		engine := NewEngine(nil, options)
+		if err := engine.AddFact("country", &ValueNode{Type: String, String: "DE"}, nil); err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		if err := engine.AddCalculatedFact("risk", func(*Almanac, ...interface{}) *ValueNode {
+			return &ValueNode{Type: Number, Number: 0.3}
+		}, &FactOptions{Cache: true, Priority: 1, ValueType: Number}); err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		return engine
+	}
+	config := ephemeralRuleConfig(t, ruleJSON)
+
+	t.Run("strict engines refuse mismatching rules", func(t *testing.T) {
+		engine := newEngine(t, &RuleEngineOptions{StrictFactTypes: true})
+		rule, err := NewRule(&config)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		err = engine.AddRule(rule)
+		var invalid *InvalidRuleError
+		if !errors.As(err, &invalid) || invalid.Code != "FACT_TYPE_MISMATCH" {
+			t.Fatalf("Expected a fact type error, got %v", err)
+		}
+		for _, expected := range []string{
+			`conditions.all[0]: operator "greaterThan" expects a fact of type number, "country" is of type string`,
+			`conditions.all[1].any[0]: operator "startsWith" expects a fact of type string, "risk" is of type number`,
+			`conditions.all[1].any[1]: operator "lessThan" expects a fact of type number, "limits.max" is of type string`,
+		} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected %q in %v", expected, err)
+			}
+		}
+		if strings.Contains(err.Error(), "user.age") || strings.Contains(err.Error(), "equal") {
+			t.Errorf("Expected document facts and untyped operators not to be checked, got %v", err)
+		}
+	})
+
+	t.Run("lint reports mismatches and Validate checks a sample document", func(t *testing.T) {
+		engine := newEngine(t, nil)
+		rule, err := NewRule(&config)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Expected the rule to be added without StrictFactTypes, got %v", err)
+		}
+		var paths []string
+		for _, issue := range engine.Lint() {
+			if issue.Analyzer == factTypeAnalyzerName {
+				paths = append(paths, issue.Paths...)
+			}
+		}
+		if strings.Join(paths, " ") != "all[0] all[1].any[0] all[1].any[1]" {
+			t.Errorf("Expected lint issues for the mismatching conditions, got %v", paths)
+		}
+
+		if err := engine.Validate([]byte(`{"user": {"age": "thirty"}}`)); err == nil || !strings.Contains(err.Error(), `"user.age" is of type string`) {
+			t.Errorf("Expected the sample document to be checked, got %v", err)
+		}
+		if err := engine.Validate([]byte(`{"user": {"age": 30}}`)); err == nil || strings.Contains(err.Error(), "user.age") {
+			t.Errorf("Expected only the registered facts to mismatch, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package rulesengine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SetFactsParam is the event param holding the facts a passing rule sets for rules of lower priority, e.g.
+// {"type": "risk", "params": {"setFacts": {"riskTier": "high"}}}, see RuleEngineOptions.ForwardChaining.
+const SetFactsParam = "setFacts"
+
+// setEventFacts adds the facts of the rule result's event as runtime facts.
+// Returns an error when the param is not an object, or a fact cannot be added, e.g. with priority barriers ignored.
+func setEventFacts(almanac *Almanac, ruleResult *RuleResult) error {
+	raw, ok := ruleResult.Event.Params[SetFactsParam]
+	if !ok || raw == nil {
+		return nil
+	}
+	facts, ok := raw.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("rule %q: event param %s must be an object, got %T", ruleResult.Name, SetFactsParam, raw)
+	}
+	paths := make([]string, 0, len(facts))
+	for path := range facts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		node, err := NewValue(facts[path])
+		if err != nil {
+			return fmt.Errorf("rule %q: fact %s: %w", ruleResult.Name, path, err)
+		}
+		if err := almanac.AddRuntimeFact(path, *node); err != nil {
+			return fmt.Errorf("rule %q: %w", ruleResult.Name, err)
+		}
+	}
+	return nil
+}
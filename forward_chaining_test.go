@@ -0,0 +1,98 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestForwardChaining(t *testing.T) {
+	newEngine := func(t *testing.T, options *RuleEngineOptions) *Engine {
+		t.Helper()
+		engine := newTestEngine(t, `{
+			"name": "risk",
+			"priority": 10,
+			"conditions": {"all": [{"fact": "amount", "operator": "greaterThan", "value": 1000}]},
+			"event": {"type": "risk", "params": {"setFacts": {"riskTier": "high", "limits.daily": 500}}}
+		}`, options)
+		var config RuleConfig
+		if err := json.Unmarshal([]byte(`{
+			"name": "review",
+			"priority": 1,
+			"conditions": {"all": [
+				{"fact": "riskTier", "operator": "equal", "value": "high"},
+				{"fact": "limits.daily", "operator": "lessThan", "value": 1000}
+			]},
+			"event": {"type": "review"}
+		}`), &config); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		if err := engine.AddRuleFromMap(&config); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+		return engine
+	}
+	decisions := func(t *testing.T, engine *Engine, facts string) []string {
+		t.Helper()
+		res, err := engine.Run(context.Background(), []byte(facts))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var names []string
+		for _, ruleResult := range res.Results {
+			names = append(names, ruleResult.Name)
+		}
+		return names
+	}
+
+	t.Run("a higher priority rule gates a lower priority rule", func(t *testing.T) {
+		engine := newEngine(t, &RuleEngineOptions{ForwardChaining: true, AllowUndefinedFacts: true})
+		if got := decisions(t, engine, `{"amount": 5000}`); len(got) != 2 || got[0] != "risk" || got[1] != "review" {
+			t.Errorf("Expected risk to set the facts review needs, got %v", got)
+		}
+		if got := decisions(t, engine, `{"amount": 10}`); len(got) != 0 {
+			t.Errorf("Expected no rule to pass, got %v", got)
+		}
+	})
+
+	t.Run("facts are only set when enabled", func(t *testing.T) {
+		engine := newEngine(t, &RuleEngineOptions{AllowUndefinedFacts: true})
+		if got := decisions(t, engine, `{"amount": 5000}`); len(got) != 1 || got[0] != "risk" {
+			t.Errorf("Expected only risk to pass, got %v", got)
+		}
+	})
+
+	t.Run("success handlers can set facts", func(t *testing.T) {
+		engine := newEngine(t, &RuleEngineOptions{AllowUndefinedFacts: true})
+		if err := engine.OnEvent("risk", func(params map[string]interface{}, almanac *Almanac, ruleResult *RuleResult) {
+			almanac.AddRuntimeFact("riskTier", ValueNode{Type: String, String: "high"})
+			almanac.AddRuntimeFact("limits.daily", ValueNode{Type: Number, Number: 100})
+		}); err != nil {
+			t.Fatalf("Failed to register event handler: %v", err)
+		}
+		if got := decisions(t, engine, `{"amount": 5000}`); len(got) != 2 {
+			t.Errorf("Expected the handler to set the facts review needs, got %v", got)
+		}
+	})
+
+	t.Run("setFacts must be an object", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "risk",
+			"conditions": {"all": [{"fact": "amount", "operator": "greaterThan", "value": 1000}]},
+			"event": {"type": "risk", "params": {"setFacts": "high"}}
+		}`, &RuleEngineOptions{ForwardChaining: true})
+		if _, err := engine.Run(context.Background(), []byte(`{"amount": 5000}`)); err == nil {
+			t.Errorf("Expected an error for setFacts that is not an object")
+		}
+	})
+
+	t.Run("facts require priority barriers", func(t *testing.T) {
+		engine := newEngine(t, &RuleEngineOptions{ForwardChaining: true, AllowUndefinedFacts: true})
+		options := DefaultRunOptions()
+		options.IgnorePriorityBarriers = true
+		if _, err := engine.RunWithOptions(context.Background(), []byte(`{"amount": 5000}`), options); !errors.Is(err, ErrPriorityBarriersRequired) {
+			t.Errorf("Expected ErrPriorityBarriersRequired, got %v", err)
+		}
+	})
+}
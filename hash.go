@@ -0,0 +1,170 @@
+package rulesengine
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/fnv"
+	"sort"
+)
+
+// Hash returns a stable content hash of the rule, covering its name, priority, schema version, conditions, event and
+// local facts.
+// Callbacks and evaluation results are not included. The hash is deterministic across processes,
+// so it can be used to detect drift between environments or to key caches.
+func (r *Rule) Hash() uint64 {
+	view := map[string]interface{}{
+		"name":       r.Name,
+		"priority":   r.Priority,
+		"conditions": conditionHashView(&r.Conditions),
+		"event": map[string]interface{}{
+			"type":   r.RuleEvent.Type,
+			"params": r.RuleEvent.Params,
+		},
+	}
+	if r.SchemaVersion != 0 {
+		view["schemaVersion"] = r.SchemaVersion
+	}
+	if len(r.Facts) > 0 {
+		facts := make(map[string]interface{}, len(r.Facts))
+		for path, value := range r.Facts {
+			facts[path] = value.Raw()
+		}
+		view["facts"] = facts
+	}
+	// encoding/json writes map keys in sorted order, which keeps the encoding stable
+	data, err := json.Marshal(view)
+	if err != nil {
+		// Event params that can not be encoded fall back to their printed form
+		view["event"] = map[string]interface{}{"type": r.RuleEvent.Type, "params": printParams(r.RuleEvent.Params)}
+		data, _ = json.Marshal(view)
+	}
+	return HashString(string(data))
+}
+
+// RuleSetHash returns a stable content hash of all rules in the engine.
+// The hash does not depend on the order in which rules were added.
+func (e *Engine) RuleSetHash() uint64 {
+	rules := e.GetRules()
+	hashes := make([]uint64, len(rules))
+	for i, r := range rules {
+		hashes[i] = r.Hash()
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, hash := range hashes {
+		binary.BigEndian.PutUint64(buf, hash)
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// conditionHashView returns the definition of a condition tree, without evaluation results, as plain maps and slices
+func conditionHashView(c *Condition) interface{} {
+	return conditionDefinition(c, false)
+}
+
+// conditionDefinition returns the definition of a condition tree as plain maps and slices.
+// When complete is set it also holds the settings left out of the hash view, cost, ifMissing and the shorthand
+// and named group forms, so it unmarshals back to the same condition.
+func conditionDefinition(c *Condition, complete bool) interface{} {
+	if c == nil {
+		return nil
+	}
+	if complete && c.Shorthand && c.Priority == nil && c.Name == "" && c.IfMissing == "" {
+		return c.Condition
+	}
+	view := map[string]interface{}{}
+	if c.Priority != nil {
+		view["priority"] = *c.Priority
+	}
+	if c.Name != "" {
+		view["name"] = c.Name
+	}
+	if c.Condition != "" {
+		view["condition"] = c.Condition
+	}
+	if c.Fact != "" || c.Operator != "" {
+		view["fact"] = c.Fact
+		view["operator"] = c.Operator
+		if c.Path != "" {
+			view["path"] = c.Path
+		}
+		if len(c.Transforms) > 0 {
+			view["transforms"] = c.Transforms
+		}
+		if c.FreshFact {
+			view["freshFact"] = true
+		}
+		view["value"] = c.Value.Raw()
+	}
+	if c.Negate {
+		view["negate"] = true
+	}
+	if c.Ordered {
+		view["ordered"] = true
+	}
+	if len(c.Facts) > 0 {
+		view["facts"] = c.Facts
+	}
+	if len(c.Params) > 0 {
+		view["params"] = c.Params
+	}
+	if complete {
+		if c.Cost != nil {
+			view["cost"] = *c.Cost
+		}
+		if c.IfMissing != "" {
+			view["ifMissing"] = c.IfMissing
+		}
+		if len(c.FailureMessage) > 0 {
+			view["failureMessage"] = c.FailureMessage
+		}
+	}
+	for _, group := range []struct {
+		operator   string
+		conditions []*Condition
+	}{{"all", c.All}, {"any", c.Any}, {"none", c.None}, {"mostOf", c.MostOf}} {
+		if group.conditions == nil {
+			continue
+		}
+		if complete && c.NamedGroups {
+			named := make(map[string]interface{}, len(group.conditions))
+			for _, child := range group.conditions {
+				named[child.Name] = conditionDefinition(child, complete)
+			}
+			view[group.operator] = named
+			continue
+		}
+		conditions := make([]interface{}, len(group.conditions))
+		for i, child := range group.conditions {
+			conditions[i] = conditionDefinition(child, complete)
+		}
+		view[group.operator] = conditions
+	}
+	if c.AtLeast > 0 {
+		view["atLeast"] = c.AtLeast
+	}
+	if c.MostOf != nil {
+		view["minPassRatio"] = c.MinPassRatio
+	}
+	if c.Not != nil {
+		view["not"] = conditionDefinition(c.Not, complete)
+	}
+	return view
+}
+
+// printParams converts params to strings so they can always be encoded
+func printParams(params map[string]interface{}) map[string]string {
+	printed := make(map[string]string, len(params))
+	for k, v := range params {
+		b, err := json.Marshal(v)
+		if err != nil {
+			printed[k] = "<unencodable>"
+			continue
+		}
+		printed[k] = string(b)
+	}
+	return printed
+}
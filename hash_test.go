@@ -0,0 +1,73 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRuleHash(t *testing.T) {
+	newRule := func(t *testing.T, ruleJSON string) *Rule {
+		t.Helper()
+		var ruleConfig RuleConfig
+		if err := json.Unmarshal([]byte(ruleJSON), &ruleConfig); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		rule, err := NewRule(&ruleConfig)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		return rule
+	}
+
+	base := `{
+		"name": "adult",
+		"priority": 2,
+		"conditions": {"all": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]},
+		"event": {"type": "adult", "params": {"x": 1, "y": {"z": true}}}
+	}`
+	permuted := `{
+		"event": {"params": {"y": {"z": true}, "x": 1}, "type": "adult"},
+		"conditions": {"all": [{"value": 18, "operator": "gte", "fact": "age"}, {"value": {"b": 2, "a": 1}, "fact": "meta", "operator": "equal"}]},
+		"priority": 2,
+		"name": "adult"
+	}`
+
+	t.Run("Field order does not change the hash", func(t *testing.T) {
+		if newRule(t, base).Hash() != newRule(t, permuted).Hash() {
+			t.Errorf("Expected equal hashes for permuted JSON")
+		}
+	})
+
+	t.Run("Changed fields change the hash", func(t *testing.T) {
+		changed := []string{
+			`{"name": "other", "priority": 2, "conditions": {"all": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]}, "event": {"type": "adult", "params": {"x": 1, "y": {"z": true}}}}`,
+			`{"name": "adult", "priority": 3, "conditions": {"all": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]}, "event": {"type": "adult", "params": {"x": 1, "y": {"z": true}}}}`,
+			`{"name": "adult", "priority": 2, "conditions": {"all": [{"fact": "age", "operator": "gte", "value": 21}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]}, "event": {"type": "adult", "params": {"x": 1, "y": {"z": true}}}}`,
+			`{"name": "adult", "priority": 2, "conditions": {"any": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]}, "event": {"type": "adult", "params": {"x": 1, "y": {"z": true}}}}`,
+			`{"name": "adult", "priority": 2, "conditions": {"all": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 3}}]}, "event": {"type": "adult", "params": {"x": 1, "y": {"z": true}}}}`,
+			`{"name": "adult", "priority": 2, "conditions": {"all": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]}, "event": {"type": "minor", "params": {"x": 1, "y": {"z": true}}}}`,
+			`{"name": "adult", "priority": 2, "conditions": {"all": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]}, "event": {"type": "adult", "params": {"x": 2, "y": {"z": true}}}}`,
+			`{"schemaVersion": 2, "name": "adult", "priority": 2, "conditions": {"all": [{"fact": "age", "operator": "gte", "value": 18}, {"fact": "meta", "operator": "equal", "value": {"a": 1, "b": 2}}]}, "event": {"type": "adult", "params": {"x": 1, "y": {"z": true}}}}`,
+		}
+		baseHash := newRule(t, base).Hash()
+		for i, ruleJSON := range changed {
+			if newRule(t, ruleJSON).Hash() == baseHash {
+				t.Errorf("Expected change %d to produce a different hash", i)
+			}
+		}
+	})
+
+	t.Run("Rule set hash ignores rule order", func(t *testing.T) {
+		a := newRule(t, base)
+		b := newRule(t, `{"name": "other", "conditions": {"all": [{"fact": "x", "operator": "equal", "value": 1}]}, "event": {"type": "other"}}`)
+		first := NewEngine([]*Rule{a, b}, nil)
+		second := NewEngine([]*Rule{b, a}, nil)
+		if first.RuleSetHash() != second.RuleSetHash() {
+			t.Errorf("Expected equal rule set hashes regardless of order")
+		}
+		second.RemoveRule(a)
+		if first.RuleSetHash() == second.RuleSetHash() {
+			t.Errorf("Expected removing a rule to change the rule set hash")
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package rulesengine
+
+import (
+	"fmt"
+	"math"
+)
+
+// LintIssue describes a problem found by a static analyzer in a rule's condition tree.
+// Paths identify the offending conditions within the rule, e.g. "all[0]" or "any[1].all[0]".
+type LintIssue struct {
+	Rule     string   `json:"rule"`
+	Analyzer string   `json:"analyzer"`
+	Message  string   `json:"message"`
+	Paths    []string `json:"paths"`
+}
+
+// LintAnalyzer is a static analyzer run against every rule by Engine.Lint.
+type LintAnalyzer struct {
+	Name string
+	Run  func(rule *Rule) []LintIssue
+}
+
+// ContradictionAnalyzer flags leaf conditions on the same fact that can never both pass in an 'all' group,
+// and leaf conditions on the same fact of which one always passes in an 'any' group.
+// It understands the equality, in/notIn and numeric range operators.
+var ContradictionAnalyzer = LintAnalyzer{
+	Name: contradictionAnalyzerName,
+	Run:  lintContradictions,
+}
+
+const contradictionAnalyzerName = "contradiction"
+
+// unregisteredEventTypeName is the analyzer name of issues for event types no consumer registered
+const unregisteredEventTypeName = "unregisteredEventType"
+
+// DefaultLintAnalyzers returns the built-in analyzers used by Engine.Lint
+func DefaultLintAnalyzers() []LintAnalyzer {
+	return []LintAnalyzer{ContradictionAnalyzer, FactTypeAnalyzer, UndefinedConditionAnalyzer}
+}
+
+// Lint runs the built-in analyzers, followed by any additional analyzers, over all rules of the engine.
+// Once event types are registered with RegisterEventTypes, rules emitting any other event type are reported too.
+// Returns the issues found, in rule order.
+func (e *Engine) Lint(analyzers ...LintAnalyzer) []LintIssue {
+	all := append(DefaultLintAnalyzers(), analyzers...)
+	var issues []LintIssue
+	for _, r := range e.GetRules() {
+		for _, analyzer := range all {
+			issues = append(issues, analyzer.Run(r)...)
+		}
+		if len(e.registeredEventTypes()) > 0 && !e.isRegisteredEventType(r.RuleEvent.Type) {
+			issues = append(issues, LintIssue{
+				Rule:     r.Name,
+				Analyzer: unregisteredEventTypeName,
+				Message:  fmt.Sprintf("event type %q is not registered by any consumer", r.RuleEvent.Type),
+			})
+		}
+	}
+	return issues
+}
+
+// leafConstraint describes the set of fact values accepted by a leaf condition.
+// Either the value is (or is not, when exclude is set) one of values, or it is a number within the interval.
+type leafConstraint struct {
+	values   []ValueNode
+	exclude  bool
+	interval *numericInterval
+}
+
+// numericInterval is a range of numbers with optionally inclusive bounds
+type numericInterval struct {
+	lo, hi         float64
+	loIncl, hiIncl bool
+}
+
+func (i *numericInterval) contains(n float64) bool {
+	aboveLo := n > i.lo || (i.loIncl && n == i.lo)
+	belowHi := n < i.hi || (i.hiIncl && n == i.hi)
+	return aboveLo && belowHi
+}
+
+// overlaps reports whether two intervals share at least one number
+func (i *numericInterval) overlaps(o *numericInterval) bool {
+	if i.hi < o.lo || o.hi < i.lo {
+		return false
+	}
+	if i.hi == o.lo {
+		return i.hiIncl && o.loIncl
+	}
+	if o.hi == i.lo {
+		return o.hiIncl && i.loIncl
+	}
+	return true
+}
+
+// covers reports whether the union of two intervals is the whole number line
+func (i *numericInterval) covers(o *numericInterval) bool {
+	lower, upper := i, o
+	if o.lo < i.lo {
+		lower, upper = o, i
+	}
+	if !math.IsInf(lower.lo, -1) || !math.IsInf(upper.hi, 1) {
+		return false
+	}
+	if lower.hi > upper.lo {
+		return true
+	}
+	return lower.hi == upper.lo && (lower.hiIncl || upper.loIncl)
+}
+
+// constraintFor derives the constraint of a leaf condition, returning nil for operators the analyzer does not understand.
+// Conditions with transforms or freshFact may see another value than a condition on the same fact, so they are not
+// compared either.
+func constraintFor(c *Condition) *leafConstraint {
+	if c.Negate || hasFactReferences(&c.Value) || len(c.Transforms) > 0 || c.FreshFact {
+		return nil
+	}
+	switch c.Operator {
+	case "equal", "=", "eq":
+		return &leafConstraint{values: []ValueNode{c.Value}}
+	case "notEqual", "ne", "!=":
+		return &leafConstraint{values: []ValueNode{c.Value}, exclude: true}
+	case "in":
+		if c.Value.IsArray() {
+			return &leafConstraint{values: c.Value.Array}
+		}
+	case "notIn":
+		if c.Value.IsArray() {
+			return &leafConstraint{values: c.Value.Array, exclude: true}
+		}
+	}
+	if !c.Value.IsNumber() {
+		return nil
+	}
+	n := c.Value.Number
+	switch c.Operator {
+	case "lessThan", "<", "lt":
+		return &leafConstraint{interval: &numericInterval{lo: math.Inf(-1), hi: n}}
+	case "lessThanInclusive", "<=", "lte":
+		return &leafConstraint{interval: &numericInterval{lo: math.Inf(-1), hi: n, hiIncl: true}}
+	case "greaterThan", ">", "gt":
+		return &leafConstraint{interval: &numericInterval{lo: n, hi: math.Inf(1)}}
+	case "greaterThanInclusive", ">=", "gte":
+		return &leafConstraint{interval: &numericInterval{lo: n, hi: math.Inf(1), loIncl: true}}
+	}
+	return nil
+}
+
+// containsValue reports whether the value is one of values
+func containsValue(values []ValueNode, v *ValueNode) bool {
+	for i := range values {
+		if EvalEqual(&values[i], v) {
+			return true
+		}
+	}
+	return false
+}
+
+// subset reports whether every value of a is one of b
+func subset(a, b []ValueNode) bool {
+	for i := range a {
+		if !containsValue(b, &a[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// disjoint reports whether no fact value can satisfy both constraints
+func (c *leafConstraint) disjoint(o *leafConstraint) bool {
+	switch {
+	case c.interval != nil && o.interval != nil:
+		return !c.interval.overlaps(o.interval)
+	case c.interval != nil || o.interval != nil:
+		set, interval := c, o.interval
+		if c.interval != nil {
+			set, interval = o, c.interval
+		}
+		if set.exclude {
+			return false
+		}
+		for _, v := range set.values {
+			if v.IsNumber() && interval.contains(v.Number) {
+				return false
+			}
+		}
+		return true
+	case !c.exclude && !o.exclude:
+		for i := range c.values {
+			if containsValue(o.values, &c.values[i]) {
+				return false
+			}
+		}
+		return true
+	case !c.exclude:
+		return subset(c.values, o.values)
+	case !o.exclude:
+		return subset(o.values, c.values)
+	default:
+		return false
+	}
+}
+
+// exhaustive reports whether every fact value satisfies at least one of the constraints
+func (c *leafConstraint) exhaustive(o *leafConstraint) bool {
+	switch {
+	case c.interval != nil && o.interval != nil:
+		return c.interval.covers(o.interval)
+	case c.interval != nil || o.interval != nil:
+		return false
+	case c.exclude && o.exclude:
+		for i := range c.values {
+			if containsValue(o.values, &c.values[i]) {
+				return false
+			}
+		}
+		return true
+	case c.exclude:
+		return subset(c.values, o.values)
+	case o.exclude:
+		return subset(o.values, c.values)
+	default:
+		return false
+	}
+}
+
+// lintContradictions walks the condition tree of a rule, comparing the leaf conditions of each group pairwise
+func lintContradictions(rule *Rule) []LintIssue {
+	var issues []LintIssue
+	var walk func(c *Condition, path string)
+	check := func(group []*Condition, operator, path string) {
+		for i := 0; i < len(group); i++ {
+			a := group[i]
+			if a.IsBooleanOperator() || a.IsConditionReference() || a.Fact == "" {
+				continue
+			}
+			ca := constraintFor(a)
+			if ca == nil {
+				continue
+			}
+			for j := i + 1; j < len(group); j++ {
+				b := group[j]
+				if b.IsBooleanOperator() || b.Fact != a.Fact || b.Path != a.Path || fmt.Sprint(a.Params) != fmt.Sprint(b.Params) {
+					continue
+				}
+				cb := constraintFor(b)
+				if cb == nil {
+					continue
+				}
+				paths := []string{fmt.Sprintf("%s%s[%d]", path, operator, i), fmt.Sprintf("%s%s[%d]", path, operator, j)}
+				if operator == "all" && ca.disjoint(cb) {
+					issues = append(issues, LintIssue{
+						Rule:     rule.Name,
+						Analyzer: contradictionAnalyzerName,
+						Message:  fmt.Sprintf("conditions on fact %q can never both pass", a.Fact),
+						Paths:    paths,
+					})
+				}
+				if operator == "any" && ca.exhaustive(cb) {
+					issues = append(issues, LintIssue{
+						Rule:     rule.Name,
+						Analyzer: contradictionAnalyzerName,
+						Message:  fmt.Sprintf("conditions on fact %q always pass, making the rest of the group dead", a.Fact),
+						Paths:    paths,
+					})
+				}
+			}
+		}
+	}
+	walk = func(c *Condition, path string) {
+		if c == nil {
+			return
+		}
+		if len(c.All) > 0 {
+			check(c.All, "all", path)
+			for i, child := range c.All {
+				walk(child, fmt.Sprintf("%sall[%d].", path, i))
+			}
+		}
+		if len(c.Any) > 0 {
+			// A group needing several true conditions can still fail when two of them always pass together
+			if c.AtLeast <= 1 {
+				check(c.Any, "any", path)
+			}
+			for i, child := range c.Any {
+				walk(child, fmt.Sprintf("%sany[%d].", path, i))
+			}
+		}
+		// The children of a 'none' group are alternatives like those of 'any', but since the group passes when
+		// they all fail, neither finding of the contradiction check applies to them
+		for i, child := range c.None {
+			walk(child, fmt.Sprintf("%snone[%d].", path, i))
+		}
+		// A 'mostOf' group tolerates failing conditions, so neither finding applies to its children either
+		for i, child := range c.MostOf {
+			walk(child, fmt.Sprintf("%smostOf[%d].", path, i))
+		}
+		walk(c.Not, path+"not.")
+	}
+	walk(&rule.Conditions, "")
+	return issues
+}
@@ -0,0 +1,91 @@
+package rulesengine
+
+import (
+	"testing"
+)
+
+func TestEngineLint(t *testing.T) {
+	testCases := []struct {
+		name       string
+		conditions string
+		paths      []string
+	}{
+		{"equality contradiction", `{"all": [
+			{"fact": "status", "operator": "equal", "value": "active"},
+			{"fact": "status", "operator": "equal", "value": "closed"}
+		]}`, []string{"all[0]", "all[1]"}},
+		{"compatible equality", `{"all": [
+			{"fact": "status", "operator": "equal", "value": "active"},
+			{"fact": "status", "operator": "notEqual", "value": "closed"}
+		]}`, nil},
+		{"numeric range contradiction", `{"all": [
+			{"fact": "age", "operator": "lessThan", "value": 18},
+			{"fact": "age", "operator": "greaterThanInclusive", "value": 18}
+		]}`, []string{"all[0]", "all[1]"}},
+		{"overlapping numeric ranges", `{"all": [
+			{"fact": "age", "operator": "lessThanInclusive", "value": 18},
+			{"fact": "age", "operator": "greaterThanInclusive", "value": 18}
+		]}`, nil},
+		{"equality outside range", `{"all": [
+			{"fact": "age", "operator": "equal", "value": 12},
+			{"fact": "age", "operator": "gt", "value": 18}
+		]}`, []string{"all[0]", "all[1]"}},
+		{"in list excluded by notIn", `{"all": [
+			{"fact": "country", "operator": "in", "value": ["CH", "DE"]},
+			{"fact": "country", "operator": "notIn", "value": ["CH", "DE", "AT"]}
+		]}`, []string{"all[0]", "all[1]"}},
+		{"disjoint in lists nested", `{"any": [{"all": [
+			{"fact": "country", "operator": "in", "value": ["CH", "DE"]},
+			{"fact": "country", "operator": "in", "value": ["AT"]}
+		]}]}`, []string{"any[0].all[0]", "any[0].all[1]"}},
+		{"numeric range tautology", `{"any": [
+			{"fact": "age", "operator": "lessThan", "value": 18},
+			{"fact": "age", "operator": "greaterThanInclusive", "value": 18},
+			{"fact": "name", "operator": "equal", "value": "x"}
+		]}`, []string{"any[0]", "any[1]"}},
+		{"equality tautology", `{"any": [
+			{"fact": "status", "operator": "equal", "value": "active"},
+			{"fact": "status", "operator": "notEqual", "value": "active"}
+		]}`, []string{"any[0]", "any[1]"}},
+		{"notIn lists tautology", `{"any": [
+			{"fact": "country", "operator": "notIn", "value": ["CH"]},
+			{"fact": "country", "operator": "notIn", "value": ["DE"]}
+		]}`, []string{"any[0]", "any[1]"}},
+		{"transformed value", `{"all": [
+			{"fact": "email", "operator": "equal", "value": "A@X.COM"},
+			{"fact": "email", "operator": "equal", "value": "a@x.com", "transforms": ["lower"]}
+		]}`, nil},
+		{"transformed tautology", `{"any": [
+			{"fact": "email", "operator": "equal", "value": "a@x.com", "transforms": ["lower"]},
+			{"fact": "email", "operator": "notEqual", "value": "a@x.com"}
+		]}`, nil},
+		{"fresh fact", `{"all": [
+			{"fact": "counter", "operator": "equal", "value": 1},
+			{"fact": "counter", "operator": "equal", "value": 2, "freshFact": true}
+		]}`, nil},
+		{"different facts", `{"all": [
+			{"fact": "a", "operator": "equal", "value": 1},
+			{"fact": "b", "operator": "equal", "value": 2}
+		]}`, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newTestEngine(t, `{"name": "lint", "conditions": `+tc.conditions+`, "event": {"type": "lint"}}`, nil)
+			issues := engine.Lint()
+			if tc.paths == nil {
+				if len(issues) != 0 {
+					t.Errorf("Expected no issues, got %+v", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("Expected 1 issue, got %+v", issues)
+			}
+			issue := issues[0]
+			if issue.Rule != "lint" || issue.Paths[0] != tc.paths[0] || issue.Paths[1] != tc.paths[1] {
+				t.Errorf("Expected issue on rule 'lint' at %v, got %+v", tc.paths, issue)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package rulesengine
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 // Operator defines a function that compares two ValueNodes and returns a boolean result.
@@ -10,6 +12,35 @@ type Operator struct {
 	Name               string
 	Callback           func(a, b *ValueNode) bool
 	FactValueValidator func(factValue *ValueNode) bool
+	// ValueValidator optionally checks the condition value the operator is used with.
+	// Rules whose literal values fail it are rejected when added to the engine.
+	ValueValidator func(value *ValueNode) bool
+	// ValueCheck optionally checks the condition value on every evaluation, failing the condition with its error,
+	// e.g. for regular expressions that do not compile
+	ValueCheck func(value *ValueNode) error
+	Metadata   *OperatorMetadata
+	// Description optionally explains the operator to rule authors, see Engine.ListOperators
+	Description string
+	// MultiFactCallback is set for operators created with NewMultiFactOperator.
+	// It receives the resolved values of the condition's facts, in order, and the condition value.
+	MultiFactCallback func(facts []*ValueNode, value *ValueNode) bool
+	// DetailCallback is set for operators created with NewDetailOperator.
+	// Besides the outcome it returns what matched, e.g. regex capture groups, recorded as the condition's MatchDetail.
+	DetailCallback func(a, b *ValueNode) (bool, interface{})
+	// ErrorCallback is set for operators created with NewOperatorE. Its error fails the condition, e.g. for malformed
+	// data the operator cannot compare, instead of evaluating it as false.
+	ErrorCallback func(a, b *ValueNode) (bool, error)
+}
+
+// OperatorMetadata describes the operand types an operator expects.
+// It is optional and used to describe the engine to rule authoring tools.
+// Type hints use the DataType names ("number", "string", ...) or "any".
+type OperatorMetadata struct {
+	FactType  string `json:"factType"`
+	ValueType string `json:"valueType"`
+	Arity     int    `json:"arity"`
+	// MultiFact is set for multi-fact operators, whose Arity is the number of facts they compare (0 for any number)
+	MultiFact bool `json:"multiFact,omitempty"`
 }
 
 // NewOperator adds a new operator to the engine.
@@ -33,11 +64,149 @@ func NewOperator(name string, cb func(a, b *ValueNode) bool, factValueValidator
 	}, nil
 }
 
+// NewOperatorE creates an operator whose callback can fail, e.g. on a date it cannot parse. Conditions using it fail
+// with the error, which takes the rule's error path like other evaluation errors.
+// Params:
+// - name: The name of the operator.
+// - cb: The operator function, returning the outcome or an error.
+// - factValueValidator: Optional validator for the fact value.
+func NewOperatorE(name string, cb func(a, b *ValueNode) (bool, error), factValueValidator func(factValue *ValueNode) bool) (*Operator, error) {
+	if name == "" {
+		return nil, errors.New("Missing operator name")
+	}
+	if cb == nil {
+		return nil, errors.New("Missing operator callback")
+	}
+	if factValueValidator == nil {
+		factValueValidator = func(factValue *ValueNode) bool { return true }
+	}
+	return &Operator{
+		Name:               name,
+		ErrorCallback:      cb,
+		FactValueValidator: factValueValidator,
+	}, nil
+}
+
+// NewDetailOperator creates an operator that reports what matched along with its outcome.
+// The detail is recorded on the condition result and can be referenced from event params with
+// {"match": "<conditionName>.<path>"}, e.g. "email.groups.1" for the first capture group of a regex.
+// Params:
+// - name: The name of the operator.
+// - cb: The operator function, returning the outcome and the match detail (nil when there is none).
+// - factValueValidator: Optional validator for the fact value.
+func NewDetailOperator(name string, cb func(a, b *ValueNode) (bool, interface{}), factValueValidator func(factValue *ValueNode) bool) (*Operator, error) {
+	if name == "" {
+		return nil, errors.New("Missing operator name")
+	}
+	if cb == nil {
+		return nil, errors.New("Missing operator callback")
+	}
+	if factValueValidator == nil {
+		factValueValidator = func(factValue *ValueNode) bool { return true }
+	}
+	return &Operator{
+		Name:               name,
+		DetailCallback:     cb,
+		FactValueValidator: factValueValidator,
+	}, nil
+}
+
+// NewMultiFactOperator creates an operator for conditions declaring several facts, e.g.
+// {"facts": ["order.itemIds", "catalog.ids"], "operator": "subsetOf"}.
+// Params:
+// - name: The name of the operator.
+// - arity: The number of facts the operator compares, 0 to accept any number.
+// - cb: The operator function, receiving the fact values in the order the condition declares them.
+func NewMultiFactOperator(name string, arity int, cb func(facts []*ValueNode, value *ValueNode) bool) (*Operator, error) {
+	if name == "" {
+		return nil, errors.New("Missing operator name")
+	}
+	if cb == nil {
+		return nil, errors.New("Missing operator callback")
+	}
+	if arity < 0 {
+		return nil, errors.New("Operator arity must not be negative")
+	}
+	return &Operator{
+		Name:              name,
+		MultiFactCallback: cb,
+		Metadata:          &OperatorMetadata{FactType: "any", ValueType: "any", Arity: arity, MultiFact: true},
+	}, nil
+}
+
+// IsMultiFact reports whether the operator compares several facts
+func (o *Operator) IsMultiFact() bool {
+	return o.MultiFactCallback != nil
+}
+
+// validateFactCount checks the number of facts of a condition against a multi-fact operator's arity
+func (o *Operator) validateFactCount(count int) error {
+	if o.Metadata != nil && o.Metadata.Arity > 0 && count != o.Metadata.Arity {
+		return fmt.Errorf("operator %q expects %d facts, got %d", o.Name, o.Metadata.Arity, count)
+	}
+	return nil
+}
+
 // Evaluate takes the fact result and compares it to the condition 'value' using the callback function.
 // Params:
 // - a: The fact value.
 // - b: The condition value.
-// Returns true if the condition is met, false otherwise.
+// Returns true if the condition is met, false otherwise, also when the operator fails.
+// Multi-fact operators always return false.
 func (o *Operator) Evaluate(a, b *ValueNode) bool {
-	return o.FactValueValidator(a) && o.Callback(a, b)
+	result, _, _ := o.evaluateDetail(a, b)
+	return result
+}
+
+// EvaluateE evaluates the operator like Evaluate, returning the error of operators created with NewOperatorE.
+// Operators that cannot fail never return an error.
+func (o *Operator) EvaluateE(a, b *ValueNode) (bool, error) {
+	result, _, err := o.evaluateDetail(a, b)
+	return result, err
+}
+
+// evaluateDetail evaluates the operator like EvaluateE, also returning the match detail of detail operators
+func (o *Operator) evaluateDetail(a, b *ValueNode) (bool, interface{}, error) {
+	if o.FactValueValidator != nil && !o.FactValueValidator(a) {
+		return false, nil, nil
+	}
+	switch {
+	case o.ErrorCallback != nil:
+		result, err := o.ErrorCallback(a, b)
+		if err != nil {
+			return false, nil, err
+		}
+		return result, nil, nil
+	case o.DetailCallback != nil:
+		result, detail := o.DetailCallback(a, b)
+		return result, detail, nil
+	case o.Callback != nil:
+		return o.Callback(a, b), nil, nil
+	}
+	return false, nil, nil
+}
+
+// ValidateValue reports whether the condition value can be used with the operator.
+// Operators without a ValueValidator accept any value.
+func (o *Operator) ValidateValue(value *ValueNode) bool {
+	return o.ValueValidator == nil || o.ValueValidator(value)
+}
+
+// lookupOperator resolves an operator name, where a "!" prefix negates a registered operator, e.g. "!in", and
+// decorators wrap it, e.g. "everyFact:greaterThan", see OperatorDecorator.
+// Registered names take precedence, so operators such as "!=" are used as is.
+// Returns the operator, whether its result must be negated and whether it was found.
+func lookupOperator(operators map[string]Operator, decorators map[string]OperatorDecorator, name string) (Operator, bool, bool) {
+	if op, ok := operators[name]; ok {
+		return op, false, true
+	}
+	if base, ok := strings.CutPrefix(name, "!"); ok && base != "" {
+		if op, ok := operators[base]; ok {
+			return op, true, ok
+		}
+		op, ok := decoratedOperator(operators, decorators, base)
+		return op, true, ok
+	}
+	op, ok := decoratedOperator(operators, decorators, name)
+	return op, false, ok
 }
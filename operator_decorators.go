@@ -0,0 +1,167 @@
+package rulesengine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// OperatorDecorator wraps the operator named after it in a decorated operator name, e.g. "everyFact" in
+// "everyFact:greaterThan". next evaluates the wrapped operator, which may be decorated itself, e.g. "someFact:not:equal".
+type OperatorDecorator func(a, b *ValueNode, next func(a, b *ValueNode) (bool, error)) (bool, error)
+
+// DefaultOperatorDecorators returns the built-in operator decorators by name:
+// - everyFact, someFact: every or some element of an array fact passes the operator; false for other facts.
+// - everyValue, someValue: the fact passes the operator with every or some element of an array value; false for other values.
+// - not: the operator does not pass.
+func DefaultOperatorDecorators() map[string]OperatorDecorator {
+	return map[string]OperatorDecorator{
+		"everyFact":  everyFactDecorator,
+		"someFact":   someFactDecorator,
+		"everyValue": everyValueDecorator,
+		"someValue":  someValueDecorator,
+		"not":        notDecorator,
+	}
+}
+
+// defaultOperatorDecorators are the built-in operator decorators used by Condition.Evaluate
+var defaultOperatorDecorators = DefaultOperatorDecorators()
+
+func everyFactDecorator(a, b *ValueNode, next func(a, b *ValueNode) (bool, error)) (bool, error) {
+	if !a.IsArray() {
+		return false, nil
+	}
+	for i := range a.Array {
+		if result, err := next(&a.Array[i], b); err != nil || !result {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func someFactDecorator(a, b *ValueNode, next func(a, b *ValueNode) (bool, error)) (bool, error) {
+	if !a.IsArray() {
+		return false, nil
+	}
+	for i := range a.Array {
+		if result, err := next(&a.Array[i], b); err != nil || result {
+			return result, err
+		}
+	}
+	return false, nil
+}
+
+func everyValueDecorator(a, b *ValueNode, next func(a, b *ValueNode) (bool, error)) (bool, error) {
+	if !b.IsArray() {
+		return false, nil
+	}
+	for i := range b.Array {
+		if result, err := next(a, &b.Array[i]); err != nil || !result {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func someValueDecorator(a, b *ValueNode, next func(a, b *ValueNode) (bool, error)) (bool, error) {
+	if !b.IsArray() {
+		return false, nil
+	}
+	for i := range b.Array {
+		if result, err := next(a, &b.Array[i]); err != nil || result {
+			return result, err
+		}
+	}
+	return false, nil
+}
+
+func notDecorator(a, b *ValueNode, next func(a, b *ValueNode) (bool, error)) (bool, error) {
+	result, err := next(a, b)
+	return !result && err == nil, err
+}
+
+// decoratedOperator resolves a decorated operator name such as "everyFact:greaterThan": the last segment names a
+// registered operator and the segments before it registered decorators, applied from the innermost outwards.
+// Multi-fact operators cannot be decorated.
+func decoratedOperator(operators map[string]Operator, decorators map[string]OperatorDecorator, name string) (Operator, bool) {
+	segments := strings.Split(name, ":")
+	if len(segments) < 2 {
+		return Operator{}, false
+	}
+	base, ok := operators[segments[len(segments)-1]]
+	if !ok || base.IsMultiFact() {
+		return Operator{}, false
+	}
+	next := func(a, b *ValueNode) (bool, error) {
+		if base.ValueCheck != nil {
+			if err := base.ValueCheck(b); err != nil {
+				return false, err
+			}
+		}
+		return base.EvaluateE(a, b)
+	}
+	for i := len(segments) - 2; i >= 0; i-- {
+		decorator, ok := decorators[segments[i]]
+		if !ok {
+			return Operator{}, false
+		}
+		inner := next
+		next = func(a, b *ValueNode) (bool, error) {
+			return decorator(a, b, inner)
+		}
+	}
+	return Operator{Name: name, ErrorCallback: next}, true
+}
+
+// decoratorRegistry holds the operator decorators of an engine; the map is replaced on every change, so readers need no lock
+type decoratorRegistry struct {
+	mu      sync.Mutex
+	current atomic.Pointer[map[string]OperatorDecorator]
+}
+
+// load returns the current decorators, the map must not be modified
+func (r *decoratorRegistry) load() map[string]OperatorDecorator {
+	if m := r.current.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// store adds or replaces a decorator
+func (r *decoratorRegistry) store(name string, decorator OperatorDecorator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.load()
+	next := make(map[string]OperatorDecorator, len(current)+1)
+	for n, existing := range current {
+		next[n] = existing
+	}
+	next[name] = decorator
+	r.current.Store(&next)
+}
+
+// AddOperatorDecorator registers a decorator for decorated operator names, e.g. "allPositive" for
+// "allPositive:greaterThan", replacing a decorator of the same name, the built-in ones included.
+// Params:
+// - name: The name operators are decorated with, it must not contain ":".
+// - decorator: The decorator.
+// Returns an error if the name is empty or contains ":", or the decorator is nil.
+func (e *Engine) AddOperatorDecorator(name string, decorator OperatorDecorator) error {
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("engine: invalid operator decorator name %q", name)
+	}
+	if decorator == nil {
+		return fmt.Errorf("engine: operator decorator %s is nil", name)
+	}
+	Debug(fmt.Sprintf("engine::addOperatorDecorator name:%s", name))
+	e.decorators.store(name, decorator)
+	e.configVersion.Add(1)
+	return nil
+}
+
+// OperatorDecorators returns a snapshot of the registered operator decorators by name.
+// The map is shared and must not be modified, use AddOperatorDecorator instead.
+func (e *Engine) OperatorDecorators() map[string]OperatorDecorator {
+	return e.decorators.load()
+}
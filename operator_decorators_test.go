@@ -0,0 +1,106 @@
+package rulesengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOperatorDecorators(t *testing.T) {
+	testCases := []struct {
+		operator string
+		fact     string
+		value    string
+		want     bool
+	}{
+		{"everyFact:greaterThan", `[5, 6, 7]`, `4`, true},
+		{"everyFact:greaterThan", `[5, 1]`, `4`, false},
+		{"everyFact:greaterThan", `[]`, `4`, true},
+		{"everyFact:greaterThan", `5`, `4`, false},
+		{"someFact:equal", `["a", "b"]`, `"b"`, true},
+		{"someFact:equal", `["a", "b"]`, `"c"`, false},
+		{"everyValue:lessThan", `3`, `[4, 5]`, true},
+		{"everyValue:lessThan", `3`, `[4, 2]`, false},
+		{"someValue:startsWith", `"gold-1"`, `["silver", "gold"]`, true},
+		{"someValue:startsWith", `"gold-1"`, `"gold"`, false},
+		{"not:equal", `1`, `2`, true},
+		{"someFact:not:equal", `["a", "a"]`, `"a"`, false},
+		{"someFact:not:equal", `["a", "b"]`, `"a"`, true},
+		{"!everyFact:greaterThan", `[5, 1]`, `4`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %s %s", tc.fact, tc.operator, tc.value), func(t *testing.T) {
+			engine := newTestEngine(t, fmt.Sprintf(`{
+				"name": "decorated",
+				"conditions": {"all": [{"fact": "x", "operator": %q, "value": %s}]},
+				"event": {"type": "decorated"}
+			}`, tc.operator, tc.value), nil)
+			res, err := engine.Run(context.Background(), []byte(`{"x": `+tc.fact+`}`))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := len(res.Results) == 1; got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("unknown decorators are rejected", func(t *testing.T) {
+		engine := NewEngine(nil, nil)
+		rule, err := NewRule(&RuleConfig{Name: "r", Conditions: Condition{All: []*Condition{
+			{Fact: "x", Operator: "eachFact:equal", Value: ValueNode{Type: Number, Number: 1}},
+		}}, Event: EventConfig{Type: "r"}})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); !strings.Contains(fmt.Sprint(err), "eachFact:equal") {
+			t.Errorf("Expected the unknown operator to be reported, got %v", err)
+		}
+	})
+
+	t.Run("custom decorators", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "tags",
+			"conditions": {"all": [{"fact": "tags", "operator": "someFact:lowerFact:equal", "value": "vip"}]},
+			"event": {"type": "tags"}
+		}`, &RuleEngineOptions{AllowUnknownOperators: true})
+		if engine.AddOperatorDecorator("a:b", notDecorator) == nil || engine.AddOperatorDecorator("lowerFact", nil) == nil {
+			t.Errorf("Expected invalid names and nil decorators to be rejected")
+		}
+		err := engine.AddOperatorDecorator("lowerFact", func(a, b *ValueNode, next func(a, b *ValueNode) (bool, error)) (bool, error) {
+			return next(&ValueNode{Type: String, String: strings.ToLower(a.String)}, b)
+		})
+		if err != nil {
+			t.Fatalf("Failed to add decorator: %v", err)
+		}
+		res, err := engine.Run(context.Background(), []byte(`{"tags": ["new", "VIP"]}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 {
+			t.Errorf("Expected the decorated operator to pass, got %v", res.FailureResults)
+		}
+	})
+
+	t.Run("errors of the operator fail the condition", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "dates",
+			"conditions": {"all": [{"fact": "dates", "operator": "everyFact:valid", "value": true}]},
+			"event": {"type": "dates"}
+		}`, &RuleEngineOptions{AllowUnknownOperators: true})
+		errInvalid := errors.New("invalid")
+		if err := engine.AddOperatorE("valid", func(a, b *ValueNode) (bool, error) {
+			if a.String == "" {
+				return false, errInvalid
+			}
+			return true, nil
+		}); err != nil {
+			t.Fatalf("Failed to add operator: %v", err)
+		}
+		if _, err := engine.Run(context.Background(), []byte(`{"dates": ["2024-01-01", ""]}`)); !errors.Is(err, errInvalid) {
+			t.Errorf("Expected the operator error, got %v", err)
+		}
+	})
+}
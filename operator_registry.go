@@ -0,0 +1,52 @@
+package rulesengine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// operatorRegistry holds the engine operators in an immutable map that is swapped atomically on every change.
+// Lookups during evaluation are lock-free; mutations copy the map and are serialized by mu.
+type operatorRegistry struct {
+	mu      sync.Mutex
+	current atomic.Pointer[map[string]Operator]
+}
+
+// load returns the current operators, the map must not be modified
+func (r *operatorRegistry) load() map[string]Operator {
+	if m := r.current.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// store adds or replaces an operator
+func (r *operatorRegistry) store(op Operator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.load()
+	next := make(map[string]Operator, len(current)+1)
+	for name, existing := range current {
+		next[name] = existing
+	}
+	next[op.Name] = op
+	r.current.Store(&next)
+}
+
+// remove deletes an operator, returning false if it was not registered
+func (r *operatorRegistry) remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.load()
+	if _, ok := current[name]; !ok {
+		return false
+	}
+	next := make(map[string]Operator, len(current))
+	for n, existing := range current {
+		if n != name {
+			next[n] = existing
+		}
+	}
+	r.current.Store(&next)
+	return true
+}
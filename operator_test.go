@@ -0,0 +1,60 @@
+package rulesengine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// afterDate compares dates in YYYY-MM-DD format, failing on dates it cannot parse
+func afterDate(a, b *ValueNode) (bool, error) {
+	fact, err := time.Parse(time.DateOnly, a.String)
+	if err != nil {
+		return false, err
+	}
+	value, err := time.Parse(time.DateOnly, b.String)
+	if err != nil {
+		return false, err
+	}
+	return fact.After(value), nil
+}
+
+func TestEngineAddOperatorE(t *testing.T) {
+	options := DefaultRuleEngineOptions()
+	options.AllowUnknownOperators = true
+	engine := newTestEngine(t, `{
+		"name": "late",
+		"conditions": {"all": [{"fact": "shipped", "operator": "afterDate", "value": "2024-01-31"}]},
+		"event": {"type": "late"}
+	}`, options)
+	if err := engine.AddOperatorE("afterDate", afterDate); err != nil {
+		t.Fatalf("Failed to add operator: %v", err)
+	}
+	if err := engine.AddOperatorE("afterDate", nil); err == nil {
+		t.Errorf("Expected a nil callback to be rejected")
+	}
+
+	res, err := engine.Run(context.Background(), []byte(`{"shipped": "2024-02-03"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Errorf("Expected the rule to pass, got %v", res.FailureResults)
+	}
+
+	_, err = engine.Run(context.Background(), []byte(`{"shipped": "03/02/2024"}`))
+	var parseErr *time.ParseError
+	if err == nil || !errors.As(err, &parseErr) || !strings.Contains(err.Error(), "shipped afterDate > operator afterDate") {
+		t.Errorf("Expected the operator error with the fact and operator, got %v", err)
+	}
+
+	op, _ := NewOperatorE("afterDate", afterDate, nil)
+	if op.Evaluate(&ValueNode{Type: String, String: "x"}, &ValueNode{Type: String, String: "2024-01-31"}) {
+		t.Errorf("Expected a failing operator to evaluate as false")
+	}
+	if _, err := op.EvaluateE(&ValueNode{Type: String, String: "x"}, &ValueNode{Type: String, String: "2024-01-31"}); err == nil {
+		t.Errorf("Expected EvaluateE to return the error")
+	}
+}
@@ -0,0 +1,187 @@
+// Package optest provides helpers for testing custom operators: table tests declaring values as plain Go values,
+// and fuzz helpers asserting an operator never panics.
+package optest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	rulesengine "github.com/nimbit-software/gojson-rules-engine"
+	"github.com/tidwall/gjson"
+)
+
+// Case is a table test case of an operator. Fact and Value are plain Go values converted with rulesengine.NewValue,
+// e.g. 42, "a", []interface{}{1, 2} or map[string]interface{}{"a": 1}; nil is Null.
+type Case struct {
+	Name string
+	// Fact is the fact value; for multi-fact operators a []interface{} holding the value of every fact
+	Fact interface{}
+	// Value is the condition value; for operators taking a fact reference, e.g. inFact, the value of the referenced fact
+	Value interface{}
+	// Want is the expected outcome of the operator
+	Want bool
+	// Rejected expects the operator to reject Value, with its ValueValidator or ValueCheck, so rules using it are
+	// refused or fail; Want is not checked then
+	Rejected bool
+	// Err expects an operator created with rulesengine.NewOperatorE to fail; Want is not checked then
+	Err bool
+}
+
+// Run runs every case as a subtest of t, named after the case or its values.
+// Params:
+// - t: The test.
+// - op: The operator under test, e.g. created with rulesengine.NewOperator.
+// - cases: The cases.
+func Run(t *testing.T, op rulesengine.Operator, cases []Case) {
+	t.Helper()
+	for i, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("%d %v %s %v", i, c.Fact, op.Name, c.Value)
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Helper()
+			value := mustValue(t, c.Value)
+			if rejected := valueRejected(&op, value); rejected != c.Rejected {
+				t.Fatalf("operator %s: expected value %v to be rejected: %v, got %v", op.Name, c.Value, c.Rejected, rejected)
+			}
+			if c.Rejected {
+				return
+			}
+			got, err := evaluate(t, &op, c.Fact, value)
+			if (err != nil) != c.Err {
+				t.Fatalf("operator %s: %v %s %v: expected an error: %v, got %v", op.Name, c.Fact, op.Name, c.Value, c.Err, err)
+			}
+			if !c.Err && got != c.Want {
+				t.Errorf("operator %s: %v %s %v: expected %v, got %v", op.Name, c.Fact, op.Name, c.Value, c.Want, got)
+			}
+		})
+	}
+}
+
+// mustValue converts a plain Go value, failing the test when it cannot be converted
+func mustValue(t *testing.T, value interface{}) *rulesengine.ValueNode {
+	t.Helper()
+	node, err := rulesengine.NewValue(value)
+	if err != nil {
+		t.Fatalf("cannot convert %v: %v", value, err)
+	}
+	return node
+}
+
+// valueRejected reports whether the operator rejects the condition value when rules are added or evaluated.
+// The value of an operator taking a fact reference is the referenced fact's value, so only its ValueCheck applies.
+func valueRejected(op *rulesengine.Operator, value *rulesengine.ValueNode) bool {
+	if (op.Metadata == nil || op.Metadata.ValueType != "fact") && !op.ValidateValue(value) {
+		return true
+	}
+	return op.ValueCheck != nil && op.ValueCheck(value) != nil
+}
+
+// evaluate evaluates the operator, passing the values of a []interface{} fact to multi-fact operators
+func evaluate(t *testing.T, op *rulesengine.Operator, fact interface{}, value *rulesengine.ValueNode) (bool, error) {
+	t.Helper()
+	if !op.IsMultiFact() {
+		return op.EvaluateE(mustValue(t, fact), value)
+	}
+	facts, ok := fact.([]interface{})
+	if !ok {
+		t.Fatalf("operator %s: multi-fact operators expect a []interface{} fact, got %T", op.Name, fact)
+	}
+	values := make([]*rulesengine.ValueNode, len(facts))
+	for i, f := range facts {
+		values[i] = mustValue(t, f)
+	}
+	return op.MultiFactCallback(values, value), nil
+}
+
+// RandomValue returns a random value of any type, with arrays and objects nested up to depth levels.
+func RandomValue(r *rand.Rand, depth int) *rulesengine.ValueNode {
+	kinds := 4
+	if depth > 0 {
+		kinds = 6
+	}
+	switch r.Intn(kinds) {
+	case 0:
+		return &rulesengine.ValueNode{Type: rulesengine.Null}
+	case 1:
+		return &rulesengine.ValueNode{Type: rulesengine.Bool, Bool: r.Intn(2) == 1}
+	case 2:
+		numbers := []float64{0, -1, 1, 0.5, 1e308, -1e308, float64(r.Intn(100))}
+		return &rulesengine.ValueNode{Type: rulesengine.Number, Number: numbers[r.Intn(len(numbers))]}
+	case 3:
+		strs := []string{"", "a", "a.b", "(", "[", "ü", "^.*$", fmt.Sprint(r.Intn(100))}
+		return &rulesengine.ValueNode{Type: rulesengine.String, String: strs[r.Intn(len(strs))]}
+	case 4:
+		array := make([]rulesengine.ValueNode, r.Intn(4))
+		for i := range array {
+			array[i] = *RandomValue(r, depth-1)
+		}
+		return &rulesengine.ValueNode{Type: rulesengine.Array, Array: array}
+	default:
+		object := map[string]rulesengine.ValueNode{}
+		for i := r.Intn(4); i > 0; i-- {
+			object[fmt.Sprintf("k%d", r.Intn(4))] = *RandomValue(r, depth-1)
+		}
+		return &rulesengine.ValueNode{Type: rulesengine.Object, Object: object}
+	}
+}
+
+// NoPanic evaluates the operator with n pairs of random fact and condition values, failing the test when it panics.
+// Multi-fact operators receive one to three random facts. The seed makes failures reproducible.
+func NoPanic(t *testing.T, op rulesengine.Operator, n int, seed int64) {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		var facts []*rulesengine.ValueNode
+		for j := 1 + r.Intn(3); j > 0; j-- {
+			facts = append(facts, RandomValue(r, 2))
+		}
+		if err := call(&op, facts, RandomValue(r, 2)); err != nil {
+			t.Fatalf("operator %s (seed %d, pair %d): %v", op.Name, seed, i, err)
+		}
+	}
+}
+
+// Fuzz runs a fuzz test of the operator with JSON encoded fact and condition values, failing when it panics.
+// Use it from a fuzz target: func FuzzMyOperator(f *testing.F) { optest.Fuzz(f, myOperator) }.
+// Inputs that are not valid JSON are skipped.
+func Fuzz(f *testing.F, op rulesengine.Operator) {
+	for _, seed := range [][2]string{{`1`, `1`}, {`"a"`, `"a"`}, {`[1, "a"]`, `[1, 2]`}, {`{"a": {"b": 1}}`, `"a.b"`}, {`null`, `true`}} {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, fact, value string) {
+		if !gjson.Valid(fact) || !gjson.Valid(value) {
+			t.Skip()
+		}
+		facts := []*rulesengine.ValueNode{rulesengine.NewValueFromGjson(gjson.Parse(fact))}
+		if err := call(&op, facts, rulesengine.NewValueFromGjson(gjson.Parse(value))); err != nil {
+			t.Fatalf("operator %s: %v", op.Name, err)
+		}
+	})
+}
+
+// call evaluates the operator with the first fact, or all facts for multi-fact operators, recovering panics
+func call(op *rulesengine.Operator, facts []*rulesengine.ValueNode, value *rulesengine.ValueNode) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panicked with facts %v and value %v: %v", raw(facts), value.Raw(), r)
+		}
+	}()
+	if op.IsMultiFact() {
+		op.MultiFactCallback(facts, value)
+	} else {
+		op.Evaluate(facts[0], value)
+	}
+	return nil
+}
+
+// raw returns the plain values of the facts, for messages
+func raw(facts []*rulesengine.ValueNode) []interface{} {
+	values := make([]interface{}, len(facts))
+	for i, f := range facts {
+		values[i] = f.Raw()
+	}
+	return values
+}
@@ -0,0 +1,71 @@
+package rulesengine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// pathShapeMismatch explains why a fact path was not found in the document when the path does not fit its shape:
+// a segment queries into a scalar, an array is indexed with a key or past its end, or '#' is applied to a non-array.
+// Returns nil when the path fits the document and the fact is simply absent, and for paths using gjson
+// modifiers, queries or wildcards, which are not analyzed.
+func pathShapeMismatch(doc gjson.Result, path string) *PathShapeMismatchError {
+	if path == "" || strings.ContainsAny(path, `\|@*?!=<>%()`) {
+		return nil
+	}
+	current := doc
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		valid := strings.Join(segments[:i], ".")
+		mismatch := func(reason string, args ...interface{}) *PathShapeMismatchError {
+			return &PathShapeMismatchError{Path: path, ValidPath: valid, Reason: fmt.Sprintf(reason, args...)}
+		}
+		switch {
+		case current.IsArray():
+			if segment == "#" {
+				// Counts and wildcards over an existing array are resolved by gjson
+				return nil
+			}
+			index, err := strconv.Atoi(segment)
+			if err != nil {
+				return mismatch("key %q on an array", segment)
+			}
+			elements := current.Array()
+			if index < 0 || index >= len(elements) {
+				return mismatch("index %d out of range for an array of length %d", index, len(elements))
+			}
+			current = elements[index]
+		case current.IsObject():
+			if segment == "#" {
+				return mismatch("'#' on an object")
+			}
+			child := current.Get(segment)
+			if !child.Exists() {
+				return nil
+			}
+			current = child
+		default:
+			return mismatch("%q on a %s value", segment, jsonTypeName(current))
+		}
+	}
+	return nil
+}
+
+// jsonTypeName names the JSON type of a gjson result
+func jsonTypeName(result gjson.Result) string {
+	switch result.Type {
+	case gjson.String:
+		return "string"
+	case gjson.Number:
+		return "number"
+	case gjson.True, gjson.False:
+		return "boolean"
+	case gjson.Null:
+		return "null"
+	default:
+		return "JSON"
+	}
+}
@@ -0,0 +1,160 @@
+package rulesengine
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PrimeReport describes a warm-up of the engine caches from a corpus of fact samples.
+type PrimeReport struct {
+	Samples int `json:"samples"`
+	// Errors counts the samples whose run failed; their error is not reported
+	Errors int `json:"errors"`
+	// CompileTime is the time spent prioritizing the rules and their condition groups
+	CompileTime time.Duration `json:"compileTimeNs"`
+	// Rules holds the evaluation statistics of every rule evaluated at least once, in priority order
+	Rules []RulePrimeStats `json:"rules"`
+	// FactResolutions is the total number of fact resolutions of the evaluated samples
+	FactResolutions int64 `json:"factResolutions"`
+	// ResultCacheHits counts the samples answered from the result cache, e.g. duplicates within the corpus
+	ResultCacheHits int `json:"resultCacheHits"`
+	// ResultCacheHitRate is ResultCacheHits over Samples, 0 when the result cache is disabled
+	ResultCacheHitRate float64 `json:"resultCacheHitRate"`
+	// ConditionMemoHits is the total number of leaf conditions answered from the condition memo
+	ConditionMemoHits int64 `json:"conditionMemoHits"`
+}
+
+// RulePrimeStats holds the evaluation statistics of a single rule while priming.
+type RulePrimeStats struct {
+	Name            string        `json:"name"`
+	Priority        int           `json:"priority"`
+	Evaluations     int           `json:"evaluations"`
+	AverageDuration time.Duration `json:"averageDurationNs"`
+}
+
+// Prime warms the engine before serving traffic: it compiles all rules and runs every sample without
+// publishing events to handlers, which populates the cross-run caches enabled on the engine.
+// Params:
+// - ctx: The context of the runs, priming stops early when it is cancelled.
+// - samples: Representative raw JSON facts.
+// Returns:
+// - A report of the compile time, per-rule durations, fact resolutions and cache hits.
+func (e *Engine) Prime(ctx context.Context, samples [][]byte) PrimeReport {
+	report := PrimeReport{}
+
+	started := time.Now()
+	rules := e.compileRules()
+	report.CompileTime = time.Since(started)
+
+	timings := &ruleTimings{durations: make(map[*Rule]*ruleTiming)}
+	for _, sample := range samples {
+		if ctx.Err() != nil {
+			break
+		}
+		options := DefaultRunOptions()
+		options.quiet = true
+		options.ruleTimings = timings
+
+		report.Samples++
+		res, err := e.runInternal(ctx, sample, options)
+		if err != nil {
+			report.Errors++
+			continue
+		}
+		if res.Cached {
+			report.ResultCacheHits++
+			continue
+		}
+		report.FactResolutions += res.Stats.FactResolutions
+		report.ConditionMemoHits += res.Stats.ConditionMemoHits
+	}
+	if e.resultCache != nil && report.Samples > 0 {
+		report.ResultCacheHitRate = float64(report.ResultCacheHits) / float64(report.Samples)
+	}
+
+	for _, r := range rules {
+		timing, ok := timings.durations[r]
+		if !ok {
+			continue
+		}
+		report.Rules = append(report.Rules, RulePrimeStats{
+			Name:            r.Name,
+			Priority:        r.Priority,
+			Evaluations:     timing.count,
+			AverageDuration: timing.total / time.Duration(timing.count),
+		})
+	}
+	return report
+}
+
+// compileRules computes the prioritized rule sets and the prioritized condition groups of every rule,
+// returning the rules in priority order
+func (e *Engine) compileRules() []*Rule {
+	var rules []*Rule
+	for _, set := range e.PrioritizeRules() {
+		for _, r := range set {
+			r.compileConditions(r.Conditions.All)
+			r.compileConditions(r.Conditions.Any)
+			r.compileConditions(r.Conditions.None)
+			r.compileConditions(r.Conditions.MostOf)
+			if r.Conditions.Not != nil {
+				r.compileConditions(r.Conditions.Not.All)
+				r.compileConditions(r.Conditions.Not.Any)
+				r.compileConditions(r.Conditions.Not.None)
+				r.compileConditions(r.Conditions.Not.MostOf)
+			}
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// compileConditions computes the prioritized grouping of a condition group and of its nested groups
+func (r *Rule) compileConditions(conditions []*Condition) {
+	if len(conditions) == 0 {
+		return
+	}
+	r.prioritizedConditions(conditions)
+	for _, cond := range conditions {
+		r.compileConditions(cond.All)
+		r.compileConditions(cond.Any)
+		r.compileConditions(cond.None)
+		r.compileConditions(cond.MostOf)
+		if cond.Not != nil {
+			r.compileConditions(cond.Not.All)
+			r.compileConditions(cond.Not.Any)
+			r.compileConditions(cond.Not.None)
+			r.compileConditions(cond.Not.MostOf)
+		}
+	}
+}
+
+// ruleTimings accumulates rule evaluation durations across runs
+type ruleTimings struct {
+	mu        sync.Mutex
+	durations map[*Rule]*ruleTiming
+}
+
+// ruleTiming is the total evaluation duration of a rule and the number of evaluations
+type ruleTiming struct {
+	total time.Duration
+	count int
+}
+
+// record adds an evaluation of r that started at started; a nil receiver ignores it
+func (t *ruleTimings) record(r *Rule, started time.Time) {
+	if t == nil {
+		return
+	}
+	elapsed := time.Since(started)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing, ok := t.durations[r]
+	if !ok {
+		timing = &ruleTiming{}
+		t.durations[r] = timing
+	}
+	timing.total += elapsed
+	timing.count++
+}
@@ -0,0 +1,47 @@
+package rulesengine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEnginePrime(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "adult", "conditions": {"all": [{"fact": "age", "operator": "greaterThanInclusive", "value": 18}]}, "event": {"type": "adult"}}`, &RuleEngineOptions{ResultCacheSize: 8})
+	published := 0
+	if err := engine.bus.Subscribe("success", func(Event, *Almanac, *RuleResult) { published++ }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	report := engine.Prime(context.Background(), [][]byte{[]byte(`{"age": 20}`), []byte(`{"age": 20}`), []byte(`{"age": 12}`), []byte(`{"age":`)})
+	if published != 0 {
+		t.Errorf("Expected no events to be published while priming, got %d", published)
+	}
+	if report.Samples != 4 || report.Errors != 1 {
+		t.Errorf("Expected 4 samples with the truncated one failing, got %+v", report)
+	}
+	if report.ResultCacheHits != 1 || report.ResultCacheHitRate != 0.25 {
+		t.Errorf("Expected the duplicate sample to hit the result cache, got %+v", report)
+	}
+	if report.FactResolutions != 2 {
+		t.Errorf("Expected 2 fact resolutions, got %d", report.FactResolutions)
+	}
+	// The truncated sample is rejected before its rules are evaluated
+	if len(report.Rules) != 1 || report.Rules[0].Name != "adult" || report.Rules[0].Evaluations != 2 {
+		t.Errorf("Expected rule statistics for the evaluated samples, got %+v", report.Rules)
+	}
+
+	// The primed result is served from the cache and events are published again
+	res, err := engine.Run(context.Background(), []byte(`{"age": 20}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !res.Cached {
+		t.Errorf("Expected the primed result to be cached")
+	}
+	if _, err := engine.Run(context.Background(), []byte(`{"age": 30}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if published != 1 {
+		t.Errorf("Expected the event to be published after priming, got %d", published)
+	}
+}
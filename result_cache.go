@@ -0,0 +1,122 @@
+package rulesengine
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// resultCache is a bounded LRU cache of run results with a time to live.
+// Entries are tagged with the engine configuration version they were computed against
+// and are treated as misses once the configuration changed.
+type resultCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type resultCacheEntry struct {
+	key     string
+	version uint64
+	expires time.Time
+	result  *RunResult
+}
+
+// newResultCache creates a cache holding at most capacity results, each valid for ttl (0 for no expiry)
+func newResultCache(capacity int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// get returns the cached result for the key if it exists, has not expired and matches the configuration version
+func (c *resultCache) get(key string, version uint64) (*RunResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*resultCacheEntry)
+	if entry.version != version || (c.ttl > 0 && c.now().After(entry.expires)) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.result, true
+}
+
+// put stores a result, evicting the least recently used entry when the cache is full
+func (c *resultCache) put(key string, version uint64, result *RunResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &resultCacheEntry{key: key, version: version, expires: c.now().Add(c.ttl), result: result}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resultCacheEntry).key)
+	}
+}
+
+// purge removes all entries
+func (c *resultCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+// resultCacheOptions are the run options that change the result of a run, part of the result cache key
+type resultCacheOptions struct {
+	maxFactResolutions, maxConditionEvaluations, maxCachedFactBytes  int64
+	lazyArrayThreshold                                               int
+	ignorePriorityBarriers, memoizeConditions, skipRulesWithoutFacts bool
+	trace, trackFactAccess, checkDeterminism                         bool
+	softDeadline, ruleTimeout                                        time.Duration
+}
+
+// resultCacheKey returns the result cache key of a run: RunOptions.CacheKey, or a hash of the facts, combined with the
+// run options that change the result, so runs with different options never share a result.
+// Runs seeding Values or reading session-cached facts are not cached and return false.
+func resultCacheKey(facts []byte, options *RunOptions) (string, bool) {
+	if options == nil {
+		options = DefaultRunOptions()
+	}
+	if len(options.Values) > 0 || options.session != nil {
+		return "", false
+	}
+	key := options.CacheKey
+	if key == "" {
+		key = fmt.Sprintf("facts:%d", HashString(string(facts)))
+	}
+	budget := newEvaluationBudget(options)
+	return fmt.Sprintf("%s|%+v", key, resultCacheOptions{
+		maxFactResolutions:      budget.maxFactResolutions,
+		maxConditionEvaluations: budget.maxConditionEvaluations,
+		maxCachedFactBytes:      options.MaxCachedFactBytes,
+		lazyArrayThreshold:      options.LazyArrayThreshold,
+		ignorePriorityBarriers:  options.IgnorePriorityBarriers,
+		memoizeConditions:       options.MemoizeConditions,
+		skipRulesWithoutFacts:   options.SkipRulesWithoutFacts,
+		trace:                   options.Trace,
+		trackFactAccess:         options.TrackFactAccess,
+		checkDeterminism:        options.CheckDeterminism,
+		softDeadline:            options.SoftDeadline,
+		ruleTimeout:             options.RuleTimeout,
+	}), true
+}
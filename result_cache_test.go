@@ -0,0 +1,153 @@
+package rulesengine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// inlineScheduler runs every task before returning, so panics reach the run
+type inlineScheduler struct{}
+
+func (inlineScheduler) Go(task func()) {
+	task()
+}
+
+func TestEngineResultCache(t *testing.T) {
+	ruleJSON := `{
+		"name": "cache",
+		"conditions": {"all": [{"fact": "score", "operator": "greaterThan", "value": 10}]},
+		"event": {"type": "cache"}
+	}`
+	calls := 0
+	newEngine := func(t *testing.T) *Engine {
+		engine := newTestEngine(t, ruleJSON, &RuleEngineOptions{ResultCacheSize: 2, ResultCacheTTL: time.Minute})
+		err := engine.AddCalculatedFact("score", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls++
+			return &ValueNode{Type: Number, Number: 20}
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		return engine
+	}
+	run := func(t *testing.T, engine *Engine, facts string, options *RunOptions) *RunResult {
+		t.Helper()
+		res, err := engine.RunWithOptions(context.Background(), []byte(facts), options)
+		if err != nil {
+			t.Fatalf("Expected run to succeed, got error: %v", err)
+		}
+		return res
+	}
+
+	t.Run("Identical facts are served from the cache", func(t *testing.T) {
+		calls = 0
+		engine := newEngine(t)
+		if res := run(t, engine, `{"id": 1}`, nil); res.Cached {
+			t.Errorf("Expected first run not to be cached")
+		}
+		res := run(t, engine, `{"id": 1}`, nil)
+		if !res.Cached || calls != 1 {
+			t.Errorf("Expected second run to be a cache hit, cached=%v calls=%d", res.Cached, calls)
+		}
+		if events := res.Events; len(events) != 1 {
+			t.Errorf("Expected cached result to contain the events, got %d", len(events))
+		}
+		if res := run(t, engine, `{"id": 2}`, nil); res.Cached {
+			t.Errorf("Expected different facts to miss the cache")
+		}
+	})
+
+	t.Run("Explicit cache key", func(t *testing.T) {
+		calls = 0
+		engine := newEngine(t)
+		run(t, engine, `{"id": 1}`, &RunOptions{CacheKey: "request-1"})
+		if res := run(t, engine, `{"id": 2}`, &RunOptions{CacheKey: "request-1"}); !res.Cached {
+			t.Errorf("Expected run with the same cache key to hit the cache")
+		}
+	})
+
+	t.Run("Configuration changes invalidate the cache", func(t *testing.T) {
+		engine := newEngine(t)
+		run(t, engine, `{"id": 1}`, nil)
+		engine.AddOperator("custom", func(a, b *ValueNode) bool { return true })
+		if res := run(t, engine, `{"id": 1}`, nil); res.Cached {
+			t.Errorf("Expected operator change to invalidate the cache")
+		}
+		engine.RemoveRuleByName("cache")
+		if res := run(t, engine, `{"id": 1}`, nil); res.Cached {
+			t.Errorf("Expected rule change to invalidate the cache")
+		}
+	})
+
+	t.Run("Options changing the result miss the cache", func(t *testing.T) {
+		engine := newEngine(t)
+		run(t, engine, `{"id": 1}`, nil)
+		if res := run(t, engine, `{"id": 1}`, DefaultRunOptions()); !res.Cached {
+			t.Errorf("Expected default options to share the result of a run without options")
+		}
+		for name, options := range map[string]*RunOptions{
+			"trace":   {Trace: true},
+			"budget":  {MaxConditionEvaluations: 50},
+			"values":  {Values: map[string]interface{}{"tenant": "a"}},
+			"timeout": {RuleTimeout: time.Second},
+		} {
+			run(t, engine, `{"id": 1}`, options)
+			if res := run(t, engine, `{"id": 1}`, options); res.Cached != (name != "values") {
+				t.Errorf("%s: unexpected cached=%v", name, res.Cached)
+			}
+		}
+		if res := run(t, engine, `{"id": 1}`, &RunOptions{MaxConditionEvaluations: 60}); res.Cached {
+			t.Errorf("Expected a different budget to miss the cache")
+		}
+	})
+
+	t.Run("Cache hits are copies", func(t *testing.T) {
+		engine := newEngine(t)
+		run(t, engine, `{"id": 1}`, nil)
+		hit := run(t, engine, `{"id": 1}`, nil)
+		hit.Results[0].Name = "modified"
+		hit.Results[0].Conditions.All[0].Fact = "modified"
+		hit.Events[0].Type = "modified"
+		hit.Results = nil
+		res := run(t, engine, `{"id": 1}`, nil)
+		if !res.Cached || len(res.Results) != 1 || res.Results[0].Name != "cache" || res.Results[0].Conditions.All[0].Fact != "score" {
+			t.Errorf("Expected the cached result to be unaffected by callers, got %+v", res.Results)
+		}
+		if res.Events[0].Type != "cache" {
+			t.Errorf("Expected the cached events to be unaffected by callers, got %+v", res.Events)
+		}
+	})
+
+	t.Run("Recovered panics are not cached", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, &RuleEngineOptions{ResultCacheSize: 2, Scheduler: inlineScheduler{}})
+		err := engine.AddCalculatedFact("score", func(a *Almanac, params ...interface{}) *ValueNode {
+			panic("boom")
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if res, err := engine.Run(context.Background(), []byte(`{"id": 1}`)); res != nil || err == nil || !strings.Contains(err.Error(), "boom") {
+				t.Errorf("Expected the recovered panic as an error, got %v, %v", res, err)
+			}
+		}
+	})
+
+	t.Run("Entries expire and are evicted", func(t *testing.T) {
+		cache := newResultCache(2, time.Minute)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		cache.put("a", 1, &RunResult{})
+		cache.put("b", 1, &RunResult{})
+		cache.put("c", 1, &RunResult{})
+		if _, ok := cache.get("a", 1); ok {
+			t.Errorf("Expected least recently used entry to be evicted")
+		}
+		now = now.Add(2 * time.Minute)
+		if _, ok := cache.get("c", 1); ok {
+			t.Errorf("Expected expired entry to miss")
+		}
+	})
+}
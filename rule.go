@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"github.com/asaskevich/EventBus"
 	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
 )
 
 // Rule represents a rule in the engine.
@@ -16,9 +20,23 @@ type Rule struct {
 	Name       string
 	Conditions Condition
 	RuleEvent  Event
-	Engine     *Engine
-	bus        EventBus.Bus
-	mu         sync.Mutex
+	// SchemaVersion is the rule schema version the rule was written for, see RuleConfig.SchemaVersion
+	SchemaVersion int
+	// Facts are the rule-local facts, see RuleConfig.Facts
+	Facts map[string]*ValueNode
+	// EventFilter can veto the rule's event, see RuleConfig.EventFilter
+	EventFilter EventFilter
+	Engine      *Engine
+	bus         EventBus.Bus
+	mu          sync.Mutex
+	// conditionSets caches the prioritized grouping of each condition group as positions within the group,
+	// keyed by the registered condition the group's first condition was cloned from.
+	// It is valid for the engine facts version it was computed against, since fact priorities feed into it.
+	conditionSets      map[*Condition]*conditionSetsEntry
+	conditionSetsFacts uint64
+	ephemeral          bool // Set for rules supplied with a single run, see RunOptions.EphemeralRules
+	// noDataProbe caches whether the rule fails without data, see RunOptions.SkipRulesWithoutFacts
+	noDataProbe *noDataProbe
 }
 
 // setPriority sets the priority of the rule
@@ -39,17 +57,42 @@ type EventConfig struct {
 func NewRule(config *RuleConfig) (*Rule, error) {
 	// Validate conditions
 	if err := config.Conditions.Validate(); err != nil {
-		return nil, err
+		return nil, conditionsError(err)
+	}
+	// Rules built in code are written against the current schema
+	schemaVersion := config.SchemaVersion
+	if schemaVersion == 0 {
+		schemaVersion = SchemaVersion
+	}
+	if schemaVersion > SchemaVersion {
+		return nil, &UnsupportedSchemaVersionError{Version: schemaVersion, Supported: SchemaVersion}
 	}
+	// The event is constructed from the validated configuration only, rules never fall back to a placeholder type
+	if config.Event.Type == "" {
+		return nil, errors.New("invalid event config Type must be provided")
+	}
+
 	// Initialize rule with default values
 	rule := &Rule{
-		Name:       config.Name,
-		Priority:   1,
-		Conditions: config.Conditions,
-		RuleEvent: Event{
-			Type: "unknown",
-		},
-		bus: EventBus.New(),
+		Name:          config.Name,
+		Priority:      1,
+		Conditions:    config.Conditions,
+		SchemaVersion: schemaVersion,
+		EventFilter:   config.EventFilter,
+		bus:           EventBus.New(),
+	}
+	rule.setEvent(config.Event)
+
+	// RULE FACTS: Convert the rule-local facts once, at load
+	if len(config.Facts) > 0 {
+		rule.Facts = make(map[string]*ValueNode, len(config.Facts))
+		for path, value := range config.Facts {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("rule fact %s: %w", path, err)
+			}
+			rule.Facts[path] = NewValueFromGjson(gjson.ParseBytes(data))
+		}
 	}
 
 	// RULE PRIORITY: Set the priority if provided
@@ -73,23 +116,17 @@ func NewRule(config *RuleConfig) (*Rule, error) {
 		}
 	}
 
-	// Set the event if the type is provided
-	if config.Event.Type != "" {
-		rule.setEvent(config.Event)
-	} else {
-		return nil, errors.New("invalid event config Type must be provided")
-	}
-
 	return rule, nil
 }
 
-// SetEvent sets the event to emit when the conditions evaluate truthy
+// SetEvent sets the event to emit when the conditions evaluate truthy.
+// The params are copied, so later changes to the configuration do not reach the rule.
 func (r *Rule) setEvent(event EventConfig) {
 	r.RuleEvent = Event{
 		Type: event.Type,
 	}
 	if event.Params != nil {
-		r.RuleEvent.Params = *event.Params
+		r.RuleEvent.Params = copyParams(*event.Params)
 	}
 }
 
@@ -115,7 +152,10 @@ func (r *Rule) GetEngine() *Engine {
 
 // SetEngine sets the engine to run the rules under
 func (r *Rule) SetEngine(engine *Engine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.Engine = engine
+	r.conditionSets = nil
 }
 
 // ToJSON converts the rule to a JSON-friendly structure
@@ -131,6 +171,13 @@ func (r *Rule) ToJSON(stringify bool) (interface{}, error) {
 		"event":      r.RuleEvent,
 		"name":       r.Name,
 	}
+	if len(r.Facts) > 0 {
+		facts := make(map[string]interface{}, len(r.Facts))
+		for path, value := range r.Facts {
+			facts[path] = value.Raw()
+		}
+		props["facts"] = facts
+	}
 	if stringify {
 		jsonStr, err := json.Marshal(props)
 		if err != nil {
@@ -146,56 +193,133 @@ func (r *Rule) ToJSON(stringify bool) (interface{}, error) {
 // - almanac: The almanac containing facts for evaluation.
 // Returns true if the rule's conditions are met, false otherwise.
 func (r *Rule) Evaluate(ctx *ExecutionContext, almanac *Almanac) (*RuleResult, error) {
-	ruleResult := NewRuleResult(r.Conditions, r.RuleEvent, r.Priority, r.Name)
-
-	var result bool
-	var err error
-
-	conditions := map[string][]*Condition{}
+	// Every evaluation works on its own copy of the conditions, so concurrent runs do not share results
+	ruleResult := NewRuleResult(*r.Conditions.clone(), r.RuleEvent, r.Priority, r.Name)
+	ruleResult.Ephemeral = r.ephemeral
+	ruleResult.rule = r
 
-	if ruleResult.Conditions.Any != nil && len(ruleResult.Conditions.Any) > 0 {
-		conditions["any"] = ruleResult.Conditions.Any
+	result, err := r.evaluateRoot(ctx, almanac, &ruleResult.Conditions)
+	if err != nil {
+		return r.handleError(ctx, almanac, ruleResult, err)
 	}
+	return r.processResult(ctx, almanac, result, ruleResult)
+}
 
-	if ruleResult.Conditions.All != nil && len(ruleResult.Conditions.All) > 0 {
-		conditions["all"] = ruleResult.Conditions.All
+// evaluateRoot evaluates the root condition of a rule: its groups, or the named condition it references
+func (r *Rule) evaluateRoot(ctx *ExecutionContext, almanac *Almanac, root *Condition) (bool, error) {
+	// If no conditions are provided, realize the default conditions
+	if root.All == nil && root.Any == nil && root.None == nil && root.MostOf == nil && root.Not == nil {
+		result, err := r.realize(ctx, almanac, root)
+		if err != nil && !errors.Is(err, errConditionSkipped) {
+			return false, err
+		}
+		return result, nil
 	}
 
-	if ruleResult.Conditions.Not != nil {
-		conditions["not"] = []*Condition{ruleResult.Conditions.Not} // Wrap `Not` in a slice
+	result, err := r.evaluateGroups(ctx, almanac, root)
+	if errors.Is(err, errConditionSkipped) {
+		// Nothing left to evaluate after skipping missing condition references
+		return false, nil
 	}
+	return result, err
+}
 
-	// If no conditions are provided, realize the default conditions
-	if ruleResult.Conditions.All == nil && ruleResult.Conditions.Any == nil && ruleResult.Conditions.Not == nil {
-		result, err = r.realize(ctx, almanac, &r.Conditions)
+// evaluateGroups evaluates the groups of a condition. Like json-rules-engine, sibling blocks are combined with a logical
+// AND: 'all', 'any' (or its atLeast form), 'none' and 'not' are evaluated in this order, stopping at the first false
+// block. Empty groups are evaluated too: an empty 'all' or 'none' is vacuously true, an empty 'any' is false.
+// A 'mostOf' group stands alone, see Condition.Validate.
+func (r *Rule) evaluateGroups(ctx *ExecutionContext, almanac *Almanac, cond *Condition) (bool, error) {
+	if cond.MostOf != nil {
+		return r.evaluateMostOf(ctx, almanac, cond)
+	}
+	for _, group := range []struct {
+		operator   string
+		conditions []*Condition
+	}{{"all", cond.All}, {"any", cond.Any}, {"none", cond.None}} {
+		if group.conditions == nil {
+			continue
+		}
+		var result bool
+		var err error
+		if group.operator == "any" && cond.AtLeast > 0 {
+			result, err = r.evaluateAtLeast(ctx, almanac, cond)
+		} else {
+			result, err = r.prioritizeAndRun(ctx, almanac, group.conditions, group.operator, cond.Ordered)
+		}
+		if err != nil || !result {
+			return false, err
+		}
+	}
+	if cond.Not != nil {
+		// The negated condition can be a leaf, a group or a condition reference
+		result, err := r.evaluateCondition(ctx, almanac, cond.Not)
+		if errors.Is(err, errConditionSkipped) {
+			return false, err
+		}
 		if err != nil {
-			return nil, err
+			return false, wrapConditionError(cond.Not, err)
 		}
-	} else {
-		// Iterate over the conditions and execute prioritizeAndRun if the condition is present
-		for operator, condition := range conditions {
-			result, err = r.prioritizeAndRun(ctx, almanac, condition, operator)
-			if err != nil {
-				return nil, err
-			}
+		if result || ctx.Stopped() {
+			// A stopped run leaves the negated condition false without having evaluated it
+			return false, nil
 		}
 	}
+	return true, nil
+}
 
-	return r.processResult(ctx, almanac, result, ruleResult)
+// handleError either aborts the evaluation with the error, or when the engine is configured to continue on error,
+// attaches it to the rule result and finalizes the rule as failed.
+func (r *Rule) handleError(ctx *ExecutionContext, almanac *Almanac, ruleResult *RuleResult, err error) (*RuleResult, error) {
+	err = r.annotateError(err)
+	if !r.Engine.ContinueOnError || errors.Is(err, ErrEvaluationBudgetExceeded) {
+		return nil, err
+	}
+	ruleResult.Error = err
+	return r.processResult(ctx, almanac, false, ruleResult)
+}
+
+// annotateError names the rule in budget errors that were charged without it, e.g. by the fact lookups of calculated facts
+func (r *Rule) annotateError(err error) error {
+	var budgetErr *EvaluationBudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Rule != "" {
+		return err
+	}
+	return fmt.Errorf("rule %q: %w", r.Name, err)
 }
 
 // realize resolves a condition reference to its actual condition and evaluates it.
+// A missing condition is handled according to the reference's IfMissing setting, falling back to
+// the engine's AllowUndefinedConditions option when it is not set.
 func (r *Rule) realize(ctx *ExecutionContext, almanac *Almanac, conditionReference *Condition) (bool, error) {
 	cond, ok := r.Engine.Conditions.Load(conditionReference.Condition)
 	if !ok {
-		if r.Engine.AllowUndefinedConditions {
+		ifMissing := conditionReference.IfMissing
+		if ifMissing == "" {
+			ifMissing = IfMissingFail
+			if r.Engine.AllowUndefinedConditions {
+				ifMissing = IfMissingFalse
+			}
+		}
+		conditionReference.MissingResolution = ifMissing
+		switch ifMissing {
+		case IfMissingSkip:
+			r.Engine.recordUndefinedCondition(almanac, r.Name, conditionReference.Condition, ifMissing)
+			return false, errConditionSkipped
+		case IfMissingFalse:
+			r.Engine.recordUndefinedCondition(almanac, r.Name, conditionReference.Condition, ifMissing)
 			conditionReference.Result = false
 			return false, nil
+		default:
+			return false, fmt.Errorf("no condition %s exists", conditionReference.Condition)
 		}
-		return false, fmt.Errorf("no condition %s exists", conditionReference.Condition)
 	}
-	conditionReference.Condition = ""
-	return r.evaluateCondition(ctx, almanac, &cond)
+	// The reference keeps its name and records the evaluated copy, so results explain what it resolved to
+	realized := cond.clone()
+	realized.parent, realized.segment = conditionReference, "{"+conditionReference.Condition+"}"
+	conditionReference.Realized = realized
+	result, err := r.evaluateCondition(ctx, almanac, realized)
+	conditionReference.Result = result
+	return result, err
 }
 
 func (r *Rule) evaluateCondition(ctx *ExecutionContext, almanac *Almanac, cond *Condition) (bool, error) {
@@ -204,73 +328,89 @@ func (r *Rule) evaluateCondition(ctx *ExecutionContext, almanac *Almanac, cond *
 		return r.realize(ctx, almanac, cond)
 	}
 
-	var result bool
-	var err error
-
-	// Evaluate 'all' block if it exists
-	if cond.All != nil && len(cond.All) > 0 {
-		result, err = r.prioritizeAndRun(ctx, almanac, cond.All, "all")
-		if err != nil || !result {
-			// Early exit if 'all' block fails
-			ctx.StopEarly = true
-			ctx.Message = "Stopping early due to 'all' condition failure"
-			ctx.Cancel()
-			return result, err
-		}
+	if cond.IsBooleanOperator() {
+		return r.evaluateGroups(ctx, almanac, cond)
 	}
 
-	// Evaluate 'any' block if it exists
-	if cond.Any != nil && len(cond.Any) > 0 {
-		result, err = r.prioritizeAndRun(ctx, almanac, cond.Any, "any")
-		if err != nil {
-			return false, err
-		}
-		if result {
-			// Early exit if 'any' block succeeds
-			ctx.StopEarly = true
-			ctx.Message = "Stopping early due to 'any' condition success"
-			ctx.Cancel()
-			return result, nil
+	// Rule-local facts can shadow the facts of other rules, so their conditions are not shared through the memo;
+	// conditions asking for a fresh fact are evaluated every time
+	memoize := almanac.conditionMemo != nil && len(r.Facts) == 0 && !cond.FreshFact
+	var memoKey string
+	if memoize {
+		memoKey = leafMemoKey(cond)
+		if evaluationResult, ok := almanac.conditionMemo.recall(memoKey, almanac); ok {
+			cond.applyEvaluationResult(evaluationResult)
+			return evaluationResult.Result, nil
 		}
 	}
-
-	// Evaluate 'not' block if it exists
-	if cond.Not != nil {
-		result, err = r.prioritizeAndRun(ctx, almanac, []*Condition{cond.Not}, "not")
-		if err != nil {
-			return false, err
-		}
-		if !result {
-			// If 'not' block is false, return true (because it's negation)
-			return true, nil
-		} else {
-			// If 'not' block is true, return false
-			return false, nil
-		}
+	fact := cond.Fact
+	if len(cond.Facts) > 0 {
+		fact = strings.Join(cond.Facts, ",")
 	}
-
-	// Base case: If there's no 'any', 'all', or 'not', it's a simple condition
-	if !cond.IsBooleanOperator() {
-		evaluationResult, err := cond.Evaluate(almanac, r.Engine.Operators)
-		if err != nil {
-			return false, err
-		}
-		cond.FactResult = evaluationResult.LeftHandSideValue
-		cond.Result = evaluationResult.Result
-		return evaluationResult.Result, nil
+	if err := almanac.budget.useConditionEvaluation(r.Name, fact); err != nil {
+		return false, err
+	}
+	timed := almanac.trace || r.Engine.SlowConditionThreshold > 0
+	var started time.Time
+	if timed {
+		started = time.Now()
+	}
+	evaluationResult, err := cond.evaluate(almanac, r.Engine.Operators(), r.Engine.OperatorDecorators(), r.Engine.Transforms(), r.factResolver(almanac))
+	if timed {
+		r.recordDuration(almanac, cond, time.Since(started))
+	}
+	var calculationErr *factCalculationError
+	if errors.As(err, &calculationErr) {
+		return r.handleFactError(almanac, cond, calculationErr)
+	}
+	if err != nil {
+		return false, err
+	}
+	if memoize {
+		almanac.conditionMemo.remember(memoKey, almanac, evaluationResult)
 	}
+	cond.applyEvaluationResult(evaluationResult)
+	return evaluationResult.Result, nil
+}
 
-	// Default to false if none of the above cases match
-	return result, err
+// handleFactError attaches the rule and condition to the error of a calculated fact, and evaluates the condition as
+// false instead of failing when the fact is configured with FactErrorFalse and the run was not cancelled
+func (r *Rule) handleFactError(almanac *Almanac, cond *Condition, calculationErr *factCalculationError) (bool, error) {
+	err := &CalculatedFactError{Path: calculationErr.path, Rule: r.Name, Condition: cond.evaluationPath(), Err: calculationErr.err}
+	if calculationErr.onError != FactErrorFalse || (almanac.execCtx != nil && almanac.execCtx.Err() != nil) {
+		return false, err
+	}
+	if !almanac.shadow {
+		r.Engine.diagnose(almanac, Diagnostic{Type: DiagnosticFactError, Rule: r.Name, Error: err.Error(), Paths: []string{err.Condition}})
+	}
+	cond.applyEvaluationResult(&EvaluationResult{Operator: cond.Operator})
+	return false, nil
 }
 
 // prioritizeAndRun prioritizes conditions and evaluates them based on the operator.
-func (r *Rule) prioritizeAndRun(ctx *ExecutionContext, almanac *Almanac, conditions []*Condition, operator string) (bool, error) {
+// An empty 'all' or 'none' group is vacuously true and an empty 'any' group is false.
+func (r *Rule) prioritizeAndRun(ctx *ExecutionContext, almanac *Almanac, conditions []*Condition, operator string, ordered bool) (bool, error) {
+	if operator == "none" {
+		// 'none' is the negation of 'any', so evaluation stops at the first condition that is true
+		result, err := r.prioritizeAndRun(ctx, almanac, conditions, "any", ordered)
+		if err != nil || ctx.Stopped() {
+			// A stopped run leaves 'any' false without having found nothing true
+			return false, err
+		}
+		return !result, nil
+	}
 	if len(conditions) == 0 {
-		return true, nil
+		return operator == "all", nil
 	}
 	if len(conditions) == 1 {
-		return r.evaluateCondition(ctx, almanac, conditions[0])
+		result, err := r.evaluateCondition(ctx, almanac, conditions[0])
+		if errors.Is(err, errConditionSkipped) {
+			return false, err
+		}
+		if err != nil {
+			return false, wrapConditionError(conditions[0], err)
+		}
+		return result, nil
 	}
 
 	var method func([]bool) bool
@@ -302,57 +442,211 @@ func (r *Rule) prioritizeAndRun(ctx *ExecutionContext, almanac *Almanac, conditi
 		earlyExitFunc = func(result bool) bool {
 			return result
 		}
-	case "not":
-		method = func(results []bool) bool {
-			return !results[0]
-		}
-		// For 'not', no early exit
-		earlyExitFunc = func(result bool) bool {
-			return false
-		}
 	default:
 		return false, errors.New("invalid operator")
 	}
 
+	if ordered {
+		return r.evaluateInOrder(ctx, almanac, conditions, operator, earlyExitFunc, nil)
+	}
+
 	// Prioritize conditions based on priority
-	orderedSets := r.prioritizeConditions(conditions)
+	orderedSets := r.prioritizedConditions(conditions)
+	evaluated := false
 	for _, set := range orderedSets {
-		if ctx.StopEarly {
+		if ctx.Stopped() {
 			return false, nil
 		}
-		result, err := r.evaluateConditions(ctx, almanac, set, method, earlyExitFunc)
+		result, err := r.evaluateConditions(ctx, almanac, set, method, earlyExitFunc, nil)
+		if errors.Is(err, errConditionSkipped) {
+			continue
+		}
 		if err != nil {
 			return false, err
 		}
+		evaluated = true
+		// A failing set decides an 'all' group and a passing set an 'any' group, later sets are not evaluated
+		if earlyExitFunc(result) {
+			return result, nil
+		}
+	}
+	if !evaluated {
+		// Every condition of the group was skipped, so the group itself is excluded from its parent
+		return false, errConditionSkipped
+	}
+	return operator == "all", nil
+}
+
+// evaluateAtLeast evaluates an 'any' group with AtLeast set. It passes once AtLeast of its conditions are true and
+// stops as soon as that count is reached or can no longer be reached. The count is recorded as the group's MetCount.
+func (r *Rule) evaluateAtLeast(ctx *ExecutionContext, almanac *Almanac, group *Condition) (bool, error) {
+	remaining := len(group.Any)
+	met := 0
+	evaluated := false
+	// Called for every evaluated condition, under the lock of evaluateConditions when evaluated concurrently
+	decided := func(result bool) bool {
+		remaining--
+		evaluated = true
 		if result {
-			return true, nil
+			met++
 		}
+		return met >= group.AtLeast || met+remaining < group.AtLeast
 	}
-	return false, nil
+	// Called for every skipped condition reference, which leaves the group. It only decides the group once a condition
+	// was evaluated, as a group whose conditions are all skipped is skipped itself.
+	skipped := func() bool {
+		remaining--
+		return evaluated && met+remaining < group.AtLeast
+	}
+
+	var err error
+	if group.Ordered {
+		_, err = r.evaluateInOrder(ctx, almanac, group.Any, "any", decided, skipped)
+	} else {
+		for _, set := range r.prioritizedConditions(group.Any) {
+			if ctx.Stopped() {
+				return false, nil
+			}
+			_, setErr := r.evaluateConditions(ctx, almanac, set, func([]bool) bool { return false }, decided, skipped)
+			if setErr != nil && !errors.Is(setErr, errConditionSkipped) {
+				err = setErr
+				break
+			}
+			if evaluated && (met >= group.AtLeast || met+remaining < group.AtLeast) {
+				break
+			}
+		}
+		if err == nil && !evaluated {
+			err = errConditionSkipped
+		}
+	}
+	group.MetCount = met
+	if err != nil {
+		return false, err
+	}
+	return met >= group.AtLeast, nil
+}
+
+// evaluateMostOf evaluates a 'mostOf' group. It passes when the share of true conditions among the evaluated ones
+// reaches MinPassRatio, skipped condition references being excluded, and stops as soon as the ratio is reached even if
+// all remaining conditions fail, or can no longer be reached. The achieved share is recorded as the group's PassRatio.
+func (r *Rule) evaluateMostOf(ctx *ExecutionContext, almanac *Almanac, group *Condition) (bool, error) {
+	remaining := len(group.MostOf)
+	passed, failed := 0, 0
+	settled := func() bool {
+		return passRatioReached(passed, failed+remaining, group.MinPassRatio) ||
+			!passRatioReached(passed+remaining, failed, group.MinPassRatio)
+	}
+	// Called for every evaluated condition, under the lock of evaluateConditions when evaluated concurrently
+	decided := func(result bool) bool {
+		remaining--
+		if result {
+			passed++
+		} else {
+			failed++
+		}
+		return settled()
+	}
+
+	var err error
+	if group.Ordered {
+		_, err = r.evaluateInOrder(ctx, almanac, group.MostOf, "all", decided, nil)
+	} else {
+		evaluated := false
+		for _, set := range r.prioritizedConditions(group.MostOf) {
+			if ctx.Stopped() {
+				return false, nil
+			}
+			_, setErr := r.evaluateConditions(ctx, almanac, set, func([]bool) bool { return false }, decided, nil)
+			if errors.Is(setErr, errConditionSkipped) {
+				continue
+			}
+			if setErr != nil {
+				err = setErr
+				break
+			}
+			evaluated = true
+			if settled() {
+				break
+			}
+		}
+		if err == nil && !evaluated {
+			err = errConditionSkipped
+		}
+	}
+	if passed+failed > 0 {
+		group.PassRatio = float64(passed) / float64(passed+failed)
+	}
+	if err != nil {
+		return false, err
+	}
+	return passRatioReached(passed, failed, group.MinPassRatio), nil
 }
 
+// passRatioReached returns whether passed of passed+failed conditions reach the ratio; false when none were evaluated.
+// The comparison tolerates rounding, so 4 of 5 reach 0.8.
+func passRatioReached(passed, failed int, ratio float64) bool {
+	total := passed + failed
+	return total > 0 && float64(passed)/float64(total) >= ratio-1e-9
+}
+
+// evaluateInOrder evaluates the conditions of an ordered group one at a time in declaration order,
+// ignoring priorities, costs and the scheduler. The first decisive condition ends the evaluation.
+// skipFunc, if not nil, is called for skipped condition references and ends the evaluation as failed when it returns true.
+func (r *Rule) evaluateInOrder(ctx *ExecutionContext, almanac *Almanac, conditions []*Condition, operator string, earlyExitFunc func(bool) bool, skipFunc func() bool) (bool, error) {
+	evaluated := false
+	for _, cond := range conditions {
+		if ctx.Stopped() {
+			return false, nil
+		}
+		result, err := r.evaluateCondition(ctx, almanac, cond)
+		if errors.Is(err, errConditionSkipped) {
+			if skipFunc != nil && skipFunc() {
+				return false, nil
+			}
+			continue
+		}
+		if err != nil {
+			return false, wrapConditionError(cond, err)
+		}
+		evaluated = true
+		if earlyExitFunc(result) {
+			return result, nil
+		}
+	}
+	if !evaluated && len(conditions) > 0 {
+		return false, errConditionSkipped
+	}
+	return operator == "all", nil
+}
+
+// maxConditionConcurrency limits the conditions of a group evaluated at the same time
+const maxConditionConcurrency = 10
+
 // evaluateConditions concurrently evaluates a set of conditions with early exit.
-func (r *Rule) evaluateConditions(ctx *ExecutionContext, almanac *Almanac, conditions []*Condition, method func([]bool) bool, earlyExitFunc func(bool) bool) (bool, error) {
+// skipFunc, if not nil, is called for skipped condition references and ends the evaluation early when it returns true.
+func (r *Rule) evaluateConditions(ctx *ExecutionContext, almanac *Almanac, conditions []*Condition, method func([]bool) bool, earlyExitFunc func(bool) bool, skipFunc func() bool) (bool, error) {
 	if len(conditions) == 0 {
 		return true, nil
 	}
 
 	results := make([]bool, len(conditions))
+	skipped := make([]bool, len(conditions))
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-	var err error
+	var errs []error
 	done := make(chan struct{})
 	var once sync.Once // Ensure done channel is closed only once
 
-	// Limit the number of concurrent goroutines to prevent resource exhaustion
-	maxConcurrency := 10
-	semaphore := make(chan struct{}, maxConcurrency)
+	// Each group has its own slots: nested groups are evaluated from inside a slot of their parent, so sharing the
+	// slots between levels would leave children waiting for slots held by their blocked parents
+	semaphore := make(chan struct{}, maxConditionConcurrency)
 
 	for i, cond := range conditions {
 		i, cond := i, cond      // Capture loop variables
 		semaphore <- struct{}{} // Acquire a semaphore slot
 		wg.Add(1)
-		go func() {
+		r.Engine.schedule(func() {
 			defer func() {
 				<-semaphore // Release the semaphore slot
 				wg.Done()
@@ -365,11 +659,21 @@ func (r *Rule) evaluateConditions(ctx *ExecutionContext, almanac *Almanac, condi
 				return
 			default:
 				res, e := r.evaluateCondition(ctx, almanac, cond)
+				if errors.Is(e, errConditionSkipped) {
+					mu.Lock()
+					skipped[i] = true
+					exitEarly := skipFunc != nil && skipFunc()
+					mu.Unlock()
+					if exitEarly {
+						once.Do(func() { close(done) })
+					}
+					return
+				}
 				if e != nil {
+					// Keep evaluating the rest of the group so all errors are reported together
 					mu.Lock()
-					err = e
+					errs = append(errs, wrapConditionError(cond, e))
 					mu.Unlock()
-					once.Do(func() { close(done) }) // Close done channel safely
 					return
 				}
 				mu.Lock()
@@ -380,16 +684,59 @@ func (r *Rule) evaluateConditions(ctx *ExecutionContext, almanac *Almanac, condi
 					once.Do(func() { close(done) }) // Close done channel safely
 				}
 			}
-		}()
+		})
 	}
 
 	// Wait for all goroutines to finish
 	wg.Wait()
 
-	if err != nil {
-		return false, err
+	if len(errs) > 0 {
+		return false, errors.Join(errs...)
+	}
+
+	// Skipped condition references are excluded from the group
+	evaluated := results[:0:0]
+	for i, res := range results {
+		if !skipped[i] {
+			evaluated = append(evaluated, res)
+		}
+	}
+	if len(evaluated) == 0 {
+		return false, errConditionSkipped
+	}
+	return method(evaluated), nil
+}
+
+// wrapConditionError prefixes an error with a label identifying the condition it occurred in.
+// Nested groups wrap the error again, so the message reads as a path through the condition tree.
+func wrapConditionError(cond *Condition, err error) error {
+	var label string
+	switch {
+	case cond.Name != "":
+		label = cond.Name
+	case cond.IsConditionReference():
+		label = fmt.Sprintf("condition:%s", cond.Condition)
+	case cond.IsBooleanOperator():
+		label = cond.booleanOperator()
+	default:
+		label = fmt.Sprintf("%s %s", cond.FactExpression(), cond.Operator)
+	}
+	return fmt.Errorf("%s > %w", label, err)
+}
+
+// recordDuration records the evaluation time of a leaf condition in trace mode and reports it when it exceeds
+// the engine's SlowConditionThreshold
+func (r *Rule) recordDuration(almanac *Almanac, cond *Condition, elapsed time.Duration) {
+	if almanac.trace {
+		cond.Duration = elapsed
+	}
+	if threshold := r.Engine.SlowConditionThreshold; threshold > 0 && elapsed > threshold && !almanac.shadow {
+		fact := cond.Fact
+		if len(cond.Facts) > 0 {
+			fact = strings.Join(cond.Facts, ",")
+		}
+		r.Engine.reportSlowCondition(SlowCondition{Rule: r.Name, Path: cond.evaluationPath(), Fact: fact, Duration: elapsed})
 	}
-	return method(results), nil
 }
 
 // processResult finalizes the evaluation result and publishes events.
@@ -400,6 +747,14 @@ func (r *Rule) processResult(ctx *ExecutionContext, almanac *Almanac, result boo
 			return nil, err
 		}
 	}
+	if result && !r.allowEvent(ctx, ruleResult) {
+		ruleResult.EventSuppressed = true
+		return ruleResult, nil
+	}
+	if almanac.quiet || r.Engine.SynchronousEvents {
+		// Synchronous callbacks are called by the engine, see Engine.publishResult
+		return ruleResult, nil
+	}
 	event := "failure"
 	if result {
 		event = "success"
@@ -408,6 +763,62 @@ func (r *Rule) processResult(ctx *ExecutionContext, almanac *Almanac, result boo
 	return ruleResult, nil
 }
 
+// prioritizedConditions returns the cached prioritized grouping of a condition group,
+// computing it when the group has not been seen or the engine facts changed since it was computed.
+func (r *Rule) prioritizedConditions(conditions []*Condition) [][]*Condition {
+	factsVersion := r.Engine.factsVersion.Load()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conditionSets == nil || r.conditionSetsFacts != factsVersion {
+		r.conditionSets = make(map[*Condition]*conditionSetsEntry)
+		r.conditionSetsFacts = factsVersion
+	}
+	entry, ok := r.conditionSets[conditions[0].source()]
+	if !ok {
+		entry = newConditionSetsEntry(conditions, r.prioritizeConditions(conditions))
+		r.conditionSets[conditions[0].source()] = entry
+	}
+	return entry.apply(conditions)
+}
+
+// conditionSetsEntry is a cached prioritized grouping of a condition group
+type conditionSetsEntry struct {
+	first     *Condition     // First condition of the group the sets were computed for
+	sets      [][]*Condition // The sets of that group, returned as is for the same group
+	positions [][]int        // The sets as positions within the group, applied to per-run copies
+}
+
+func newConditionSetsEntry(conditions []*Condition, sets [][]*Condition) *conditionSetsEntry {
+	index := make(map[*Condition]int, len(conditions))
+	for i, cond := range conditions {
+		index[cond] = i
+	}
+	positions := make([][]int, len(sets))
+	for i, set := range sets {
+		positions[i] = make([]int, len(set))
+		for j, cond := range set {
+			positions[i][j] = index[cond]
+		}
+	}
+	return &conditionSetsEntry{first: conditions[0], sets: sets, positions: positions}
+}
+
+// apply returns the cached sets for the given group, which may be a copy of the group they were computed for
+func (e *conditionSetsEntry) apply(conditions []*Condition) [][]*Condition {
+	if conditions[0] == e.first {
+		return e.sets
+	}
+	sets := make([][]*Condition, len(e.positions))
+	for i, set := range e.positions {
+		sets[i] = make([]*Condition, len(set))
+		for j, position := range set {
+			sets[i][j] = conditions[position]
+		}
+	}
+	return sets
+}
+
 func (r *Rule) prioritizeConditions(conditions []*Condition) [][]*Condition {
 	// Preallocate the map with an estimated size
 	factSets := make(map[int][]*Condition, len(conditions))
@@ -429,13 +840,63 @@ func (r *Rule) prioritizeConditions(conditions []*Condition) [][]*Condition {
 	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
 
 	// Preallocate the result slice
-	result := make([][]*Condition, len(keys))
-	for i, k := range keys {
-		result[i] = factSets[k]
+	result := make([][]*Condition, 0, len(keys))
+	for _, k := range keys {
+		if r.Engine.CostAwareOrdering {
+			result = append(result, splitByCost(factSets[k], &r.Engine.Facts)...)
+			continue
+		}
+		result = append(result, factSets[k])
 	}
 	return result
 }
 
+// splitByCost splits a set of conditions of equal priority into sets of equal cost, cheapest first.
+// Evaluating the cheap sets first lets 'any' groups short-circuit before expensive conditions are evaluated,
+// and 'all' groups fail before them.
+func splitByCost(conditions []*Condition, facts *FactMap) [][]*Condition {
+	costSets := map[int][]*Condition{}
+	costs := make([]int, 0, len(conditions))
+	for _, cond := range conditions {
+		cost := getCost(cond, facts)
+		if _, ok := costSets[cost]; !ok {
+			costs = append(costs, cost)
+		}
+		costSets[cost] = append(costSets[cost], cond)
+	}
+	sort.Ints(costs)
+	sets := make([][]*Condition, len(costs))
+	for i, cost := range costs {
+		sets[i] = costSets[cost]
+	}
+	return sets
+}
+
+// getCost returns the estimated cost of evaluating a condition: its own cost when set, otherwise the cost of
+// its fact, or the highest cost of its nested conditions
+func getCost(cond *Condition, facts *FactMap) int {
+	if cond.Cost != nil {
+		return *cond.Cost
+	}
+	cost := 0
+	if f, ok := facts.Load(cond.Fact); ok {
+		cost = f.Cost
+	}
+	for _, fact := range cond.Facts {
+		if f, ok := facts.Load(fact); ok && f.Cost > cost {
+			cost = f.Cost
+		}
+	}
+	for _, group := range [][]*Condition{cond.All, cond.Any, cond.None, cond.MostOf, {cond.Not}} {
+		for _, child := range group {
+			if child != nil {
+				cost = max(cost, getCost(child, facts))
+			}
+		}
+	}
+	return cost
+}
+
 func getPriority(cond *Condition, facts *FactMap) int {
 	if cond.Priority != nil {
 		return *cond.Priority
@@ -445,3 +906,34 @@ func getPriority(cond *Condition, facts *FactMap) int {
 	}
 	return 0
 }
+
+// factResolver returns the fact lookup for the rule's conditions: rule-local facts first, then the almanac.
+// A path below a local fact, e.g. "rates.gold" for a local fact "rates", resolves within its value.
+func (r *Rule) factResolver(almanac *Almanac) factLookup {
+	if len(r.Facts) == 0 {
+		return func(path string, options FactLookupOptions) (*Fact, error) {
+			return almanac.ruleFactValue(r.Name, path, options)
+		}
+	}
+	return func(path string, options FactLookupOptions) (*Fact, error) {
+		for base, rest := path, ""; ; {
+			if value, ok := r.Facts[base]; ok {
+				if resolved, found := value.Get(rest); found {
+					return NewFact(path, *resolved, &FactOptions{Cache: false, Priority: 1})
+				}
+				break
+			}
+			i := strings.LastIndexByte(base, '.')
+			if i < 0 {
+				break
+			}
+			if rest == "" {
+				rest = base[i+1:]
+			} else {
+				rest = base[i+1:] + "." + rest
+			}
+			base = base[:i]
+		}
+		return almanac.ruleFactValue(r.Name, path, options)
+	}
+}
@@ -2,7 +2,13 @@ package rulesengine
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/tidwall/gjson"
 )
 
 // RuleResult represents the result of a rule evaluation
@@ -12,7 +18,39 @@ type RuleResult struct {
 	Priority   int
 	Name       string
 	Result     *bool
-	mu         sync.Mutex
+	Error      error      // Set when the evaluation failed and the engine continues on error
+	Skipped    SkipReason // Set when the rule was not evaluated
+	Dropped    bool       // Set when the rule's event was dropped to resolve an exclusive event conflict
+	Ephemeral  bool       // Set when the rule was supplied with the run instead of added to the engine
+	// EventSuppressed is set when an EventFilter vetoed the event of a passing rule; Result stays true
+	EventSuppressed bool
+	// Serialization controls how fact results are written by ToJSON and MarshalJSON, nil for full fidelity
+	Serialization *SerializationOptions
+	// Locale selects the message catalog and failure messages of Explain, see RunOptions.Locale
+	Locale string
+	rule   *Rule
+	mu     sync.Mutex
+}
+
+// SkipReason describes why a rule was not evaluated
+type SkipReason string
+
+const (
+	// SkippedCancelled marks rules that were not evaluated because the run's context was cancelled
+	SkippedCancelled SkipReason = "SkippedCancelled"
+	// SkippedBudget marks rules that were not evaluated because the run's soft deadline had passed
+	SkippedBudget SkipReason = "SkippedBudget"
+	// SkippedNoData marks rules that were not evaluated because none of their facts had data, see RunOptions.SkipRulesWithoutFacts
+	SkippedNoData SkipReason = "SkippedNoData"
+)
+
+// newSkippedResult creates the result of a rule that was not evaluated for the given reason
+func newSkippedResult(r *Rule, reason SkipReason) *RuleResult {
+	skipped := NewRuleResult(r.Conditions, r.RuleEvent, r.Priority, r.Name)
+	skipped.Ephemeral = r.ephemeral
+	skipped.rule = r
+	skipped.Skipped = reason
+	return skipped
 }
 
 // NewRuleResult creates a new RuleResult instance
@@ -26,6 +64,26 @@ func NewRuleResult(conditions Condition, event Event, priority int, name string)
 	}
 }
 
+// clone returns a deep copy of the rule result, its condition tree and event params included
+func (rr *RuleResult) clone() *RuleResult {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	cloned := NewRuleResult(*rr.Conditions.clone(), Event{Type: rr.Event.Type, Params: copyParams(rr.Event.Params)}, rr.Priority, rr.Name)
+	if rr.Result != nil {
+		result := *rr.Result
+		cloned.Result = &result
+	}
+	cloned.Error = rr.Error
+	cloned.Skipped = rr.Skipped
+	cloned.Dropped = rr.Dropped
+	cloned.Ephemeral = rr.Ephemeral
+	cloned.EventSuppressed = rr.EventSuppressed
+	cloned.Serialization = rr.Serialization
+	cloned.Locale = rr.Locale
+	cloned.rule = rr.rule
+	return cloned
+}
+
 // SetResult sets the result of the rule evaluation
 func (rr *RuleResult) SetResult(result *bool) {
 	rr.mu.Lock()
@@ -33,57 +91,126 @@ func (rr *RuleResult) SetResult(result *bool) {
 	rr.Result = result
 }
 
-// ResolveEventParams resolves the event parameters using the given almanac
+// ResolveEventParams resolves the event parameters using the given almanac.
+// Params are resolved sequentially in key order; fact values are cached by the almanac, so fanning out
+// would only add scheduling overhead. Every failing param is reported, joined into a single error.
 func (rr *RuleResult) ResolveEventParams(almanac *Almanac) error {
-	if IsObjectLike(rr.Event.Params) {
-		var wg sync.WaitGroup
-		var mu sync.Mutex
-		errorsCh := make(chan error, len(rr.Event.Params))
-
-		for key, value := range rr.Event.Params {
-			wg.Add(1)
-			go func(key string, value interface{}) {
-				defer wg.Done()
-				// check if value is a string
-				if IsObjectLike(value) {
-					valMap, ok := value.(map[string]interface{})
-					if ok {
-						if factPath, ok := valMap["fact"].(string); ok {
-							resolvedValue, err := almanac.GetValue(factPath)
-							if err != nil {
-								errorsCh <- err
-								return
-							}
-
-							mu.Lock()
-							rr.Event.Params[key] = resolvedValue
-							mu.Unlock()
-						}
-					}
-				}
-
-			}(key, value)
+	if !IsObjectLike(rr.Event.Params) {
+		return nil
+	}
+	// Resolve into a copy, the params map is shared with the rule and with concurrent runs
+	params := make(map[string]interface{}, len(rr.Event.Params))
+	keys := make([]string, 0, len(rr.Event.Params))
+	for key, value := range rr.Event.Params {
+		params[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		valMap, ok := params[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if factPath, ok := valMap["fact"].(string); ok {
+			resolvedValue, err := almanac.GetValue(factPath)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("event param %s: %w", key, err))
+				continue
+			}
+			params[key] = resolvedValue
+		} else if matchedPath, ok := valMap["matched"].(string); ok {
+			all, _ := valMap["all"].(bool)
+			params[key] = rr.resolveMatched(matchedPath, all)
+		} else if matchPath, ok := valMap["match"].(string); ok {
+			params[key] = rr.resolveMatchDetail(matchPath)
 		}
+	}
+	rr.Event.Params = params
+	return errors.Join(errs...)
+}
 
-		wg.Wait()
-		close(errorsCh)
+// resolveMatched resolves a "<conditionName>.<path>" reference against the elements matched by the named condition.
+// The special path "$index" resolves to the index of the matched element.
+// If all is true, the values of all matched elements are returned as a slice, otherwise only the first match is used.
+func (rr *RuleResult) resolveMatched(reference string, all bool) interface{} {
+	name, path, _ := strings.Cut(reference, ".")
+	cond := rr.Conditions.FindByName(name)
+	if cond == nil || len(cond.Matches) == 0 {
+		if all {
+			return []interface{}{}
+		}
+		return nil
+	}
 
-		if len(errorsCh) > 0 {
-			return <-errorsCh
+	resolve := func(match ElementMatch) interface{} {
+		if path == "$index" {
+			return match.Index
 		}
+		value, ok := match.Value.Get(path)
+		if !ok {
+			return nil
+		}
+		return value.Raw()
+	}
+
+	if !all {
+		return resolve(cond.Matches[0])
 	}
-	return nil
+	values := make([]interface{}, len(cond.Matches))
+	for i, match := range cond.Matches {
+		values[i] = resolve(match)
+	}
+	return values
+}
+
+// resolveMatchDetail resolves a "<conditionName>.<path>" reference against the match detail of the named condition,
+// e.g. "email.groups.1". Without a path the whole detail is returned. Unknown conditions and paths resolve to nil.
+func (rr *RuleResult) resolveMatchDetail(reference string) interface{} {
+	name, path, _ := strings.Cut(reference, ".")
+	cond := rr.Conditions.FindByName(name)
+	if cond == nil || cond.MatchDetail == nil {
+		return nil
+	}
+	if path == "" {
+		return cond.MatchDetail
+	}
+	data, err := json.Marshal(cond.MatchDetail)
+	if err != nil {
+		return nil
+	}
+	return gjson.GetBytes(data, path).Value()
 }
 
 // ToJSON converts the rule result to a JSON-friendly structure
 func (rr *RuleResult) ToJSON(stringify bool) (interface{}, error) {
+	conditions, err := rr.Conditions.toJSON(false, rr.Serialization)
+	if err != nil {
+		return nil, err
+	}
 	props := map[string]interface{}{
-		"conditions": rr.Conditions,
+		"conditions": conditions,
 		"event":      rr.Event,
 		"priority":   rr.Priority,
 		"name":       rr.Name,
 		"result":     rr.Result,
 	}
+	if rr.Error != nil {
+		props["error"] = rr.Error.Error()
+	}
+	if rr.Dropped {
+		props["dropped"] = true
+	}
+	if rr.Skipped != "" {
+		props["skipped"] = rr.Skipped
+	}
+	if rr.Ephemeral {
+		props["ephemeral"] = true
+	}
+	if rr.EventSuppressed {
+		props["eventSuppressed"] = true
+	}
 
 	if stringify {
 		jsonStr, err := json.Marshal(props)
@@ -94,3 +221,51 @@ func (rr *RuleResult) ToJSON(stringify bool) (interface{}, error) {
 	}
 	return props, nil
 }
+
+// MarshalJSON implements json.Marshaler using the same representation as ToJSON
+func (rr *RuleResult) MarshalJSON() ([]byte, error) {
+	props, err := rr.ToJSON(false)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(props)
+}
+
+// SerializationOptions controls the size of serialized rule results.
+type SerializationOptions struct {
+	// MaxFactValueBytes truncates fact results whose JSON encoding is larger, 0 for no limit
+	MaxFactValueBytes int
+	// OmitFactResults drops fact results entirely, keeping operators, values and results
+	OmitFactResults bool
+}
+
+// TruncatedValue replaces a fact result that exceeded SerializationOptions.MaxFactValueBytes
+type TruncatedValue struct {
+	Truncated     bool   `json:"truncated"`
+	OriginalBytes int    `json:"originalBytes"`
+	Preview       string `json:"preview"` // The first MaxFactValueBytes of the JSON encoding followed by an ellipsis
+}
+
+func (o *SerializationOptions) omitFactResults() bool {
+	return o != nil && o.OmitFactResults
+}
+
+// factValue returns the JSON-friendly form of a fact result, truncated when it exceeds the size limit
+func (o *SerializationOptions) factValue(v *ValueNode) interface{} {
+	if v == nil {
+		return nil
+	}
+	raw := v.Raw()
+	if o == nil || o.MaxFactValueBytes <= 0 {
+		return raw
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil || len(encoded) <= o.MaxFactValueBytes {
+		return raw
+	}
+	return TruncatedValue{
+		Truncated:     true,
+		OriginalBytes: len(encoded),
+		Preview:       strings.ToValidUTF8(string(encoded[:o.MaxFactValueBytes]), "") + "…",
+	}
+}
@@ -0,0 +1,344 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRuleResultMatchedEventParams(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "matched",
+		"conditions": {
+			"all": [
+				{"name": "flagged", "fact": "items", "operator": "hasElement", "value": {"sku": "B", "flagged": true}}
+			]
+		},
+		"event": {
+			"type": "flagged",
+			"params": {
+				"index": {"matched": "flagged.$index"},
+				"sku": {"matched": "flagged.sku"},
+				"skus": {"matched": "flagged.sku", "all": true},
+				"missing": {"matched": "unknown.sku"}
+			}
+		}
+	}`, &RuleEngineOptions{ReplaceFactsInEventParams: true, AllowUnknownOperators: true})
+	engine.AddOperator("hasElement", func(a, b *ValueNode) bool {
+		return a.IsArray() && len(matchElements(a, b)) > 0
+	})
+
+	res, err := engine.Run(context.Background(), []byte(`{"items": [
+		{"sku": "A", "flagged": false},
+		{"sku": "B", "flagged": true},
+		{"sku": "B", "flagged": true}
+	]}`))
+	if err != nil {
+		t.Fatalf("Expected run to succeed, got error: %v", err)
+	}
+	events := res.Events
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 success event, got %d", len(events))
+	}
+	params := events[0].Params
+	if params["index"] != 1 {
+		t.Errorf("Expected first matched index 1, got %v", params["index"])
+	}
+	if params["sku"] != "B" {
+		t.Errorf("Expected matched sku B, got %v", params["sku"])
+	}
+	if skus, ok := params["skus"].([]interface{}); !ok || len(skus) != 2 {
+		t.Errorf("Expected all matched skus, got %v", params["skus"])
+	}
+	if params["missing"] != nil {
+		t.Errorf("Expected nil for unknown condition, got %v", params["missing"])
+	}
+}
+
+func TestRuleResultMatchedElementsPassTheOperator(t *testing.T) {
+	for _, tc := range []struct {
+		operator string
+		value    int
+		want     []interface{}
+	}{
+		{"someFact:notEqual", 1, []interface{}{2, 3}},
+		{"someFact:greaterThan", 1, []interface{}{2, 3}},
+		{"everyFact:lessThan", 4, []interface{}{1, 2, 3}},
+		{"someFact:equal", 2, []interface{}{2}},
+	} {
+		t.Run(tc.operator, func(t *testing.T) {
+			engine := newTestEngine(t, fmt.Sprintf(`{
+				"name": "matched",
+				"conditions": {"all": [{"name": "numbers", "fact": "numbers", "operator": %q, "value": %d}]},
+				"event": {"type": "matched", "params": {"values": {"matched": "numbers", "all": true}}}
+			}`, tc.operator, tc.value), &RuleEngineOptions{ReplaceFactsInEventParams: true})
+			res, err := engine.Run(context.Background(), []byte(`{"numbers": [1, 2, 3]}`))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(res.Events) != 1 {
+				t.Fatalf("Expected the rule to pass, got %v", res.FailureResults)
+			}
+			if values := fmt.Sprint(res.Events[0].Params["values"]); values != fmt.Sprint(tc.want) {
+				t.Errorf("Expected the matched elements %v, got %s", tc.want, values)
+			}
+		})
+	}
+}
+
+func TestRuleResultSerializationOptions(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "big", "conditions": {"all": [{"fact": "profile", "operator": "hasKey", "value": "bio"}]}, "event": {"type": "big"}}`, nil)
+	facts := []byte(`{"profile": {"bio": "` + strings.Repeat("x", 500) + `"}}`)
+
+	conditionJSON := func(t *testing.T, options *RunOptions) map[string]interface{} {
+		t.Helper()
+		res, err := engine.RunWithOptions(context.Background(), facts, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data, err := json.Marshal(res.Results[0])
+		if err != nil {
+			t.Fatalf("Failed to marshal rule result: %v", err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to decode rule result: %v", err)
+		}
+		return decoded["conditions"].(map[string]interface{})["all"].([]interface{})[0].(map[string]interface{})
+	}
+
+	t.Run("Full fidelity by default", func(t *testing.T) {
+		condition := conditionJSON(t, nil)
+		profile, ok := condition["factResult"].(map[string]interface{})
+		if !ok || len(profile["bio"].(string)) != 500 {
+			t.Errorf("Expected the full fact result, got %v", condition["factResult"])
+		}
+	})
+
+	t.Run("Large values are truncated", func(t *testing.T) {
+		condition := conditionJSON(t, &RunOptions{Serialization: &SerializationOptions{MaxFactValueBytes: 32}})
+		truncated, ok := condition["factResult"].(map[string]interface{})
+		if !ok || truncated["truncated"] != true {
+			t.Fatalf("Expected a truncated fact result, got %v", condition["factResult"])
+		}
+		if truncated["originalBytes"].(float64) <= 500 {
+			t.Errorf("Expected the original size to be recorded, got %v", truncated["originalBytes"])
+		}
+		if preview := truncated["preview"].(string); !strings.HasSuffix(preview, "…") || len(preview) > 32+len("…") {
+			t.Errorf("Expected a short preview with an ellipsis, got %q", preview)
+		}
+	})
+
+	t.Run("Fact results can be omitted", func(t *testing.T) {
+		condition := conditionJSON(t, &RunOptions{Serialization: &SerializationOptions{OmitFactResults: true}})
+		if _, ok := condition["factResult"]; ok {
+			t.Errorf("Expected the fact result to be omitted")
+		}
+		if condition["result"] != true || condition["operator"] != "hasKey" {
+			t.Errorf("Expected the result and operator to be kept, got %v", condition)
+		}
+	})
+}
+
+func TestRuleResultFactEventParams(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "params",
+		"conditions": {"all": [{"fact": "score", "operator": "greaterThan", "value": 10}]},
+		"event": {"type": "params", "params": {
+			"score": {"fact": "score"},
+			"user": {"fact": "user.name"},
+			"unknown": {"fact": "missing"},
+			"static": "kept"
+		}}
+	}`, &RuleEngineOptions{ReplaceFactsInEventParams: true, AllowUndefinedFacts: true})
+
+	for _, facts := range []struct {
+		input string
+		score float64
+		user  string
+	}{
+		{`{"score": 50, "user": {"name": "ada"}}`, 50, "ada"},
+		{`{"score": 70, "user": {"name": "bob"}}`, 70, "bob"},
+	} {
+		res, err := engine.Run(context.Background(), []byte(facts.input))
+		if err != nil {
+			t.Fatalf("Expected run to succeed, got error: %v", err)
+		}
+		params := res.Events[0].Params
+		if params["score"] != facts.score || params["user"] != facts.user || params["unknown"] != nil || params["static"] != "kept" {
+			t.Errorf("Unexpected params %v", params)
+		}
+	}
+	// Resolution works on a copy, the rule keeps its fact references for the next run
+	if _, ok := engine.Rules[0].RuleEvent.Params["score"].(map[string]interface{}); !ok {
+		t.Errorf("Expected the rule's params to be left unresolved, got %v", engine.Rules[0].RuleEvent.Params)
+	}
+}
+
+// TestRuleResultFactEventParamsParity resolves the same paths in conditions and event params, into a calculated
+// fact shadowing the raw document and into the raw document
+func TestRuleResultFactEventParamsParity(t *testing.T) {
+	paths := map[string]interface{}{
+		"customer.address.city": "Berlin",
+		"customer.orders.1.sku": "B",
+		"customer.orders.#.sku": nil,
+		"items.0.sku":           "raw",
+		"customer.tier":         nil,
+	}
+	var conditions []string
+	params := map[string]interface{}{}
+	for path, value := range paths {
+		if value != nil {
+			conditions = append(conditions, fmt.Sprintf(`{"fact": %q, "operator": "equal", "value": %q}`, path, value))
+		}
+		params[path] = map[string]interface{}{"fact": path}
+	}
+	encoded, _ := json.Marshal(params)
+	engine := newTestEngine(t, fmt.Sprintf(`{
+		"name": "parity",
+		"conditions": {"all": [%s]},
+		"event": {"type": "parity", "params": %s}
+	}`, strings.Join(conditions, ","), encoded), &RuleEngineOptions{ReplaceFactsInEventParams: true, AllowUndefinedFacts: true})
+	err := engine.AddCalculatedFact("customer", func(a *Almanac, params ...interface{}) *ValueNode {
+		customer, _ := NewValue(map[string]interface{}{
+			"address": map[string]interface{}{"city": "Berlin"},
+			"orders":  []interface{}{map[string]interface{}{"sku": "A"}, map[string]interface{}{"sku": "B"}},
+		})
+		return customer
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+
+	res, err := engine.Run(context.Background(), []byte(`{
+		"customer": {"address": {"city": "Paris"}, "tier": "gold", "orders": []},
+		"items": [{"sku": "raw"}]
+	}`))
+	if err != nil {
+		t.Fatalf("Expected run to succeed, got error: %v", err)
+	}
+	if len(res.Events) != 1 {
+		t.Fatalf("Expected the conditions to resolve into the calculated fact, got %v", res.FailureResults)
+	}
+	resolved := res.Events[0].Params
+	for path, value := range paths {
+		if value == nil {
+			continue
+		}
+		if resolved[path] != value {
+			t.Errorf("Expected event param %s to resolve to %v like the condition, got %v", path, value, resolved[path])
+		}
+	}
+	// The calculated fact shadows the raw document below its path, gjson syntax included
+	if skus, ok := resolved["customer.orders.#.sku"].([]ValueNode); !ok || len(skus) != 2 || skus[1].String != "B" {
+		t.Errorf("Expected the skus of the calculated fact, got %v", resolved["customer.orders.#.sku"])
+	}
+	if resolved["customer.tier"] != nil {
+		t.Errorf("Expected a path missing from the calculated fact to be undefined, got %v", resolved["customer.tier"])
+	}
+}
+
+func TestRuleResultMatchDetailEventParams(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "contact",
+		"conditions": {"all": [
+			{"name": "email", "fact": "email", "operator": "regex", "value": "^(?P<user>[^@]+)@(.+)$"},
+			{"name": "tag", "fact": "tags", "operator": "anyElement", "value": "vip"},
+			{"name": "domain", "fact": "email", "operator": "regex", "value": "@example\\.org$", "negate": true}
+		]},
+		"event": {"type": "contact", "params": {
+			"domain": {"match": "email.groups.2"},
+			"user": {"match": "email.named.user"},
+			"tagIndex": {"match": "tag.index"},
+			"negated": {"match": "domain"},
+			"unknown": {"match": "email.groups.9"}
+		}}
+	}`, &RuleEngineOptions{ReplaceFactsInEventParams: true})
+
+	res, err := engine.Run(context.Background(), []byte(`{"email": "jane@example.com", "tags": ["new", "vip"]}`))
+	if err != nil {
+		t.Fatalf("Expected run to succeed, got error: %v", err)
+	}
+	events := res.Events
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 success event, got %d", len(events))
+	}
+	params := events[0].Params
+	if params["domain"] != "example.com" || params["user"] != "jane" || params["tagIndex"] != float64(1) {
+		t.Errorf("Unexpected match params %v", params)
+	}
+	if params["negated"] != nil || params["unknown"] != nil {
+		t.Errorf("Expected nil for negated conditions and unknown paths, got %v", params)
+	}
+
+	if tag := res.Results[0].Conditions.All[1]; tag.Matches != nil {
+		t.Errorf("Expected the matched element only as the match detail, got matches %v", tag.Matches)
+	}
+
+	results := res.Results
+	out, err := results[0].ToJSON(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	email := out.(map[string]interface{})["conditions"].(map[string]interface{})["all"].([]interface{})[0].(map[string]interface{})
+	if _, ok := email["matchDetail"].(*RegexMatch); !ok {
+		t.Errorf("Expected the result tree to carry the match detail, got %v", email)
+	}
+}
+
+func TestRuleResultConditionResults(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "checkout",
+		"conditions": {"all": [
+			{"fact": "age", "operator": "greaterThan", "value": 17},
+			{"condition": "vip"},
+			{"fact": "country", "operator": "equal", "value": {"fact": "billing.country"}}
+		]},
+		"event": {"type": "checkout"}
+	}`, nil)
+	vip := Condition{Any: []*Condition{{Fact: "tier", Operator: "equal", Value: ValueNode{Type: String, String: "gold"}, ValueSet: true}}}
+	if err := engine.SetCondition("vip", vip); err != nil {
+		t.Fatalf("Failed to set condition: %v", err)
+	}
+
+	leaves := func(t *testing.T, facts string) []map[string]interface{} {
+		t.Helper()
+		res, err := engine.Run(context.Background(), []byte(facts))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		out, err := append(res.Results, res.FailureResults...)[0].ToJSON(false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		all := out.(map[string]interface{})["conditions"].(map[string]interface{})["all"].([]interface{})
+		reference := all[1].(map[string]interface{})
+		if reference["condition"] != "vip" {
+			t.Fatalf("Expected the reference to keep its name, got %v", reference)
+		}
+		tier := reference["realized"].(map[string]interface{})["any"].([]interface{})[0].(map[string]interface{})
+		return []map[string]interface{}{all[0].(map[string]interface{}), reference, tier, all[2].(map[string]interface{})}
+	}
+
+	got := leaves(t, `{"age": 30, "tier": "gold", "country": "CH", "billing": {"country": "CH"}}`)
+	if got[0]["operator"] != "greaterThan" || got[0]["factResult"] != float64(30) || got[0]["value"] != float64(17) || got[0]["result"] != true {
+		t.Errorf("Expected operator, fact value, value and result of the age condition, got %v", got[0])
+	}
+	if got[1]["result"] != true || got[2]["factResult"] != "gold" || got[2]["result"] != true {
+		t.Errorf("Expected the results of the referenced condition, got %v and %v", got[1], got[2])
+	}
+	if got[3]["valueResult"] != "CH" || got[3]["value"].(map[string]interface{})["fact"] != "billing.country" {
+		t.Errorf("Expected the fact reference and its resolved value, got %v", got[3])
+	}
+
+	// Every run records its results on its own copy of the conditions; the failing reference decides the group
+	got = leaves(t, `{"age": 40, "tier": "silver", "country": "DE", "billing": {"country": "DE"}}`)
+	if got[1]["result"] != false || got[2]["factResult"] != "silver" || got[2]["result"] != false {
+		t.Errorf("Expected the results of the second run, got %v", got)
+	}
+	if engine.Rules[0].Conditions.All[0].FactResult.Value != nil || engine.Rules[0].Conditions.All[1].Realized != nil {
+		t.Errorf("Expected the rule's conditions to be left untouched")
+	}
+}
@@ -1,7 +1,13 @@
 package rulesengine
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNewRule(t *testing.T) {
@@ -115,3 +121,830 @@ func TestNewRule(t *testing.T) {
 		}
 	})
 }
+
+func TestRulePrioritizedConditionsCache(t *testing.T) {
+	conditions := []*Condition{
+		{Fact: "a", Operator: "equal", Value: ValueNode{Type: Number, Number: 1}},
+		{Fact: "b", Operator: "equal", Value: ValueNode{Type: Number, Number: 1}},
+	}
+	rule, err := NewRule(&RuleConfig{Name: "cached", Conditions: Condition{Any: conditions}, Event: EventConfig{Type: "cached"}})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	engine := NewEngine([]*Rule{rule}, nil)
+
+	first := rule.prioritizedConditions(conditions)
+	if len(first) != 1 {
+		t.Fatalf("Expected a single priority set, got %d", len(first))
+	}
+	if second := rule.prioritizedConditions(conditions); &second[0] != &first[0] {
+		t.Errorf("Expected cached grouping to be reused")
+	}
+
+	// Changing the priority of an engine fact invalidates the cached grouping
+	if err := engine.AddFact("b", &ValueNode{Type: Number, Number: 1}, &FactOptions{Priority: 5}); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	sets := rule.prioritizedConditions(conditions)
+	if len(sets) != 2 || sets[0][0].Fact != "b" {
+		t.Errorf("Expected fact 'b' to be prioritized after the fact changed, got %v", sets)
+	}
+}
+
+func TestRuleEvaluateAggregatesErrors(t *testing.T) {
+	ruleJSON := `{
+		"name": "errors",
+		"conditions": {
+			"any": [
+				{"fact": "a", "operator": "unknownA", "value": 1},
+				{"fact": "b", "operator": "equal", "value": 1},
+				{"fact": "c", "operator": "unknownC", "value": 1}
+			]
+		},
+		"event": {"type": "errors"}
+	}`
+	facts := []byte(`{"a": 1, "b": 2, "c": 1}`)
+
+	t.Run("All errors of a group are returned", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, &RuleEngineOptions{AllowUnknownOperators: true})
+		_, err := engine.Run(context.Background(), facts)
+		if err == nil {
+			t.Fatalf("Expected an error")
+		}
+		for _, expected := range []string{"a unknownA > Unknown operator: unknownA", "c unknownC > Unknown operator: unknownC"} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected error to contain %q, got %q", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Errors are attached to the result with ContinueOnError", func(t *testing.T) {
+		engine := newTestEngine(t, ruleJSON, &RuleEngineOptions{ContinueOnError: true, AllowUnknownOperators: true})
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Expected run to continue, got error: %v", err)
+		}
+		failures := res.FailureResults
+		if len(failures) != 1 || failures[0].Error == nil {
+			t.Fatalf("Expected a failed result carrying the error, got %v", failures)
+		}
+		if !strings.Contains(failures[0].Error.Error(), "unknownA") || !strings.Contains(failures[0].Error.Error(), "unknownC") {
+			t.Errorf("Expected both errors on the result, got %q", failures[0].Error.Error())
+		}
+	})
+}
+
+func TestRuleConditionReferenceIfMissing(t *testing.T) {
+	newRule := func(t *testing.T, conditions string, options *RuleEngineOptions) *Engine {
+		return newTestEngine(t, `{"name": "missing", "conditions": `+conditions+`, "event": {"type": "missing"}}`, options)
+	}
+	facts := []byte(`{"age": 20}`)
+	succeeded := func(t *testing.T, res *RunResult) bool {
+		return len(res.Events) == 1
+	}
+
+	t.Run("Skip excludes the reference from its group", func(t *testing.T) {
+		engine := newRule(t, `{"all": [
+			{"condition": "premiumChecks", "ifMissing": "skip"},
+			{"fact": "age", "operator": "greaterThan", "value": 18}
+		]}`, nil)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Expected run to succeed, got error: %v", err)
+		}
+		if !succeeded(t, res) {
+			t.Errorf("Expected rule to pass with the missing reference skipped")
+		}
+		if resolution := res.Results[0].Conditions.All[0].MissingResolution; resolution != IfMissingSkip {
+			t.Errorf("Expected result tree to record the skip, got %q", resolution)
+		}
+	})
+
+	t.Run("False evaluates the reference as false", func(t *testing.T) {
+		engine := newRule(t, `{"all": [
+			{"condition": "premiumChecks", "ifMissing": "false"},
+			{"fact": "age", "operator": "greaterThan", "value": 18}
+		]}`, nil)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Expected run to succeed, got error: %v", err)
+		}
+		if succeeded(t, res) {
+			t.Errorf("Expected rule to fail with the missing reference evaluated as false")
+		}
+	})
+
+	t.Run("Fail errors even when undefined conditions are allowed", func(t *testing.T) {
+		engine := newRule(t, `{"all": [
+			{"condition": "premiumChecks", "ifMissing": "fail"},
+			{"fact": "age", "operator": "greaterThan", "value": 18}
+		]}`, &RuleEngineOptions{AllowUndefinedConditions: true})
+		if _, err := engine.Run(context.Background(), facts); err == nil || !strings.Contains(err.Error(), "no condition premiumChecks exists") {
+			t.Errorf("Expected missing condition error, got %v", err)
+		}
+	})
+
+	t.Run("Invalid ifMissing is rejected", func(t *testing.T) {
+		var c Condition
+		err := json.Unmarshal([]byte(`{"condition": "premiumChecks", "ifMissing": "ignore"}`), &c)
+		if err == nil || !strings.Contains(err.Error(), "invalid ifMissing") {
+			t.Errorf("Expected invalid ifMissing error, got %v", err)
+		}
+	})
+}
+
+func TestRuleEmptyConditionGroups(t *testing.T) {
+	facts := []byte(`{"age": 20}`)
+	passed := func(t *testing.T, engine *Engine) bool {
+		t.Helper()
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return len(res.Events) == 1
+	}
+	allowEmpty := &RuleEngineOptions{RejectEmptyGroups: false}
+
+	t.Run("Empty all is vacuously true", func(t *testing.T) {
+		engine := newTestEngine(t, `{"name": "r", "conditions": {"all": []}, "event": {"type": "r"}}`, allowEmpty)
+		if !passed(t, engine) {
+			t.Errorf("Expected an empty 'all' group to pass")
+		}
+	})
+
+	t.Run("Empty any is false", func(t *testing.T) {
+		engine := newTestEngine(t, `{"name": "r", "conditions": {"any": []}, "event": {"type": "r"}}`, allowEmpty)
+		if passed(t, engine) {
+			t.Errorf("Expected an empty 'any' group to fail")
+		}
+	})
+
+	t.Run("Nested empty any is false", func(t *testing.T) {
+		engine := newTestEngine(t, `{"name": "r", "conditions": {"any": [{"any": []}]}, "event": {"type": "r"}}`, allowEmpty)
+		if passed(t, engine) {
+			t.Errorf("Expected a nested empty 'any' group to fail")
+		}
+	})
+
+	t.Run("Empty groups are rejected by default", func(t *testing.T) {
+		var ruleConfig RuleConfig
+		if err := json.Unmarshal([]byte(`{"name": "r", "conditions": {"all": [{"fact": "age", "operator": "greaterThan", "value": 18}, {"any": []}]}, "event": {"type": "r"}}`), &ruleConfig); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		rule, err := NewRule(&ruleConfig)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		err = NewEngine(nil, nil).AddRule(rule)
+		if err == nil || !strings.Contains(err.Error(), "all[1].any") {
+			t.Errorf("Expected the empty group to be rejected with its path, got %v", err)
+		}
+	})
+
+	t.Run("Not over an empty condition is invalid", func(t *testing.T) {
+		var c Condition
+		if err := json.Unmarshal([]byte(`{"not": {}}`), &c); err == nil {
+			t.Errorf("Expected 'not' over an empty condition to be rejected")
+		}
+	})
+}
+
+func TestRuleGroupsAcrossPrioritySets(t *testing.T) {
+	facts := []byte(`{"a": 1, "b": 2}`)
+	testCases := []struct {
+		name, conditions string
+		expected         bool
+	}{
+		{"all fails when a higher priority set fails", `{"all": [{"fact": "a", "operator": "equal", "value": 2, "priority": 10}, {"fact": "b", "operator": "equal", "value": 2, "priority": 1}]}`, false},
+		{"all fails when a lower priority set fails", `{"all": [{"fact": "a", "operator": "equal", "value": 1, "priority": 10}, {"fact": "b", "operator": "equal", "value": 3, "priority": 1}]}`, false},
+		{"all passes when every set passes", `{"all": [{"fact": "a", "operator": "equal", "value": 1, "priority": 10}, {"fact": "b", "operator": "equal", "value": 2, "priority": 1}]}`, true},
+		{"any passes when a lower priority set passes", `{"any": [{"fact": "a", "operator": "equal", "value": 2, "priority": 10}, {"fact": "b", "operator": "equal", "value": 2, "priority": 1}]}`, true},
+		{"any fails when no set passes", `{"any": [{"fact": "a", "operator": "equal", "value": 2, "priority": 10}, {"fact": "b", "operator": "equal", "value": 3, "priority": 1}]}`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newTestEngine(t, `{"name": "r", "conditions": `+tc.conditions+`, "event": {"type": "r"}}`, nil)
+			res, err := engine.Run(context.Background(), facts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if passed := len(res.Results) == 1; passed != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, passed)
+			}
+		})
+	}
+}
+
+func TestRuleCostAwareOrdering(t *testing.T) {
+	newCostEngine := func(t *testing.T, conditions string) (*Engine, *atomic.Int32) {
+		t.Helper()
+		engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, &RuleEngineOptions{CostAwareOrdering: true})
+		var calls atomic.Int32
+		err := engine.AddCalculatedFact("expensive", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls.Add(1)
+			return &ValueNode{Type: Number, Number: 1}
+		}, &FactOptions{Cache: true, Priority: 1, Cost: 100})
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		return engine, &calls
+	}
+	facts := []byte(`{"cheap": 1}`)
+
+	t.Run("any tries cheap conditions first", func(t *testing.T) {
+		engine, calls := newCostEngine(t, `{"any": [
+			{"fact": "expensive", "operator": "equal", "value": 1},
+			{"fact": "cheap", "operator": "equal", "value": 1, "priority": 1}
+		]}`)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 || calls.Load() != 0 {
+			t.Errorf("Expected the rule to pass without calculating the expensive fact, got %d calculations", calls.Load())
+		}
+	})
+
+	t.Run("all evaluates expensive conditions last", func(t *testing.T) {
+		engine, calls := newCostEngine(t, `{"all": [
+			{"fact": "expensive", "operator": "equal", "value": 1},
+			{"fact": "cheap", "operator": "equal", "value": 2, "priority": 1}
+		]}`)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 0 || calls.Load() != 0 {
+			t.Errorf("Expected the rule to fail without calculating the expensive fact, got %d calculations", calls.Load())
+		}
+	})
+
+	t.Run("condition cost overrides the fact cost", func(t *testing.T) {
+		engine, calls := newCostEngine(t, `{"any": [
+			{"fact": "expensive", "operator": "equal", "value": 1, "cost": 0},
+			{"fact": "cheap", "operator": "equal", "value": 1, "priority": 1, "cost": 5}
+		]}`)
+		if _, err := engine.Run(context.Background(), facts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected the expensive fact to be tried first, got %d calculations", calls.Load())
+		}
+	})
+}
+
+func TestRuleLocalFacts(t *testing.T) {
+	engine := NewEngine(nil, nil)
+	for _, ruleJSON := range []string{
+		`{"name": "local", "facts": {"threshold": 100, "rates": {"gold": 0.2}}, "conditions": {"all": [
+			{"fact": "threshold", "operator": "equal", "value": 100},
+			{"fact": "rates.gold", "operator": "equal", "value": 0.2}
+		]}, "event": {"type": "local"}}`,
+		`{"name": "shared", "conditions": {"all": [{"fact": "threshold", "operator": "equal", "value": 100}]}, "event": {"type": "shared"}}`,
+	} {
+		var config RuleConfig
+		if err := json.Unmarshal([]byte(ruleJSON), &config); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		rule, err := NewRule(&config)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+
+	options := DefaultRunOptions()
+	options.MemoizeConditions = true
+	res, err := engine.RunWithOptions(context.Background(), []byte(`{"threshold": 5}`), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := res.Results
+	if len(results) != 1 || results[0].Name != "local" {
+		t.Fatalf("Expected only the rule with the local facts to pass, got %v", results)
+	}
+	if failed := res.FailureResults; len(failed) != 1 || failed[0].Name != "shared" {
+		t.Errorf("Expected the other rule not to see the local facts, got %v", failed)
+	}
+}
+
+func TestRuleOrderedGroups(t *testing.T) {
+	newOrderedEngine := func(t *testing.T, conditions string) (*Engine, *atomic.Int32) {
+		t.Helper()
+		engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, nil)
+		var calls atomic.Int32
+		err := engine.AddCalculatedFact("expensive", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls.Add(1)
+			return &ValueNode{Type: Number, Number: 1}
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		return engine, &calls
+	}
+	facts := []byte(`{"cheap": 1}`)
+
+	testCases := []struct {
+		name, conditions string
+		passes           bool
+	}{
+		{"ordered all stops at the first failing check", `{"ordered": true, "all": [
+			{"fact": "cheap", "operator": "equal", "value": 2},
+			{"fact": "expensive", "operator": "equal", "value": 1}
+		]}`, false},
+		{"ordered any stops at the first passing check", `{"all": [{"ordered": true, "any": [
+			{"fact": "cheap", "operator": "equal", "value": 1},
+			{"fact": "expensive", "operator": "equal", "value": 1}
+		]}]}`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, calls := newOrderedEngine(t, tc.conditions)
+			for i := 0; i < 20; i++ {
+				res, err := engine.Run(context.Background(), facts)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if passed := len(res.Results) == 1; passed != tc.passes {
+					t.Fatalf("Expected %v, got %v", tc.passes, passed)
+				}
+			}
+			if calls.Load() != 0 {
+				t.Errorf("Expected the expensive fact never to be calculated, got %d calculations", calls.Load())
+			}
+		})
+	}
+
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"ordered": true, "all": [{"fact": "cheap", "operator": "equal", "value": 1}]}`), &c); err != nil {
+		t.Fatalf("Failed to unmarshal condition: %v", err)
+	}
+	out, err := c.ToJSON(true)
+	if err != nil || !strings.Contains(out.(string), `"ordered":true`) {
+		t.Errorf("Expected ordered in the JSON, got %v, %v", out, err)
+	}
+	var leaf Condition
+	if err := json.Unmarshal([]byte(`{"ordered": true, "fact": "cheap", "operator": "equal", "value": 1}`), &leaf); err == nil || !strings.Contains(err.Error(), "ordered") {
+		t.Errorf("Expected ordered on a fact condition to be rejected")
+	}
+}
+
+func TestRuleNoneGroup(t *testing.T) {
+	testCases := []struct {
+		name, conditions string
+		passes           bool
+	}{
+		{"none of the conditions true", `{"none": [
+			{"fact": "country", "operator": "equal", "value": "KP"},
+			{"fact": "age", "operator": "lessThan", "value": 18}
+		]}`, true},
+		{"one condition true", `{"none": [
+			{"fact": "country", "operator": "equal", "value": "KP"},
+			{"fact": "age", "operator": "lessThan", "value": 30}
+		]}`, false},
+		{"nested in all", `{"all": [
+			{"fact": "age", "operator": "greaterThan", "value": 18},
+			{"none": [{"fact": "country", "operator": "in", "value": ["KP", "IR"]}]}
+		]}`, true},
+		{"empty group", `{"none": []}`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newTestEngine(t, `{"name": "r", "conditions": `+tc.conditions+`, "event": {"type": "r"}}`, &RuleEngineOptions{})
+			res, err := engine.Run(context.Background(), []byte(`{"country": "DE", "age": 25}`))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if passed := len(res.Results) == 1; passed != tc.passes {
+				t.Errorf("Expected %v, got %v", tc.passes, passed)
+			}
+		})
+	}
+
+	t.Run("stops at the first true condition", func(t *testing.T) {
+		engine := newTestEngine(t, `{"name": "r", "conditions": {"ordered": true, "none": [
+			{"fact": "country", "operator": "equal", "value": "DE"},
+			{"fact": "expensive", "operator": "equal", "value": 1}
+		]}, "event": {"type": "r"}}`, nil)
+		var calls atomic.Int32
+		err := engine.AddCalculatedFact("expensive", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls.Add(1)
+			return &ValueNode{Type: Number, Number: 1}
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		res, err := engine.Run(context.Background(), []byte(`{"country": "DE"}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.FailureResults) != 1 || calls.Load() != 0 {
+			t.Errorf("Expected the rule to fail without calculating the second fact, got %d calculations", calls.Load())
+		}
+	})
+
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"none": [{"fact": "country", "operator": "equal", "value": "KP"}]}`), &c); err != nil {
+		t.Fatalf("Failed to unmarshal condition: %v", err)
+	}
+	out, err := c.ToJSON(true)
+	if err != nil || !strings.Contains(out.(string), `"none":[`) {
+		t.Errorf("Expected none in the JSON, got %v, %v", out, err)
+	}
+	var mixed Condition
+	if err := json.Unmarshal([]byte(`{"fact": "age", "operator": "equal", "value": 1, "none": [{"fact": "country", "operator": "equal", "value": "KP"}]}`), &mixed); err == nil {
+		t.Errorf("Expected none mixed with fact fields to be rejected")
+	}
+}
+
+func TestRuleSiblingGroups(t *testing.T) {
+	const (
+		anyTrue  = `"any": [{"fact": "country", "operator": "equal", "value": "KP"}, {"fact": "age", "operator": "greaterThan", "value": 18}]`
+		anyFalse = `"any": [{"fact": "country", "operator": "equal", "value": "KP"}, {"fact": "age", "operator": "lessThan", "value": 18}]`
+		allTrue  = `"all": [{"fact": "country", "operator": "equal", "value": "DE"}, {"fact": "age", "operator": "greaterThan", "value": 18}]`
+		allFalse = `"all": [{"fact": "country", "operator": "equal", "value": "DE"}, {"fact": "age", "operator": "lessThan", "value": 18}]`
+	)
+	testCases := []struct {
+		name, conditions string
+		passes           bool
+	}{
+		{"any true, all true", anyTrue + ", " + allTrue, true},
+		{"any true, all false", anyTrue + ", " + allFalse, false},
+		{"any false, all true", anyFalse + ", " + allTrue, false},
+		{"any false, all false", anyFalse + ", " + allFalse, false},
+		{"any true, none true", anyTrue + `, "none": [{"fact": "age", "operator": "greaterThan", "value": 18}]`, false},
+		{"any true, not true", anyTrue + `, "not": {"fact": "country", "operator": "equal", "value": "DE"}`, false},
+		{"any true, not false", anyTrue + `, "not": {"fact": "country", "operator": "equal", "value": "KP"}`, true},
+		{"not true", `"not": {"fact": "country", "operator": "equal", "value": "DE"}`, false},
+	}
+	for _, tc := range testCases {
+		for _, nested := range []bool{false, true} {
+			conditions := `{` + tc.conditions + `}`
+			name := tc.name
+			if nested {
+				conditions = `{"all": [` + conditions + `]}`
+				name += ", nested"
+			}
+			t.Run(name, func(t *testing.T) {
+				engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, nil)
+				// Repeated runs must agree, whatever order the groups would be visited in
+				for i := 0; i < 10; i++ {
+					res, err := engine.Run(context.Background(), []byte(`{"country": "DE", "age": 25}`))
+					if err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+					if passed := len(res.Results) == 1; passed != tc.passes {
+						t.Fatalf("Run %d: expected %v, got %v", i, tc.passes, passed)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRuleNotGroup(t *testing.T) {
+	const (
+		isDE    = `{"fact": "country", "operator": "equal", "value": "DE"}`
+		isKP    = `{"fact": "country", "operator": "equal", "value": "KP"}`
+		isAdult = `{"fact": "age", "operator": "greaterThan", "value": 18}`
+	)
+	testCases := []struct {
+		name, conditions string
+		passes           bool
+	}{
+		{"not all, all true", `{"not": {"all": [` + isDE + `, ` + isAdult + `]}}`, false},
+		{"not all, one false", `{"not": {"all": [` + isKP + `, ` + isAdult + `]}}`, true},
+		{"not any, one true", `{"not": {"any": [` + isKP + `, ` + isAdult + `]}}`, false},
+		{"not any, none true", `{"not": {"any": [` + isKP + `]}}`, true},
+		{"not not", `{"not": {"not": ` + isDE + `}}`, true},
+		{"not not not", `{"not": {"not": {"not": ` + isDE + `}}}`, false},
+		{"not reference", `{"not": {"condition": "sanctioned"}}`, true},
+		{"nested not all", `{"all": [` + isAdult + `, {"not": {"all": [` + isDE + `, ` + isAdult + `]}}]}`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := NewEngine(nil, nil)
+			if err := engine.SetCondition("sanctioned", Condition{Any: []*Condition{
+				{Fact: "country", Operator: "in", Value: ValueNode{Type: Array, Array: []ValueNode{{Type: String, String: "KP"}}}},
+			}}); err != nil {
+				t.Fatalf("Failed to set condition: %v", err)
+			}
+			var ruleConfig RuleConfig
+			if err := json.Unmarshal([]byte(`{"name": "r", "conditions": `+tc.conditions+`, "event": {"type": "r"}}`), &ruleConfig); err != nil {
+				t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+			}
+			rule, err := NewRule(&ruleConfig)
+			if err != nil {
+				t.Fatalf("Failed to create rule: %v", err)
+			}
+			if err := engine.AddRule(rule); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+			res, err := engine.Run(context.Background(), []byte(`{"country": "DE", "age": 25}`))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if passed := len(res.Results) == 1; passed != tc.passes {
+				t.Errorf("Expected %v, got %v", tc.passes, passed)
+			}
+		})
+	}
+}
+
+func TestRuleAtLeastGroup(t *testing.T) {
+	signals := `[
+		{"name": "newDevice", "fact": "newDevice", "operator": "equal", "value": true},
+		{"name": "foreignIp", "fact": "country", "operator": "notEqual", "value": "CH"},
+		{"name": "highAmount", "fact": "amount", "operator": "greaterThan", "value": 1000},
+		{"name": "night", "fact": "hour", "operator": "lessThan", "value": 6}
+	]`
+	testCases := []struct {
+		name, facts string
+		passes      bool
+	}{
+		{"enough signals", `{"newDevice": true, "country": "US", "amount": 5000, "hour": 12}`, true},
+		{"too few signals", `{"newDevice": true, "country": "CH", "amount": 50, "hour": 12}`, false},
+	}
+	for _, tc := range testCases {
+		for _, ordered := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s ordered=%v", tc.name, ordered), func(t *testing.T) {
+				conditions := fmt.Sprintf(`{"all": [{"atLeast": 3, "ordered": %v, "any": %s}]}`, ordered, signals)
+				engine := newTestEngine(t, `{"name": "fraud", "conditions": `+conditions+`, "event": {"type": "fraud"}}`, nil)
+				res, err := engine.Run(context.Background(), []byte(tc.facts))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				results := res.Results
+				if passed := len(results) == 1; passed != tc.passes {
+					t.Fatalf("Expected %v, got %v", tc.passes, passed)
+				}
+				if tc.passes {
+					group := results[0].Conditions.All[0]
+					if group.MetCount < 3 || !group.FindByName("highAmount").Result {
+						t.Errorf("Expected the matched signals on the result, got metCount %d", group.MetCount)
+					}
+				}
+			})
+		}
+	}
+
+	t.Run("stops once the count can no longer be reached", func(t *testing.T) {
+		conditions := `{"atLeast": 2, "ordered": true, "any": [
+			{"fact": "a", "operator": "equal", "value": 2},
+			{"fact": "a", "operator": "equal", "value": 3},
+			{"fact": "expensive", "operator": "equal", "value": 1}
+		]}`
+		engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, nil)
+		var calls atomic.Int32
+		err := engine.AddCalculatedFact("expensive", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls.Add(1)
+			return &ValueNode{Type: Number, Number: 1}
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		res, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.FailureResults) != 1 || calls.Load() != 0 {
+			t.Errorf("Expected the rule to fail without calculating the last fact, got %d calculations", calls.Load())
+		}
+	})
+
+	t.Run("skipped references count towards the count being out of reach", func(t *testing.T) {
+		conditions := `{"atLeast": 2, "ordered": true, "any": [
+			{"fact": "a", "operator": "equal", "value": 2},
+			{"condition": "missing", "ifMissing": "skip"},
+			{"fact": "expensive", "operator": "equal", "value": 1}
+		]}`
+		engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, nil)
+		var calls atomic.Int32
+		err := engine.AddCalculatedFact("expensive", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls.Add(1)
+			return &ValueNode{Type: Number, Number: 1}
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		res, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.FailureResults) != 1 || calls.Load() != 0 {
+			t.Errorf("Expected the rule to fail without calculating the last fact, got %d calculations", calls.Load())
+		}
+	})
+
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"atLeast": 3, "any": [{"fact": "a", "operator": "equal", "value": 1}]}`), &c); err == nil || !strings.Contains(err.Error(), "atLeast") {
+		t.Errorf("Expected atLeast above the group size to be rejected, got %v", err)
+	}
+}
+
+func TestRuleMostOfGroup(t *testing.T) {
+	checks := `[
+		{"name": "hasEmail", "fact": "email", "operator": "includes", "value": "@"},
+		{"name": "hasPhone", "fact": "phone", "operator": "notEqual", "value": ""},
+		{"name": "hasCountry", "fact": "country", "operator": "notEqual", "value": ""},
+		{"name": "adult", "fact": "age", "operator": "greaterThanInclusive", "value": 18},
+		{"name": "verified", "fact": "verified", "operator": "equal", "value": true}
+	]`
+	testCases := []struct {
+		name, facts string
+		passes      bool
+	}{
+		{"4 of 5 checks", `{"email": "a@b.ch", "phone": "1", "country": "CH", "age": 30, "verified": false}`, true},
+		{"3 of 5 checks", `{"email": "a@b.ch", "phone": "", "country": "CH", "age": 30, "verified": false}`, false},
+	}
+	for _, tc := range testCases {
+		for _, ordered := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s ordered=%v", tc.name, ordered), func(t *testing.T) {
+				conditions := fmt.Sprintf(`{"all": [{"minPassRatio": 0.8, "ordered": %v, "mostOf": %s}]}`, ordered, checks)
+				engine := newTestEngine(t, `{"name": "quality", "conditions": `+conditions+`, "event": {"type": "quality"}}`, nil)
+				res, err := engine.Run(context.Background(), []byte(tc.facts))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if passed := len(res.Results) == 1; passed != tc.passes {
+					t.Fatalf("Expected %v, got %v", tc.passes, passed)
+				}
+				// The ratio covers the conditions evaluated before the group was decided
+				results := append(res.Results, res.FailureResults...)
+				if group := results[0].Conditions.All[0]; (group.PassRatio >= 0.8) != tc.passes {
+					t.Errorf("Expected the pass ratio to match the outcome, got %v", group.PassRatio)
+				}
+			})
+		}
+	}
+
+	t.Run("skipped conditions are not counted", func(t *testing.T) {
+		conditions := `{"minPassRatio": 0.75, "mostOf": [
+			{"fact": "a", "operator": "equal", "value": 1},
+			{"fact": "b", "operator": "equal", "value": 1},
+			{"fact": "c", "operator": "equal", "value": 1},
+			{"condition": "unregistered", "ifMissing": "skip"},
+			{"fact": "d", "operator": "equal", "value": 1}
+		]}`
+		engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, nil)
+		res, err := engine.Run(context.Background(), []byte(`{"a": 1, "b": 1, "c": 1, "d": 2}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 || res.Results[0].Conditions.PassRatio != 0.75 {
+			t.Errorf("Expected 3 of 4 evaluated conditions to pass, got %v", res.FailureResults)
+		}
+		data, err := res.Results[0].ToJSON(true)
+		if err != nil || !strings.Contains(data.(string), `"passRatio":0.75`) {
+			t.Errorf("Expected the pass ratio in the result JSON, got %v (%v)", data, err)
+		}
+	})
+
+	t.Run("stops once the ratio is guaranteed or impossible", func(t *testing.T) {
+		testCases := []struct {
+			name, facts string
+			ratio       float64
+			passes      bool
+		}{
+			{"guaranteed", `{"a": 1, "b": 1}`, 0.5, true},
+			{"impossible", `{"a": 2, "b": 1}`, 1, false},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				conditions := fmt.Sprintf(`{"minPassRatio": %v, "ordered": true, "mostOf": [
+					{"fact": "a", "operator": "equal", "value": 1},
+					{"fact": "b", "operator": "equal", "value": 1},
+					{"fact": "expensive", "operator": "equal", "value": 1}
+				]}`, tc.ratio)
+				engine := newTestEngine(t, `{"name": "r", "conditions": `+conditions+`, "event": {"type": "r"}}`, nil)
+				var calls atomic.Int32
+				if err := engine.AddCalculatedFact("expensive", func(a *Almanac, params ...interface{}) *ValueNode {
+					calls.Add(1)
+					return &ValueNode{Type: Number, Number: 1}
+				}, nil); err != nil {
+					t.Fatalf("Failed to add fact: %v", err)
+				}
+				res, err := engine.Run(context.Background(), []byte(tc.facts))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if passed := len(res.Results) == 1; passed != tc.passes || calls.Load() != 0 {
+					t.Errorf("Expected %v without calculating the last fact, got %v after %d calculations", tc.passes, passed, calls.Load())
+				}
+			})
+		}
+	})
+
+	t.Run("built in code", func(t *testing.T) {
+		leaf, err := NewLeafCondition("a", "equal", 1)
+		if err != nil {
+			t.Fatalf("Failed to build condition: %v", err)
+		}
+		group, err := NewMostOfCondition(0.5, leaf, leaf)
+		if err != nil {
+			t.Fatalf("Failed to build condition: %v", err)
+		}
+		data, err := json.Marshal(group)
+		if err != nil {
+			t.Fatalf("Failed to marshal condition: %v", err)
+		}
+		var decoded Condition
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded.MinPassRatio != 0.5 || len(decoded.MostOf) != 2 {
+			t.Errorf("Expected the group to round-trip, got %s (%v)", data, err)
+		}
+	})
+
+	for _, invalid := range []string{
+		`{"minPassRatio": 0, "mostOf": [{"fact": "a", "operator": "equal", "value": 1}]}`,
+		`{"minPassRatio": 1.5, "mostOf": [{"fact": "a", "operator": "equal", "value": 1}]}`,
+		`{"minPassRatio": 0.5, "all": [{"fact": "a", "operator": "equal", "value": 1}]}`,
+		`{"minPassRatio": 0.5, "mostOf": [{"fact": "a", "operator": "equal", "value": 1}], "all": []}`,
+		`{"minPassRatio": 0.5, "mostOf": []}`,
+	} {
+		var c Condition
+		if err := json.Unmarshal([]byte(invalid), &c); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestNewRuleCopiesEventParams(t *testing.T) {
+	params := map[string]interface{}{
+		"discount": 10,
+		"tags":     []interface{}{"a", map[string]interface{}{"b": 1}},
+		"limits":   map[string][]int{"daily": {1, 2}},
+	}
+	config := &RuleConfig{
+		Name:       "params",
+		Conditions: Condition{All: []*Condition{{Fact: "a", Operator: "equal", Value: ValueNode{Type: Number, Number: 1}}}},
+		Event:      EventConfig{Type: "params", Params: &params},
+	}
+	rule, err := NewRule(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The config is reused for the next rule
+	params["discount"] = 20
+	params["tags"].([]interface{})[1].(map[string]interface{})["b"] = 2
+	params["limits"].(map[string][]int)["daily"][0] = 5
+	params["added"] = true
+
+	event := rule.GetEvent()
+	if event.Params["discount"] != 10 || event.Params["added"] != nil {
+		t.Errorf("Expected the rule's params to be unaffected, got %v", event.Params)
+	}
+	if b := event.Params["tags"].([]interface{})[1].(map[string]interface{})["b"]; b != 1 {
+		t.Errorf("Expected nested maps to be copied, got %v", b)
+	}
+	if daily := event.Params["limits"].(map[string][]int)["daily"]; daily[0] != 1 {
+		t.Errorf("Expected typed slices to be copied, got %v", daily)
+	}
+}
+
+func TestRuleWideNestedGroups(t *testing.T) {
+	// More children per level than a group evaluates at a time, each containing another group
+	const width = 15
+	var build func(depth int) *Condition
+	build = func(depth int) *Condition {
+		if depth == 0 {
+			return &Condition{Fact: "a", Operator: "equal", Value: ValueNode{Type: Number, Number: 1}}
+		}
+		children := make([]*Condition, width)
+		for i := range children {
+			children[i] = build(depth - 1)
+		}
+		if depth%2 == 0 {
+			return &Condition{Any: children}
+		}
+		return &Condition{All: children}
+	}
+	root := build(3)
+	rule, err := NewRule(&RuleConfig{Name: "wide", Conditions: *root, Event: EventConfig{Type: "wide"}})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	engine := NewEngine(nil, nil)
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	done := make(chan *RunResult, 1)
+	go func() {
+		res, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		done <- res
+	}()
+	select {
+	case res := <-done:
+		if res != nil && len(res.Results) != 1 {
+			t.Errorf("Expected the rule to pass, got %v", res.FailureResults)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run of a wide nested rule did not complete")
+	}
+}
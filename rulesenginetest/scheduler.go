@@ -0,0 +1,29 @@
+// Package rulesenginetest provides helpers for testing code built on the rules engine.
+package rulesenginetest
+
+import (
+	rulesengine "github.com/nimbit-software/gojson-rules-engine"
+)
+
+// SerialScheduler runs every task to completion before returning, in the order the engine submits them.
+// Rules are evaluated in priority order and, within a group, in the order they were added; conditions are
+// evaluated in the order of their group. This makes short-circuiting and error propagation deterministic.
+type SerialScheduler struct{}
+
+// Go runs the task on the calling goroutine
+func (SerialScheduler) Go(task func()) {
+	task()
+}
+
+// NewEngine creates an engine evaluating with a SerialScheduler.
+// The options are copied; nil uses rulesengine.DefaultRuleEngineOptions.
+func NewEngine(rules []*rulesengine.Rule, options *rulesengine.RuleEngineOptions) *rulesengine.Engine {
+	if options == nil {
+		options = rulesengine.DefaultRuleEngineOptions()
+	}
+	serial := *options
+	serial.Scheduler = SerialScheduler{}
+	return rulesengine.NewEngine(rules, &serial)
+}
+
+var _ rulesengine.Scheduler = SerialScheduler{}
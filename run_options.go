@@ -0,0 +1,188 @@
+package rulesengine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxFactResolutions is the default number of fact resolutions allowed in a single run
+	DefaultMaxFactResolutions int64 = 1_000_000
+	// DefaultMaxConditionEvaluations is the default number of leaf condition evaluations allowed in a single run
+	DefaultMaxConditionEvaluations int64 = 1_000_000
+)
+
+// RunOptions configures a single engine run.
+// Zero values fall back to the defaults; a negative limit disables the corresponding guard.
+// MaxCachedFactBytes and LazyArrayThreshold are disabled when zero.
+type RunOptions struct {
+	MaxFactResolutions      int64
+	MaxConditionEvaluations int64
+	MaxCachedFactBytes      int64 // Estimated size after which facts are resolved from the raw input on every access
+	LazyArrayThreshold      int   // Arrays with more elements than this are never cached in the almanac
+	// Values seeds the run-scoped key/value store available from the ExecutionContext and Almanac.Values
+	Values map[string]interface{}
+	// CacheKey identifies the run in the engine result cache; when empty a hash of the raw facts is used.
+	// Runs only share a result when the options changing it, such as budgets and Trace, are the same too.
+	// Event handlers do not fire for runs served from the cache, see RuleEngineOptions.ResultCacheSize.
+	CacheKey string
+	// IgnorePriorityBarriers evaluates all rules concurrently instead of one priority group at a time.
+	// Results are still reported in priority order. Only valid for independent rules: adding runtime facts
+	// during such a run fails it with ErrPriorityBarriersRequired.
+	IgnorePriorityBarriers bool
+	// Serialization is applied to the rule results of the run, e.g. to keep API responses small
+	Serialization *SerializationOptions
+	// MemoizeConditions reuses the result of identical leaf conditions within the run, across priority groups,
+	// for as long as no fact was added to the almanac. Operators must be deterministic.
+	MemoizeConditions bool
+	// EphemeralRules are evaluated alongside the engine's rules for this run only; they are validated like added
+	// rules but never stored. Runs with ephemeral rules bypass the result cache.
+	EphemeralRules []RuleConfig
+	// MaxEphemeralRules limits the ephemeral rules of a run, including those embedded in the facts;
+	// 0 for DefaultMaxEphemeralRules, negative for no limit
+	MaxEphemeralRules int
+	// MaxEphemeralConditions limits the conditions of each ephemeral rule, groups included;
+	// 0 for DefaultMaxEphemeralConditions, negative for no limit
+	MaxEphemeralConditions int
+	// SoftDeadline is a soft time budget for the run: once it has elapsed, the priority group being evaluated completes
+	// and the remaining groups are skipped, their rules reported with SkippedBudget. 0 for no deadline.
+	// Unlike cancelling the context, it never interrupts a group. Ignored with IgnorePriorityBarriers.
+	SoftDeadline time.Duration
+	// SkipRulesWithoutFacts skips rules whose referenced facts all live under top-level keys absent from the fact
+	// document, e.g. rules on "device.*" for documents without "device", reporting them with SkippedNoData.
+	// Only rules that are known to fail without their facts are skipped; rules passing or erroring on undefined facts,
+	// reading engine or rule-local facts, or referencing unregistered conditions are always evaluated.
+	SkipRulesWithoutFacts bool
+	// Trace records the evaluation time of every leaf condition on the results, see Condition.Duration.
+	// The time spent resolving a fact is attributed to the first condition that needed it.
+	Trace bool
+	// TrackFactAccess records the fact values resolved during the run, for Almanac.AccessedFacts and
+	// Almanac.MarshalSnapshot. The first value resolved for a path and set of params is kept.
+	TrackFactAccess bool
+	// RuleTimeout is a soft time limit per rule: rules evaluating longer are reported with a DiagnosticRuleTimeout
+	// diagnostic. Their evaluation is not interrupted and their result is kept. 0 for no limit.
+	RuleTimeout time.Duration
+	// CheckDeterminism evaluates every rule a second time against a copy of the almanac, calculated facts included,
+	// and reports rules whose outcome differs with a DiagnosticNonDeterministicRule diagnostic, e.g. for fact callbacks
+	// keeping state in package-level variables. It doubles the cost of the run and is meant for CI and staging.
+	CheckDeterminism bool
+	// AllowFactFragments skips the validation of the fact input, for callers passing JSON fragments on purpose.
+	// Otherwise input that is not a JSON object, e.g. empty or truncated, fails the run with ErrInvalidFactsJSON.
+	AllowFactFragments bool
+	// Locale selects the message catalog and the failureMessage variants RuleResult.Explain renders the results of
+	// the run with, e.g. "de" or "de-CH"; empty for DefaultLocale. See Engine.AddMessageCatalog.
+	Locale string
+
+	quiet       bool         // Events are collected but not published to handlers, used by Prime and Replay
+	ruleTimings *ruleTimings // Collects rule evaluation durations, used by Prime
+	replay      *replayFacts // Recorded facts served in place of the fact document, used by Replay
+	session     *Session     // Shares session-cached facts between runs, used by Session
+}
+
+// DefaultRunOptions returns the default set of options used for a run.
+func DefaultRunOptions() *RunOptions {
+	return &RunOptions{
+		MaxFactResolutions:      DefaultMaxFactResolutions,
+		MaxConditionEvaluations: DefaultMaxConditionEvaluations,
+	}
+}
+
+// RunStats holds counters collected during a single engine run.
+type RunStats struct {
+	FactResolutions       int64 `json:"factResolutions"`
+	ConditionEvaluations  int64 `json:"conditionEvaluations"`
+	CachedFactBytes       int64 `json:"cachedFactBytes"`
+	FactCacheLimitReached bool  `json:"factCacheLimitReached"`
+	// EventConflicts holds the resolutions of exclusive event group violations
+	EventConflicts []EventConflict `json:"eventConflicts,omitempty"`
+	// ConditionMemoHits counts the leaf conditions answered from the condition memo
+	ConditionMemoHits int64 `json:"conditionMemoHits"`
+	// CrossGroupMemoHits counts the memo hits on results recorded by an earlier priority group
+	CrossGroupMemoHits int64 `json:"crossGroupMemoHits"`
+	// PriorityGroupDurations holds the time spent on each evaluated priority group, in evaluation order
+	PriorityGroupDurations []time.Duration `json:"priorityGroupDurationsNs"`
+	// UndefinedConditions holds the references to missing conditions that were tolerated, see UndefinedCondition
+	UndefinedConditions []UndefinedCondition `json:"undefinedConditions,omitempty"`
+}
+
+// evaluationBudget tracks the per-run evaluation counters against their limits.
+// Counters are updated atomically since conditions are evaluated concurrently.
+type evaluationBudget struct {
+	maxFactResolutions      int64
+	maxConditionEvaluations int64
+	factResolutions         atomic.Int64
+	conditionEvaluations    atomic.Int64
+	// exceeded keeps the first budget error, so a run fails even when a calculated fact swallowed the error
+	exceeded atomic.Pointer[EvaluationBudgetExceededError]
+}
+
+// newEvaluationBudget creates a budget from the given run options, applying defaults for unset limits.
+func newEvaluationBudget(options *RunOptions) *evaluationBudget {
+	if options == nil {
+		options = DefaultRunOptions()
+	}
+	b := &evaluationBudget{
+		maxFactResolutions:      options.MaxFactResolutions,
+		maxConditionEvaluations: options.MaxConditionEvaluations,
+	}
+	if b.maxFactResolutions == 0 {
+		b.maxFactResolutions = DefaultMaxFactResolutions
+	}
+	if b.maxConditionEvaluations == 0 {
+		b.maxConditionEvaluations = DefaultMaxConditionEvaluations
+	}
+	return b
+}
+
+// useFactResolution records a fact resolution and returns an error once the budget is exhausted.
+// rule is the rule whose condition resolved the fact, if any.
+func (b *evaluationBudget) useFactResolution(rule, fact string) error {
+	if b == nil {
+		return nil
+	}
+	n := b.factResolutions.Add(1)
+	if b.maxFactResolutions > 0 && n > b.maxFactResolutions {
+		return b.exceed(&EvaluationBudgetExceededError{Budget: "factResolutions", Limit: b.maxFactResolutions, Rule: rule, Fact: fact})
+	}
+	return nil
+}
+
+// useConditionEvaluation records a leaf condition evaluation and returns an error once the budget is exhausted.
+func (b *evaluationBudget) useConditionEvaluation(rule, fact string) error {
+	if b == nil {
+		return nil
+	}
+	n := b.conditionEvaluations.Add(1)
+	if b.maxConditionEvaluations > 0 && n > b.maxConditionEvaluations {
+		return b.exceed(&EvaluationBudgetExceededError{Budget: "conditionEvaluations", Limit: b.maxConditionEvaluations, Rule: rule, Fact: fact})
+	}
+	return nil
+}
+
+// exceed records the first budget error of the run and returns err
+func (b *evaluationBudget) exceed(err *EvaluationBudgetExceededError) error {
+	b.exceeded.CompareAndSwap(nil, err)
+	return err
+}
+
+// err returns the first budget error of the run, or nil when the budget was never exhausted
+func (b *evaluationBudget) err() error {
+	if b == nil {
+		return nil
+	}
+	if err := b.exceeded.Load(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stats returns a snapshot of the budget counters.
+func (b *evaluationBudget) stats() RunStats {
+	if b == nil {
+		return RunStats{}
+	}
+	return RunStats{
+		FactResolutions:      b.factResolutions.Load(),
+		ConditionEvaluations: b.conditionEvaluations.Load(),
+	}
+}
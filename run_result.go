@@ -0,0 +1,156 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RunResult is the outcome of a run of the engine.
+type RunResult struct {
+	// Almanac holds the facts and rule results of the run. It is not serialized.
+	Almanac *Almanac
+	// Results holds the results of the rules that passed
+	Results []*RuleResult
+	// FailureResults holds the results of the rules that failed
+	FailureResults []*RuleResult
+	// Events holds the events of the rules that passed, without the events dropped by event conflicts
+	Events []Event
+	// FailureEvents holds the events of the rules that failed
+	FailureEvents []Event
+	// DroppedEvents holds the success events removed while resolving exclusive event conflicts
+	DroppedEvents []Event
+	// SkippedResults holds the rules that were not evaluated, see RuleResult.Skipped
+	SkippedResults []*RuleResult
+	// Diagnostics holds the diagnostics of the run, such as rule errors and timeouts; they are never part of Events
+	Diagnostics []Diagnostic
+	Stats       RunStats
+	// Cached is true when the result was served from the result cache
+	Cached bool
+	// Partial is true when the run was cancelled or its soft deadline passed before all rules were evaluated
+	Partial bool
+	// DeadlineExceeded is true when the soft deadline of the run passed
+	DeadlineExceeded bool
+	// Error is the error of the caller's context when the run was cancelled
+	Error error
+}
+
+// cachedCopy returns a copy of a cached result for a result cache hit. The result slices, rule results, condition
+// trees and event params are copied, so callers can modify them without affecting the cache or other hits; the
+// almanac is shared and must be treated as read-only. The rule results use the serialization and locale of the run.
+func (r *RunResult) cachedCopy(serialization *SerializationOptions, locale string) *RunResult {
+	res := *r
+	res.Results = cloneRuleResults(r.Results, serialization, locale)
+	res.FailureResults = cloneRuleResults(r.FailureResults, serialization, locale)
+	res.SkippedResults = cloneRuleResults(r.SkippedResults, serialization, locale)
+	res.Events = cloneEvents(r.Events)
+	res.FailureEvents = cloneEvents(r.FailureEvents)
+	res.DroppedEvents = cloneEvents(r.DroppedEvents)
+	if r.Diagnostics != nil {
+		res.Diagnostics = make([]Diagnostic, len(r.Diagnostics))
+		for i, diagnostic := range r.Diagnostics {
+			diagnostic.Paths = append([]string(nil), diagnostic.Paths...)
+			res.Diagnostics[i] = diagnostic
+		}
+	}
+	if r.Stats.EventConflicts != nil {
+		res.Stats.EventConflicts = make([]EventConflict, len(r.Stats.EventConflicts))
+		for i, conflict := range r.Stats.EventConflicts {
+			res.Stats.EventConflicts[i] = EventConflict{
+				Events:  append([]string(nil), conflict.Events...),
+				Rules:   append([]string(nil), conflict.Rules...),
+				Winner:  conflict.Winner,
+				Dropped: append([]string(nil), conflict.Dropped...),
+			}
+		}
+	}
+	if r.Stats.PriorityGroupDurations != nil {
+		res.Stats.PriorityGroupDurations = append(make([]time.Duration, 0, len(r.Stats.PriorityGroupDurations)), r.Stats.PriorityGroupDurations...)
+	}
+	if r.Stats.UndefinedConditions != nil {
+		res.Stats.UndefinedConditions = append(make([]UndefinedCondition, 0, len(r.Stats.UndefinedConditions)), r.Stats.UndefinedConditions...)
+	}
+	res.Cached = true
+	return &res
+}
+
+// cloneRuleResults deep copies rule results, see RuleResult.clone
+func cloneRuleResults(results []*RuleResult, serialization *SerializationOptions, locale string) []*RuleResult {
+	if results == nil {
+		return nil
+	}
+	cloned := make([]*RuleResult, len(results))
+	for i, ruleResult := range results {
+		cloned[i] = ruleResult.clone()
+		cloned[i].Serialization = serialization
+		cloned[i].Locale = locale
+	}
+	return cloned
+}
+
+// cloneEvents copies events along with their params
+func cloneEvents(events []Event) []Event {
+	if events == nil {
+		return nil
+	}
+	cloned := make([]Event, len(events))
+	for i, event := range events {
+		cloned[i] = Event{Type: event.Type, Params: copyParams(event.Params)}
+	}
+	return cloned
+}
+
+// Decision returns the event of the highest priority successful rule of the run, see Decision.
+func (r *RunResult) Decision() (*Event, *RuleResult, bool) {
+	return Decision(r.Results)
+}
+
+// DecisionsByType returns the events of the given type emitted by successful rules, see DecisionsByType.
+func (r *RunResult) DecisionsByType(eventType string) []*Event {
+	return DecisionsByType(r.Results, eventType)
+}
+
+// runResultJSON is the serialized form of a RunResult
+type runResultJSON struct {
+	Results          []*RuleResult `json:"results"`
+	FailureResults   []*RuleResult `json:"failureResults"`
+	Events           []Event       `json:"events"`
+	FailureEvents    []Event       `json:"failureEvents"`
+	DroppedEvents    []Event       `json:"droppedEvents"`
+	SkippedResults   []*RuleResult `json:"skippedResults"`
+	Diagnostics      []Diagnostic  `json:"diagnostics"`
+	Stats            RunStats      `json:"stats"`
+	Cached           bool          `json:"cached"`
+	Partial          bool          `json:"partial"`
+	DeadlineExceeded bool          `json:"deadlineExceeded"`
+	Error            string        `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, so the outcome of a run can be logged or returned as is.
+// The almanac is left out; empty lists are encoded as [] rather than null.
+func (r *RunResult) MarshalJSON() ([]byte, error) {
+	out := runResultJSON{
+		Results:          nonNilSlice(r.Results),
+		FailureResults:   nonNilSlice(r.FailureResults),
+		Events:           nonNilSlice(r.Events),
+		FailureEvents:    nonNilSlice(r.FailureEvents),
+		DroppedEvents:    nonNilSlice(r.DroppedEvents),
+		SkippedResults:   nonNilSlice(r.SkippedResults),
+		Diagnostics:      nonNilSlice(r.Diagnostics),
+		Stats:            r.Stats,
+		Cached:           r.Cached,
+		Partial:          r.Partial,
+		DeadlineExceeded: r.DeadlineExceeded,
+	}
+	if r.Error != nil {
+		out.Error = r.Error.Error()
+	}
+	return json.Marshal(out)
+}
+
+// nonNilSlice returns an empty slice for nil
+func nonNilSlice[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRunResultMarshalJSON(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "adult",
+		"conditions": {"all": [{"fact": "age", "operator": "greaterThan", "value": 17}]},
+		"event": {"type": "adult", "params": {"discount": 10}}
+	}`, nil)
+
+	res, err := engine.Run(context.Background(), []byte(`{"age": 30}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if res.Almanac == nil || len(res.Results) != 1 || len(res.Events) != 1 || res.Events[0].Type != "adult" {
+		t.Fatalf("Unexpected result %+v", res)
+	}
+	if event, ruleResult, ok := res.Decision(); !ok || event.Type != "adult" || ruleResult.Name != "adult" {
+		t.Errorf("Expected the adult rule to decide, got %v %v", event, ruleResult)
+	}
+	if events := res.DecisionsByType("adult"); len(events) != 1 {
+		t.Errorf("Expected 1 adult event, got %d", len(events))
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("Failed to marshal run result: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal run result: %v", err)
+	}
+	if _, ok := decoded["almanac"]; ok {
+		t.Errorf("Expected the almanac to be left out")
+	}
+	if results := decoded["results"].([]interface{}); len(results) != 1 || results[0].(map[string]interface{})["name"] != "adult" {
+		t.Errorf("Expected the adult result, got %v", decoded["results"])
+	}
+	if failures, ok := decoded["failureResults"].([]interface{}); !ok || len(failures) != 0 {
+		t.Errorf("Expected an empty list of failures, got %v", decoded["failureResults"])
+	}
+	if stats := decoded["stats"].(map[string]interface{}); stats["factResolutions"] != float64(1) {
+		t.Errorf("Expected the run stats, got %v", stats)
+	}
+	if _, ok := decoded["error"]; ok || decoded["partial"] != false || decoded["cached"] != false {
+		t.Errorf("Unexpected flags in %s", data)
+	}
+}
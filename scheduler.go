@@ -0,0 +1,25 @@
+package rulesengine
+
+// Scheduler runs the evaluation tasks of a run: one task per rule of a priority group, and one per condition of a group.
+// The default scheduler starts a goroutine per task. Tests can replace it, e.g. with rulesenginetest.SerialScheduler,
+// to make the interleaving of tasks deterministic.
+type Scheduler interface {
+	// Go runs the task, either concurrently or before returning
+	Go(task func())
+}
+
+// goroutineScheduler runs every task on its own goroutine
+type goroutineScheduler struct{}
+
+func (goroutineScheduler) Go(task func()) {
+	go task()
+}
+
+// schedule runs a task with the engine's scheduler
+func (e *Engine) schedule(task func()) {
+	if e == nil || e.scheduler == nil {
+		go task()
+		return
+	}
+	e.scheduler.Go(task)
+}
@@ -0,0 +1,140 @@
+package rulesengine_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	rulesengine "github.com/nimbit-software/gojson-rules-engine"
+	"github.com/nimbit-software/gojson-rules-engine/rulesenginetest"
+)
+
+// newSerialEngine creates a serially scheduled engine with the rules parsed from JSON and a "counted" operator
+// behaving like equal, whose invocations are counted
+func newSerialEngine(t *testing.T, options *rulesengine.RuleEngineOptions, rulesJSON ...string) (*rulesengine.Engine, *atomic.Int32) {
+	t.Helper()
+	engine := rulesenginetest.NewEngine(nil, options)
+	var calls atomic.Int32
+	engine.AddOperator("counted", func(a, b *rulesengine.ValueNode) bool {
+		calls.Add(1)
+		return rulesengine.EvalEqual(a, b)
+	})
+	for _, ruleJSON := range rulesJSON {
+		var ruleConfig rulesengine.RuleConfig
+		if err := json.Unmarshal([]byte(ruleJSON), &ruleConfig); err != nil {
+			t.Fatalf("Failed to unmarshal rule JSON: %v", err)
+		}
+		rule, err := rulesengine.NewRule(&ruleConfig)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+	return engine, &calls
+}
+
+func TestSerialSchedulerShortCircuits(t *testing.T) {
+	facts := []byte(`{"a": 1, "b": 2}`)
+
+	t.Run("any stops after the first passing condition", func(t *testing.T) {
+		engine, calls := newSerialEngine(t, nil, `{"name": "r", "conditions": {"any": [
+			{"fact": "a", "operator": "equal", "value": 1},
+			{"fact": "b", "operator": "counted", "value": 2}
+		]}, "event": {"type": "r"}}`)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 {
+			t.Errorf("Expected the rule to pass")
+		}
+		if calls.Load() != 0 {
+			t.Errorf("Expected the second condition not to be evaluated, got %d evaluations", calls.Load())
+		}
+	})
+
+	t.Run("all stops after the first failing condition", func(t *testing.T) {
+		engine, calls := newSerialEngine(t, nil, `{"name": "r", "conditions": {"all": [
+			{"fact": "a", "operator": "equal", "value": 2},
+			{"fact": "b", "operator": "counted", "value": 2}
+		]}, "event": {"type": "r"}}`)
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 0 {
+			t.Errorf("Expected the rule to fail")
+		}
+		if calls.Load() != 0 {
+			t.Errorf("Expected the second condition not to be evaluated, got %d evaluations", calls.Load())
+		}
+	})
+
+	t.Run("a failing reference ahead of a passing condition errors in an any group", func(t *testing.T) {
+		engine, _ := newSerialEngine(t, &rulesengine.RuleEngineOptions{AllowUndefinedConditions: true}, `{"name": "r", "conditions": {"any": [
+			{"condition": "premiumChecks", "ifMissing": "fail"},
+			{"fact": "a", "operator": "equal", "value": 1}
+		]}, "event": {"type": "r"}}`)
+		if _, err := engine.Run(context.Background(), facts); err == nil || !strings.Contains(err.Error(), "no condition premiumChecks exists") {
+			t.Errorf("Expected missing condition error, got %v", err)
+		}
+	})
+
+	t.Run("rules of a priority group complete in the order they were added", func(t *testing.T) {
+		engine, _ := newSerialEngine(t, nil,
+			`{"name": "first", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "first"}}`,
+			`{"name": "second", "conditions": {"all": [{"fact": "b", "operator": "equal", "value": 2}]}, "event": {"type": "second"}}`,
+			`{"name": "third", "conditions": {"all": [{"fact": "a", "operator": "equal", "value": 1}]}, "event": {"type": "third"}}`,
+		)
+		for i := 0; i < 5; i++ {
+			res, err := engine.Run(context.Background(), facts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			events := res.Events
+			if len(events) != 3 || events[0].Type != "first" || events[1].Type != "second" || events[2].Type != "third" {
+				t.Fatalf("Expected events in rule order, got %v", events)
+			}
+		}
+	})
+}
+
+func TestSerialSchedulerStopProcessing(t *testing.T) {
+	engine, _ := newSerialEngine(t, nil,
+		`{"name": "decline", "priority": 3, "conditions": {"all": [{"fact": "score", "operator": "lessThan", "value": 500}]}, "event": {"type": "decline"}}`,
+		`{"name": "audit", "priority": 3, "conditions": {"all": [{"fact": "score", "operator": "greaterThan", "value": 0}]}, "event": {"type": "audit"}}`,
+		`{"name": "review", "priority": 1, "conditions": {"all": [{"fact": "score", "operator": "greaterThan", "value": 0}]}, "event": {"type": "review"}}`,
+	)
+	if err := engine.OnEvent("decline", func(_ map[string]interface{}, almanac *rulesengine.Almanac, _ *rulesengine.RuleResult) {
+		almanac.ExecutionContext().StopProcessing()
+	}); err != nil {
+		t.Fatalf("Failed to register handler: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		// The group of the declining rule completes, the lower priority review is not evaluated
+		res, err := engine.Run(context.Background(), []byte(`{"score": 300}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if events := res.Events; len(events) != 2 || events[0].Type != "decline" || events[1].Type != "audit" {
+			t.Fatalf("Expected the decline and audit events only, got %v", events)
+		}
+		if res.Partial || len(res.FailureResults) != 0 {
+			t.Errorf("Expected a complete result without failures, got partial=%v failures=%d", res.Partial, len(res.FailureResults))
+		}
+
+		// Stopping does not carry over to the next run
+		res, err = engine.Run(context.Background(), []byte(`{"score": 700}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Events) != 2 || res.Events[1].Type != "review" {
+			t.Fatalf("Expected the audit and review events, got %v", res.Events)
+		}
+	}
+}
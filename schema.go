@@ -0,0 +1,107 @@
+package rulesengine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+// SchemaVersion is the newest rule schema version this engine understands.
+// Version 1 is the original, unversioned rule format; version 2 adds multi-fact conditions ("facts"),
+// "cost", "negate", "ifMissing", named condition groups, condition name shorthand, "ordered" groups and rule-local "facts".
+const SchemaVersion = 2
+
+// ruleSchemaKeys, conditionSchemaKeys and eventSchemaKeys map the keys of the rule schema to the version
+// that introduced them. Keys not listed are not understood by this engine.
+var (
+	ruleSchemaKeys = map[string]int{
+		"name": 1, "priority": 1, "conditions": 1, "event": 1, "schemaVersion": 2, "facts": 2,
+	}
+	conditionSchemaKeys = map[string]int{
+		"all": 1, "any": 1, "not": 1, "fact": 1, "operator": 1, "value": 1, "priority": 1, "name": 1,
+		"params": 1, "condition": 1, "path": 1, "facts": 2, "cost": 2, "negate": 2, "ifMissing": 2, "ordered": 2,
+		"none": 2, "atLeast": 2, "mostOf": 2, "minPassRatio": 2, "transforms": 2,
+		"freshFact": 2, "failureMessage": 2,
+	}
+	eventSchemaKeys = map[string]int{
+		"type": 1, "params": 1,
+	}
+)
+
+// SupportedSchemaVersion returns the newest rule schema version the engine can load, see SchemaVersion.
+func (e *Engine) SupportedSchemaVersion() int {
+	return SchemaVersion
+}
+
+// checkSchemaVersion validates the declared schema version of a rule document.
+// Rules without a schemaVersion are version 1. Versions newer than SchemaVersion are rejected with the
+// constructs of the document this engine does not understand.
+// Returns the effective version.
+func checkSchemaVersion(data []byte) (int, error) {
+	doc := gjson.ParseBytes(data)
+	declared := doc.Get("schemaVersion")
+	if !declared.Exists() {
+		return 1, nil
+	}
+	if declared.Type != gjson.Number || declared.Num != float64(int(declared.Num)) || declared.Int() < 1 {
+		return 0, fmt.Errorf("schemaVersion must be a positive integer, got %s", declared.Raw)
+	}
+	version := int(declared.Int())
+	if version > SchemaVersion {
+		return 0, &UnsupportedSchemaVersionError{Version: version, Supported: SchemaVersion, Constructs: unsupportedConstructs(doc)}
+	}
+	return version, nil
+}
+
+// unsupportedConstructs returns the paths of the keys of a rule document that are not part of the supported schema,
+// e.g. "conditions.all[0].valueFact"
+func unsupportedConstructs(rule gjson.Result) []string {
+	var found []string
+	unknownKeys := func(obj gjson.Result, known map[string]int, path string) {
+		obj.ForEach(func(key, _ gjson.Result) bool {
+			if _, ok := known[key.String()]; !ok {
+				found = append(found, joinSchemaPath(path, key.String()))
+			}
+			return true
+		})
+	}
+
+	var walkCondition func(cond gjson.Result, path string)
+	walkCondition = func(cond gjson.Result, path string) {
+		if !cond.IsObject() {
+			return
+		}
+		unknownKeys(cond, conditionSchemaKeys, path)
+		for _, group := range []string{"all", "any", "none", "mostOf"} {
+			children := cond.Get(group)
+			groupPath := joinSchemaPath(path, group)
+			if children.IsArray() {
+				for i, child := range children.Array() {
+					walkCondition(child, fmt.Sprintf("%s[%d]", groupPath, i))
+				}
+			} else if children.IsObject() {
+				children.ForEach(func(name, child gjson.Result) bool {
+					walkCondition(child, joinSchemaPath(groupPath, name.String()))
+					return true
+				})
+			}
+		}
+		walkCondition(cond.Get("not"), joinSchemaPath(path, "not"))
+	}
+
+	unknownKeys(rule, ruleSchemaKeys, "")
+	walkCondition(rule.Get("conditions"), "conditions")
+	if event := rule.Get("event"); event.IsObject() {
+		unknownKeys(event, eventSchemaKeys, "event")
+	}
+	sort.Strings(found)
+	return found
+}
+
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,110 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// schemaFixture is a rule file of a schema version together with its expected outcome
+type schemaFixture struct {
+	Rule        json.RawMessage `json:"rule"`
+	Facts       json.RawMessage `json:"facts"`
+	Expected    bool            `json:"expected"`
+	Unsupported []string        `json:"unsupported"`
+}
+
+func TestSchemaVersionFixtures(t *testing.T) {
+	files, err := filepath.Glob("testdata/schema/v*/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Expected schema fixtures, got %v", err)
+	}
+	for _, file := range files {
+		version, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(file)), "v"))
+		if err != nil {
+			t.Fatalf("Unexpected fixture directory for %s", file)
+		}
+		t.Run(filepath.ToSlash(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			var fixture schemaFixture
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("Failed to parse fixture: %v", err)
+			}
+
+			var config RuleConfig
+			err = json.Unmarshal(fixture.Rule, &config)
+			if version > SchemaVersion {
+				var schemaErr *UnsupportedSchemaVersionError
+				if !errors.As(err, &schemaErr) || !errors.Is(err, ErrUnsupportedSchemaVersion) {
+					t.Fatalf("Expected an UnsupportedSchemaVersionError, got %v", err)
+				}
+				if schemaErr.Version != version || !reflect.DeepEqual(schemaErr.Constructs, fixture.Unsupported) {
+					t.Errorf("Expected version %d with constructs %v, got %d with %v", version, fixture.Unsupported, schemaErr.Version, schemaErr.Constructs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to unmarshal rule: %v", err)
+			}
+			if config.SchemaVersion != version {
+				t.Errorf("Expected schema version %d, got %d", version, config.SchemaVersion)
+			}
+			rule, err := NewRule(&config)
+			if err != nil {
+				t.Fatalf("Failed to create rule: %v", err)
+			}
+			if rule.SchemaVersion != version {
+				t.Errorf("Expected the rule to record schema version %d, got %d", version, rule.SchemaVersion)
+			}
+			engine := NewEngine(nil, nil)
+			if err := engine.AddRule(rule); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+			res, err := engine.Run(context.Background(), fixture.Facts)
+			if err != nil {
+				t.Fatalf("Unexpected run error: %v", err)
+			}
+			if passed := len(res.Results) == 1; passed != fixture.Expected {
+				t.Errorf("Expected the rule to pass: %v, got %v", fixture.Expected, passed)
+			}
+		})
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	if NewEngine(nil, nil).SupportedSchemaVersion() != SchemaVersion {
+		t.Errorf("Expected the engine to support schema version %d", SchemaVersion)
+	}
+
+	t.Run("invalid versions", func(t *testing.T) {
+		for _, version := range []string{`0`, `-1`, `1.5`, `"2"`} {
+			var config RuleConfig
+			err := json.Unmarshal([]byte(`{"schemaVersion": `+version+`, "name": "r", "event": {"type": "r"}}`), &config)
+			if err == nil || !strings.Contains(err.Error(), "schemaVersion must be a positive integer") {
+				t.Errorf("Expected %s to be rejected, got %v", version, err)
+			}
+		}
+	})
+
+	t.Run("rules built in code use the current version", func(t *testing.T) {
+		rule, err := NewRule(&RuleConfig{Name: "r", Event: EventConfig{Type: "r"}})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if rule.SchemaVersion != SchemaVersion {
+			t.Errorf("Expected schema version %d, got %d", SchemaVersion, rule.SchemaVersion)
+		}
+		if _, err := NewRule(&RuleConfig{Name: "r", Event: EventConfig{Type: "r"}, SchemaVersion: SchemaVersion + 1}); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+			t.Errorf("Expected a newer schema version to be rejected, got %v", err)
+		}
+	})
+}
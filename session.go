@@ -0,0 +1,136 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Session groups consecutive runs over related fact documents, e.g. the documents of one user's stream, and shares the
+// values of calculated facts marked with FactOptions.SessionCache between them. Cached values expire after their TTL
+// or when invalidated with Invalidate. Sessions do not share values with each other and may run concurrently.
+type Session struct {
+	engine  *Engine
+	options *RunOptions
+	mu      sync.Mutex
+	entries map[string]*sessionEntry
+	now     func() time.Time
+}
+
+// sessionEntry holds a cached fact value; done is closed once value and err are set
+type sessionEntry struct {
+	path    string
+	done    chan struct{}
+	value   *ValueNode
+	err     error
+	expires time.Time
+}
+
+// NewSession creates a session on the engine.
+// Params:
+// - options: The run options of the session's runs; if nil, DefaultRunOptions are used.
+// Returns the session.
+func (e *Engine) NewSession(options *RunOptions) *Session {
+	if options == nil {
+		options = DefaultRunOptions()
+	}
+	return &Session{
+		engine:  e,
+		options: options,
+		entries: map[string]*sessionEntry{},
+		now:     time.Now,
+	}
+}
+
+// Run evaluates the rules against the facts with the options of the session, see Engine.Run.
+func (s *Session) Run(ctx context.Context, facts []byte) (*RunResult, error) {
+	return s.RunWithOptions(ctx, facts, s.options)
+}
+
+// RunWithOptions evaluates the rules against the facts, reading session-cached facts from the session.
+// Params:
+// - ctx: The context of the run.
+// - facts: The fact document.
+// - options: The run options; if nil, the options of the session are used.
+// Returns the outcome of the run.
+func (s *Session) RunWithOptions(ctx context.Context, facts []byte, options *RunOptions) (*RunResult, error) {
+	if options == nil {
+		options = s.options
+	}
+	sessionOptions := *options
+	sessionOptions.session = s
+	return s.engine.RunWithOptions(ctx, facts, &sessionOptions)
+}
+
+// Invalidate removes the cached values of the fact at the given path, for all params, so that the next run
+// calculates them again. Runs in progress keep the values they already read.
+func (s *Session) Invalidate(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.entries {
+		if entry.path == path {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// fact returns the cached value of a fact, calculating it when missing or expired.
+// Concurrent runs of the session asking for the same value wait for a single calculation.
+func (s *Session) fact(f *Fact, params map[string]interface{}, compute func() (*ValueNode, error)) (*ValueNode, error) {
+	key := f.Path
+	if params != nil {
+		// encoding/json sorts map keys, so equal params share a key
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("session: fact %s params: %w", f.Path, err)
+		}
+		key += "\x00" + string(encoded)
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	if entry, ok := s.entries[key]; ok {
+		select {
+		case <-entry.done:
+			if now.Before(entry.expires) && entry.err == nil {
+				s.mu.Unlock()
+				return entry.value, nil
+			}
+		default:
+			// Being calculated by another run of the session
+			s.mu.Unlock()
+			<-entry.done
+			return entry.value, entry.err
+		}
+	}
+	s.evictExpired(now)
+	entry := &sessionEntry{path: f.Path, done: make(chan struct{})}
+	s.entries[key] = entry
+	s.mu.Unlock()
+
+	defer close(entry.done)
+	defer func() {
+		if r := recover(); r != nil {
+			entry.err = fmt.Errorf("session: fact %s panicked: %v", f.Path, r)
+			panic(r)
+		}
+	}()
+	entry.value, entry.err = compute()
+	entry.expires = s.now().Add(f.SessionCache)
+	return entry.value, entry.err
+}
+
+// evictExpired removes the calculated values that have expired; the caller holds the lock
+func (s *Session) evictExpired(now time.Time) {
+	for key, entry := range s.entries {
+		select {
+		case <-entry.done:
+			if !now.Before(entry.expires) {
+				delete(s.entries, key)
+			}
+		default:
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package rulesengine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSessionFactCache(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "premium",
+		"conditions": {"all": [
+			{"fact": "tier", "operator": "equal", "value": "gold"},
+			{"fact": "risk", "operator": "lessThan", "value": 5},
+			{"fact": "amount", "operator": "greaterThan", "value": 100}
+		]},
+		"event": {"type": "premium"}
+	}`, nil)
+	var tierCalls, riskCalls atomic.Int32
+	err := engine.AddCalculatedFact("tier", func(a *Almanac, params ...interface{}) *ValueNode {
+		tierCalls.Add(1)
+		return &ValueNode{Type: String, String: "gold"}
+	}, &FactOptions{Cache: true, Priority: 1, SessionCache: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	err = engine.AddCalculatedFact("risk", func(a *Almanac, params ...interface{}) *ValueNode {
+		riskCalls.Add(1)
+		return &ValueNode{Type: Number, Number: 1}
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+
+	run := func(t *testing.T, session *Session) {
+		t.Helper()
+		res, err := session.Run(context.Background(), []byte(`{"amount": 150}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 {
+			t.Fatalf("Expected the premium rule to pass, got %v", res.FailureResults)
+		}
+	}
+	expectCalls := func(t *testing.T, tier, risk int32) {
+		t.Helper()
+		if tierCalls.Load() != tier || riskCalls.Load() != risk {
+			t.Errorf("Expected %d tier and %d risk calculations, got %d and %d", tier, risk, tierCalls.Load(), riskCalls.Load())
+		}
+		tierCalls.Store(0)
+		riskCalls.Store(0)
+	}
+
+	t.Run("runs of a session share the value", func(t *testing.T) {
+		session := engine.NewSession(nil)
+		for i := 0; i < 3; i++ {
+			run(t, session)
+		}
+		expectCalls(t, 1, 3)
+	})
+
+	t.Run("sessions are isolated", func(t *testing.T) {
+		run(t, engine.NewSession(nil))
+		run(t, engine.NewSession(nil))
+		if _, err := engine.Run(context.Background(), []byte(`{"amount": 150}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectCalls(t, 3, 3)
+	})
+
+	t.Run("values expire after the TTL", func(t *testing.T) {
+		session := engine.NewSession(nil)
+		now := time.Now()
+		session.now = func() time.Time { return now }
+		run(t, session)
+		now = now.Add(59 * time.Second)
+		run(t, session)
+		now = now.Add(time.Second)
+		run(t, session)
+		expectCalls(t, 2, 3)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		session := engine.NewSession(nil)
+		run(t, session)
+		session.Invalidate("risk")
+		run(t, session)
+		session.Invalidate("tier")
+		run(t, session)
+		expectCalls(t, 2, 3)
+	})
+
+	t.Run("concurrent runs calculate once", func(t *testing.T) {
+		session := engine.NewSession(nil)
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := session.Run(context.Background(), []byte(`{"amount": 150}`)); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		expectCalls(t, 1, 20)
+	})
+}
@@ -1,10 +1,14 @@
 package rulesengine
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/asaskevich/EventBus"
+	"github.com/tidwall/gjson"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Event struct {
@@ -15,16 +19,59 @@ type Event struct {
 type FactOptions struct {
 	Cache    bool
 	Priority int
+	Cost     int // Estimated cost of resolving the fact, used with CostAwareOrdering
+	// ValueType declares the type of the values of a calculated fact, checked against the operators of the conditions
+	// referencing it, see RuleEngineOptions.StrictFactTypes. Null, the zero value, leaves the type undeclared.
+	// The type of static facts is taken from their value.
+	ValueType DataType
+	// SessionCache shares the values of a calculated fact between the runs of a Session for this long, e.g. for
+	// a user profile fetched once per stream instead of once per document. 0, the default, disables it.
+	// The values are cached per set of params, like within a run; runs outside a session are not affected.
+	SessionCache time.Duration
+	// Override lets Engine.AddFacts and Engine.AddCalculatedFacts replace facts already registered under the same
+	// paths instead of failing with ErrFactPathConflict. AddFact and AddCalculatedFact always replace.
+	Override bool
+	// OnError is how the conditions referencing a calculated fact added with Engine.AddCalculatedFactE handle an error
+	// of its callback: FactErrorFail, the default, fails the rule like other evaluation errors, FactErrorFalse
+	// evaluates them as false and reports a DiagnosticFactError.
+	OnError string
 }
 
 type DynamicFactCallback func(almanac *Almanac, params ...interface{}) *ValueNode
+
+// FactCallbackE calculates a fact added with Engine.AddCalculatedFactE. ctx is the context of the run, cancelled when
+// the run is, and params are the params of the referencing condition, nil without.
+type FactCallbackE func(ctx context.Context, almanac *Almanac, params map[string]interface{}) (*ValueNode, error)
 type EventCallback func(result *RuleResult) interface{}
 
 type EvaluationResult struct {
-	Result             bool        `json:"Result"`
-	LeftHandSideValue  Fact        `json:"LeftHandSideValue"`
-	RightHandSideValue interface{} `json:"RightHandSideValue"`
-	Operator           string      `json:"Operator"`
+	Result             bool           `json:"Result"`
+	LeftHandSideValue  Fact           `json:"LeftHandSideValue"`
+	RightHandSideValue interface{}    `json:"RightHandSideValue"`
+	Operator           string         `json:"Operator"`
+	Matches            []ElementMatch `json:"Matches,omitempty"`
+	// LeftHandSideValues holds the resolved values of a multi-fact condition, in the order of its facts
+	LeftHandSideValues []*ValueNode `json:"LeftHandSideValues,omitempty"`
+	// Negated is set when the operator outcome was negated by the condition's negate flag or a "!" operator prefix
+	Negated bool `json:"Negated,omitempty"`
+	// OperatorResult is the outcome of the operator before negation
+	OperatorResult bool `json:"OperatorResult"`
+	// MatchDetail is what a detail operator reported as matched, see NewDetailOperator
+	MatchDetail interface{} `json:"MatchDetail,omitempty"`
+	// TransformedValue is the fact value after the transforms of the condition, nil without transforms
+	TransformedValue *ValueNode `json:"TransformedValue,omitempty"`
+	// ResolvedFact is the concrete path of a fact with {fact} segments
+	ResolvedFact string `json:"ResolvedFact,omitempty"`
+	// MatchedFact is the path of the fact document a fact path matched ignoring case, see CaseInsensitiveFactPaths
+	MatchedFact string `json:"MatchedFact,omitempty"`
+	// FreshFact is set when the fact was resolved again for the condition instead of read from the caches
+	FreshFact bool `json:"FreshFact,omitempty"`
+}
+
+// ElementMatch captures an array element of a fact that matched a condition
+type ElementMatch struct {
+	Index int       `json:"index"`
+	Value ValueNode `json:"value"`
 }
 
 const (
@@ -53,8 +100,11 @@ type TopLevelCondition struct {
 	Priority  *int                   `json:"priority,omitempty"`
 }
 
-// EventHandler represents an event handler function.
-type EventHandler func(event Event, almanac Almanac, ruleResult RuleResult)
+// EventHandler handles the success or failure event of a rule, see Engine.OnSuccess and Engine.OnFailure.
+type EventHandler func(event Event, almanac *Almanac, ruleResult *RuleResult)
+
+// EventTypeHandler handles the events of one type with their params, see Engine.OnEvent.
+type EventTypeHandler func(params map[string]interface{}, almanac *Almanac, ruleResult *RuleResult)
 
 // ConditionProperties represents a condition inEvaluator the rule.
 type ConditionProperties struct {
@@ -99,20 +149,93 @@ type Engine struct {
 	Rules                     []*Rule
 	AllowUndefinedFacts       bool
 	AllowUndefinedConditions  bool
+	AllowUnknownOperators     bool
 	ReplaceFactsInEventParams bool
-	Operators                 map[string]Operator
+	ContinueOnError           bool
+	EventConflictPolicy       EventConflictPolicy
+	RejectEmptyGroups         bool
+	CostAwareOrdering         bool
+	EphemeralRulesFact        string
+	EventFilter               EventFilter
+	StrictEventTypes          bool
+	StrictFactTypes           bool
+	SynchronousEvents         bool
+	ForwardChaining           bool
+	SlowConditionThreshold    time.Duration
+	OnSlowCondition           SlowConditionHandler
+	CaseInsensitiveFactPaths  bool
 	Facts                     FactMap
 	Conditions                ConditionMap
 	Status                    string
-	prioritizedRules          [][]*Rule
+	prioritizedRules          atomic.Pointer[[][]*Rule] // Snapshot of the rules grouped by priority, nil when stale
+	factsVersion              atomic.Uint64
+	configVersion             atomic.Uint64
+	resultCache               *resultCache
+	operators                 operatorRegistry
+	transforms                transformRegistry
+	decorators                decoratorRegistry
+	catalogs                  catalogRegistry
+	exclusiveEvents           atomic.Pointer[[][]string]          // Exclusive event groups, replaced under mu on every change
+	eventTypes                atomic.Pointer[map[string]struct{}] // Event types registered with RegisterEventTypes, replaced under mu
+	constants                 map[string]struct{}                 // Paths of the facts registered as bundle constants, removed by Reset
+	scheduler                 Scheduler
+	counters                  runCounters
 	bus                       EventBus.Bus
-	mu                        sync.Mutex
+	handlerTopics             sync.Map                       // Topics handlers were subscribed to, some may have none left, see Descriptor
+	mu                        sync.Mutex                     // Guards the rule list and the prioritized rule cache, serializes changes of exclusiveEvents and eventTypes
+	statusMu                  sync.Mutex                     // Guards Status and activeRuns
+	activeRuns                map[*ExecutionContext]struct{} // Execution contexts of the active runs, stopped by Stop
 }
 
 type RuleEngineOptions struct {
-	AllowUndefinedFacts       bool
-	AllowUndefinedConditions  bool
+	AllowUndefinedFacts      bool
+	AllowUndefinedConditions bool
+	// AllowUnknownOperators accepts rules using operators that are not registered yet, e.g. custom operators added
+	// after the rules; unknown operators then fail the evaluation instead
+	AllowUnknownOperators     bool
 	ReplaceFactsInEventParams bool
+	ContinueOnError           bool // Record rule evaluation errors on the RuleResult instead of aborting the run
+	// EventConflictPolicy decides how violations of exclusive event groups are resolved, see Engine.DeclareExclusiveEvents
+	EventConflictPolicy EventConflictPolicy
+	// RejectEmptyGroups refuses rules containing an empty 'all' or 'any' group; on in DefaultRuleEngineOptions
+	RejectEmptyGroups bool
+	// Scheduler runs the rule and condition evaluation tasks, nil to start a goroutine per task
+	Scheduler Scheduler
+	// CostAwareOrdering evaluates cheaper conditions of equal priority first, see FactOptions.Cost and Condition.Cost
+	CostAwareOrdering bool
+	// ResultCacheSize enables caching of run results for identical fact documents (or RunOptions.CacheKey) and run options
+	// when greater than zero. A cache hit returns a copy of the cached result without evaluating the rules, so event
+	// handlers and rule callbacks do not fire; runs seeding RunOptions.Values or using a Session are never cached.
+	ResultCacheSize int
+	// ResultCacheTTL is how long a cached run result stays valid, 0 for no expiry
+	ResultCacheTTL time.Duration
+	// EphemeralRulesFact is the path of a rule array embedded in the fact document, evaluated as ephemeral rules
+	// of the run, see RunOptions.EphemeralRules. Empty to ignore rules in facts.
+	EphemeralRulesFact string
+	// EventFilter can veto the events of rules that passed, see EventFilter
+	EventFilter EventFilter
+	// StrictEventTypes refuses rules whose event type was not registered with Engine.RegisterEventTypes
+	StrictEventTypes bool
+	// StrictFactTypes refuses rules using an operator on a fact of another type than the operator expects, e.g.
+	// greaterThan on a string fact. Only facts registered before the rule are checked, see Engine.Validate;
+	// without it Engine.Lint reports them.
+	StrictFactTypes bool
+	// SynchronousEvents calls the OnSuccess and OnFailure callbacks of rules inline instead of in a goroutine, and
+	// publishes all events of a priority group once it completes, in rule order rather than completion order.
+	// A panicking handler fails the run with an error naming the rule.
+	SynchronousEvents bool
+	// ForwardChaining adds the facts in the "setFacts" param of the events of passing rules as runtime facts once
+	// their priority group completed, so rules of lower priority see them, see SetFactsParam
+	ForwardChaining bool
+	// SlowConditionThreshold reports leaf conditions taking longer than this to evaluate, fact resolution included,
+	// to OnSlowCondition; 0 to disable. Works without RunOptions.Trace.
+	SlowConditionThreshold time.Duration
+	// OnSlowCondition receives the slow conditions, nil to log them with the standard logger
+	OnSlowCondition SlowConditionHandler
+	// CaseInsensitiveFactPaths matches fact paths missing from the fact document against its keys ignoring case, e.g.
+	// "userId" finds "userID". Conditions record the path they matched as MatchedFact. Keys matching several keys of
+	// an object ignoring case are not matched.
+	CaseInsensitiveFactPaths bool
 }
 
 type RuleConfig struct {
@@ -120,12 +243,25 @@ type RuleConfig struct {
 	Priority   *int        `json:"priority"`
 	Conditions Condition   `json:"conditions"`
 	Event      EventConfig `json:"event"`
-	OnSuccess  func(result *RuleResult) interface{}
-	OnFailure  func(result *RuleResult) interface{}
+	// Facts are static values visible only to the conditions of this rule, consulted before engine and raw facts
+	Facts map[string]interface{} `json:"facts"`
+	// SchemaVersion is the rule schema version the rule was written for, 1 when the rule JSON does not declare one
+	SchemaVersion int `json:"schemaVersion"`
+	// EventFilter can veto the rule's event after its conditions passed, in addition to the engine's filter
+	EventFilter EventFilter `json:"-"`
+	OnSuccess   func(result *RuleResult) interface{}
+	OnFailure   func(result *RuleResult) interface{}
 }
 
-// UnmarshalJSON is a custom JSON unmarshaller for RuleConfig to ensure proper unmarshaling of Condition
+// UnmarshalJSON is a custom JSON unmarshaller for RuleConfig to ensure proper unmarshaling of Condition.
+// Invalid nested conditions are reported with their JSON path, e.g. conditions.all[1].any[0].
+// Rules declaring a schemaVersion newer than SchemaVersion are rejected with an UnsupportedSchemaVersionError.
 func (r *RuleConfig) UnmarshalJSON(data []byte) error {
+	version, err := checkSchemaVersion(data)
+	if err != nil {
+		return err
+	}
+
 	// Define an alias to avoid recursion
 	type Alias RuleConfig
 	aux := &struct {
@@ -136,13 +272,25 @@ func (r *RuleConfig) UnmarshalJSON(data []byte) error {
 
 	// Unmarshal the data into the auxiliary struct
 	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
+		return conditionsError(err)
 	}
 
-	// Now manually unmarshal and validate the Conditions field
+	// Now manually unmarshal and validate the Conditions field.
+	// Rule-local facts share their key with multi-fact conditions, so they are left out.
+	if gjson.GetBytes(data, "facts").IsObject() {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		delete(fields, "facts")
+		if data, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
 	if err := json.Unmarshal(data, &r.Conditions); err != nil {
 		return fmt.Errorf("failed to unmarshal conditions: %v", err)
 	}
+	r.SchemaVersion = version
 
 	return nil
 }
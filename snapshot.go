@@ -0,0 +1,301 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// SnapshotKind identifies the JSON documents written by Almanac.MarshalSnapshot
+	SnapshotKind = "gojson-rules-engine/almanac-snapshot"
+	// SnapshotVersion is the version of the snapshot format written by Almanac.MarshalSnapshot
+	SnapshotVersion = 1
+)
+
+// AlmanacSnapshot is a compact, replayable record of a run: the fact values the rules actually read,
+// the runtime facts, the events and the rule results. Callbacks and caches are not part of it.
+type AlmanacSnapshot struct {
+	Kind    string `json:"kind"`
+	Version int    `json:"version"`
+	// Facts holds the fact values resolved during the run, sorted by path
+	Facts []AccessedFact `json:"facts"`
+	// RuntimeFacts holds the facts added with AddRuntimeFact, sorted by path
+	RuntimeFacts []AccessedFact   `json:"runtimeFacts"`
+	Events       []SnapshotEvent  `json:"events"`
+	Results      []SnapshotResult `json:"results"`
+}
+
+// AccessedFact is a fact value resolved during a run.
+type AccessedFact struct {
+	Path string `json:"path"`
+	// Params are the params the fact was resolved with, set for conditions passing params to calculated facts
+	Params map[string]interface{} `json:"params,omitempty"`
+	// Value is the resolved value, nil when the fact was undefined
+	Value *ValueNode `json:"-"`
+}
+
+// SnapshotEvent is an event emitted during a run.
+type SnapshotEvent struct {
+	Outcome EventOutcome           `json:"outcome"`
+	Type    string                 `json:"type"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// SnapshotResult is the result of a rule evaluated or skipped during a run.
+type SnapshotResult struct {
+	Name     string     `json:"name"`
+	Priority int        `json:"priority"`
+	Result   *bool      `json:"result"`
+	Skipped  SkipReason `json:"skipped,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	// Conditions holds the evaluated conditions in the format of RuleResult.ToJSON
+	Conditions json.RawMessage `json:"conditions,omitempty"`
+}
+
+// accessedFactJSON is the serialized form of an AccessedFact; undefined facts have no value
+type accessedFactJSON struct {
+	Path      string                 `json:"path"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	Value     json.RawMessage        `json:"value,omitempty"`
+	Undefined bool                   `json:"undefined,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the value as plain JSON
+func (f AccessedFact) MarshalJSON() ([]byte, error) {
+	out := accessedFactJSON{Path: f.Path, Params: f.Params, Undefined: f.Value == nil}
+	if f.Value != nil {
+		value, err := json.Marshal(f.Value.Raw())
+		if err != nil {
+			return nil, fmt.Errorf("fact %s: %w", f.Path, err)
+		}
+		out.Value = value
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (f *AccessedFact) UnmarshalJSON(data []byte) error {
+	var in accessedFactJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*f = AccessedFact{Path: in.Path, Params: in.Params}
+	if in.Undefined {
+		return nil
+	}
+	if in.Value == nil {
+		return fmt.Errorf("fact %s: value required unless undefined", in.Path)
+	}
+	f.Value = &ValueNode{}
+	return f.Value.UnmarshalJSON(in.Value)
+}
+
+// factAccessLog records the fact values resolved during a run, see RunOptions.TrackFactAccess
+type factAccessLog struct {
+	mu      sync.Mutex
+	facts   map[string]AccessedFact
+	runtime map[string]AccessedFact
+}
+
+func newFactAccessLog() *factAccessLog {
+	return &factAccessLog{facts: map[string]AccessedFact{}, runtime: map[string]AccessedFact{}}
+}
+
+// record keeps the first value resolved for a path and set of params
+func (l *factAccessLog) record(path string, params map[string]interface{}, f *Fact) {
+	key, err := factAccessKey(path, params)
+	if err != nil {
+		return
+	}
+	var value *ValueNode
+	if f != nil {
+		value = f.Value
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.facts[key]; !ok {
+		l.facts[key] = AccessedFact{Path: path, Params: copyParams(params), Value: value}
+	}
+}
+
+// recordRuntime keeps the last value of a runtime fact
+func (l *factAccessLog) recordRuntime(path string, value ValueNode) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.runtime[path] = AccessedFact{Path: path, Value: &value}
+}
+
+// factAccessKey identifies a fact resolution; encoding/json sorts map keys, so equal params share a key
+func factAccessKey(path string, params map[string]interface{}) (string, error) {
+	if params == nil {
+		return path, nil
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return path + "\x00" + string(encoded), nil
+}
+
+// sortedAccessedFacts returns the facts sorted by path, and facts of equal path by params
+func sortedAccessedFacts(facts map[string]AccessedFact) []AccessedFact {
+	keys := make([]string, 0, len(facts))
+	for key := range facts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sorted := make([]AccessedFact, len(keys))
+	for i, key := range keys {
+		sorted[i] = facts[key]
+	}
+	return sorted
+}
+
+// AccessedFacts returns the fact values resolved during the run, sorted by path.
+// Returns nil unless the run was started with RunOptions.TrackFactAccess.
+func (a *Almanac) AccessedFacts() []AccessedFact {
+	if a.accessed == nil {
+		return nil
+	}
+	a.accessed.mu.Lock()
+	defer a.accessed.mu.Unlock()
+	return sortedAccessedFacts(a.accessed.facts)
+}
+
+// Snapshot returns a snapshot of the run, see AlmanacSnapshot.
+// Returns an error unless the run was started with RunOptions.TrackFactAccess.
+func (a *Almanac) Snapshot() (*AlmanacSnapshot, error) {
+	if a.accessed == nil {
+		return nil, errors.New("almanac snapshot requires RunOptions.TrackFactAccess")
+	}
+	snapshot := &AlmanacSnapshot{
+		Kind:    SnapshotKind,
+		Version: SnapshotVersion,
+		Events:  []SnapshotEvent{},
+		Results: make([]SnapshotResult, 0, len(a.ruleResults)),
+	}
+	a.accessed.mu.Lock()
+	snapshot.Facts = sortedAccessedFacts(a.accessed.facts)
+	snapshot.RuntimeFacts = sortedAccessedFacts(a.accessed.runtime)
+	a.accessed.mu.Unlock()
+
+	for _, outcome := range []EventOutcome{Success, Failure} {
+		for _, event := range a.events[outcome] {
+			snapshot.Events = append(snapshot.Events, SnapshotEvent{Outcome: outcome, Type: event.Type, Params: event.Params})
+		}
+	}
+	for _, ruleResult := range a.ruleResults {
+		result := SnapshotResult{
+			Name:     ruleResult.Name,
+			Priority: ruleResult.Priority,
+			Result:   ruleResult.Result,
+			Skipped:  ruleResult.Skipped,
+		}
+		if ruleResult.Error != nil {
+			result.Error = ruleResult.Error.Error()
+		}
+		conditions, err := ruleResult.Conditions.toJSON(true, ruleResult.Serialization)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", ruleResult.Name, err)
+		}
+		result.Conditions = json.RawMessage(conditions.(string))
+		snapshot.Results = append(snapshot.Results, result)
+	}
+	return snapshot, nil
+}
+
+// MarshalSnapshot encodes a snapshot of the run as versioned JSON, for audits and Engine.Replay.
+// Only the facts the rules read are included, not the whole fact document.
+// Returns an error unless the run was started with RunOptions.TrackFactAccess.
+func (a *Almanac) MarshalSnapshot() ([]byte, error) {
+	snapshot, err := a.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalSnapshot decodes a snapshot written by Almanac.MarshalSnapshot.
+// Returns an error for other documents and for snapshot versions newer than SnapshotVersion.
+func UnmarshalSnapshot(data []byte) (*AlmanacSnapshot, error) {
+	var snapshot AlmanacSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("almanac snapshot: %w", err)
+	}
+	if snapshot.Kind != SnapshotKind {
+		return nil, fmt.Errorf("almanac snapshot: unexpected kind %q", snapshot.Kind)
+	}
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return nil, fmt.Errorf("almanac snapshot: unsupported version %d", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+// replayFacts serves the facts recorded in a snapshot in place of the fact document, see Engine.Replay
+type replayFacts struct {
+	facts      map[string]AccessedFact
+	namespaces map[string]struct{}
+}
+
+func newReplayFacts(snapshot *AlmanacSnapshot) (*replayFacts, error) {
+	replay := &replayFacts{facts: make(map[string]AccessedFact, len(snapshot.Facts)), namespaces: map[string]struct{}{}}
+	for _, f := range snapshot.Facts {
+		key, err := factAccessKey(f.Path, f.Params)
+		if err != nil {
+			return nil, fmt.Errorf("almanac snapshot: fact %s: %w", f.Path, err)
+		}
+		replay.facts[key] = f
+		if f.Value != nil {
+			if namespace, ok := factNamespace(f.Path); ok {
+				replay.namespaces[namespace] = struct{}{}
+			}
+		}
+	}
+	return replay, nil
+}
+
+// fact returns the recorded fact; facts that were undefined or not read during the recorded run are undefined
+func (r *replayFacts) fact(path string, params map[string]interface{}, allowUndefinedFacts bool) (*Fact, error) {
+	key, err := factAccessKey(path, params)
+	if err != nil {
+		return nil, err
+	}
+	recorded, ok := r.facts[key]
+	if ok && recorded.Value != nil {
+		return NewFact(path, *recorded.Value, &FactOptions{Cache: false, Priority: 1})
+	}
+	if ok || allowUndefinedFacts {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("undefined fact: %s, not recorded in the snapshot", path)
+}
+
+// Replay evaluates the rules against the facts recorded in a snapshot instead of a fact document, e.g. to check
+// that a past decision is reproduced by the current rules. Recorded values are used for all facts, calculated facts
+// included, so callbacks are not called. Facts the recorded run did not read are undefined.
+// Events are collected but not published to handlers, and the result cache is bypassed.
+// Params:
+// - ctx: The context of the run.
+// - snapshot: A snapshot from Almanac.Snapshot or UnmarshalSnapshot.
+// - options: The run options; if nil, DefaultRunOptions are used.
+// Returns the outcome of the replayed run.
+func (e *Engine) Replay(ctx context.Context, snapshot *AlmanacSnapshot, options *RunOptions) (*RunResult, error) {
+	if snapshot == nil {
+		return nil, errors.New("engine::replay snapshot required")
+	}
+	replay, err := newReplayFacts(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = DefaultRunOptions()
+	}
+	replayOptions := *options
+	replayOptions.quiet = true
+	replayOptions.replay = replay
+	return e.evaluate(ctx, []byte(`{}`), &replayOptions)
+}
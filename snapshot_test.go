@@ -0,0 +1,108 @@
+package rulesengine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAlmanacSnapshot(t *testing.T) {
+	var calls atomic.Int32
+	newEngine := func(t *testing.T, minAge int) *Engine {
+		engine := newTestEngine(t, fmt.Sprintf(`{
+			"name": "eligible",
+			"conditions": {"all": [
+				{"fact": "user.age", "operator": "greaterThan", "value": %d},
+				{"fact": "score", "params": {"model": "v2"}, "operator": "greaterThanInclusive", "value": 700}
+			]},
+			"event": {"type": "eligible", "params": {"tier": "gold"}}
+		}`, minAge), nil)
+		engine.AddCalculatedFact("score", func(a *Almanac, params ...interface{}) *ValueNode {
+			calls.Add(1)
+			return &ValueNode{Type: Number, Number: 720}
+		}, nil)
+		return engine
+	}
+	facts := []byte(`{"user": {"age": 30, "name": "Ada", "history": [1, 2, 3]}}`)
+
+	engine := newEngine(t, 17)
+	options := DefaultRunOptions()
+	options.TrackFactAccess = true
+	res, err := engine.RunWithOptions(context.Background(), facts, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	accessed := res.Almanac.AccessedFacts()
+	if len(accessed) != 2 || accessed[0].Path != "score" || accessed[0].Params["model"] != "v2" || accessed[1].Path != "user.age" {
+		t.Fatalf("Expected the score and user.age facts only, got %+v", accessed)
+	}
+
+	data, err := res.Almanac.MarshalSnapshot()
+	if err != nil {
+		t.Fatalf("Failed to marshal snapshot: %v", err)
+	}
+	if strings.Contains(string(data), "Ada") {
+		t.Errorf("Expected facts not read by the rules to be left out, got %s", data)
+	}
+	snapshot, err := UnmarshalSnapshot(data)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal snapshot: %v", err)
+	}
+	if snapshot.Version != SnapshotVersion || len(snapshot.Facts) != 2 || snapshot.Facts[1].Value.Number != 30 {
+		t.Fatalf("Unexpected snapshot %+v", snapshot)
+	}
+	if len(snapshot.Events) != 1 || snapshot.Events[0].Outcome != Success || snapshot.Events[0].Params["tier"] != "gold" {
+		t.Errorf("Expected the eligible event, got %+v", snapshot.Events)
+	}
+	if len(snapshot.Results) != 1 || snapshot.Results[0].Name != "eligible" || !*snapshot.Results[0].Result {
+		t.Errorf("Expected the eligible result, got %+v", snapshot.Results)
+	}
+
+	t.Run("replay reproduces the decision without calculating facts", func(t *testing.T) {
+		calls.Store(0)
+		replayed, err := engine.Replay(context.Background(), snapshot, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(replayed.Results) != 1 || replayed.Results[0].Name != "eligible" {
+			t.Errorf("Expected the eligible rule to pass on replay, got %v", replayed.Results)
+		}
+		if calls.Load() != 0 {
+			t.Errorf("Expected the calculated fact not to be called, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("replay against changed rules", func(t *testing.T) {
+		replayed, err := newEngine(t, 40).Replay(context.Background(), snapshot, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(replayed.Results) != 0 || len(replayed.FailureResults) != 1 {
+			t.Errorf("Expected the stricter rule to fail on replay, got %v", replayed.Results)
+		}
+	})
+
+	t.Run("snapshots require access tracking", func(t *testing.T) {
+		res, err := engine.Run(context.Background(), facts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := res.Almanac.MarshalSnapshot(); err == nil {
+			t.Errorf("Expected an error without access tracking")
+		}
+	})
+
+	t.Run("unsupported snapshots are rejected", func(t *testing.T) {
+		for _, data := range []string{
+			`{"kind": "other", "version": 1}`,
+			`{"kind": "` + SnapshotKind + `", "version": 99}`,
+			`{"kind": "` + SnapshotKind + `", "version": 1, "facts": [{"path": "a"}]}`,
+		} {
+			if _, err := UnmarshalSnapshot([]byte(data)); err == nil {
+				t.Errorf("Expected an error for %s", data)
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package rulesengine
+
+// EngineState is a saved copy of the engine configuration, see Engine.SaveState.
+// It holds the rules with their priorities, the named conditions, the registered facts and the bundle constants.
+// Operators, event handlers and the engine options are not part of it.
+type EngineState struct {
+	rules      []*Rule
+	priorities []int
+	conditions map[string]Condition
+	facts      map[string]*Fact
+	constants  map[string]struct{}
+}
+
+// SaveState captures the engine configuration, e.g. to undo temporary rules and facts added by a test.
+// Rules, conditions and facts are immutable once registered, so the state shares them with the engine.
+// Returns the saved state, to be passed to RestoreState.
+func (e *Engine) SaveState() *EngineState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state := &EngineState{
+		rules:      append([]*Rule(nil), e.Rules...),
+		priorities: make([]int, len(e.Rules)),
+		conditions: map[string]Condition{},
+		facts:      map[string]*Fact{},
+		constants:  make(map[string]struct{}, len(e.constants)),
+	}
+	for i, r := range e.Rules {
+		state.priorities[i] = r.Priority
+	}
+	e.Conditions.Range(func(key, value interface{}) bool {
+		state.conditions[key.(string)] = value.(Condition)
+		return true
+	})
+	e.Facts.Range(func(key string, f *Fact) bool {
+		state.facts[key] = f
+		return true
+	})
+	for key := range e.constants {
+		state.constants[key] = struct{}{}
+	}
+	return state
+}
+
+// RestoreState returns the engine to a state captured with SaveState.
+// The prioritized rules, the rules' cached condition ordering and the result cache are invalidated.
+// Runs already in progress keep evaluating the rules they started with.
+// Params:
+// - state: The state returned by SaveState.
+func (e *Engine) RestoreState(state *EngineState) {
+	if state == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules := append([]*Rule(nil), state.rules...)
+	for i, r := range rules {
+		if r.Priority != state.priorities[i] {
+			r.Priority = state.priorities[i]
+		}
+		r.SetEngine(e)
+	}
+	e.setRules(rules)
+
+	e.Conditions.Clear()
+	for name, condition := range state.conditions {
+		e.Conditions.Store(name, condition)
+	}
+
+	e.Facts.Range(func(key string, _ *Fact) bool {
+		if _, ok := state.facts[key]; !ok {
+			e.Facts.Delete(key)
+		}
+		return true
+	})
+	for key, f := range state.facts {
+		e.Facts.Set(key, f)
+	}
+	e.constants = make(map[string]struct{}, len(state.constants))
+	for key := range state.constants {
+		e.constants[key] = struct{}{}
+	}
+	e.factsVersion.Add(1)
+}
@@ -0,0 +1,57 @@
+package rulesengine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEngineSaveRestoreState(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "adult", "conditions": {"all": [{"condition": "isAdult"}]}, "event": {"type": "adult"}}`, nil)
+	err := engine.LoadBundle([]byte(`{
+		"constants": {"minAge": 18},
+		"conditions": {"isAdult": {"all": [{"fact": "age", "operator": "greaterThanInclusive", "value": 18}]}}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to load bundle: %v", err)
+	}
+	facts := []byte(`{"age": 20}`)
+	state := engine.SaveState()
+
+	// Temporary changes
+	temp := newTestEngine(t, `{"name": "temp", "priority": 10, "conditions": {"all": [{"fact": "age", "operator": "equal", "value": 20}]}, "event": {"type": "temp"}}`, nil).GetRules()[0]
+	if err := engine.AddRule(temp); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	if err := engine.AddCondition("isAdult", &Condition{All: []*Condition{{Fact: "age", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 21}}}}); err != nil {
+		t.Fatalf("Failed to replace condition: %v", err)
+	}
+	if err := engine.AddFact("tempFact", &ValueNode{Type: Number, Number: 1}, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	engine.GetRules()[0].Priority = 3
+	res, err := engine.Run(context.Background(), facts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if results := res.Results; len(results) != 1 || results[0].Name != "temp" {
+		t.Fatalf("Expected only the temporary rule to pass, got %v", results)
+	}
+
+	engine.RestoreState(state)
+	if rules := engine.GetRules(); len(rules) != 1 || rules[0].Name != "adult" || rules[0].Priority != 1 {
+		t.Fatalf("Expected the original rule with its priority, got %v", rules)
+	}
+	if _, ok := engine.Facts.Load("tempFact"); ok {
+		t.Errorf("Expected the temporary fact to be removed")
+	}
+	if _, ok := engine.Facts.Load("minAge"); !ok {
+		t.Errorf("Expected the constant to be restored")
+	}
+	res, err = engine.Run(context.Background(), facts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if results := res.Results; len(results) != 1 || results[0].Name != "adult" {
+		t.Errorf("Expected the original condition to be restored, got %v", results)
+	}
+}
@@ -0,0 +1,84 @@
+package rulesengine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EngineStats is a JSON-serializable snapshot of the engine configuration and its cumulative run counters.
+type EngineStats struct {
+	Rules            int         `json:"rules"`
+	RulesByPriority  map[int]int `json:"rulesByPriority"`
+	Conditions       int         `json:"conditions"`
+	BuiltinOperators int         `json:"builtinOperators"`
+	CustomOperators  int         `json:"customOperators"`
+	StaticFacts      int         `json:"staticFacts"`
+	CalculatedFacts  int         `json:"calculatedFacts"`
+	Runs             int64       `json:"runs"`
+	RunErrors        int64       `json:"runErrors"`
+	// AverageRunDuration is the mean duration of all runs, including runs served from the result cache
+	AverageRunDuration time.Duration `json:"averageRunDurationNs"`
+}
+
+// runCounters holds the cumulative run counters of an engine
+type runCounters struct {
+	runs     atomic.Int64
+	errors   atomic.Int64
+	duration atomic.Int64 // Total duration of all runs in nanoseconds
+}
+
+// record adds a finished run to the counters
+func (c *runCounters) record(started time.Time, err error) {
+	c.runs.Add(1)
+	c.duration.Add(int64(time.Since(started)))
+	if err != nil {
+		c.errors.Add(1)
+	}
+}
+
+// builtinOperatorNames holds the names of the operators registered by DefaultOperators
+var builtinOperatorNames = func() map[string]struct{} {
+	names := map[string]struct{}{}
+	for _, op := range DefaultOperators() {
+		names[op.Name] = struct{}{}
+	}
+	return names
+}()
+
+// Stats returns a snapshot of the rules, named conditions, operators and facts registered on the engine,
+// together with cumulative run counters. It is safe to call concurrently with runs.
+func (e *Engine) Stats() EngineStats {
+	rules := e.GetRules()
+	stats := EngineStats{
+		Rules:           len(rules),
+		RulesByPriority: map[int]int{},
+		Runs:            e.counters.runs.Load(),
+		RunErrors:       e.counters.errors.Load(),
+	}
+	for _, r := range rules {
+		stats.RulesByPriority[r.Priority]++
+	}
+	e.Conditions.Range(func(_, _ interface{}) bool {
+		stats.Conditions++
+		return true
+	})
+	for name := range e.Operators() {
+		if _, ok := builtinOperatorNames[name]; ok {
+			stats.BuiltinOperators++
+		} else {
+			stats.CustomOperators++
+		}
+	}
+	e.Facts.Range(func(_ string, f *Fact) bool {
+		if f.Dynamic {
+			stats.CalculatedFacts++
+		} else {
+			stats.StaticFacts++
+		}
+		return true
+	})
+	if stats.Runs > 0 {
+		stats.AverageRunDuration = time.Duration(e.counters.duration.Load() / stats.Runs)
+	}
+	return stats
+}
@@ -0,0 +1,56 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestEngineStats(t *testing.T) {
+	engine := newTestEngine(t, `{"name": "adult", "priority": 5, "conditions": {"all": [{"fact": "age", "operator": "greaterThan", "value": 18}]}, "event": {"type": "adult"}}`, nil)
+	engine.AddOperator("custom", EvalEqual)
+	if err := engine.AddFact("limit", &ValueNode{Type: Number, Number: 1}, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	if err := engine.AddCalculatedFact("derived", func(a *Almanac, params ...interface{}) *ValueNode {
+		return &ValueNode{Type: Number, Number: 2}
+	}, nil); err != nil {
+		t.Fatalf("Failed to add fact: %v", err)
+	}
+	if err := engine.AddCondition("isAdult", &Condition{Fact: "age", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 18}}); err != nil {
+		t.Fatalf("Failed to add condition: %v", err)
+	}
+
+	if _, err := engine.Run(context.Background(), []byte(`{"age": 20}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := engine.Run(context.Background(), []byte(`{}`)); err == nil {
+		t.Fatalf("Expected an undefined fact error")
+	}
+
+	stats := engine.Stats()
+	if stats.Rules != 1 || stats.RulesByPriority[5] != 1 {
+		t.Errorf("Unexpected rule counts: %+v", stats)
+	}
+	if stats.Conditions != 1 || stats.StaticFacts != 1 || stats.CalculatedFacts != 1 {
+		t.Errorf("Unexpected condition or fact counts: %+v", stats)
+	}
+	if stats.CustomOperators != 1 || stats.BuiltinOperators != len(DefaultOperators()) {
+		t.Errorf("Unexpected operator counts: %+v", stats)
+	}
+	if stats.Runs != 2 || stats.RunErrors != 1 || stats.AverageRunDuration <= 0 {
+		t.Errorf("Unexpected run counters: %+v", stats)
+	}
+	if _, err := json.Marshal(stats); err != nil {
+		t.Errorf("Expected stats to be JSON serializable, got %v", err)
+	}
+}
+
+// The stats can be published with expvar, making them available on /debug/vars
+func ExampleEngine_Stats() {
+	engine := NewEngine(nil, nil)
+	expvar.Publish("rules", expvar.Func(func() interface{} {
+		return engine.Stats()
+	}))
+}
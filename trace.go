@@ -0,0 +1,30 @@
+package rulesengine
+
+import (
+	"log"
+	"time"
+)
+
+// SlowCondition describes a leaf condition whose evaluation exceeded the engine's SlowConditionThreshold.
+type SlowCondition struct {
+	Rule string
+	// Path locates the condition within the rule, e.g. "conditions.all[1].any[0]"
+	Path string
+	// Fact is the fact path of the condition, or the comma separated facts of a multi-fact condition
+	Fact string
+	// Duration is the evaluation time of the condition, including the facts it was first to resolve
+	Duration time.Duration
+}
+
+// SlowConditionHandler receives slow conditions, e.g. to record a metric. It is called from the goroutines
+// evaluating the rules, so it must be safe for concurrent use.
+type SlowConditionHandler func(SlowCondition)
+
+// reportSlowCondition passes a slow condition to the engine's OnSlowCondition handler, or logs it when there is none
+func (e *Engine) reportSlowCondition(slow SlowCondition) {
+	if e.OnSlowCondition != nil {
+		e.OnSlowCondition(slow)
+		return
+	}
+	log.Printf("rules engine: slow condition in rule %q at %s on fact %q took %s", slow.Rule, slow.Path, slow.Fact, slow.Duration)
+}
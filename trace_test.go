@@ -0,0 +1,110 @@
+package rulesengine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlowConditions(t *testing.T) {
+	const delay = 30 * time.Millisecond
+	newEngine := func(t *testing.T, ruleJSON string) (*Engine, func() []SlowCondition) {
+		t.Helper()
+		var mu sync.Mutex
+		var slow []SlowCondition
+		options := DefaultRuleEngineOptions()
+		options.SlowConditionThreshold = delay / 2
+		options.OnSlowCondition = func(s SlowCondition) {
+			mu.Lock()
+			defer mu.Unlock()
+			slow = append(slow, s)
+		}
+		engine := NewEngine(nil, options)
+		err := engine.AddCalculatedFact("score", func(a *Almanac, params ...interface{}) *ValueNode {
+			time.Sleep(delay)
+			return &ValueNode{Type: Number, Number: 5}
+		}, &FactOptions{Cache: true, Priority: 1})
+		if err != nil {
+			t.Fatalf("Failed to add fact: %v", err)
+		}
+		if err := engine.SetCondition("scored", Condition{All: []*Condition{{Fact: "score", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 1}}}}); err != nil {
+			t.Fatalf("Failed to set condition: %v", err)
+		}
+		config := ephemeralRuleConfig(t, ruleJSON)
+		rule, err := NewRule(&config)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+		return engine, func() []SlowCondition {
+			mu.Lock()
+			defer mu.Unlock()
+			return slow
+		}
+	}
+
+	ruleJSON := `{"name": "slow", "conditions": {"ordered": true, "all": [
+		{"fact": "a", "operator": "equal", "value": 1},
+		{"all": [{"fact": "score", "operator": "greaterThan", "value": 1}]},
+		{"fact": "score", "operator": "greaterThan", "value": 2}
+	]}, "event": {"type": "slow"}}`
+
+	t.Run("trace records leaf durations", func(t *testing.T) {
+		engine, slow := newEngine(t, ruleJSON)
+		options := DefaultRunOptions()
+		options.Trace = true
+		res, err := engine.RunWithOptions(context.Background(), []byte(`{"a": 1}`), options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		results := res.Results
+		if len(results) != 1 {
+			t.Fatalf("Expected the rule to pass, got %d results", len(results))
+		}
+		conditions := results[0].Conditions
+		if d := conditions.All[1].All[0].Duration; d < delay {
+			t.Errorf("Expected the fact resolution to be attributed to the first leaf needing it, got %s", d)
+		}
+		if d := conditions.All[2].Duration; d <= 0 || d >= delay {
+			t.Errorf("Expected the second leaf on the cached fact to be fast, got %s", d)
+		}
+		if d := conditions.All[0].Duration; d <= 0 {
+			t.Errorf("Expected every leaf to be timed, got %s", d)
+		}
+
+		reported := slow()
+		if len(reported) != 1 {
+			t.Fatalf("Expected one slow condition, got %v", reported)
+		}
+		if s := reported[0]; s.Rule != "slow" || s.Path != "conditions.all[1].all[0]" || s.Fact != "score" || s.Duration < delay {
+			t.Errorf("Unexpected slow condition %+v", s)
+		}
+	})
+
+	t.Run("slow conditions are reported without trace", func(t *testing.T) {
+		engine, slow := newEngine(t, ruleJSON)
+		res, err := engine.Run(context.Background(), []byte(`{"a": 1}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if d := res.Results[0].Conditions.All[1].All[0].Duration; d != 0 {
+			t.Errorf("Expected no durations outside trace mode, got %s", d)
+		}
+		if reported := slow(); len(reported) != 1 || reported[0].Path != "conditions.all[1].all[0]" {
+			t.Errorf("Expected the slow condition to be reported, got %v", reported)
+		}
+	})
+
+	t.Run("paths continue through condition references", func(t *testing.T) {
+		engine, slow := newEngine(t, `{"name": "referenced", "conditions": {"all": [{"condition": "scored"}]}, "event": {"type": "referenced"}}`)
+		if _, err := engine.Run(context.Background(), []byte(`{}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if reported := slow(); len(reported) != 1 || reported[0].Path != "conditions.all[0]{scored}.all[0]" {
+			t.Errorf("Expected the path through the reference, got %v", reported)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package rulesengine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Transform converts a resolved fact value before the operator of a condition runs, see Condition.Transforms.
+// Transforms must not modify the value they receive, and should pass Null (undefined facts) through.
+type Transform func(value *ValueNode) (*ValueNode, error)
+
+// DefaultTransforms returns the built-in transforms by name:
+// - lower, upper: the string in lower or upper case.
+// - trim: the string without leading and trailing white space.
+// - abs: the absolute value of a number.
+// - length: the number of characters of a string, elements of an array or keys of an object.
+func DefaultTransforms() map[string]Transform {
+	return map[string]Transform{
+		"lower":  stringTransform("lower", strings.ToLower),
+		"upper":  stringTransform("upper", strings.ToUpper),
+		"trim":   stringTransform("trim", strings.TrimSpace),
+		"abs":    absTransform,
+		"length": lengthTransform,
+	}
+}
+
+// defaultTransforms are the built-in transforms used by Condition.Evaluate
+var defaultTransforms = DefaultTransforms()
+
+// stringTransform returns a transform applying fn to string values
+func stringTransform(name string, fn func(string) string) Transform {
+	return func(value *ValueNode) (*ValueNode, error) {
+		switch value.Type {
+		case Null:
+			return value, nil
+		case String:
+			return &ValueNode{Type: String, String: fn(value.String)}, nil
+		}
+		return nil, fmt.Errorf("transform %s: expects a string, got %s", name, value.Type)
+	}
+}
+
+func absTransform(value *ValueNode) (*ValueNode, error) {
+	switch value.Type {
+	case Null:
+		return value, nil
+	case Number:
+		return &ValueNode{Type: Number, Number: math.Abs(value.Number)}, nil
+	}
+	return nil, fmt.Errorf("transform abs: expects a number, got %s", value.Type)
+}
+
+func lengthTransform(value *ValueNode) (*ValueNode, error) {
+	switch value.Type {
+	case Null:
+		return value, nil
+	case String:
+		return &ValueNode{Type: Number, Number: float64(utf8.RuneCountInString(value.String))}, nil
+	case Array:
+		return &ValueNode{Type: Number, Number: float64(len(value.Array))}, nil
+	case Object:
+		return &ValueNode{Type: Number, Number: float64(len(value.Object))}, nil
+	}
+	return nil, fmt.Errorf("transform length: expects a string, array or object, got %s", value.Type)
+}
+
+// transformRegistry holds the transforms of an engine; the map is replaced on every change, so readers need no lock
+type transformRegistry struct {
+	mu      sync.Mutex
+	current atomic.Pointer[map[string]Transform]
+}
+
+// load returns the current transforms, the map must not be modified
+func (r *transformRegistry) load() map[string]Transform {
+	if m := r.current.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// store adds or replaces a transform
+func (r *transformRegistry) store(name string, transform Transform) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.load()
+	next := make(map[string]Transform, len(current)+1)
+	for n, existing := range current {
+		next[n] = existing
+	}
+	next[name] = transform
+	r.current.Store(&next)
+}
+
+// AddTransform registers a transform for the "transforms" of conditions, replacing a transform of the same name,
+// the built-in ones included.
+// Params:
+// - name: The name conditions refer to the transform by.
+// - transform: The transform.
+// Returns an error if the name is empty or the transform is nil.
+func (e *Engine) AddTransform(name string, transform Transform) error {
+	if name == "" {
+		return errors.New("engine: transform name is required")
+	}
+	if transform == nil {
+		return fmt.Errorf("engine: transform %s is nil", name)
+	}
+	Debug(fmt.Sprintf("engine::addTransform name:%s", name))
+	e.transforms.store(name, transform)
+	e.configVersion.Add(1)
+	return nil
+}
+
+// Transforms returns a snapshot of the registered transforms by name.
+// The map is shared and must not be modified, use AddTransform instead.
+func (e *Engine) Transforms() map[string]Transform {
+	return e.transforms.load()
+}
+
+// applyTransforms applies the transforms of the condition to a fact value, in order
+func (c *Condition) applyTransforms(value *ValueNode, transforms map[string]Transform) (*ValueNode, error) {
+	for _, name := range c.Transforms {
+		transform, ok := transforms[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transform: %s", name)
+		}
+		transformed, err := transform(value)
+		if err != nil {
+			return nil, err
+		}
+		if transformed == nil {
+			transformed = &ValueNode{Type: Null}
+		}
+		value = transformed
+	}
+	return value, nil
+}
+
+// FactExpression returns the fact of a leaf condition wrapped in its transforms, e.g. "lower(trim(email))" for
+// the transforms ["trim", "lower"], or the fact itself without transforms.
+func (c *Condition) FactExpression() string {
+	expression := c.Fact
+	for _, name := range c.Transforms {
+		expression = name + "(" + expression + ")"
+	}
+	return expression
+}
+
+// unknownTransforms returns every leaf condition of the tree using a transform that is not registered, as its path
+// and transform, e.g. `conditions.all[1] "lowr"`
+func unknownTransforms(c *Condition, transforms map[string]Transform, path string) []string {
+	if c == nil {
+		return nil
+	}
+	var unknown []string
+	for _, name := range c.Transforms {
+		if _, ok := transforms[name]; !ok {
+			unknown = append(unknown, fmt.Sprintf("%s %q", path, name))
+		}
+	}
+	for _, group := range []struct {
+		operator   string
+		conditions []*Condition
+	}{{"all", c.All}, {"any", c.Any}, {"none", c.None}, {"mostOf", c.MostOf}} {
+		for i, child := range group.conditions {
+			unknown = append(unknown, unknownTransforms(child, transforms, fmt.Sprintf("%s.%s[%d]", path, group.operator, i))...)
+		}
+	}
+	return append(unknown, unknownTransforms(c.Not, transforms, path+".not")...)
+}
@@ -0,0 +1,108 @@
+package rulesengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConditionTransforms(t *testing.T) {
+	run := func(t *testing.T, engine *Engine, facts string) *RunResult {
+		t.Helper()
+		res, err := engine.Run(context.Background(), []byte(facts))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return res
+	}
+
+	t.Run("transforms are applied in order", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "corp",
+			"conditions": {"all": [
+				{"fact": "email", "transforms": ["trim", "lower"], "operator": "endsWith", "value": "@corp.com"},
+				{"fact": "name", "transforms": ["trim", "length"], "operator": "greaterThan", "value": 2},
+				{"fact": "balance", "transforms": ["abs"], "operator": "greaterThan", "value": 10}
+			]},
+			"event": {"type": "corp"}
+		}`, nil)
+		res := run(t, engine, `{"email": " Ada@CORP.com ", "name": "  Ada ", "balance": -50}`)
+		if len(res.Results) != 1 {
+			t.Fatalf("Expected the transformed facts to pass, got %v", res.FailureResults)
+		}
+		out, err := res.Results[0].ToJSON(false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		email := out.(map[string]interface{})["conditions"].(map[string]interface{})["all"].([]interface{})[0].(map[string]interface{})
+		if email["factResult"] != " Ada@CORP.com " || email["transformedResult"] != "ada@corp.com" || len(email["transforms"].([]string)) != 2 {
+			t.Errorf("Expected the fact, its transforms and the transformed value in the result, got %v", email)
+		}
+		if expression := res.Results[0].Conditions.All[0].FactExpression(); expression != "lower(trim(email))" {
+			t.Errorf("Expected lower(trim(email)), got %s", expression)
+		}
+		if res := run(t, engine, `{"email": "ada@example.com", "name": "Ada", "balance": -50}`); len(res.Results) != 0 {
+			t.Errorf("Expected another domain to fail")
+		}
+	})
+
+	t.Run("custom transforms", func(t *testing.T) {
+		engine := NewEngine(nil, nil)
+		if engine.AddTransform("", absTransform) == nil || engine.AddTransform("domain", nil) == nil {
+			t.Errorf("Expected empty names and nil transforms to be rejected")
+		}
+		if err := engine.AddTransform("domain", func(value *ValueNode) (*ValueNode, error) {
+			_, domain, _ := strings.Cut(value.String, "@")
+			return &ValueNode{Type: String, String: domain}, nil
+		}); err != nil {
+			t.Fatalf("Failed to add transform: %v", err)
+		}
+		rule, err := NewRule(&RuleConfig{
+			Name:       "corp",
+			Conditions: Condition{All: []*Condition{{Fact: "email", Transforms: []string{"domain"}, Operator: "equal", Value: ValueNode{Type: String, String: "corp.com"}, ValueSet: true}}},
+			Event:      EventConfig{Type: "corp"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+		if res := run(t, engine, `{"email": "ada@corp.com"}`); len(res.Results) != 1 {
+			t.Errorf("Expected the custom transform to be applied")
+		}
+	})
+
+	t.Run("unknown transforms are rejected", func(t *testing.T) {
+		var config RuleConfig
+		if err := json.Unmarshal([]byte(`{"name": "r", "conditions": {"any": [{"fact": "email", "transforms": ["lowr"], "operator": "equal", "value": "a"}]}, "event": {"type": "r"}}`), &config); err != nil {
+			t.Fatalf("Failed to unmarshal rule: %v", err)
+		}
+		err := NewEngine(nil, nil).AddRuleFromMap(&config)
+		var invalid *InvalidRuleError
+		if !errors.As(err, &invalid) || invalid.Code != "UNKNOWN_TRANSFORM" || !strings.Contains(err.Error(), `conditions.any[0] "lowr"`) {
+			t.Errorf("Expected an UNKNOWN_TRANSFORM error naming the condition, got %v", err)
+		}
+	})
+
+	t.Run("transforms require a single fact", func(t *testing.T) {
+		c := &Condition{Facts: []string{"a", "b"}, Transforms: []string{"lower"}, Operator: "subsetOf"}
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected transforms on a multi-fact condition to be rejected")
+		}
+	})
+
+	t.Run("transforms of the wrong type fail the condition", func(t *testing.T) {
+		engine := newTestEngine(t, `{
+			"name": "corp",
+			"conditions": {"all": [{"fact": "email", "transforms": ["lower"], "operator": "equal", "value": "a"}]},
+			"event": {"type": "corp"}
+		}`, nil)
+		_, err := engine.Run(context.Background(), []byte(`{"email": 1}`))
+		if err == nil || !strings.Contains(err.Error(), "lower(email) equal") || !strings.Contains(err.Error(), "expects a string") {
+			t.Errorf("Expected a transform error naming the condition, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package rulesengine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// undefinedConditionAnalyzerName is the analyzer name of issues for references to conditions that are not registered
+const undefinedConditionAnalyzerName = "undefinedCondition"
+
+// UndefinedCondition is a reference to a condition that was not registered, tolerated during a run because of
+// RuleEngineOptions.AllowUndefinedConditions or the reference's ifMissing.
+type UndefinedCondition struct {
+	Rule      string `json:"rule"`
+	Condition string `json:"condition"`
+	// Resolution is how the reference was evaluated, IfMissingFalse or IfMissingSkip
+	Resolution string `json:"resolution"`
+	// Count is the number of times the reference was evaluated during the run
+	Count int `json:"count"`
+}
+
+// UndefinedConditionAnalyzer flags references to conditions that are not registered on the engine. Such rules fail
+// with an error, or evaluate the reference as false or skip it with AllowUndefinedConditions or ifMissing.
+var UndefinedConditionAnalyzer = LintAnalyzer{
+	Name: undefinedConditionAnalyzerName,
+	Run: func(r *Rule) []LintIssue {
+		if r.Engine == nil {
+			return nil
+		}
+		var issues []LintIssue
+		var walk func(c *Condition, path string)
+		walk = func(c *Condition, path string) {
+			if c == nil {
+				return
+			}
+			if c.IsConditionReference() {
+				if _, ok := r.Engine.Conditions.Load(c.Condition); !ok {
+					issues = append(issues, LintIssue{
+						Rule:     r.Name,
+						Analyzer: undefinedConditionAnalyzerName,
+						Message:  fmt.Sprintf("condition %q is not registered", c.Condition),
+						Paths:    []string{path},
+					})
+				}
+				return
+			}
+			for _, group := range []struct {
+				operator   string
+				conditions []*Condition
+			}{{"all", c.All}, {"any", c.Any}, {"none", c.None}, {"mostOf", c.MostOf}} {
+				for i, child := range group.conditions {
+					walk(child, joinConditionPath(path, fmt.Sprintf("%s[%d]", group.operator, i)))
+				}
+			}
+			walk(c.Not, joinConditionPath(path, "not"))
+		}
+		walk(&r.Conditions, "")
+		return issues
+	},
+}
+
+// undefinedConditionKey identifies a tolerated reference within a run
+type undefinedConditionKey struct {
+	rule, condition string
+}
+
+// recordUndefinedCondition counts a tolerated reference to a missing condition; the first occurrence of a reference
+// in a run is diagnosed. Safe to call from the goroutines evaluating rules.
+func (e *Engine) recordUndefinedCondition(almanac *Almanac, rule, condition, resolution string) {
+	key := undefinedConditionKey{rule: rule, condition: condition}
+	almanac.undefinedMu.Lock()
+	if almanac.undefinedConditions == nil {
+		almanac.undefinedConditions = map[undefinedConditionKey]*UndefinedCondition{}
+	}
+	undefined, seen := almanac.undefinedConditions[key]
+	if !seen {
+		undefined = &UndefinedCondition{Rule: rule, Condition: condition, Resolution: resolution}
+		almanac.undefinedConditions[key] = undefined
+	}
+	undefined.Count++
+	almanac.undefinedMu.Unlock()
+	if !seen && !almanac.shadow {
+		e.diagnose(almanac, Diagnostic{
+			Type:  DiagnosticUndefinedCondition,
+			Rule:  rule,
+			Error: fmt.Sprintf("condition %q is not registered, ifMissing %q applied", condition, resolution),
+		})
+	}
+}
+
+// UndefinedConditions returns the references to missing conditions tolerated so far by the run,
+// sorted by rule and condition.
+func (a *Almanac) UndefinedConditions() []UndefinedCondition {
+	a.undefinedMu.Lock()
+	defer a.undefinedMu.Unlock()
+	if len(a.undefinedConditions) == 0 {
+		return nil
+	}
+	undefined := make([]UndefinedCondition, 0, len(a.undefinedConditions))
+	for _, u := range a.undefinedConditions {
+		undefined = append(undefined, *u)
+	}
+	sort.Slice(undefined, func(i, j int) bool {
+		if undefined[i].Rule != undefined[j].Rule {
+			return undefined[i].Rule < undefined[j].Rule
+		}
+		return undefined[i].Condition < undefined[j].Condition
+	})
+	return undefined
+}
@@ -0,0 +1,68 @@
+package rulesengine
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUndefinedConditions(t *testing.T) {
+	engine := newTestEngine(t, `{
+		"name": "premium",
+		"conditions": {"any": [
+			{"condition": "isPremium"},
+			{"ordered": true, "all": [{"condition": "isVerified", "ifMissing": "skip"}, {"condition": "isPremium"}]},
+			{"fact": "age", "operator": "greaterThan", "value": 100}
+		]},
+		"event": {"type": "premium"}
+	}`, &RuleEngineOptions{AllowUndefinedConditions: true})
+	var diagnosed atomic.Int32
+	if err := engine.OnDiagnostic(func(diagnostic Diagnostic, almanac *Almanac) {
+		if diagnostic.Type == DiagnosticUndefinedCondition && diagnostic.Rule == "premium" {
+			diagnosed.Add(1)
+		}
+	}); err != nil {
+		t.Fatalf("Failed to register diagnostic handler: %v", err)
+	}
+
+	issues := engine.Lint()
+	if len(issues) != 3 || issues[0].Analyzer != undefinedConditionAnalyzerName || issues[1].Paths[0] != "any[1].all[0]" {
+		t.Errorf("Expected the three references to be linted, got %+v", issues)
+	}
+
+	res, err := engine.Run(context.Background(), []byte(`{"age": 30}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(res.FailureResults) != 1 {
+		t.Fatalf("Expected the rule to fail, got %v", res.Results)
+	}
+	undefined := res.Stats.UndefinedConditions
+	if len(undefined) != 2 ||
+		undefined[0] != (UndefinedCondition{Rule: "premium", Condition: "isPremium", Resolution: IfMissingFalse, Count: 2}) ||
+		undefined[1] != (UndefinedCondition{Rule: "premium", Condition: "isVerified", Resolution: IfMissingSkip, Count: 1}) {
+		t.Errorf("Unexpected undefined conditions %+v", undefined)
+	}
+	if diagnosed.Load() != 2 {
+		t.Errorf("Expected a diagnostic per reference, got %d", diagnosed.Load())
+	}
+
+	t.Run("registered conditions are not reported", func(t *testing.T) {
+		leaf := Condition{Fact: "age", Operator: "greaterThan", Value: ValueNode{Type: Number, Number: 18}}
+		for _, name := range []string{"isPremium", "isVerified"} {
+			if err := engine.SetCondition(name, Condition{All: []*Condition{&leaf}}); err != nil {
+				t.Fatalf("Failed to set condition: %v", err)
+			}
+		}
+		if issues := engine.Lint(); len(issues) != 0 {
+			t.Errorf("Expected no issues, got %+v", issues)
+		}
+		res, err := engine.Run(context.Background(), []byte(`{"age": 30}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(res.Results) != 1 || res.Stats.UndefinedConditions != nil {
+			t.Errorf("Expected the rule to pass without undefined conditions, got %+v", res.Stats.UndefinedConditions)
+		}
+	})
+}
@@ -15,3 +15,67 @@ func HashString(data string) uint64 {
 	h.Write([]byte(data))
 	return h.Sum64()
 }
+
+// copyParams returns a deep copy of event params, so the caller's map and the rule do not share storage
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		copied[key] = deepCopy(value)
+	}
+	return copied
+}
+
+// deepCopy copies the maps and slices of a value, recursively; other values are returned unchanged
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyParams(v)
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make([]interface{}, len(v))
+		for i, element := range v {
+			copied[i] = deepCopy(element)
+		}
+		return copied
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopyValue(iter.Value(), rv.Type().Elem()))
+		}
+		return copied.Interface()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			copied.Index(i).Set(deepCopyValue(rv.Index(i), rv.Type().Elem()))
+		}
+		return copied.Interface()
+	}
+	return value
+}
+
+// deepCopyValue copies an element of a map or slice with element type elem
+func deepCopyValue(v reflect.Value, elem reflect.Type) reflect.Value {
+	if elem.Kind() == reflect.Interface && v.IsNil() {
+		return reflect.Zero(elem)
+	}
+	copied := reflect.ValueOf(deepCopy(v.Interface()))
+	if elem.Kind() == reflect.Interface {
+		return copied
+	}
+	return copied.Convert(elem)
+}
@@ -2,8 +2,13 @@ package rulesengine
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
 )
 
 type DataType int
@@ -17,6 +22,26 @@ const (
 	Object
 )
 
+// String returns the lower case name of the data type
+func (d DataType) String() string {
+	switch d {
+	case Null:
+		return "null"
+	case Bool:
+		return "bool"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Array:
+		return "array"
+	case Object:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
 // ValueNode represents a value used in conditions and comparisons.
 // It supports types such as strings, numbers, booleans, arrays, and null.
 type ValueNode struct {
@@ -56,6 +81,28 @@ func (v *ValueNode) SameType(other *ValueNode) bool {
 	return v.Type == other.Type
 }
 
+// deepCopy returns a copy of the value sharing no arrays or objects with it
+func (v *ValueNode) deepCopy() ValueNode {
+	copied := *v
+	switch v.Type {
+	case Array:
+		if v.Array != nil {
+			copied.Array = make([]ValueNode, len(v.Array))
+			for i := range v.Array {
+				copied.Array[i] = v.Array[i].deepCopy()
+			}
+		}
+	case Object:
+		if v.Object != nil {
+			copied.Object = make(map[string]ValueNode, len(v.Object))
+			for key, value := range v.Object {
+				copied.Object[key] = value.deepCopy()
+			}
+		}
+	}
+	return copied
+}
+
 func (v *ValueNode) Raw() interface{} {
 	switch v.Type {
 	case Null:
@@ -83,6 +130,114 @@ func (v *ValueNode) Raw() interface{} {
 	}
 }
 
+// Compare defines a total order over values, returning -1, 0 or +1 when v sorts before, equal to or after other.
+// Values of different types are ordered by type: Null < Bool < Number < String < Array < Object.
+// Within a type false < true, numbers compare numerically with NaN before every other number, strings compare
+// byte-wise, arrays compare element by element with a prefix first, and objects compare their sorted keys and
+// the values under them pairwise, with a prefix of keys first.
+func (v *ValueNode) Compare(other *ValueNode) int {
+	if v.Type != other.Type {
+		return cmp.Compare(v.Type, other.Type)
+	}
+	switch v.Type {
+	case Bool:
+		if v.Bool == other.Bool {
+			return 0
+		}
+		if !v.Bool {
+			return -1
+		}
+		return 1
+	case Number:
+		return cmp.Compare(v.Number, other.Number)
+	case String:
+		return strings.Compare(v.String, other.String)
+	case Array:
+		for i := 0; i < len(v.Array) && i < len(other.Array); i++ {
+			if c := v.Array[i].Compare(&other.Array[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(v.Array), len(other.Array))
+	case Object:
+		keys, otherKeys := v.Keys(), other.Keys()
+		for i := 0; i < len(keys) && i < len(otherKeys); i++ {
+			if c := strings.Compare(keys[i], otherKeys[i]); c != 0 {
+				return c
+			}
+			a, b := v.Object[keys[i]], other.Object[otherKeys[i]]
+			if c := a.Compare(&b); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(keys), len(otherKeys))
+	default:
+		return 0
+	}
+}
+
+// Equal reports whether both values have the same type and deeply equal contents, see EvalEqual.
+// It agrees with Compare returning 0, except that NaN is never equal to itself.
+func (v *ValueNode) Equal(other *ValueNode) bool {
+	return EvalEqual(v, other)
+}
+
+// Keys returns the keys of an object value in ascending order, nil for other types.
+// Callers producing output from an object should iterate the keys so the output does not depend on map order.
+func (v *ValueNode) Keys() []string {
+	if v.Type != Object {
+		return nil
+	}
+	keys := make([]string, 0, len(v.Object))
+	for key := range v.Object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get resolves a dot separated path of object keys and array indexes against the node.
+// An empty path returns the node itself.
+// Returns the resolved node and whether the path exists.
+func (v *ValueNode) Get(path string) (*ValueNode, bool) {
+	if path == "" {
+		return v, true
+	}
+	current := v
+	for _, key := range strings.Split(path, ".") {
+		switch current.Type {
+		case Object:
+			child, ok := current.Object[key]
+			if !ok {
+				return nil, false
+			}
+			current = &child
+		case Array:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current.Array) {
+				return nil, false
+			}
+			current = &current.Array[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// EstimatedSize returns a rough estimate in bytes of the memory held by the node and its children.
+// It is based on counting nodes and is intended for sizing caches, not exact accounting.
+func (v *ValueNode) EstimatedSize() int64 {
+	size := int64(unsafe.Sizeof(*v)) + int64(len(v.String))
+	for i := range v.Array {
+		size += v.Array[i].EstimatedSize()
+	}
+	for key, child := range v.Object {
+		size += int64(len(key)) + child.EstimatedSize()
+	}
+	return size
+}
+
 func (v *ValueNode) UnmarshalJSON(data []byte) error {
 	// Remove leading and trailing whitespace
 	data = bytes.TrimSpace(data)
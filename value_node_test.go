@@ -0,0 +1,102 @@
+package rulesengine
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// quickValue wraps a ValueNode so testing/quick can generate random values of every type
+type quickValue struct {
+	ValueNode
+}
+
+func (quickValue) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(quickValue{randomValueNode(r, 3)})
+}
+
+// randomValueNode returns a random value; small domains make equal and prefix values likely
+func randomValueNode(r *rand.Rand, depth int) ValueNode {
+	kinds := 6
+	if depth == 0 {
+		kinds = 4
+	}
+	switch DataType(r.Intn(kinds)) {
+	case Bool:
+		return ValueNode{Type: Bool, Bool: r.Intn(2) == 0}
+	case Number:
+		return ValueNode{Type: Number, Number: float64(r.Intn(5) - 2)}
+	case String:
+		return ValueNode{Type: String, String: []string{"", "a", "ab", "b"}[r.Intn(4)]}
+	case Array:
+		array := make([]ValueNode, r.Intn(3))
+		for i := range array {
+			array[i] = randomValueNode(r, depth-1)
+		}
+		return ValueNode{Type: Array, Array: array}
+	case Object:
+		object := map[string]ValueNode{}
+		for i := r.Intn(3); i > 0; i-- {
+			object[[]string{"a", "b", "c"}[r.Intn(3)]] = randomValueNode(r, depth-1)
+		}
+		return ValueNode{Type: Object, Object: object}
+	default:
+		return ValueNode{Type: Null}
+	}
+}
+
+func TestValueNodeCompare(t *testing.T) {
+	config := &quick.Config{MaxCount: 2000}
+
+	t.Run("reflexive", func(t *testing.T) {
+		reflexive := func(a quickValue) bool {
+			return a.Compare(&a.ValueNode) == 0 && a.Equal(&a.ValueNode)
+		}
+		if err := quick.Check(reflexive, config); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("antisymmetric", func(t *testing.T) {
+		antisymmetric := func(a, b quickValue) bool {
+			ab, ba := a.Compare(&b.ValueNode), b.Compare(&a.ValueNode)
+			return ab == -ba && (ab == 0) == a.Equal(&b.ValueNode)
+		}
+		if err := quick.Check(antisymmetric, config); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("transitive", func(t *testing.T) {
+		transitive := func(a, b, c quickValue) bool {
+			if a.Compare(&b.ValueNode) <= 0 && b.Compare(&c.ValueNode) <= 0 {
+				return a.Compare(&c.ValueNode) <= 0
+			}
+			return true
+		}
+		if err := quick.Check(transitive, config); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("type order", func(t *testing.T) {
+		ordered := []ValueNode{
+			{Type: Null},
+			{Type: Bool, Bool: false},
+			{Type: Bool, Bool: true},
+			{Type: Number, Number: -1},
+			{Type: Number, Number: 10},
+			{Type: String, String: "a"},
+			{Type: Array, Array: []ValueNode{{Type: Number, Number: 1}}},
+			{Type: Array, Array: []ValueNode{{Type: Number, Number: 1}, {Type: Null}}},
+			{Type: Object, Object: map[string]ValueNode{"a": {Type: Number, Number: 2}}},
+			{Type: Object, Object: map[string]ValueNode{"b": {Type: Null}}},
+		}
+		for i := 0; i+1 < len(ordered); i++ {
+			if ordered[i].Compare(&ordered[i+1]) != -1 {
+				t.Errorf("Expected %v to sort before %v", ordered[i].Raw(), ordered[i+1].Raw())
+			}
+		}
+	})
+}